@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ABIEntry is an item of a contract ABI.
+type ABIEntry struct {
+	Type            string      `json:"type"`
+	Name            string      `json:"name"`
+	Inputs          []*ABIParam `json:"inputs"`
+	Outputs         []*ABIParam `json:"outputs"`
+	StateMutability string      `json:"stateMutability"`
+	Anonymous       bool        `json:"anonymous"`
+}
+
+type ABIParam struct {
+	Name         string      `json:"name"`
+	Type         string      `json:"type"`
+	InternalType string      `json:"internalType"`
+	Components   []*ABIParam `json:"components"`
+	Indexed      bool        `json:"indexed"`
+}
+
+// hasABI reports whether abi is a whole JSON ABI, not an explorer's message like "Contract source code not verified"
+// or one cut short.
+func hasABI(abi string) bool {
+	abi = strings.TrimSpace(abi)
+
+	return strings.HasPrefix(abi, "[") && json.Valid([]byte(abi))
+}
+
+// fillABI fetches the ABI of the verified contract d with getabi when the ABI of its getsourcecode response is missing
+// or truncated, as explorers return for some contracts with sources or proxies, so abi.json is written all the same.
+// The ABI is set in the cached response, for the other uses of d in the run. A getabi failing leaves it as it is.
+func (dl *downloader) fillABI(ctx context.Context, d *deployment, rawCodes []*RawCode) error {
+	if isUnverified(rawCodes) || hasABI(rawCodes[0].Abi) || d.Source == sourceTenderly {
+		return nil
+	}
+
+	explorer, ok := blockExploers[d.Chain]
+	if !ok || explorer.api == zksyncAPI {
+		// the zkSync explorer's verifications always have their ABI
+		return nil
+	}
+
+	var abi string
+	err := queryExplorer(ctx, explorer, url.Values{"module": {"contract"}, "action": {"getabi"}, "address": {d.Address}}, &abi)
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case errors.Is(err, errBudgetExhausted):
+		return err
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "%s: warning: no ABI in the verification, and getabi failed: %s\n", d.Name, redactAPIKeys(err.Error()))
+		return nil
+	case !hasABI(abi):
+		fmt.Fprintf(os.Stderr, "%s: warning: no ABI in the verification, nor from getabi\n", d.Name)
+		return nil
+	}
+
+	rawCodes[0].Abi = abi
+	fmt.Fprintf(humanOut, "%s: no ABI in the verification, fetched it with getabi\n", d.Name)
+
+	return nil
+}
+
+func parseABI(abi string) ([]*ABIEntry, error) {
+	entries := []*ABIEntry{}
+	if err := json.Unmarshal([]byte(abi), &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// canonicalType returns the type of p as used in signatures, expanding tuples to their component types.
+func (p *ABIParam) canonicalType() string {
+	if !strings.HasPrefix(p.Type, "tuple") {
+		return p.Type
+	}
+
+	types := make([]string, 0, len(p.Components))
+	for _, c := range p.Components {
+		types = append(types, c.canonicalType())
+	}
+
+	return "(" + strings.Join(types, ",") + ")" + strings.TrimPrefix(p.Type, "tuple")
+}
+
+// signature returns the canonical signature of e, e.g. "transfer(address,uint256)".
+func (e *ABIEntry) signature() string {
+	types := make([]string, 0, len(e.Inputs))
+	for _, p := range e.Inputs {
+		types = append(types, p.canonicalType())
+	}
+
+	return e.Name + "(" + strings.Join(types, ",") + ")"
+}
+
+// selector returns the 4-byte selector of the function or error e as 0x-prefixed hex.
+func (e *ABIEntry) selector() string {
+	return "0x" + hex.EncodeToString(keccak256([]byte(e.signature()))[:4])
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var addressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+func isAddress(s string) bool {
+	return addressPattern.MatchString(s)
+}
+
+// caip2Namespace is the CAIP-2 namespace of EVM chains.
+const caip2Namespace = "eip155"
+
+// parseChainAddress parses an EIP-3770 chain-specific address like "eth:0xABC...",
+// a CAIP-10 account id like "eip155:1:0xABC..." or a block explorer URL like "https://etherscan.io/address/0xABC...".
+func parseChainAddress(s string) (chain, string, error) {
+	prefix, address, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, "", fmt.Errorf("missing chain prefix: %s", s)
+	}
+
+	switch prefix {
+	case caip2Namespace:
+		return parseCAIP10(address)
+	case "http", "https":
+		return parseExplorerURL(s)
+	}
+
+	c, ok := chainShortNames[strings.ToLower(prefix)]
+	if !ok {
+		if c, ok = chainNames[strings.ToLower(prefix)]; !ok {
+			return 0, "", fmt.Errorf("%w short name: %s", errUnknownChain, prefix)
+		}
+	}
+
+	if !isAddress(address) {
+		return 0, "", fmt.Errorf("%w: %s", errBadAddress, address)
+	}
+
+	return c, address, nil
+}
+
+// parseCAIP10 parses the "<chain id>:<address>" part of an eip155 CAIP-10 account id.
+func parseCAIP10(s string) (chain, string, error) {
+	reference, address, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, "", fmt.Errorf("invalid CAIP-10 account id: %s:%s", caip2Namespace, s)
+	}
+
+	id, err := strconv.ParseUint(reference, 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid chain id: %s", reference)
+	}
+
+	if !isAddress(address) {
+		return 0, "", fmt.Errorf("%w: %s", errBadAddress, address)
+	}
+
+	return chain(id), address, nil
+}
+
+// parseExplorerURL parses a block explorer page URL like "https://etherscan.io/address/0xABC...#code".
+func parseExplorerURL(s string) (chain, string, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return 0, "", err
+	}
+
+	host := strings.TrimPrefix(u.Hostname(), "www.")
+
+	var (
+		c     chain
+		found bool
+	)
+	for explorerChain, explorer := range blockExploers {
+		if explorer.site == host {
+			c, found = explorerChain, true
+			break
+		}
+	}
+
+	if !found {
+		return 0, "", fmt.Errorf("%w: unknown block explorer %s", errUnknownChain, host)
+	}
+
+	// e.g. /address/0xABC..., /token/0xABC...
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 || !isAddress(segments[1]) {
+		return 0, "", fmt.Errorf("no address in block explorer URL: %s", s)
+	}
+
+	return c, segments[1], nil
+}
+
+// parseChain parses a chain given as a numeric chain id, an EIP-3770 short name or a chain name.
+func parseChain(s string) (chain, error) {
+	if id, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return chain(id), nil
+	}
+
+	if c, ok := chainShortNames[strings.ToLower(s)]; ok {
+		return c, nil
+	}
+
+	if c, ok := chainNames[strings.ToLower(s)]; ok {
+		return c, nil
+	}
+
+	names := make([]string, 0, len(chainNames))
+	for name := range chainNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return 0, fmt.Errorf("%w: %s, want a chain id or one of %s", errUnknownChain, s, strings.Join(names, ", "))
+}
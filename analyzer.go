@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+const defaultAnalyzerOutput = "analysis.txt"
+
+// AnalyzerConfig is a static analyzer run in the directory of each downloaded contract, e.g. ["slither", "."].
+type AnalyzerConfig struct {
+	Command []string `json:"command"`
+	Output  string   `json:"output"`
+}
+
+// analyze runs the analyzer in dir and stores its combined output in dir.
+// Analyzers like slither exit nonzero when they report findings, so only failing to run it is an error.
+func analyze(ctx context.Context, dir string, a *AnalyzerConfig) error {
+	if len(a.Command) == 0 {
+		return errors.New("analyzer command is not configured")
+	}
+
+	output := a.Output
+	if output == "" {
+		output = defaultAnalyzerOutput
+	}
+
+	out := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, a.Command[0], a.Command[1:]...)
+	cmd.Dir = dir
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	err := cmd.Run()
+	if exitErr := (&exec.ExitError{}); err != nil && !errors.As(err, &exitErr) {
+		return fmt.Errorf("analyzer: %w", err)
+	}
+
+	return os.WriteFile(filepath.Join(dir, output), out.Bytes(), fileMode)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const artifactsDir = "artifacts"
+
+// HardhatArtifact is a Hardhat compilation artifact (hh-sol-artifact-1), as consumed by TypeChain and other JS tooling.
+type HardhatArtifact struct {
+	Format                 string          `json:"_format"`
+	ContractName           string          `json:"contractName"`
+	SourceName             string          `json:"sourceName"`
+	ABI                    json.RawMessage `json:"abi"`
+	Bytecode               string          `json:"bytecode"`
+	DeployedBytecode       string          `json:"deployedBytecode"`
+	LinkReferences         json.RawMessage `json:"linkReferences"`
+	DeployedLinkReferences json.RawMessage `json:"deployedLinkReferences"`
+}
+
+// writeHardhatArtifact compiles the sources and writes artifacts/<ContractName>.json.
+func writeHardhatArtifact(ctx context.Context, dir string, rawCode *RawCode, sourceCode *SourceCode) error {
+	output, err := compileWithOutput(ctx, rawCode.CompilerVersion, sourceCode, OutputSelection{
+		"*": {"*": {"abi", "evm.bytecode.object", "evm.bytecode.linkReferences", "evm.deployedBytecode.object", "evm.deployedBytecode.linkReferences"}},
+	})
+	if err != nil {
+		return err
+	}
+
+	sourceName, compiled := findContract(output, rawCode.ContractName)
+	if compiled == nil || compiled.EVM == nil || compiled.EVM.Bytecode == nil || compiled.EVM.DeployedBytecode == nil {
+		return fmt.Errorf("contract %s not found in compiler output", rawCode.ContractName)
+	}
+
+	artifact := &HardhatArtifact{
+		Format:                 "hh-sol-artifact-1",
+		ContractName:           rawCode.ContractName,
+		SourceName:             sourceName,
+		ABI:                    compiled.ABI,
+		Bytecode:               "0x" + compiled.EVM.Bytecode.Object,
+		DeployedBytecode:       "0x" + compiled.EVM.DeployedBytecode.Object,
+		LinkReferences:         linkReferences(compiled.EVM.Bytecode),
+		DeployedLinkReferences: linkReferences(compiled.EVM.DeployedBytecode),
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, artifactsDir), dirMode); err != nil {
+		return err
+	}
+
+	return writeJSON(filepath.Join(dir, artifactsDir, rawCode.ContractName+".json"), artifact)
+}
+
+func linkReferences(b *SolcBytecode) json.RawMessage {
+	if len(b.LinkReferences) == 0 {
+		return json.RawMessage("{}")
+	}
+
+	return b.LinkReferences
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const astDir = "ast"
+
+// writeASTs compiles the sources with AST output and saves the AST of each source file as ast/<source>.json.
+func writeASTs(ctx context.Context, dir string, rawCode *RawCode, sourceCode *SourceCode) error {
+	output, err := compileWithOutput(ctx, rawCode.CompilerVersion, sourceCode, OutputSelection{"*": {"": {"ast"}}})
+	if err != nil {
+		return err
+	}
+
+	if len(output.Sources) == 0 {
+		return errors.New("no AST in compiler output")
+	}
+
+	for path, source := range output.Sources {
+		dst := filepath.Join(dir, astDir, sourcePath(path)+".json")
+		if err := os.MkdirAll(filepath.Dir(dst), dirMode); err != nil {
+			return err
+		}
+
+		if err := writeJSON(dst, source.AST); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SolcSource is the output of a source file.
+type SolcSource struct {
+	ID  int             `json:"id"`
+	AST json.RawMessage `json:"ast"`
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+const (
+	abiFile       = "abi.json"
+	mergedABIFile = "abi.merged.json"
+	bindingsDir   = "bindings"
+)
+
+// writeABI writes the verified ABI as abi.json. Nothing is written when the explorer has no ABI for the contract.
+func writeABI(dir string, rawCode *RawCode) error {
+	if !hasABI(rawCode.Abi) {
+		return nil
+	}
+
+	return os.WriteFile(filepath.Join(dir, abiFile), []byte(rawCode.Abi+"\n"), fileMode)
+}
+
+// writeMergedABI writes abi.merged.json, the ABI of a proxy called as its implementation like Etherscan's "Read as Proxy":
+// the proxy's entries followed by the implementation's it lacks, but its constructor.
+func writeMergedABI(dir string, proxyABI, implementationABI string) error {
+	merged := []json.RawMessage{}
+	seen := map[string]bool{}
+	for i, abi := range []string{proxyABI, implementationABI} {
+		raw := []json.RawMessage{}
+		if err := json.Unmarshal([]byte(abi), &raw); err != nil {
+			return fmt.Errorf("parse ABI: %w", err)
+		}
+
+		for _, r := range raw {
+			e := &ABIEntry{}
+			if err := json.Unmarshal(r, e); err != nil {
+				return fmt.Errorf("parse ABI: %w", err)
+			}
+
+			key := e.Type + " " + e.signature()
+			if seen[key] || (i > 0 && e.Type == "constructor") {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, r)
+		}
+	}
+
+	return writeJSON(filepath.Join(dir, mergedABIFile), merged)
+}
+
+// generateGoBindings runs go-ethereum's abigen on abi.json and writes the package to bindings/<package>/<package>.go.
+func generateGoBindings(ctx context.Context, dir string, contractName string) error {
+	abiPath := filepath.Join(dir, abiFile)
+	if _, err := os.Stat(abiPath); err != nil {
+		return fmt.Errorf("no ABI to generate bindings from: %w", err)
+	}
+
+	pkg := goPackageName(contractName)
+	out := filepath.Join(dir, bindingsDir, pkg, pkg+".go")
+	if err := os.MkdirAll(filepath.Dir(out), dirMode); err != nil {
+		return err
+	}
+
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, "abigen", "--abi", abiPath, "--pkg", pkg, "--type", contractName, "--out", out)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("abigen: %w: %s", err, stderr.String())
+	}
+
+	return nil
+}
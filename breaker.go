@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// circuitBreaker stops sending requests to the explorer of a chain after threshold consecutive failures,
+// until cooldown has passed.
+type circuitBreaker struct {
+	threshold int // 0 disables the breaker
+	cooldown  time.Duration
+
+	mu        sync.Mutex // the fetch stage of the pipeline checks the breaker while the downloads record into it
+	failures  map[chain]int
+	openUntil map[chain]time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		failures:  map[chain]int{},
+		openUntil: map[chain]time.Time{},
+	}
+}
+
+// allow reports whether the explorer of c may be requested, returning when it may be otherwise.
+func (b *circuitBreaker) allow(c chain) (bool, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	until := b.openUntil[c]
+
+	return !time.Now().Before(until), until
+}
+
+// record records the outcome of a download from the explorer of c.
+// Once tripped, the next failure after the cooldown trips the breaker again.
+func (b *circuitBreaker) record(c chain, err error) {
+	if b.threshold == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !isExplorerFailure(err) {
+		delete(b.failures, c)
+		return
+	}
+
+	b.failures[c]++
+	if b.failures[c] < b.threshold {
+		return
+	}
+
+	b.openUntil[c] = time.Now().Add(b.cooldown)
+	fmt.Fprintf(os.Stderr, "warning: the explorer of %s failed %d times in a row, deferring its contracts for %s\n", c, b.failures[c], b.cooldown)
+}
+
+// isExplorerFailure reports whether err is the explorer failing to answer, rather than a failure of the contract.
+func isExplorerFailure(err error) bool {
+	var netErr net.Error
+
+	return errors.Is(err, errRateLimited) || errors.Is(err, errExplorerDown) || errors.As(err, &netErr)
+}
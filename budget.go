@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// BudgetConfig caps the explorer API calls, e.g. to share the daily quota of a free-tier key across a team.
+type BudgetConfig struct {
+	// PerRun is the most API calls of a run, none when zero.
+	PerRun int `json:"perRun,omitempty"`
+	// PerDay is the most API calls of the day (UTC) on this machine, counted across runs, none when zero.
+	PerDay int `json:"perDay,omitempty"`
+}
+
+const budgetFile = "budget.json"
+
+const (
+	budgetLockPoll  = 10 * time.Millisecond
+	budgetLockStale = 10 * time.Second // a lock file older than this was left by a run killed while holding it
+)
+
+// budgetUsage is the persisted count of the API calls of a day.
+type budgetUsage struct {
+	Day  string `json:"day"`
+	Used int    `json:"used"`
+}
+
+// requestBudget is the budget of the run's explorer requests, nil when it has none.
+var requestBudget *budget
+
+type budget struct {
+	perRun int
+	perDay int
+	path   string // budgetFile in the user cache directory, where the calls of the day are counted
+
+	mu   sync.Mutex
+	used int
+}
+
+// configureBudget sets requestBudget from bc, with the non-zero fields of override taking precedence.
+func configureBudget(bc *BudgetConfig, override *BudgetConfig) error {
+	merged := &BudgetConfig{}
+	if bc != nil {
+		*merged = *bc
+	}
+	if override != nil {
+		if override.PerRun != 0 {
+			merged.PerRun = override.PerRun
+		}
+		if override.PerDay != 0 {
+			merged.PerDay = override.PerDay
+		}
+	}
+
+	if merged.PerRun < 0 || merged.PerDay < 0 {
+		return errors.New("budget: perRun and perDay can't be negative")
+	}
+
+	if merged.PerRun == 0 && merged.PerDay == 0 {
+		requestBudget = nil
+		return nil
+	}
+
+	b := &budget{perRun: merged.PerRun, perDay: merged.PerDay}
+	if b.perDay != 0 {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return fmt.Errorf("budget: perDay needs a cache directory: %w", err)
+		}
+		b.path = filepath.Join(cacheDir, "etherscan-downloader", budgetFile)
+	}
+	if requestBudget != nil {
+		// a budget reconfigured by the flags keeps the calls already made
+		b.used = requestBudget.used
+	}
+	requestBudget = b
+
+	return nil
+}
+
+// take counts an API call, failing with errBudgetExhausted once the run's or the day's calls are used up.
+func (b *budget) take() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.perRun != 0 && b.used >= b.perRun {
+		return fmt.Errorf("%w: the %d API calls of the run are used up: raise budget.perRun in config.json or --max-requests", errBudgetExhausted, b.perRun)
+	}
+
+	if b.perDay != 0 {
+		if err := os.MkdirAll(filepath.Dir(b.path), dirMode); err != nil {
+			return err
+		}
+		unlock, err := b.lock()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		usage, err := b.load()
+		if err != nil {
+			return err
+		}
+		if usage.Used >= b.perDay {
+			return fmt.Errorf("%w: the %d API calls of %s are used up: retry tomorrow (UTC) or raise budget.perDay in config.json or --max-daily-requests", errBudgetExhausted, b.perDay, usage.Day)
+		}
+
+		usage.Used++
+		bs, err := json.Marshal(usage)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(b.path, bs); err != nil {
+			return fmt.Errorf("budget: %w", err)
+		}
+	}
+
+	b.used++
+
+	return nil
+}
+
+// exhausted reports whether the run's or the day's calls are used up, without taking one,
+// e.g. to not fetch contracts ahead of their download the budget has no call left for.
+func (b *budget) exhausted() bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.perRun != 0 && b.used >= b.perRun {
+		return true
+	}
+	if b.perDay != 0 {
+		usage, err := b.load()
+		// a count failing to load fails the take of the download instead
+		return err == nil && usage.Used >= b.perDay
+	}
+
+	return false
+}
+
+// lock takes the lock of the day's count across the runs on this machine, an exclusively created lock file
+// next to it, so concurrent runs don't lose each other's calls. It returns the function releasing it.
+func (b *budget) lock() (func(), error) {
+	path := b.path + ".lock"
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fileMode)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("budget: %w", err)
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > budgetLockStale {
+			os.Remove(path)
+			continue
+		}
+		time.Sleep(budgetLockPoll)
+	}
+}
+
+// load reads the calls of today, starting from zero on a new day.
+func (b *budget) load() (*budgetUsage, error) {
+	today := time.Now().UTC().Format("2006-01-02")
+
+	bs, err := os.ReadFile(b.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &budgetUsage{Day: today}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("budget: %w", err)
+	}
+
+	usage := &budgetUsage{}
+	if err := json.Unmarshal(bs, usage); err != nil {
+		return nil, fmt.Errorf("budget: %s: %w", b.path, err)
+	}
+	if usage.Day != today {
+		return &budgetUsage{Day: today}, nil
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxBuildFixRounds bounds the rounds of remappings added by ensureBuilds, each fixing the imports the last one left unresolved.
+const maxBuildFixRounds = 10
+
+var sourceNotFoundPattern = regexp.MustCompile(`Source "([^"]+)" not found`)
+
+// ensureBuilds compiles the tree written into dir, as forge or solc would read it with remappings.txt, instead of
+// the verified standard-json input. Imports the tree doesn't resolve, e.g. of a package written by --lib-dir or moved
+// by the download, get a remapping to the file with the longest matching path, replacing the one of the same prefix,
+// until solc builds the tree with the verified settings. The remappings added are reported and written to remappings.txt.
+// It fails when an import can't be resolved or the tree doesn't compile.
+func ensureBuilds(ctx context.Context, dir string, libDir string, d *deployment, compilerVersion string, verified *SourceCode) error {
+	if !strings.EqualFold(verified.Language, "Solidity") && verified.Language != "" {
+		return fmt.Errorf("cannot build %s sources", verified.Language)
+	}
+
+	candidates, err := treeSources(dir, libDir)
+	if err != nil {
+		return err
+	}
+
+	remappings, err := readRemappings(dir)
+	if err != nil {
+		return err
+	}
+
+	added := []string{}
+	built := false
+	for round := 0; round < maxBuildFixRounds && !built; round++ {
+		sources, missing := collectTreeSources(candidates, remappings)
+
+		if len(missing) == 0 {
+			settings, err := withRemappings(verified.Settings, remappings)
+			if err != nil {
+				return err
+			}
+
+			output, err := compileStandardJSON(ctx, compilerVersion, &SourceCode{Language: "Solidity", Sources: sources, Settings: settings})
+			if err != nil {
+				return err
+			}
+			for _, e := range output.Errors {
+				if m := sourceNotFoundPattern.FindStringSubmatch(e.FormattedMessage); e.Severity == "error" && m != nil {
+					missing = append(missing, m[1])
+				}
+			}
+			if len(missing) == 0 {
+				if err := compileErrors(output); err != nil {
+					return fmt.Errorf("the tree doesn't build: %w", err)
+				}
+				built = true
+				continue
+			}
+		}
+
+		fixes := []string{}
+		unresolved := []string{}
+		for _, imported := range missing {
+			remapping, ok := remappingFor(imported, candidates)
+			if !ok || containsString(remappings, remapping) {
+				unresolved = append(unresolved, imported)
+				continue
+			}
+			if !containsString(fixes, remapping) {
+				fixes = append(fixes, remapping)
+			}
+		}
+		if len(fixes) == 0 {
+			sort.Strings(unresolved)
+			return fmt.Errorf("the tree doesn't build: no file in %s matches the imports %s", dir, strings.Join(unresolved, ", "))
+		}
+
+		for _, fix := range fixes {
+			remappings = replaceRemapping(remappings, fix)
+		}
+		added = append(added, fixes...)
+	}
+
+	if !built {
+		return fmt.Errorf("the tree doesn't build after adding %d remappings: %s", len(added), strings.Join(added, " "))
+	}
+
+	if len(added) == 0 {
+		fmt.Fprintf(humanOut, "%s: the tree builds as written\n", d.Name)
+		return nil
+	}
+
+	for _, remapping := range added {
+		fmt.Fprintf(humanOut, "%s: builds: added remapping %s\n", d.Name, remapping)
+	}
+
+	return os.WriteFile(filepath.Join(dir, remappingsFile), []byte(strings.Join(remappings, "\n")+"\n"), fileMode)
+}
+
+// replaceRemapping returns remappings with remapping instead of those of the same prefix, which it is meant to fix.
+func replaceRemapping(remappings []string, remapping string) []string {
+	prefix, _, _ := strings.Cut(remapping, "=")
+
+	replaced := []string{}
+	for _, r := range remappings {
+		if p, _, _ := strings.Cut(r, "="); p != prefix {
+			replaced = append(replaced, r)
+		}
+	}
+
+	return append(replaced, remapping)
+}
+
+// withRemappings returns a copy of settings with remappings instead of the verified ones, keeping the other verified fields.
+func withRemappings(settings Settings, remappings []string) (Settings, error) {
+	bs, err := json.Marshal(settings)
+	if err != nil {
+		return Settings{}, err
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(bs, &fields); err != nil {
+		return Settings{}, err
+	}
+
+	delete(fields, "remappings")
+	if len(remappings) > 0 {
+		if fields["remappings"], err = json.Marshal(remappings); err != nil {
+			return Settings{}, err
+		}
+	}
+
+	if bs, err = json.Marshal(fields); err != nil {
+		return Settings{}, err
+	}
+
+	out := Settings{}
+	if err := json.Unmarshal(bs, &out); err != nil {
+		return Settings{}, err
+	}
+
+	return out, nil
+}
+
+// treeSources returns the Solidity files of dir and of the shared libDir, by their slash-separated paths relative to dir.
+func treeSources(dir string, libDir string) (map[string]string, error) {
+	files := map[string]string{}
+	for _, root := range []string{dir, libDir} {
+		if root == "" {
+			continue
+		}
+
+		err := filepath.WalkDir(root, func(p string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if root == libDir && errors.Is(err, fs.ErrNotExist) {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if entry.IsDir() || filepath.Ext(p) != ".sol" {
+				return nil
+			}
+
+			rel, err := relPath(dir, p)
+			if err != nil {
+				return err
+			}
+			files[filepath.ToSlash(rel)] = p
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// readRemappings returns the remappings of dir's remappings.txt, none when there is no such file.
+func readRemappings(dir string) ([]string, error) {
+	bs, err := os.ReadFile(filepath.Join(dir, remappingsFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	remappings := []string{}
+	for _, line := range strings.Split(string(bs), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			remappings = append(remappings, line)
+		}
+	}
+
+	return remappings, nil
+}
+
+// collectTreeSources reads the files of the tree reachable from the candidates in the contract's directory, resolving their imports with remappings,
+// and returns them as sources keyed by their source unit names along with the imports resolving to no file.
+func collectTreeSources(candidates map[string]string, remappings []string) (Sources, []string) {
+	sources := Sources{}
+	missing := map[string]bool{}
+
+	queue := []string{}
+	for name := range candidates {
+		if !strings.HasPrefix(name, "../") {
+			queue = append(queue, name)
+		}
+	}
+	sort.Strings(queue)
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if _, ok := sources[name]; ok {
+			continue
+		}
+
+		bs, err := os.ReadFile(candidates[name])
+		if err != nil {
+			missing[name] = true
+			continue
+		}
+		sources[name] = &Contract{Content: string(bs)}
+
+		for _, m := range importPattern.FindAllStringSubmatch(commentPattern.ReplaceAllString(string(bs), ""), -1) {
+			resolved := resolveImport(name, m[1], remappings)
+			if _, ok := candidates[resolved]; !ok {
+				missing[m[1]] = true
+				continue
+			}
+			queue = append(queue, resolved)
+		}
+	}
+
+	names := make([]string, 0, len(missing))
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return sources, names
+}
+
+// remappingFor returns the remapping resolving the direct import imported to the candidate sharing the longest
+// trailing path with it, e.g. "@openzeppelin/=lib/openzeppelin-contracts/" for "@openzeppelin/contracts/access/Ownable.sol"
+// and lib/openzeppelin-contracts/contracts/access/Ownable.sol. Relative imports can't be remapped.
+func remappingFor(imported string, candidates map[string]string) (string, bool) {
+	if strings.HasPrefix(imported, "./") || strings.HasPrefix(imported, "../") {
+		return "", false
+	}
+
+	parts := strings.Split(path.Clean(imported), "/")
+
+	best, bestLen := "", 0
+	for name := range candidates {
+		segments := strings.Split(name, "/")
+		n := 0
+		for n < len(parts) && n < len(segments) && parts[len(parts)-1-n] == segments[len(segments)-1-n] {
+			n++
+		}
+		if n == 0 || n < bestLen || (n == bestLen && name > best) {
+			continue
+		}
+		best, bestLen = name, n
+	}
+	// a prefix of the import is left to remap, at least its first directory
+	if bestLen == len(parts) {
+		bestLen--
+	}
+	if best == "" || bestLen == 0 {
+		return "", false
+	}
+
+	segments := strings.Split(best, "/")
+	from := strings.Join(parts[:len(parts)-bestLen], "/") + "/"
+	to := strings.Join(segments[:len(segments)-bestLen], "/")
+	if to != "" {
+		to += "/"
+	}
+
+	return from + "=" + to, true
+}
+
+// containsString reports whether s is one of ss.
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+
+	return false
+}
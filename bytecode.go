@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type bytecodeMatch string
+
+const (
+	exactMatch       bytecodeMatch = "exact"
+	partialMatch     bytecodeMatch = "partial"
+	bytecodeMismatch bytecodeMatch = "mismatch"
+)
+
+// verifyBytecode compiles the sources of contractName and compares the runtime bytecode with the code deployed at d.
+// A partial match means the code only differs in the metadata hash appended by solc.
+func verifyBytecode(ctx context.Context, d *deployment, compilerVersion string, contractName string, input *SourceCode) (bytecodeMatch, error) {
+	explorer, ok := blockExploers[d.Chain]
+	if !ok {
+		return "", unsupportedChain(d.Chain)
+	}
+
+	output, err := compileWithOutput(ctx, compilerVersion, input, OutputSelection{
+		"*": {"*": {"evm.deployedBytecode.object", "evm.deployedBytecode.immutableReferences"}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	_, compiled := findContract(output, contractName)
+	if compiled == nil || compiled.EVM == nil || compiled.EVM.DeployedBytecode == nil {
+		return "", fmt.Errorf("contract %s not found in compiler output", contractName)
+	}
+
+	onChainHex, err := getCode(ctx, explorer, d.Address)
+	if err != nil {
+		return "", err
+	}
+
+	onChain, err := decodeHex(onChainHex)
+	if err != nil {
+		return "", fmt.Errorf("on-chain code: %w", err)
+	}
+
+	if len(onChain) == 0 {
+		return "", errors.New("no code at address")
+	}
+
+	local, err := decodeHex(compiled.EVM.DeployedBytecode.Object)
+	if err != nil {
+		return "", fmt.Errorf("compiled code: %w", err)
+	}
+
+	// immutables are zero in the compiled code and filled in at deployment
+	for _, spans := range compiled.EVM.DeployedBytecode.ImmutableReferences {
+		for _, span := range spans {
+			if span.Start+span.Length <= len(onChain) {
+				copy(onChain[span.Start:span.Start+span.Length], make([]byte, span.Length))
+			}
+		}
+	}
+
+	switch {
+	case bytes.Equal(onChain, local):
+		return exactMatch, nil
+	case bytes.Equal(stripMetadata(onChain), stripMetadata(local)):
+		return partialMatch, nil
+	}
+
+	return bytecodeMismatch, nil
+}
+
+// findContract returns the compiled contract named name, which may be qualified by its source file ("path:Name"),
+// along with the source file it is defined in.
+func findContract(output *SolcOutput, name string) (string, *SolcContract) {
+	file, contractName, qualified := strings.Cut(name, ":")
+	if !qualified {
+		contractName = name
+	}
+
+	for path, contracts := range output.Contracts {
+		if qualified && path != file {
+			continue
+		}
+
+		if c, ok := contracts[contractName]; ok {
+			return path, c
+		}
+	}
+
+	return "", nil
+}
+
+// stripMetadata removes the CBOR encoded metadata solc appends to the bytecode.
+// Its length is stored in the last two bytes.
+func stripMetadata(code []byte) []byte {
+	if len(code) < 2 {
+		return code
+	}
+
+	n := int(code[len(code)-2])<<8 | int(code[len(code)-1])
+	if n+2 > len(code) {
+		return code
+	}
+
+	return code[:len(code)-n-2]
+}
+
+func decodeHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(s, "0x"))
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheConfig selects where serve caches the verified sources it fetched.
+type CacheConfig struct {
+	Backend string `json:"backend,omitempty"` // memory (default), fs or redis
+	Dir     string `json:"dir,omitempty"`     // directory of the fs backend, <user cache dir>/etherscan-downloader/sources by default
+	URL     string `json:"url,omitempty"`     // redis://[[user]:password@]host[:port][/db] of the redis backend
+	TTL     string `json:"ttl,omitempty"`     // how long entries are kept, forever by default
+}
+
+func (c *CacheConfig) validate() error {
+	if c == nil {
+		return nil
+	}
+
+	if _, err := c.ttl(); err != nil {
+		return err
+	}
+
+	switch c.Backend {
+	case "", "memory", "fs":
+	case "redis":
+		if c.URL == "" {
+			return errors.New("cache.url: the redis backend needs the URL of the server")
+		}
+		if _, err := url.Parse(c.URL); err != nil {
+			return fmt.Errorf("cache.url: %w", err)
+		}
+	default:
+		return fmt.Errorf("cache.backend: unknown cache backend: %q, want memory, fs or redis", c.Backend)
+	}
+
+	return nil
+}
+
+func (c *CacheConfig) ttl() (time.Duration, error) {
+	if c.TTL == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(c.TTL)
+	if err != nil {
+		return 0, fmt.Errorf("cache.ttl: %w", err)
+	}
+
+	return d, nil
+}
+
+// open returns the configured cache, the in-memory one when c is nil.
+func (c *CacheConfig) open() (cache, error) {
+	if c == nil {
+		return newMemoryCache(0), nil
+	}
+
+	ttl, err := c.ttl()
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.Backend {
+	case "fs":
+		dir := c.Dir
+		if dir == "" {
+			cacheDir, err := os.UserCacheDir()
+			if err != nil {
+				return nil, err
+			}
+			dir = filepath.Join(cacheDir, "etherscan-downloader", "sources")
+		}
+		return &fileCache{dir: dir, ttl: ttl}, nil
+	case "redis":
+		return newRedisCache(c.URL, ttl)
+	}
+
+	return newMemoryCache(ttl), nil
+}
+
+// cache stores byte values by key. A miss is not an error.
+// The fs and redis backends let the replicas of a server share what they fetched.
+type cache interface {
+	get(key string) ([]byte, bool, error)
+	set(key string, value []byte) error
+}
+
+// memoryCache is a cache in the memory of the process.
+type memoryCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time // zero when the entry is kept forever
+}
+
+func newMemoryCache(ttl time.Duration) *memoryCache {
+	return &memoryCache{ttl: ttl, entries: map[string]*memoryEntry{}}
+}
+
+func (c *memoryCache) get(key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+
+	return e.value, true, nil
+}
+
+func (c *memoryCache) set(key string, value []byte) error {
+	e := &memoryEntry{value: value}
+	if c.ttl > 0 {
+		e.expires = time.Now().Add(c.ttl)
+	}
+
+	c.mu.Lock()
+	c.entries[key] = e
+	c.mu.Unlock()
+
+	return nil
+}
+
+// fileCache is a cache of one file per key in dir, e.g. on a volume shared by replicas.
+type fileCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// path returns the file of key, named by its SHA-256 as keys may contain any character.
+func (c *fileCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	hash := hex.EncodeToString(sum[:])
+
+	return filepath.Join(c.dir, hash[:2], hash)
+}
+
+func (c *fileCache) get(key string) ([]byte, bool, error) {
+	path := c.path(key)
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if c.ttl > 0 && time.Since(info.ModTime()) > c.ttl {
+		return nil, false, nil
+	}
+
+	bs, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return bs, true, nil
+}
+
+func (c *fileCache) set(key string, value []byte) error {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), dirMode); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, value)
+}
+
+// redisTimeout bounds connecting to the redis server and each command.
+const redisTimeout = 5 * time.Second
+
+// redisKeyPrefix namespaces the keys of the tool in a shared redis database.
+const redisKeyPrefix = "etherscan-downloader:"
+
+// redisPoolSize is the most connections a redisCache opens, so concurrent requests of a server don't wait on each other.
+const redisPoolSize = 8
+
+// redisCache is a cache in a redis server, spoken to over RESP with a small pool of connections,
+// a connection being dropped after an error.
+type redisCache struct {
+	addr     string
+	username string
+	password string
+	db       int
+	ttl      time.Duration
+
+	slots chan struct{}   // a token per connection in use
+	idle  chan *redisConn // the connections opened and not in use
+}
+
+// redisConn is a connection to the redis server, authenticated and on the database of the URL.
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func newRedisCache(rawURL string, ttl time.Duration) (*redisCache, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("cache.url: %w", err)
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("cache.url: unsupported scheme: %q, want redis", u.Scheme)
+	}
+
+	c := &redisCache{addr: u.Host, ttl: ttl, slots: make(chan struct{}, redisPoolSize), idle: make(chan *redisConn, redisPoolSize)}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("cache.url: bad database: %q", db)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *redisCache) get(key string) ([]byte, bool, error) {
+	reply, err := c.do("GET", redisKeyPrefix+key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+
+	return reply, true, nil
+}
+
+func (c *redisCache) set(key string, value []byte) error {
+	args := []string{"SET", redisKeyPrefix + key, string(value)}
+	if c.ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(c.ttl.Milliseconds(), 10))
+	}
+
+	_, err := c.do(args...)
+
+	return err
+}
+
+// do sends the command args on an idle connection, or a new one while the pool isn't full, and returns its reply,
+// nil for a nil bulk string.
+func (c *redisCache) do(args ...string) ([]byte, error) {
+	c.slots <- struct{}{}
+	defer func() { <-c.slots }()
+
+	var rc *redisConn
+	select {
+	case rc = <-c.idle:
+	default:
+		var err error
+		if rc, err = c.connect(); err != nil {
+			return nil, fmt.Errorf("redis %s: %w", c.addr, err)
+		}
+	}
+
+	reply, err := rc.roundTrip(args...)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// the state of the connection is unknown after e.g. a timeout
+		rc.conn.Close()
+	} else {
+		c.idle <- rc
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis %s: %w", args[0], err)
+	}
+
+	return reply, nil
+}
+
+// connect opens a connection, authenticated as the user of the URL, with ACLs, or with its password alone.
+func (c *redisCache) connect() (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", c.addr, redisTimeout)
+	if err != nil {
+		return nil, err
+	}
+	rc := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+
+	setup := [][]string{}
+	switch {
+	case c.username != "":
+		setup = append(setup, []string{"AUTH", c.username, c.password})
+	case c.password != "":
+		setup = append(setup, []string{"AUTH", c.password})
+	}
+	if c.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.db)})
+	}
+	for _, args := range setup {
+		if _, err := rc.roundTrip(args...); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s: %w", args[0], err)
+		}
+	}
+
+	return rc, nil
+}
+
+// redisError is an error reply of the server.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+func (c *redisConn) roundTrip(args ...string) ([]byte, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("bad reply: %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		bs := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, bs); err != nil {
+			return nil, err
+		}
+		return bs[:n], nil
+	}
+
+	return nil, fmt.Errorf("unexpected reply: %q", line)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedisRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		reply   string
+		want    []byte
+		wantErr bool
+	}{
+		{"simple string", "+OK\r\n", []byte("OK"), false},
+		{"integer", ":1\r\n", []byte("1"), false},
+		{"bulk string", "$5\r\nhello\r\n", []byte("hello"), false},
+		{"bulk string with a line break", "$7\r\nhel\r\nlo\r\n", []byte("hel\r\nlo"), false},
+		{"empty bulk string", "$0\r\n\r\n", []byte{}, false},
+		{"nil bulk string", "$-1\r\n", nil, false},
+		{"error", "-ERR unknown command\r\n", nil, true},
+		{"array", "*0\r\n", nil, true},
+		{"bad length", "$x\r\n", nil, true},
+		{"truncated", "$5\r\nhel", nil, true},
+	} {
+		client, server := net.Pipe()
+		c := &redisConn{conn: client, r: bufio.NewReader(client)}
+
+		request := make(chan string, 1)
+		go func() {
+			defer server.Close()
+			// the request of GET key, then the reply
+			bs := make([]byte, len("*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n"))
+			if _, err := io.ReadFull(server, bs); err != nil {
+				request <- err.Error()
+				return
+			}
+			request <- string(bs)
+			io.WriteString(server, tt.reply)
+		}()
+
+		got, err := c.roundTrip("GET", "key")
+		client.Close()
+
+		if r := <-request; r != "*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n" {
+			t.Errorf("%s: request %q", tt.name, r)
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want one: %t", tt.name, err, tt.wantErr)
+			continue
+		}
+		if string(got) != string(tt.want) || (got == nil) != (tt.want == nil) {
+			t.Errorf("%s: reply %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRedisErrorReply(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		io.ReadFull(server, make([]byte, len("*1\r\n$4\r\nPING\r\n")))
+		io.WriteString(server, "-WRONGPASS invalid password\r\n")
+	}()
+
+	c := &redisConn{conn: client, r: bufio.NewReader(client)}
+	_, err := c.roundTrip("PING")
+
+	var redisErr redisError
+	if !errors.As(err, &redisErr) || string(redisErr) != "WRONGPASS invalid password" {
+		t.Fatalf("roundTrip: %v, want the server's error", err)
+	}
+}
+
+// redisCommands serves a connection, replying OK to AUTH and SELECT and nil to the rest, and sends the commands it reads.
+func redisCommands(conn net.Conn, commands chan<- string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+		args := []string{}
+		for i := 0; i < n; i++ {
+			r.ReadString('\n')
+			arg, _ := r.ReadString('\n')
+			args = append(args, strings.TrimSpace(arg))
+		}
+		commands <- strings.Join(args, " ")
+		if args[0] == "AUTH" || args[0] == "SELECT" {
+			io.WriteString(conn, "+OK\r\n")
+		} else {
+			io.WriteString(conn, "$-1\r\n")
+		}
+	}
+}
+
+func TestRedisAuth(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer l.Close()
+
+	accepted := make(chan struct{}, 10)
+	commands := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- struct{}{}
+			go redisCommands(conn, commands)
+		}
+	}()
+
+	for _, tt := range []struct {
+		userinfo string
+		auth     string
+	}{
+		{"alice:secret@", "AUTH alice secret"},
+		{":secret@", "AUTH secret"},
+	} {
+		c, err := newRedisCache("redis://"+tt.userinfo+l.Addr().String()+"/2", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, ok, err := c.get("key"); err != nil || ok {
+				t.Fatalf("%s: get: %t, %v", tt.userinfo, ok, err)
+			}
+		}
+
+		want := []string{tt.auth, "SELECT 2", "GET etherscan-downloader:key", "GET etherscan-downloader:key"}
+		for _, w := range want {
+			select {
+			case got := <-commands:
+				if got != w {
+					t.Errorf("%s: sent %q, want %q", tt.userinfo, got, w)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("%s: no command, want %q", tt.userinfo, w)
+			}
+		}
+		// the second get reuses the connection
+		if n := len(accepted); n != 1 {
+			t.Errorf("%s: %d connections, want 1", tt.userinfo, n)
+		}
+		<-accepted
+	}
+}
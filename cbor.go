@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+)
+
+// decodeMetadataCBOR decodes the CBOR map solc appends to the runtime bytecode,
+// e.g. {"ipfs": <multihash>, "solc": <version>}. Only the types solc emits are supported.
+func decodeMetadataCBOR(bs []byte) (map[string]interface{}, error) {
+	d := &cborDecoder{bs: bs}
+	v, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("cbor: metadata is not a map")
+	}
+
+	return m, nil
+}
+
+type cborDecoder struct {
+	bs  []byte
+	pos int
+}
+
+func (d *cborDecoder) byte() (byte, error) {
+	if d.pos >= len(d.bs) {
+		return 0, errors.New("cbor: unexpected end of input")
+	}
+
+	b := d.bs[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *cborDecoder) length(info byte) (int, error) {
+	switch {
+	case info < 24:
+		return int(info), nil
+	case info <= 27:
+		n := 1 << (info - 24)
+		if d.pos+n > len(d.bs) {
+			return 0, errors.New("cbor: unexpected end of input")
+		}
+		v := new(big.Int).SetBytes(d.bs[d.pos : d.pos+n])
+		d.pos += n
+		if !v.IsInt64() || v.Int64() > int64(len(d.bs)) {
+			return 0, errors.New("cbor: length out of range")
+		}
+		return int(v.Int64()), nil
+	}
+
+	return 0, errors.New("cbor: indefinite lengths are not supported")
+}
+
+func (d *cborDecoder) value() (interface{}, error) {
+	b, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	major, info := b>>5, b&0x1f
+	switch major {
+	case 0: // unsigned integer
+		return d.length(info)
+	case 2, 3: // byte string, text string
+		n, err := d.length(info)
+		if err != nil {
+			return nil, err
+		}
+		if d.pos+n > len(d.bs) {
+			return nil, errors.New("cbor: unexpected end of input")
+		}
+		v := d.bs[d.pos : d.pos+n]
+		d.pos += n
+		if major == 3 {
+			return string(v), nil
+		}
+		return v, nil
+	case 5: // map
+		n, err := d.length(info)
+		if err != nil {
+			return nil, err
+		}
+		m := map[string]interface{}{}
+		for i := 0; i < n; i++ {
+			k, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, errors.New("cbor: map key is not a string")
+			}
+			if m[key], err = d.value(); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case 7: // simple values
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22:
+			return nil, nil
+		}
+	}
+
+	return nil, errors.New("cbor: unsupported type")
+}
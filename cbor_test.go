@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestDecodeMetadataCBOR(t *testing.T) {
+	// the metadata solc 0.8.19 appends, without its length suffix: {"ipfs": <multihash>, "solc": 0.8.19}
+	multihash := "1220" + strings.Repeat("ab", 32)
+	bs, _ := hex.DecodeString("a2" + "6469706673" + "5822" + multihash + "64736f6c63" + "43000813")
+
+	m, err := decodeMetadataCBOR(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := m["ipfs"].([]byte); hex.EncodeToString(got) != multihash {
+		t.Errorf("ipfs = %x, want %s", got, multihash)
+	}
+	if got, _ := m["solc"].([]byte); !bytes.Equal(got, []byte{0, 8, 19}) {
+		t.Errorf("solc = %x, want 000813", got)
+	}
+
+	// {"bzzr0": <hash>, "experimental": true} of older compilers
+	bs, _ = hex.DecodeString("a2" + "65627a7a7230" + "5820" + strings.Repeat("cd", 32) + "6c6578706572696d656e74616c" + "f5")
+	if m, err = decodeMetadataCBOR(bs); err != nil {
+		t.Fatal(err)
+	}
+	if m["experimental"] != true || len(m["bzzr0"].([]byte)) != 32 {
+		t.Errorf("decoded %v", m)
+	}
+}
+
+func TestDecodeMetadataCBORErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		hex  string
+	}{
+		{"empty", ""},
+		{"not a map", "01"},
+		{"truncated map", "a2" + "6469706673"},
+		{"truncated string", "a1" + "6469706673" + "5822" + "1220"},
+		{"key not a string", "a1" + "01" + "02"},
+		{"indefinite length", "bf" + "ff"},
+		{"length beyond the input", "a1" + "6469706673" + "5bffffffffffffffff"},
+		{"unsupported type", "a1" + "6469706673" + "20"},
+	} {
+		bs, _ := hex.DecodeString(tt.hex)
+		if m, err := decodeMetadataCBOR(bs); err == nil {
+			t.Errorf("%s: decoded %v, want an error", tt.name, m)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+const (
+	ethereum  chain = 1
+	polygon   chain = 137
+	arbitrum  chain = 42161
+	zkSyncEra chain = 324
+	avalanche chain = 43114
+
+	// testnets
+	sepolia         chain = 11155111
+	holesky         chain = 17000
+	polygonAmoy     chain = 80002
+	arbitrumSepolia chain = 421614
+	baseSepolia     chain = 84532
+	avalancheFuji   chain = 43113
+)
+
+var chainShortNames = map[string]chain{
+	"eth":         ethereum,
+	"matic":       polygon,
+	"arb1":        arbitrum,
+	"zksync":      zkSyncEra,
+	"avax":        avalanche,
+	"sep":         sepolia,
+	"holesky":     holesky,
+	"polygonamoy": polygonAmoy,
+	"arb-sep":     arbitrumSepolia,
+	"basesep":     baseSepolia,
+	"fuji":        avalancheFuji,
+}
+
+// chainNames are the names chains can be given by besides their short names, e.g. in config.json.
+var chainNames = map[string]chain{
+	"ethereum":         ethereum,
+	"polygon":          polygon,
+	"arbitrum":         arbitrum,
+	"zksync-era":       zkSyncEra,
+	"avalanche":        avalanche,
+	"sepolia":          sepolia,
+	"holesky":          holesky,
+	"polygon-amoy":     polygonAmoy,
+	"arbitrum-sepolia": arbitrumSepolia,
+	"base-sepolia":     baseSepolia,
+	"avalanche-fuji":   avalancheFuji,
+}
+
+var blockExploers = map[chain]blockExplorer{
+	ethereum: {endpoint: "https://api.etherscan.io/", site: "etherscan.io", apiKeyEnv: "ETHERSCAN_APIKEY", apiKey: os.Getenv("ETHERSCAN_APIKEY")},
+	polygon:  {endpoint: "https://api.polygonscan.com/", site: "polygonscan.com", apiKeyEnv: "POLYGONSCAN_APIKEY", apiKey: os.Getenv("POLYGONSCAN_APIKEY")},
+	arbitrum: {endpoint: "https://api.arbiscan.io/", site: "arbiscan.io", apiKeyEnv: "ARBISCAN_APIKEY", apiKey: os.Getenv("ARBISCAN_APIKEY")},
+
+	// the zkSync Era explorer has its own API, which takes no key
+	zkSyncEra: {endpoint: "https://zksync2-mainnet-explorer.zksync.io/", site: "explorer.zksync.io", api: zksyncAPI, rpc: "https://mainnet.era.zksync.io"},
+
+	// Snowtrace moved to Routescan's API, shared with its other chains, where the key is optional
+	avalanche:     {endpoint: routescanEndpoint(routescanMainnet, avalanche), site: "snowtrace.io", api: routescanAPI, rate: routescanRate, apiKeyEnv: "ROUTESCAN_APIKEY", apiKey: os.Getenv("ROUTESCAN_APIKEY")},
+	avalancheFuji: {endpoint: routescanEndpoint(routescanTestnet, avalancheFuji), site: "testnet.snowtrace.io", api: routescanAPI, rate: routescanRate, apiKeyEnv: "ROUTESCAN_APIKEY", apiKey: os.Getenv("ROUTESCAN_APIKEY")},
+
+	// testnet explorers take the key of their mainnet explorer
+	sepolia:         {endpoint: "https://api-sepolia.etherscan.io/", site: "sepolia.etherscan.io", apiKeyEnv: "ETHERSCAN_APIKEY", apiKey: os.Getenv("ETHERSCAN_APIKEY")},
+	holesky:         {endpoint: "https://api-holesky.etherscan.io/", site: "holesky.etherscan.io", apiKeyEnv: "ETHERSCAN_APIKEY", apiKey: os.Getenv("ETHERSCAN_APIKEY")},
+	polygonAmoy:     {endpoint: "https://api-amoy.polygonscan.com/", site: "amoy.polygonscan.com", apiKeyEnv: "POLYGONSCAN_APIKEY", apiKey: os.Getenv("POLYGONSCAN_APIKEY")},
+	arbitrumSepolia: {endpoint: "https://api-sepolia.arbiscan.io/", site: "sepolia.arbiscan.io", apiKeyEnv: "ARBISCAN_APIKEY", apiKey: os.Getenv("ARBISCAN_APIKEY")},
+	baseSepolia:     {endpoint: "https://api-sepolia.basescan.org/", site: "sepolia.basescan.org", apiKeyEnv: "BASESCAN_APIKEY", apiKey: os.Getenv("BASESCAN_APIKEY")},
+}
+
+type chain uint
+
+type blockExplorer struct {
+	endpoint  string
+	site      string // host of the explorer's web UI
+	apiKeyEnv string // environment variable apiKey is read from
+	apiKey    string
+	keychain  string  // the OS keychain account its key is looked up from by key when apiKey is empty, if logged in
+	rate      float64 // requests per second allowed with the key, freeTierRate when 0
+	inFlight  int     // maximum concurrent requests, unlimited when 0
+	rpc       string  // JSON-RPC endpoint of a node of the chain, if any
+	api       string  // zksyncAPI for the zkSync Era explorer's API, routescanAPI for Routescan's, empty for Etherscan's
+	keyIn     string  // where apiKey is sent: apiKeyInHeader or apiKeyInBody, in the apikey query parameter when empty
+	keyHeader string  // the header of apiKeyInHeader, X-API-Key when empty
+}
+
+// host returns the host of the explorer's API.
+func (e blockExplorer) host() string {
+	u, err := url.Parse(e.endpoint)
+	if err != nil {
+		return ""
+	}
+
+	return u.Host
+}
+
+// UnmarshalJSON accepts a chain id, or a chain name or short name as a string.
+func (c *chain) UnmarshalJSON(bs []byte) error {
+	var id uint
+	if err := json.Unmarshal(bs, &id); err == nil {
+		*c = chain(id)
+		return nil
+	}
+
+	var name string
+	if err := json.Unmarshal(bs, &name); err != nil {
+		return fmt.Errorf("chain must be an id or a name: %s", bs)
+	}
+
+	parsed, err := parseChain(name)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+
+	return nil
+}
+
+// String returns the EIP-3770 short name of c, or its id when it has none.
+func (c chain) String() string {
+	for name, id := range chainShortNames {
+		if id == c {
+			return name
+		}
+	}
+
+	return strconv.FormatUint(uint64(c), 10)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	chainListURL = "https://chainid.network/chains.json"
+
+	// chainListMaxAge is how long the cached chain list is used before it is fetched again.
+	chainListMaxAge = 7 * 24 * time.Hour
+)
+
+// chainListEntry is a chain of the chainid.network registry.
+type chainListEntry struct {
+	Name      string `json:"name"`
+	ShortName string `json:"shortName"`
+	ChainID   chain  `json:"chainId"`
+	Explorers []struct {
+		Name     string `json:"name"`
+		URL      string `json:"url"`
+		Standard string `json:"standard"`
+	} `json:"explorers"`
+}
+
+// loadChainList returns the chainid.network registry, cached in the user cache directory.
+func loadChainList(ctx context.Context) ([]*chainListEntry, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(cacheDir, "etherscan-downloader", "chains.json")
+
+	bs, err := os.ReadFile(path)
+	if info, statErr := os.Stat(path); err != nil || statErr != nil || time.Since(info.ModTime()) > chainListMaxAge {
+		fetched, fetchErr := fetch(ctx, chainListURL)
+		switch {
+		case fetchErr == nil:
+			bs = fetched
+			if err := os.MkdirAll(filepath.Dir(path), dirMode); err != nil {
+				return nil, err
+			}
+			if err := writeFileAtomic(path, bs); err != nil {
+				return nil, err
+			}
+		case err != nil:
+			return nil, fmt.Errorf("fetch chain list: %w", fetchErr)
+		}
+		// a stale cache is better than nothing when the registry is unreachable
+	}
+
+	entries := []*chainListEntry{}
+	if err := json.Unmarshal(bs, &entries); err != nil {
+		return nil, fmt.Errorf("decode chain list: %w", err)
+	}
+
+	return entries, nil
+}
+
+// candidateEndpoint guesses the Etherscan-compatible API endpoint of an explorer's web UI:
+// the api subdomain of Etherscan-family explorers, and the UI itself for the others, e.g. Blockscout.
+func candidateEndpoint(explorerURL string) string {
+	u, err := url.Parse(explorerURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	host := u.Host
+	if labels := strings.Split(host, "."); len(labels) >= 2 && strings.HasSuffix(labels[len(labels)-2], "scan") && !strings.Contains(host, "blockscout") {
+		if len(labels) == 2 {
+			host = "api." + host
+		} else {
+			// e.g. optimistic.etherscan.io -> api-optimistic.etherscan.io
+			host = "api-" + host
+		}
+		return u.Scheme + "://" + host + "/"
+	}
+
+	return strings.TrimSuffix(explorerURL, "/") + "/"
+}
+
+// resolveUnknownChains registers explorers for the chains of ds without one, looked up in the chainid.network registry.
+// The looked-up explorer is confirmed, or overridden, at a prompt when stdin is a terminal.
+func resolveUnknownChains(ctx context.Context, ds []*deployment) error {
+	unknown := []chain{}
+	seen := map[chain]bool{}
+	for _, d := range ds {
+		if _, ok := blockExploers[d.Chain]; ok || seen[d.Chain] || d.Source == sourceTenderly {
+			// the contracts of a Tenderly project need no explorer
+			continue
+		}
+		seen[d.Chain] = true
+		unknown = append(unknown, d.Chain)
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	entries, err := loadChainList(ctx)
+	if err != nil {
+		return err
+	}
+
+	byID := map[chain]*chainListEntry{}
+	for _, e := range entries {
+		byID[e.ChainID] = e
+	}
+
+	interactive := isTerminal(os.Stdin)
+	p := &prompter{r: bufio.NewReader(os.Stdin), w: os.Stderr}
+
+	for _, ch := range unknown {
+		e, ok := byID[ch]
+		if !ok {
+			return unsupportedChain(ch)
+		}
+
+		endpoint := ""
+		site := ""
+		if len(e.Explorers) > 0 {
+			endpoint = candidateEndpoint(e.Explorers[0].URL)
+			if u, err := url.Parse(e.Explorers[0].URL); err == nil {
+				site = u.Host
+			}
+		}
+
+		if !interactive {
+			return fmt.Errorf("%w, chainid.network lists it as %s: set its endpoint in config.json, e.g. \"explorers\": {\"%d\": {\"endpoint\": %q}}", unsupportedChain(ch), e.Name, ch, endpoint)
+		}
+
+		fmt.Fprintf(os.Stderr, "chain %d is %s on chainid.network\n", ch, e.Name)
+		endpoint, err := p.ask("explorer API endpoint", endpoint)
+		if err != nil {
+			return err
+		}
+		if endpoint == "" {
+			return unsupportedChain(ch)
+		}
+
+		apiKeyEnv := strings.ToUpper(strings.NewReplacer("-", "_", " ", "_").Replace(e.ShortName)) + "_APIKEY"
+		blockExploers[ch] = blockExplorer{endpoint: endpoint, site: firstNonEmpty(site, e.ShortName), apiKeyEnv: apiKeyEnv, apiKey: os.Getenv(apiKeyEnv)}
+		if _, ok := chainShortNames[e.ShortName]; !ok && e.ShortName != "" {
+			chainShortNames[e.ShortName] = ch
+		}
+
+		fmt.Fprintf(os.Stderr, "using %s, add \"explorers\": {\"%d\": {\"endpoint\": %q, \"apiKeyEnv\": %q}} to config.json to skip this next time\n", endpoint, ch, endpoint, apiKeyEnv)
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CheckReport is the summary printed by check.
+type CheckReport struct {
+	Total     int            `json:"total"`
+	Drifted   int            `json:"drifted"`
+	Failed    int            `json:"failed"`
+	Contracts []*CheckResult `json:"contracts"`
+}
+
+// CheckResult is how the sources verified for a contract compare with those expected.
+type CheckResult struct {
+	Name      string          `json:"name"`
+	ChainID   chain           `json:"chainId"`
+	Address   string          `json:"address"`
+	Status    string          `json:"status"` // ok, drift or error
+	Changes   []*fileChange   `json:"changes,omitempty"`
+	Integrity *CheckIntegrity `json:"integrity,omitempty"` // with --lock
+	Error     string          `json:"error,omitempty"`
+}
+
+// CheckIntegrity is the integrity expected of the verified sources, pinned or recorded, and theirs.
+type CheckIntegrity struct {
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// checkCommand compares the sources verified on the explorer with the local tree, or, with --lock, with the integrity
+// pinned in config.json or recorded in PROVENANCE.json, printing a JSON summary of the differences and failing
+// with the drift exit code when any contract differs, e.g. for a nightly CI job guarding vendored contracts.
+func checkCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	lock := fs.Bool("lock", false, "compare the integrity of the verified sources with the pinned or recorded one instead of the local tree")
+	against := fs.String("against", "", "compare the sources committed at this git ref instead of the working tree")
+	output := fs.String("output", "json", "output format: json for a summary on stdout, or text for a line per file that differs")
+
+	return func(ctx context.Context, args []string) error {
+		if *output != "json" && *output != "text" {
+			return &configError{fmt.Errorf("unknown output format %q, want json or text", *output)}
+		}
+		if *lock && *against != "" {
+			return &configError{errors.New("--lock compares integrities, not trees: it takes no --against")}
+		}
+
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		deployments, err := c.deployments(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		report := &CheckReport{Total: len(deployments), Contracts: []*CheckResult{}}
+		var firstErr error
+		for _, d := range deployments {
+			r := &CheckResult{Name: d.Name, ChainID: d.Chain, Address: d.Address, Status: "ok"}
+
+			if *lock {
+				err = checkLock(ctx, d, d.dir(c.ContractDir), r)
+			} else {
+				err = checkTree(ctx, c, d, *against, r)
+			}
+
+			switch {
+			case err != nil:
+				r.Status, r.Error = "error", redactAPIKeys(err.Error())
+				report.Failed++
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", d.Name, err)
+				}
+			case r.Status == "drift":
+				report.Drifted++
+			}
+			report.Contracts = append(report.Contracts, r)
+
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+		}
+
+		if *output == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				return err
+			}
+		} else {
+			printCheck(report)
+		}
+
+		switch {
+		case report.Drifted > 0:
+			return fmt.Errorf("%w: %d of %d contracts", errDrift, report.Drifted, report.Total)
+		case report.Failed > 0:
+			return fmt.Errorf("%d of %d contracts failed to check, the first: %w", report.Failed, report.Total, firstErr)
+		}
+
+		return nil
+	}
+}
+
+// checkTree compares the verified sources of d with its downloaded sources, committed at the git ref against if set.
+func checkTree(ctx context.Context, c *Config, d *deployment, against string, r *CheckResult) error {
+	dir := d.dir(c.ContractDir)
+	if against != "" {
+		tree, cleanup, err := checkoutGitTree(ctx, against, dir)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		dir = tree
+	}
+
+	switch localStatus(dir) {
+	case "missing":
+		r.Status = "drift"
+		r.Changes = []*fileChange{{Status: "A", Path: metadataFile}}
+		return nil
+	case "unverified":
+		rawCodes, err := fetchRawCode(ctx, d)
+		if err != nil {
+			return err
+		}
+		if !isUnverified(rawCodes) {
+			// verified since the download of its bytecode
+			r.Status = "drift"
+			r.Changes = []*fileChange{{Status: "A", Path: metadataFile}}
+		}
+		return nil
+	}
+
+	changes, err := diffDeployment(ctx, c, d, dir)
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		r.Status, r.Changes = "drift", changes
+	}
+
+	return nil
+}
+
+// checkLock compares the integrity of the verified sources of d with the one pinned in config.json,
+// or the one recorded in the PROVENANCE.json of its download into dir.
+func checkLock(ctx context.Context, d *deployment, dir string, r *CheckResult) error {
+	expected := d.Integrity
+	if expected == "" {
+		p := &Provenance{}
+		bs, err := os.ReadFile(filepath.Join(dir, provenanceFile))
+		if err != nil {
+			return fmt.Errorf("no integrity pinned in config.json nor recorded: %w", err)
+		}
+		if err := json.Unmarshal(bs, p); err != nil {
+			return fmt.Errorf("%s: %w", provenanceFile, err)
+		}
+		if p.Integrity == "" {
+			return fmt.Errorf("no integrity pinned in config.json nor recorded in %s", provenanceFile)
+		}
+		expected = p.Integrity
+	}
+
+	rawCodes, err := fetchRawCode(ctx, d)
+	if err != nil {
+		return err
+	}
+	if isUnverified(rawCodes) {
+		return errNotVerified
+	}
+
+	sourceCodes, err := parseContractCode(rawCodes)
+	if err != nil {
+		return err
+	}
+
+	r.Integrity = &CheckIntegrity{Expected: expected, Actual: sourcesIntegrity(sourceCodes)}
+	if !strings.EqualFold(r.Integrity.Expected, r.Integrity.Actual) {
+		r.Status = "drift"
+	}
+
+	return nil
+}
+
+// printCheck prints the files which differ, as diff does, the integrities which differ and the errors.
+func printCheck(report *CheckReport) {
+	for _, r := range report.Contracts {
+		for _, change := range r.Changes {
+			fmt.Printf("%s %s\n", change.Status, filepath.ToSlash(filepath.Join(r.Name, change.Path)))
+		}
+		if r.Integrity != nil && r.Status == "drift" {
+			fmt.Printf("%s: integrity %s, expected %s\n", r.Name, r.Integrity.Actual, r.Integrity.Expected)
+		}
+		if r.Error != "" {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", r.Name, r.Error)
+		}
+	}
+
+	fmt.Printf("%d of %d contracts drifted, %d failed to check\n", report.Drifted, report.Total, report.Failed)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// checkpointState is the progress of a batch run persisted by --checkpoint.
+type checkpointState struct {
+	// Run identifies the deployments of the run, so the checkpoint of another is not resumed.
+	Run  string   `json:"run"`
+	Done []string `json:"done"`
+}
+
+// checkpoint records the deployments downloaded in a run into a file, which a run of the same deployments
+// interrupted by Ctrl-C or exhausted rate limits resumes from. It is removed once every deployment was downloaded.
+type checkpoint struct {
+	path  string
+	state *checkpointState
+	done  map[string]bool
+}
+
+func checkpointKey(d *deployment) string {
+	return fmt.Sprintf("%d:%s:%s", d.Chain, strings.ToLower(d.Address), d.Name)
+}
+
+// openCheckpoint loads the checkpoint at path for deployments, starting over when it is missing or of another run.
+func openCheckpoint(path string, deployments []*deployment) (*checkpoint, error) {
+	keys := make([]string, 0, len(deployments))
+	for _, d := range deployments {
+		keys = append(keys, checkpointKey(d))
+	}
+	sort.Strings(keys)
+	sum := sha256.Sum256([]byte(strings.Join(keys, "\n")))
+	run := hex.EncodeToString(sum[:])
+
+	cp := &checkpoint{path: path, state: &checkpointState{Run: run, Done: []string{}}, done: map[string]bool{}}
+
+	bs, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &checkpointState{}
+	if err := json.Unmarshal(bs, state); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+	if state.Run != run {
+		fmt.Fprintf(os.Stderr, "warning: checkpoint %s is of another set of contracts, starting over\n", path)
+		return cp, nil
+	}
+
+	cp.state = state
+	for _, key := range state.Done {
+		cp.done[key] = true
+	}
+
+	return cp, nil
+}
+
+func (cp *checkpoint) isDone(d *deployment) bool {
+	return cp.done[checkpointKey(d)]
+}
+
+// markDone records d as downloaded, saving the file so the progress survives the process being killed.
+func (cp *checkpoint) markDone(d *deployment) error {
+	key := checkpointKey(d)
+	if cp.done[key] {
+		return nil
+	}
+	cp.done[key] = true
+	cp.state.Done = append(cp.state.Done, key)
+
+	bs, err := json.MarshalIndent(cp.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(cp.path), dirMode); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(cp.path, append(bs, '\n'))
+}
+
+// remove deletes the file once the run completed.
+func (cp *checkpoint) remove() error {
+	if err := os.Remove(cp.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
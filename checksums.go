@@ -0,0 +1,30 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const checksumsFile = "SHA256SUMS"
+
+// writeChecksums writes SHA256SUMS into dir for the written files, keyed by their path relative to dir,
+// in the format of sha256sum so the tree can be checked with `sha256sum -c SHA256SUMS`.
+func writeChecksums(dir string, files map[string][]byte) error {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	b := &strings.Builder{}
+	for _, path := range paths {
+		sum := sha256.Sum256(files[path])
+		fmt.Fprintf(b, "%s  %s\n", hex.EncodeToString(sum[:]), filepath.ToSlash(path))
+	}
+
+	return writeFileAtomic(filepath.Join(dir, checksumsFile), []byte(b.String()))
+}
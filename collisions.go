@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// checkDirCollisions fails when two of deployments, e.g. entries with the same outDir and as, would be downloaded into the same directory.
+func checkDirCollisions(contractDir string, deployments []*deployment) error {
+	byDir := map[string]*deployment{}
+	for _, d := range deployments {
+		dir := filepath.Clean(d.dir(contractDir))
+		other, ok := byDir[dir]
+		if !ok {
+			byDir[dir] = d
+			continue
+		}
+		if other.Chain != d.Chain || other.Address != d.Address {
+			return &configError{fmt.Errorf("%s and %s are both downloaded into %s: set \"as\" or \"outDir\" on one of them", other.Name, d.Name, dir)}
+		}
+	}
+
+	return nil
+}
+
+// pathClaims records the contract and source key each file was written from in the run,
+// so a contract writing different content to a file of another fails instead of overwriting it.
+type pathClaims struct {
+	mu    sync.Mutex
+	files map[string]*pathClaim
+}
+
+type pathClaim struct {
+	owner  string
+	source string
+	sum    [sha256.Size]byte
+}
+
+func newPathClaims() *pathClaims {
+	return &pathClaims{files: map[string]*pathClaim{}}
+}
+
+// claim records files as written by owner. No file is claimed when any collides with a file of another contract.
+func (c *pathClaims) claim(owner string, files []*pendingFile) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	claims := map[string]*pathClaim{}
+	for _, f := range files {
+		path := filepath.Clean(f.path)
+		claim := &pathClaim{owner: owner, source: f.source, sum: sha256.Sum256(f.content)}
+
+		if other, ok := c.files[path]; ok && other.owner != owner && !bytes.Equal(other.sum[:], claim.sum[:]) {
+			return fmt.Errorf("%s: %s of %s would overwrite %s of %s with different content", path, claim.source, owner, other.source, other.owner)
+		}
+		claims[path] = claim
+	}
+
+	for path, claim := range claims {
+		c.files[path] = claim
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// comparedContract is one side of a compare.
+type comparedContract struct {
+	d       *deployment
+	raw     *RawCode
+	sources Sources
+}
+
+// runCompare fetches the verified sources of two deployments, e.g. of one protocol on two chains,
+// and prints the settings and files which differ between them.
+func runCompare(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: compare <target> <target>")
+	}
+
+	c, err := loadConfig()
+	if errors.Is(err, fs.ErrNotExist) {
+		// addresses can be compared without a config
+		c, err = &Config{}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	sides := make([]*comparedContract, 0, len(args))
+	for _, target := range args {
+		d, err := c.deployment(ctx, target)
+		if err != nil {
+			return err
+		}
+
+		side, err := fetchCompared(ctx, d)
+		if err != nil {
+			return fmt.Errorf("%s: %w", target, err)
+		}
+		sides = append(sides, side)
+	}
+	a, b := sides[0], sides[1]
+
+	fmt.Printf("a  %s:%s\nb  %s:%s\n\n", a.d.Chain, a.d.Address, b.d.Chain, b.d.Address)
+
+	differs := false
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, s := range []struct{ name, a, b string }{
+		{"contract", a.raw.ContractName, b.raw.ContractName},
+		{"compiler", a.raw.CompilerVersion, b.raw.CompilerVersion},
+		{"optimization", a.raw.OptimizationUsed + " (" + a.raw.Runs + " runs)", b.raw.OptimizationUsed + " (" + b.raw.Runs + " runs)"},
+		{"evm version", a.raw.EVMVersion, b.raw.EVMVersion},
+		{"license", a.raw.LicenseType, b.raw.LicenseType},
+	} {
+		if s.a == s.b {
+			continue
+		}
+		if !differs {
+			fmt.Fprintln(tw, "SETTING\tA\tB")
+		}
+		differs = true
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.name, s.a, s.b)
+	}
+	tw.Flush()
+
+	changes := compareSources(a.sources, b.sources)
+	if len(changes) > 0 {
+		if differs {
+			fmt.Println()
+		}
+		differs = true
+	}
+	for _, change := range changes {
+		fmt.Printf("%s %s\n", change.Status, change.Path)
+	}
+
+	if differs {
+		return fmt.Errorf("%w: %d files differ", errDrift, len(changes))
+	}
+
+	fmt.Println("identical")
+
+	return nil
+}
+
+// fetchCompared fetches the verified sources of d.
+func fetchCompared(ctx context.Context, d *deployment) (*comparedContract, error) {
+	rawCodes, err := fetchRawCode(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	if isUnverified(rawCodes) {
+		return nil, errNotVerified
+	}
+
+	sourceCodes, err := parseContractCode(rawCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := Sources{}
+	for _, sourceCode := range sourceCodes {
+		for path, source := range sourceCode.Sources {
+			sources[path] = source
+		}
+	}
+
+	return &comparedContract{d: d, raw: rawCodes[0], sources: sources}, nil
+}
+
+// compareSources returns the files which differ between a and b:
+// "D" only in a, "A" only in b and "M" changed, ignoring line ending differences.
+func compareSources(a, b Sources) []*fileChange {
+	changes := []*fileChange{}
+	for path, source := range a {
+		other, ok := b[path]
+		switch {
+		case !ok:
+			changes = append(changes, &fileChange{Status: "D", Path: path})
+		case !bytes.Equal(normalizeNewlines(source.Content), normalizeNewlines(other.Content)):
+			changes = append(changes, &fileChange{Status: "M", Path: path})
+		}
+	}
+
+	for path := range b {
+		if _, ok := a[path]; !ok {
+			changes = append(changes, &fileChange{Status: "A", Path: path})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+
+	return changes
+}
+
+func normalizeNewlines(s string) []byte {
+	return []byte(strings.ReplaceAll(s, "\r\n", "\n"))
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// commandFlags returns the flags of the subcommand name, prefixed with "-" for single letters and "--" otherwise.
+func commandFlags(name string) []string {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	addConfigFlags(fs)
+	commands[name].define(fs)
+
+	flags := []string{}
+	fs.VisitAll(func(f *flag.Flag) {
+		if len(f.Name) == 1 {
+			flags = append(flags, "-"+f.Name)
+		} else {
+			flags = append(flags, "--"+f.Name)
+		}
+	})
+
+	return flags
+}
+
+// runCompletion prints a completion script for the shell named by args[0].
+// Contract names are completed from config.json at completion time through `list --names`.
+func runCompletion(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: completion bash|zsh|fish")
+	}
+
+	switch args[0] {
+	case "bash":
+		writeBashCompletion(os.Stdout)
+	case "zsh":
+		fmt.Fprint(os.Stdout, "#compdef etherscan-downloader\n\nautoload -U bashcompinit && bashcompinit\n\n")
+		writeBashCompletion(os.Stdout)
+	case "fish":
+		writeFishCompletion(os.Stdout)
+	default:
+		return fmt.Errorf("unsupported shell: %s", args[0])
+	}
+
+	return nil
+}
+
+func writeBashCompletion(w io.Writer) {
+	names := commandNames()
+
+	fmt.Fprintf(w, `_etherscan_downloader() {
+  local cur="${COMP_WORDS[COMP_CWORD]}"
+  local contracts
+  contracts="$("${COMP_WORDS[0]}" list --names 2>/dev/null)"
+
+  if [ "$COMP_CWORD" -eq 1 ]; then
+    COMPREPLY=($(compgen -W "%s $contracts" -- "$cur"))
+    return
+  fi
+
+  local flags=""
+  case "${COMP_WORDS[1]}" in
+`, strings.Join(names, " "))
+
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s) flags=\"%s\" ;;\n", name, strings.Join(commandFlags(name), " "))
+	}
+
+	fmt.Fprint(w, `  *) flags="`+strings.Join(commandFlags("download"), " ")+`" ;;
+  esac
+
+  case "$cur" in
+  -*) COMPREPLY=($(compgen -W "$flags" -- "$cur")) ;;
+  *) COMPREPLY=($(compgen -W "$contracts" -- "$cur")) ;;
+  esac
+}
+
+complete -o default -F _etherscan_downloader etherscan-downloader
+`)
+}
+
+func writeFishCompletion(w io.Writer) {
+	names := commandNames()
+
+	fmt.Fprintf(w, "complete -c etherscan-downloader -f\n")
+	fmt.Fprintf(w, "complete -c etherscan-downloader -n 'not __fish_seen_subcommand_from %s' -a '%s'\n", strings.Join(names, " "), strings.Join(names, " "))
+	fmt.Fprintf(w, "complete -c etherscan-downloader -a '(etherscan-downloader list --names 2>/dev/null)'\n")
+
+	for _, name := range names {
+		fs := flag.NewFlagSet(name, flag.ContinueOnError)
+		commands[name].define(fs)
+
+		fs.VisitAll(func(f *flag.Flag) {
+			option := "-l"
+			if len(f.Name) == 1 {
+				option = "-s"
+			}
+			fmt.Fprintf(w, "complete -c etherscan-downloader -n '__fish_seen_subcommand_from %s' %s %s -d %s\n", name, option, f.Name, fishQuote(f.Usage))
+		})
+	}
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), "'", `\'`) + "'"
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// acceptEncoding is the Accept-Encoding of the requests, as the standard-json inputs of large contracts
+// compress to a fraction of their size.
+const acceptEncoding = "gzip, deflate"
+
+// compressionTransport asks for gzip or deflate responses and decompresses them, so the fixtures,
+// the --debug-http log and the size limit of the responses all see the decompressed bodies.
+// Requests setting their own Accept-Encoding, e.g. in http.headers, get the responses as they are sent.
+type compressionTransport struct {
+	base http.RoundTripper
+}
+
+func (t *compressionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") != "" || req.Header.Get("Range") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	// a RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if (encoding != "gzip" && encoding != "deflate") || req.Method == http.MethodHead || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return resp, nil
+	}
+
+	resp.Body = &decompressingBody{body: resp.Body, encoding: encoding}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return resp, nil
+}
+
+// decompressingBody decompresses body, from its first read so a response is returned before its body arrives.
+type decompressingBody struct {
+	body     io.ReadCloser
+	encoding string
+	r        io.ReadCloser
+	err      error
+}
+
+func (b *decompressingBody) Read(p []byte) (int, error) {
+	if b.r == nil && b.err == nil {
+		b.r, b.err = newDecompressor(b.body, b.encoding)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	return b.r.Read(p)
+}
+
+func (b *decompressingBody) Close() error {
+	if b.r != nil {
+		b.r.Close()
+	}
+
+	return b.body.Close()
+}
+
+// newDecompressor returns the reader of body decompressed from encoding. Servers sending deflate send
+// zlib streams as HTTP specifies, but some send raw deflate, told apart by the zlib header.
+func newDecompressor(body io.Reader, encoding string) (io.ReadCloser, error) {
+	if encoding == "gzip" {
+		r, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("gzip response: %w", err)
+		}
+		return r, nil
+	}
+
+	br := bufio.NewReader(body)
+	header, _ := br.Peek(2)
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		r, err := zlib.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("deflate response: %w", err)
+		}
+		return r, nil
+	}
+
+	return flate.NewReader(br), nil
+}
@@ -0,0 +1,685 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const configFile = "config.json"
+
+// configPath is the config file selected by --config, read from stdin when it is "-".
+var configPath = configFile
+
+// configProfile is the profile of config.json selected by --profile.
+var configProfile string
+
+// strictConfig is set by --strict-config, failing on config fields this tool doesn't know, e.g. a misspelled "adress".
+var strictConfig bool
+
+// runTimeout is the deadline of the whole run selected by --timeout, none when zero.
+var runTimeout time.Duration
+
+// addConfigFlags adds the flags selecting the config, and the other flags of every command, to fs.
+func addConfigFlags(fs *flag.FlagSet) {
+	fs.StringVar(&configPath, "config", configFile, "config file to use, or - to read it from stdin")
+	fs.StringVar(&configProfile, "profile", "", "overlay this profile of config.json's profiles on the config")
+	fs.BoolVar(&strictConfig, "strict-config", false, "fail on unknown fields in the config, as \"strict\": true in config.json does")
+	fs.DurationVar(&runTimeout, "timeout", 0, "give up on the whole run after this long, e.g. 10m (default no limit)")
+}
+
+type Config struct {
+	Version     int                        `json:"version,omitempty"` // the schema of the file, see migrate
+	Strict      bool                       `json:"strict,omitempty"`  // fail on unknown fields, like --strict-config
+	Extends     string                     `json:"extends,omitempty"` // config overlaid by this one, relative to it
+	Target      string                     `json:"target"`
+	ContractDir string                     `json:"contractDir"`
+	Contracts   map[string]ConfigContract  `json:"contracts"`
+	Analyzer    *AnalyzerConfig            `json:"analyzer,omitempty"`
+	Decompiler  *DecompilerConfig          `json:"decompiler,omitempty"`
+	LibDir      string                     `json:"libDir,omitempty"`
+	Normalize   *NormalizeConfig           `json:"normalize,omitempty"`
+	Licenses    *LicensePolicyConfig       `json:"licenses,omitempty"`
+	History     *HistoryConfig             `json:"history,omitempty"`
+	Tenderly    *TenderlyConfig            `json:"tenderly,omitempty"`
+	HTTP        *HTTPConfig                `json:"http,omitempty"`
+	Permissions *PermissionsConfig         `json:"permissions,omitempty"`
+	Signing     *SigningConfig             `json:"signing,omitempty"`
+	Mirror      *MirrorConfig              `json:"mirror,omitempty"`
+	Notify      *NotifyConfig              `json:"notify,omitempty"`
+	Cache       *CacheConfig               `json:"cache,omitempty"`
+	Limits      *LimitsConfig              `json:"limits,omitempty"`
+	Budget      *BudgetConfig              `json:"budget,omitempty"`
+	Schedule    string                     `json:"schedule,omitempty"` // cron expression of the daemon, for the contracts without their own
+	Explorers   map[string]*ExplorerConfig `json:"explorers,omitempty"`
+	Groups      map[string][]string        `json:"groups,omitempty"` // targets by name, given as @name
+	Profiles    map[string]json.RawMessage `json:"profiles,omitempty"`
+
+	// SimilarMatchPolicy is what to do when the explorer only has a similar match's source: allow, warn (default) or fail.
+	SimilarMatchPolicy string `json:"similarMatchPolicy,omitempty"`
+
+	// UnverifiedTTL is how long contracts found unverified aren't queried again, e.g. "6h" (default 1h), or "0" to query them every run.
+	UnverifiedTTL string `json:"unverifiedTTL,omitempty"`
+
+	// expanded are the names of the entries with deployments, and the names of the contracts they were expanded into.
+	expanded map[string][]string
+}
+
+type ConfigContract struct {
+	Chain    chain    `json:"chain,omitempty"`
+	Address  string   `json:"address"`
+	Tags     []string `json:"tags,omitempty"`
+	OutDir   string   `json:"outDir,omitempty"`   // directory the sources are written to instead of contractDir
+	As       string   `json:"as,omitempty"`       // name of the sources' directory instead of the entry's
+	Schedule string   `json:"schedule,omitempty"` // cron expression of the daemon
+	Hooks    *Hooks   `json:"hooks,omitempty"`
+	Source   string   `json:"source,omitempty"` // where the sources are fetched from: the explorer (default) or tenderly
+
+	// Integrity pins the verified sources, e.g. "sha256-<hex>" as recorded in PROVENANCE.json.
+	Integrity string `json:"integrity,omitempty"`
+
+	// Registry is a registry or factory contract returning the address of the contract instead of Address,
+	// resolved at download time.
+	Registry *RegistryConfig `json:"registry,omitempty"`
+
+	// Deployments are the addresses of the contract on several chains instead of Address,
+	// each downloaded into a subdirectory named after its chain, e.g. <name>/arb1.
+	Deployments []ConfigDeployment `json:"deployments,omitempty"`
+}
+
+// ConfigDeployment is one of the deployments of a contract entry.
+type ConfigDeployment struct {
+	Chain   chain  `json:"chain,omitempty"`
+	Address string `json:"address"`
+}
+
+// resolve returns the chain and bare address of the contract.
+// Address may be given chain-prefixed (e.g. "eth:0xABC..." or "eip155:1:0xABC..."), in which case Chain can be omitted.
+// A contract of a registry has no address until it is resolved, and the chain of the registry.
+func (cc ConfigContract) resolve() (chain, string, error) {
+	if cc.Address == "" && cc.Registry != nil {
+		c, _, err := cc.Registry.resolve(cc.Chain)
+		return c, "", err
+	}
+
+	if !strings.Contains(cc.Address, ":") {
+		return cc.Chain, cc.Address, nil
+	}
+
+	c, address, err := parseChainAddress(cc.Address)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if cc.Chain != 0 && cc.Chain != c {
+		return 0, "", fmt.Errorf("chain %d does not match address prefix: %s", cc.Chain, cc.Address)
+	}
+
+	return c, address, nil
+}
+
+var (
+	stdinConfigOnce sync.Once
+	stdinConfig     []byte
+	stdinConfigErr  error
+)
+
+// readConfig returns the config document at configPath, reading stdin once when it is "-".
+func readConfig() ([]byte, error) {
+	if configPath != "-" {
+		return os.ReadFile(configPath)
+	}
+
+	stdinConfigOnce.Do(func() {
+		stdinConfig, stdinConfigErr = io.ReadAll(os.Stdin)
+	})
+
+	return stdinConfig, stdinConfigErr
+}
+
+// errStdinConfig is returned by commands writing the config when it is read from stdin.
+var errStdinConfig = errors.New("the config is read from stdin and can't be written, use --config <file>")
+
+// userConfigPath returns the path of the user's config, overlaid by the project's config.json.
+func userConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "etherscan-downloader", configFile), nil
+}
+
+// loadConfig returns the user config overlaid with config.json and the selected profile.
+func loadConfig() (*Config, error) {
+	c := &Config{}
+
+	if path, err := userConfigPath(); err == nil {
+		bs, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := c.overlay(bs); err != nil {
+				return nil, &configError{fmt.Errorf("%s: %w", path, err)}
+			}
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, &configError{err}
+		}
+	}
+
+	bs, err := readConfig()
+	if err != nil {
+		return nil, &configError{err}
+	}
+
+	if err := c.overlayExtended(configPath, bs, nil); err != nil {
+		return nil, &configError{err}
+	}
+
+	if err := checkConfigVersion(c); err != nil {
+		return nil, &configError{err}
+	}
+
+	if err := c.applyProfile(configProfile); err != nil {
+		return nil, &configError{err}
+	}
+
+	if err := c.expandDeployments(); err != nil {
+		return nil, &configError{err}
+	}
+
+	if err := configureHTTP(c.HTTP, nil); err != nil {
+		return nil, &configError{err}
+	}
+
+	if err := configureExplorers(c.Explorers); err != nil {
+		return nil, &configError{err}
+	}
+
+	if err := configureBudget(c.Budget, nil); err != nil {
+		return nil, &configError{err}
+	}
+
+	if err := configureKeychainKeys(); err != nil {
+		// a locked or missing keychain should not fail the commands needing no key
+		fmt.Fprintf(os.Stderr, "warning: %s\n", err)
+	}
+
+	if err := configurePermissions(c.Permissions, nil); err != nil {
+		return nil, &configError{err}
+	}
+
+	if err := c.Normalize.validate(); err != nil {
+		return nil, &configError{err}
+	}
+
+	if err := c.Licenses.validate(); err != nil {
+		return nil, &configError{err}
+	}
+
+	if err := c.Signing.validate(); err != nil {
+		return nil, &configError{err}
+	}
+
+	if err := c.Cache.validate(); err != nil {
+		return nil, &configError{err}
+	}
+
+	if err := c.Tenderly.validate(); err != nil {
+		return nil, &configError{err}
+	}
+	tenderlyProject = c.Tenderly
+
+	return c, err
+}
+
+// loadProjectConfig returns config.json alone, for commands rewriting it.
+func loadProjectConfig() (*Config, error) {
+	bs, err := readConfig()
+	if err != nil {
+		return nil, &configError{err}
+	}
+
+	c := &Config{}
+	if err := json.Unmarshal(stripJSONC(bs), c); err != nil {
+		return nil, &configError{fmt.Errorf("%s: %w", configPath, err)}
+	}
+
+	return c, nil
+}
+
+// overlay decodes the config document bs on c: the fields it sets replace c's,
+// its contracts and profiles are added to c's, and its explorer settings are merged field by field with c's.
+func (c *Config) overlay(bs []byte) error {
+	bs = stripJSONC(bs)
+
+	explorers := map[string]*ExplorerConfig{}
+	for key, ec := range c.Explorers {
+		explorers[key] = ec
+	}
+
+	if err := json.Unmarshal(bs, c); err != nil {
+		return err
+	}
+
+	if strictConfig || c.Strict {
+		dec := json.NewDecoder(bytes.NewReader(bs))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&Config{}); err != nil {
+			return err
+		}
+	}
+
+	// json.Unmarshal replaces map entries as a whole, so merge the explorers present in both again
+	raw := &struct {
+		Explorers map[string]json.RawMessage `json:"explorers"`
+	}{}
+	if err := json.Unmarshal(bs, raw); err != nil {
+		return err
+	}
+	for key, msg := range raw.Explorers {
+		base, ok := explorers[key]
+		if !ok || base == nil {
+			continue
+		}
+
+		merged := *base
+		if err := json.Unmarshal(msg, &merged); err != nil {
+			return err
+		}
+		c.Explorers[key] = &merged
+	}
+
+	return nil
+}
+
+// overlayExtended overlays the config document bs read from path on c, after the config it extends, if any,
+// so a config can share the explorers, keys and defaults of a base config and set only its contracts or contractDir.
+// seen are the configs extending it, to fail on cycles.
+func (c *Config) overlayExtended(path string, bs []byte, seen []string) error {
+	extends := &struct {
+		Extends string `json:"extends"`
+	}{}
+	if err := json.Unmarshal(stripJSONC(bs), extends); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if extends.Extends != "" {
+		base := extends.Extends
+		if !filepath.IsAbs(base) && path != "-" {
+			base = filepath.Join(filepath.Dir(path), base)
+		}
+
+		for _, p := range append(seen, path) {
+			if filepath.Clean(p) == filepath.Clean(base) {
+				return fmt.Errorf("%s: extends %s, which extends it", path, extends.Extends)
+			}
+		}
+
+		baseBytes, err := os.ReadFile(base)
+		if err != nil {
+			return fmt.Errorf("%s: extends: %w", path, err)
+		}
+
+		if err := c.overlayExtended(base, baseBytes, append(seen, path)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.overlay(bs); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// applyProfile overlays the profile named name on c: its fields replace c's, and its contracts are added to c's.
+func (c *Config) applyProfile(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile: %s", name)
+	}
+
+	if err := c.overlay(profile); err != nil {
+		return fmt.Errorf("profile %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func saveConfig(c *Config) error {
+	if configPath == "-" {
+		return errStdinConfig
+	}
+
+	return writeJSON(configPath, c)
+}
+
+// lookup returns the configured contract named target.
+// Targets not found in the config are treated as addresses, chain-prefixed or not, and named by their address.
+func (c *Config) lookup(target string) (string, ConfigContract, error) {
+	if cc, ok := c.Contracts[target]; ok {
+		return target, cc, nil
+	}
+
+	if isAddress(target) {
+		// the chain is detected before the download
+		return target, ConfigContract{Address: target}, nil
+	}
+
+	if !strings.Contains(target, ":") {
+		return "", ConfigContract{}, &configError{fmt.Errorf("unknown target: %s", target)}
+	}
+
+	_, address, err := parseChainAddress(target)
+	if err != nil {
+		return "", ConfigContract{}, err
+	}
+
+	return address, ConfigContract{Address: target}, nil
+}
+
+// expandDeployments replaces the entries with deployments by a contract per deployment, named <name>/<chain>,
+// so they are listed, downloaded and scheduled like the others.
+func (c *Config) expandDeployments() error {
+	for _, name := range c.names() {
+		cc := c.Contracts[name]
+		if len(cc.Deployments) == 0 {
+			continue
+		}
+		if cc.Address != "" {
+			return fmt.Errorf("%s: set either address or deployments", name)
+		}
+
+		delete(c.Contracts, name)
+		names := []string{}
+		for _, dep := range cc.Deployments {
+			ch, address, err := ConfigContract{Chain: dep.Chain, Address: dep.Address}.resolve()
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if ch == 0 {
+				return fmt.Errorf("%s: deployment %s has no chain", name, dep.Address)
+			}
+
+			sub := name + "/" + ch.String()
+			if _, ok := c.Contracts[sub]; ok {
+				return fmt.Errorf("%s: several deployments on %s", name, ch)
+			}
+
+			deployed := cc
+			deployed.Chain, deployed.Address, deployed.Deployments = ch, address, nil
+			if cc.As != "" {
+				deployed.As = cc.As + "/" + ch.String()
+			}
+			c.Contracts[sub] = deployed
+			names = append(names, sub)
+		}
+
+		if c.expanded == nil {
+			c.expanded = map[string][]string{}
+		}
+		sort.Strings(names)
+		c.expanded[name] = names
+	}
+
+	return nil
+}
+
+// deployment resolves target to the deployment to download, its address returned by its registry if it has one.
+func (c *Config) deployment(ctx context.Context, target string) (*deployment, error) {
+	d, err := c.configured(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resolveDeployments(ctx, []*deployment{d}); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// configured returns the deployment of target as configured, without resolving what is left to the chain.
+func (c *Config) configured(target string) (*deployment, error) {
+	if names, ok := c.expanded[target]; ok {
+		return nil, &configError{fmt.Errorf("%s has %d deployments, pick one of %s", target, len(names), strings.Join(names, ", "))}
+	}
+
+	name, cc, err := c.lookup(target)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, address, err := cc.resolve()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if cc.Integrity != "" && !strings.HasPrefix(cc.Integrity, integrityPrefix) {
+		return nil, &configError{fmt.Errorf("%s: integrity must start with %s", name, integrityPrefix)}
+	}
+
+	d := &deployment{Name: name, Chain: ch, Address: address, OutDir: cc.OutDir, As: cc.As, Integrity: cc.Integrity}
+	switch cc.Source {
+	case "", "explorer":
+	case sourceTenderly:
+		if c.Tenderly == nil {
+			return nil, &configError{fmt.Errorf(`%s: source is tenderly, set "tenderly" to the account and project`, name)}
+		}
+		d.Source = sourceTenderly
+	default:
+		return nil, &configError{fmt.Errorf("%s: unknown source %q, want explorer or tenderly", name, cc.Source)}
+	}
+	if cc.Address == "" && cc.Registry != nil {
+		if ch == 0 {
+			return nil, &configError{fmt.Errorf("%s: the chain of the registry is unknown, set chain or use a chain-prefixed address", name)}
+		}
+		d.Registry = cc.Registry
+	}
+	if cc.Hooks != nil {
+		d.PostDownload = cc.Hooks.PostDownload
+	}
+
+	if err := checkFolder(d.folder()); err != nil {
+		return nil, &configError{fmt.Errorf("%s: %w", name, err)}
+	}
+
+	return d, nil
+}
+
+// checkFolder fails when folder, the directory of a contract named after it or its "as", wouldn't be below the
+// contract directory: an absolute path, or one with empty, "." or ".." segments, e.g. "../x".
+func checkFolder(folder string) error {
+	if filepath.IsAbs(folder) || filepath.VolumeName(folder) != "" {
+		return fmt.Errorf("directory %q is an absolute path", folder)
+	}
+
+	for _, segment := range strings.Split(strings.ReplaceAll(folder, "\\", "/"), "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("directory %q is not a path below the contract directory", folder)
+		}
+	}
+
+	return nil
+}
+
+// dir returns the directory the sources of the contract named name are written to.
+func (c *Config) dir(name string) string {
+	cc := c.Contracts[name]
+
+	return filepath.Join(firstNonEmpty(cc.OutDir, c.ContractDir), firstNonEmpty(cc.As, name))
+}
+
+// deployments resolves the targets given on the command line, defaulting to the config's target,
+// the addresses of those of a registry returned by it.
+func (c *Config) deployments(ctx context.Context, targets []string) ([]*deployment, error) {
+	deployments, err := c.configuredDeployments(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resolveDeployments(ctx, deployments); err != nil {
+		return nil, err
+	}
+
+	return deployments, nil
+}
+
+// configuredDeployments returns the deployments of the targets as configured, as configured does.
+func (c *Config) configuredDeployments(targets []string) ([]*deployment, error) {
+	if len(targets) == 0 {
+		targets = []string{c.Target}
+	}
+
+	targets, err := c.expandGroups(targets, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	deployments := make([]*deployment, 0, len(targets))
+	for _, target := range targets {
+		if names, ok := c.expanded[target]; ok {
+			for _, name := range names {
+				d, err := c.configured(name)
+				if err != nil {
+					return nil, err
+				}
+
+				deployments = append(deployments, d)
+			}
+			continue
+		}
+
+		if !strings.ContainsAny(target, "*?[") {
+			d, err := c.configured(target)
+			if err != nil {
+				return nil, err
+			}
+
+			deployments = append(deployments, d)
+			continue
+		}
+
+		names, err := c.match(target)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			d, err := c.configured(name)
+			if err != nil {
+				return nil, err
+			}
+
+			deployments = append(deployments, d)
+		}
+	}
+
+	return deployments, nil
+}
+
+// expandGroups replaces the @group targets by the targets of the group, which may be groups themselves.
+// seen are the groups being expanded, to catch a group including itself.
+func (c *Config) expandGroups(targets []string, seen map[string]bool) ([]string, error) {
+	expanded := []string{}
+	for _, target := range targets {
+		if !strings.HasPrefix(target, "@") {
+			expanded = append(expanded, target)
+			continue
+		}
+
+		name := strings.TrimPrefix(target, "@")
+		members, ok := c.Groups[name]
+		if !ok {
+			return nil, &configError{fmt.Errorf("unknown group: %s", name)}
+		}
+		if seen[name] {
+			return nil, &configError{fmt.Errorf("group %s includes itself", name)}
+		}
+
+		inner := map[string]bool{name: true}
+		for g := range seen {
+			inner[g] = true
+		}
+		members, err := c.expandGroups(members, inner)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, members...)
+	}
+
+	return expanded, nil
+}
+
+// match returns the names of the configured contracts matching the glob pattern in order.
+func (c *Config) match(pattern string) ([]string, error) {
+	names := []string{}
+	for _, name := range c.names() {
+		ok, err := path.Match(pattern, name)
+		if err != nil {
+			return nil, &configError{fmt.Errorf("bad pattern %s: %w", pattern, err)}
+		}
+		if ok {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, &configError{fmt.Errorf("no contract matches %s", pattern)}
+	}
+
+	return names, nil
+}
+
+// tagged returns the names of the configured contracts having any of tags in order.
+func (c *Config) tagged(tags []string) ([]string, error) {
+	names := []string{}
+	for _, name := range c.names() {
+		if hasAnyTag(c.Contracts[name].Tags, tags) {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, &configError{fmt.Errorf("no contract is tagged %s", strings.Join(tags, " or "))}
+	}
+
+	return names, nil
+}
+
+func hasAnyTag(have, want []string) bool {
+	for _, h := range have {
+		for _, w := range want {
+			if h == w {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// names returns the names of the configured contracts in order.
+func (c *Config) names() []string {
+	names := make([]string, 0, len(c.Contracts))
+	for name := range c.Contracts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
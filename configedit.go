@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// addCommand adds a contract to config.json.
+func addCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	chainFlag := fs.String("chain", "", "chain id or short name, when the address isn't chain-prefixed")
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 2 {
+			return errors.New("usage: add [--chain <chain>] <name> <address>")
+		}
+		name, address := args[0], args[1]
+
+		cc := ConfigContract{Address: address}
+		if *chainFlag != "" {
+			ch, err := parseChain(*chainFlag)
+			if err != nil {
+				return err
+			}
+			cc.Chain = ch
+		}
+
+		ch, _, err := cc.resolve()
+		if err != nil {
+			return err
+		}
+		if ch == 0 {
+			return fmt.Errorf("%s: chain is unknown, use --chain or a chain-prefixed address", name)
+		}
+
+		if configPath == "-" {
+			return errStdinConfig
+		}
+
+		bs, err := readConfig()
+		if err != nil {
+			return err
+		}
+
+		bs, err = addConfigContract(bs, name, cc)
+		if err != nil {
+			return err
+		}
+
+		if err := writeConfigFile(bs); err != nil {
+			return err
+		}
+
+		fmt.Println("added", name)
+
+		return nil
+	}
+}
+
+// runRemove removes contracts from config.json.
+func runRemove(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: remove <name>...")
+	}
+
+	if configPath == "-" {
+		return errStdinConfig
+	}
+
+	bs, err := readConfig()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range args {
+		if bs, err = removeConfigContract(bs, name); err != nil {
+			return err
+		}
+	}
+
+	if err := writeConfigFile(bs); err != nil {
+		return err
+	}
+
+	for _, name := range args {
+		fmt.Println("removed", name)
+	}
+
+	return nil
+}
+
+// writeConfigFile writes an edited config.json, making sure it still parses.
+func writeConfigFile(bs []byte) error {
+	if err := json.Unmarshal(stripJSONC(bs), &Config{}); err != nil {
+		return fmt.Errorf("edited %s is invalid: %w", configPath, err)
+	}
+
+	return os.WriteFile(configPath, bs, fileMode)
+}
+
+// configEntry is the byte range of a "contracts" member in the config file.
+type configEntry struct {
+	name string
+	// start is the separating comma (or the name of the first member), key is where the name's quote is, and end is just after the value.
+	start, key, end int
+}
+
+// contractEntries locates the members of the "contracts" object in the config file bs.
+// It returns the offset just after the object's opening brace and its members in order, comments in bs skipped over.
+func contractEntries(bs []byte) (int, []*configEntry, error) {
+	bs = stripJSONC(bs)
+	dec := json.NewDecoder(bytes.NewReader(bs))
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return 0, nil, fmt.Errorf("%s is not a JSON object", configPath)
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if tok != "contracts" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return 0, nil, err
+			}
+			continue
+		}
+
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return 0, nil, fmt.Errorf("contracts in %s is not an object", configPath)
+		}
+		open := int(dec.InputOffset())
+
+		entries := []*configEntry{}
+		for dec.More() {
+			start := int(dec.InputOffset())
+
+			tok, err := dec.Token()
+			if err != nil {
+				return 0, nil, err
+			}
+
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return 0, nil, err
+			}
+
+			key := start + bytes.IndexByte(bs[start:], '"')
+			entries = append(entries, &configEntry{name: tok.(string), start: start, key: key, end: int(dec.InputOffset())})
+		}
+
+		return open, entries, nil
+	}
+
+	return 0, nil, fmt.Errorf("%s has no contracts", configPath)
+}
+
+// addConfigContract inserts name after the last member of "contracts", indented like the existing members.
+func addConfigContract(bs []byte, name string, cc ConfigContract) ([]byte, error) {
+	open, entries, err := contractEntries(bs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if e.name == name {
+			return nil, fmt.Errorf("%s is already in %s", name, configPath)
+		}
+	}
+
+	key, err := json.Marshal(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		// indented a level deeper than the line of "contracts"
+		indent := "    "
+		line := bs[bytes.LastIndexByte(bs[:open], '\n')+1 : open]
+		if outer := line[:len(line)-len(bytes.TrimLeft(line, " \t"))]; len(outer) > 0 {
+			indent = string(outer) + string(outer)
+		}
+		value, err := json.MarshalIndent(cc, indent, indent[:len(indent)/2])
+		if err != nil {
+			return nil, err
+		}
+
+		// drop whitespace between the braces of an empty object
+		edited := append([]byte{}, bs[:open]...)
+		edited = append(edited, "\n"+indent+string(key)+": "+string(value)+"\n"+indent[:len(indent)/2]...)
+		return append(edited, bytes.TrimLeft(bs[open:], " \t\r\n")...), nil
+	}
+
+	last := entries[len(entries)-1]
+	indent := lineIndent(bs, last.key)
+	value, err := json.MarshalIndent(cc, indent, indent[:len(indent)/2])
+	if err != nil {
+		return nil, err
+	}
+	entry := indent + string(key) + ": " + string(value)
+
+	lineEnd, ok := ownLineEnd(bs, last.end)
+	if !ok {
+		edited := append([]byte{}, bs[:last.end]...)
+		edited = append(edited, ",\n"+entry...)
+		return append(edited, bs[last.end:]...), nil
+	}
+
+	// on a line of its own after the last member's, which keeps its comment and trailing comma, given one if it has none
+	edited := append([]byte{}, bs[:last.end]...)
+	if rest := bytes.TrimLeft(bs[last.end:lineEnd], " \t"); len(rest) == 0 || rest[0] != ',' {
+		edited = append(edited, ',')
+	}
+	edited = append(edited, bs[last.end:lineEnd]...)
+	edited = append(edited, entry+"\n"...)
+
+	return append(edited, bs[lineEnd:]...), nil
+}
+
+// removeConfigContract deletes name from "contracts" along with its separating comma.
+func removeConfigContract(bs []byte, name string) ([]byte, error) {
+	open, entries, err := contractEntries(bs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, e := range entries {
+		if e.name != name {
+			continue
+		}
+
+		stripped := stripJSONC(bs)
+		from, to := e.start, e.end
+		lineStart := bytes.LastIndexByte(bs[:e.key], '\n') + 1
+		lineEnd, ownLine := ownLineEnd(bs, e.end)
+		ownLine = ownLine && len(bytes.Trim(stripped[lineStart:e.key], " \t")) == 0
+		switch {
+		case len(entries) == 1:
+			// leave an empty object
+			from = open
+			to = len(bs) - len(bytes.TrimLeft(stripped[to:], " \t\r\n"))
+		case ownLine:
+			// its lines with the comments on them, and the comma before it when it is the last member
+			from, to = lineStart, lineEnd
+			if i == len(entries)-1 {
+				prev := entries[i-1]
+				comma := prev.end + bytes.IndexByte(stripped[prev.end:e.key], ',')
+				return append(append(append([]byte{}, bs[:comma]...), bs[comma+1:from]...), bs[to:]...), nil
+			}
+		case i == 0:
+			// up to the comma, leaving the comments before the next member
+			from, to = open, e.end+bytes.IndexByte(stripped[e.end:], ',')+1
+		}
+
+		return append(append([]byte{}, bs[:from]...), bs[to:]...), nil
+	}
+
+	return nil, fmt.Errorf("%s is not in %s", name, configPath)
+}
+
+// ownLineEnd returns the offset just after the line of offset, and whether only a comma, whitespace and comments
+// follow offset on it.
+func ownLineEnd(bs []byte, offset int) (int, bool) {
+	rest := stripJSONC(bs)[offset:]
+	if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+		rest = rest[:i+1]
+	}
+	rest = bytes.TrimLeft(rest, " \t")
+	if len(rest) > 0 && rest[0] == ',' {
+		rest = rest[1:]
+	}
+	if len(bytes.TrimLeft(rest, " \t\r")) != 1 {
+		return 0, false
+	}
+
+	return offset + bytes.IndexByte(bs[offset:], '\n') + 1, true
+}
+
+// lineIndent returns the whitespace before offset on its line.
+func lineIndent(bs []byte, offset int) string {
+	line := bs[bytes.LastIndexByte(bs[:offset], '\n')+1 : offset]
+	if strings.TrimSpace(string(line)) != "" {
+		return "    "
+	}
+
+	return string(line)
+}
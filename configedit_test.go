@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// a config with comments, a trailing comma and two members
+const editedConfig = `{
+  "contractDir": "contracts", // vendored
+  "contracts": {
+    "a": {"address": "0x1"}, // main
+    /* old */ "b": {"address": "0x2"},
+  }
+}
+`
+
+func TestContractEntries(t *testing.T) {
+	for _, tt := range []struct {
+		config string
+		open   int
+		names  []string
+	}{
+		{editedConfig, 60, []string{"a", "b"}},
+		{`{"contracts": {}}`, 15, []string{}},
+		{"{\n\t\"contracts\": {\n\t}\n}\n", 17, []string{}},
+		// the "contracts" key of another object isn't the top-level one
+		{`{"x": {"contracts": {"c": {}}}, "contracts": {"a": {}, "b": {}}}`, 46, []string{"a", "b"}},
+	} {
+		open, entries, err := contractEntries([]byte(tt.config))
+		if err != nil {
+			t.Errorf("contractEntries(%q): %s", tt.config, err)
+			continue
+		}
+		if open != tt.open {
+			t.Errorf("contractEntries(%q): open %d, want %d", tt.config, open, tt.open)
+		}
+
+		names := []string{}
+		for _, e := range entries {
+			names = append(names, e.name)
+			if tt.config[e.key] != '"' {
+				t.Errorf("contractEntries(%q): %s: key at %q", tt.config, e.name, tt.config[e.key:])
+			}
+		}
+		if len(names) != len(tt.names) {
+			t.Errorf("contractEntries(%q) = %q, want %q", tt.config, names, tt.names)
+			continue
+		}
+		for i := range names {
+			if names[i] != tt.names[i] {
+				t.Errorf("contractEntries(%q) = %q, want %q", tt.config, names, tt.names)
+			}
+		}
+	}
+
+	for _, config := range []string{`[]`, `{"contracts": []}`, `{"target": "a"}`} {
+		if _, _, err := contractEntries([]byte(config)); err == nil {
+			t.Errorf("contractEntries(%q) succeeded", config)
+		}
+	}
+}
+
+func TestAddConfigContract(t *testing.T) {
+	cc := ConfigContract{Chain: 1, Address: "0x3"}
+
+	for _, tt := range []struct {
+		config string
+		want   string
+	}{
+		{
+			// the comments and the trailing comma of the last member are kept
+			editedConfig,
+			`{
+  "contractDir": "contracts", // vendored
+  "contracts": {
+    "a": {"address": "0x1"}, // main
+    /* old */ "b": {"address": "0x2"},
+    "c": {
+      "chain": 1,
+      "address": "0x3"
+    }
+  }
+}
+`,
+		},
+		{
+			"{\n  \"contracts\": {\n    \"a\": {\"address\": \"0x1\"} // main\n  }\n}\n",
+			"{\n  \"contracts\": {\n    \"a\": {\"address\": \"0x1\"}, // main\n    \"c\": {\n      \"chain\": 1,\n      \"address\": \"0x3\"\n    }\n  }\n}\n",
+		},
+		{
+			"{\n\t\"contracts\": {\n\t}\n}\n",
+			"{\n\t\"contracts\": {\n\t\t\"c\": {\n\t\t\t\"chain\": 1,\n\t\t\t\"address\": \"0x3\"\n\t\t}\n\t}\n}\n",
+		},
+		{
+			`{"contracts": {}}`,
+			"{\"contracts\": {\n    \"c\": {\n      \"chain\": 1,\n      \"address\": \"0x3\"\n    }\n  }}",
+		},
+		{
+			`{"contracts": {"a": {}}}`,
+			"{\"contracts\": {\"a\": {},\n    \"c\": {\n      \"chain\": 1,\n      \"address\": \"0x3\"\n    }}}",
+		},
+	} {
+		got, err := addConfigContract([]byte(tt.config), "c", cc)
+		if err != nil {
+			t.Errorf("addConfigContract(%q): %s", tt.config, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("addConfigContract(%q) = %q, want %q", tt.config, got, tt.want)
+		}
+		if !json.Valid(stripJSONC(got)) {
+			t.Errorf("addConfigContract(%q) = %q, not valid JSONC", tt.config, got)
+		}
+	}
+
+	if _, err := addConfigContract([]byte(editedConfig), "a", cc); err == nil {
+		t.Error("adding a contract already in the config succeeded")
+	}
+}
+
+func TestRemoveConfigContract(t *testing.T) {
+	for _, tt := range []struct {
+		config string
+		name   string
+		want   string
+	}{
+		{
+			// the first member, its comment with it
+			editedConfig,
+			"a",
+			`{
+  "contractDir": "contracts", // vendored
+  "contracts": {
+    /* old */ "b": {"address": "0x2"},
+  }
+}
+`,
+		},
+		{
+			// the last member, with the comma before it
+			editedConfig,
+			"b",
+			`{
+  "contractDir": "contracts", // vendored
+  "contracts": {
+    "a": {"address": "0x1"} // main
+  }
+}
+`,
+		},
+		{
+			"{\n  \"contracts\": {\n    \"a\": {},\n    \"b\": {},\n    \"c\": {}\n  }\n}\n",
+			"b",
+			"{\n  \"contracts\": {\n    \"a\": {},\n    \"c\": {}\n  }\n}\n",
+		},
+		{
+			// the only member, leaving an empty object
+			"{\n  \"contracts\": {\n    \"a\": {\"address\": \"0x1\"} // main\n  }\n}\n",
+			"a",
+			"{\n  \"contracts\": {}\n}\n",
+		},
+		{`{"contracts": {"a": {}, "b": {}, "c": {}}}`, "a", `{"contracts": { "b": {}, "c": {}}}`},
+		{`{"contracts": {"a": {}, "b": {}, "c": {}}}`, "b", `{"contracts": {"a": {}, "c": {}}}`},
+		{`{"contracts": {"a": {}, "b": {}, "c": {}}}`, "c", `{"contracts": {"a": {}, "b": {}}}`},
+	} {
+		got, err := removeConfigContract([]byte(tt.config), tt.name)
+		if err != nil {
+			t.Errorf("removeConfigContract(%q, %s): %s", tt.config, tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("removeConfigContract(%q, %s) = %q, want %q", tt.config, tt.name, got, tt.want)
+		}
+		if !json.Valid(stripJSONC(got)) {
+			t.Errorf("removeConfigContract(%q, %s) = %q, not valid JSONC", tt.config, tt.name, got)
+		}
+	}
+
+	if _, err := removeConfigContract([]byte(`{"contracts": {}}`), "a"); err == nil {
+		t.Error("removing a contract of an empty contracts object succeeded")
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const constantsDir = "constants"
+
+// constantsContract is a contract of the generated constants package, gathering its deployments on each chain.
+type constantsContract struct {
+	ident     string
+	name      string
+	abi       string
+	bytecode  string
+	addresses map[chain]string
+}
+
+func constantsCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	out := fs.String("out", "", "Go file to write (default <contractDir>/"+constantsDir+"/"+constantsDir+".go)")
+	pkg := fs.String("pkg", "", "package of the file (default named after its directory)")
+	offline := fs.Bool("offline", false, "leave the bytecode out instead of fetching it")
+
+	return func(ctx context.Context, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			args = c.names()
+		}
+		ds, err := c.deployments(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		dst := *out
+		if dst == "" {
+			dst = filepath.Join(c.ContractDir, constantsDir, constantsDir+".go")
+		}
+		abs, err := filepath.Abs(dst)
+		if err != nil {
+			return err
+		}
+		name := *pkg
+		if name == "" {
+			name = goPackageName(filepath.Base(filepath.Dir(abs)))
+		}
+
+		contracts, err := constantsContracts(ctx, c.ContractDir, ds, *offline)
+		if err != nil {
+			return err
+		}
+
+		src, err := generateConstants(name, contracts)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dst), dirMode); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dst, src, fileMode); err != nil {
+			return err
+		}
+
+		fmt.Printf("wrote %d contracts to %s\n", len(contracts), dst)
+
+		return nil
+	}
+}
+
+// constantsContracts gathers the downloaded deployments ds by contract name, so a contract deployed on several chains
+// is a single constant with an address per chain. A second deployment on the same chain is named after its config name.
+func constantsContracts(ctx context.Context, contractDir string, ds []*deployment, offline bool) ([]*constantsContract, error) {
+	byIdent := map[string]*constantsContract{}
+	for _, d := range ds {
+		dir := d.dir(contractDir)
+		m, err := loadMetadata(filepath.Join(dir, metadataFile))
+		if err != nil {
+			return nil, fmt.Errorf("%s is not downloaded: %w", d.Name, err)
+		}
+
+		abi, err := os.ReadFile(filepath.Join(dir, abiFile))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		ident := goExportedName(firstNonEmpty(m.ContractName, filepath.Base(d.Name)))
+		if cc, ok := byIdent[ident]; ok && (cc.addresses[d.Chain] != "" || cc.abi != strings.TrimSpace(string(abi))) {
+			ident = goExportedName(d.Name)
+		}
+		if ident == "Contract" {
+			// the type of the variables
+			ident = "ContractContract"
+		}
+
+		cc, ok := byIdent[ident]
+		if !ok {
+			cc = &constantsContract{ident: ident, name: m.ContractName, abi: strings.TrimSpace(string(abi)), addresses: map[chain]string{}}
+			byIdent[ident] = cc
+		}
+		if _, ok := cc.addresses[d.Chain]; ok {
+			return nil, fmt.Errorf("%s: %s is already deployed on %s as another contract", d.Name, ident, d.Chain)
+		}
+		cc.addresses[d.Chain] = d.Address
+
+		if cc.bytecode == "" && !offline {
+			if cc.bytecode, err = deployedBytecode(ctx, dir, d); err != nil {
+				return nil, fmt.Errorf("%s: bytecode: %w", d.Name, err)
+			}
+		}
+	}
+
+	contracts := make([]*constantsContract, 0, len(byIdent))
+	for _, cc := range byIdent {
+		contracts = append(contracts, cc)
+	}
+	sort.Slice(contracts, func(i, j int) bool { return contracts[i].ident < contracts[j].ident })
+
+	return contracts, nil
+}
+
+// deployedBytecode returns the runtime bytecode of d, the saved bytecode.hex of an unverified contract or the code at its address.
+func deployedBytecode(ctx context.Context, dir string, d *deployment) (string, error) {
+	if bs, err := os.ReadFile(filepath.Join(dir, bytecodeFile)); err == nil {
+		return strings.TrimSpace(string(bs)), nil
+	}
+
+	explorer, ok := blockExploers[d.Chain]
+	if !ok {
+		return "", unsupportedChain(d.Chain)
+	}
+
+	return getCode(ctx, explorer, d.Address)
+}
+
+// generateConstants returns the Go file declaring a variable per contract with its ABI, bytecode and addresses.
+func generateConstants(pkg string, contracts []*constantsContract) ([]byte, error) {
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "// Code generated by etherscan-downloader constants; DO NOT EDIT.\n\n")
+	fmt.Fprintf(b, "// Package %s holds the ABI, bytecode and addresses of the contracts downloaded by etherscan-downloader.\n", pkg)
+	fmt.Fprintf(b, "package %s\n\n", pkg)
+
+	fmt.Fprintf(b, "// Contract is a verified contract and its deployments.\n")
+	fmt.Fprintf(b, "type Contract struct {\n")
+	fmt.Fprintf(b, "// Name is the name of the contract in its sources.\nName string\n")
+	fmt.Fprintf(b, "// ABI is the verified ABI as JSON.\nABI string\n")
+	fmt.Fprintf(b, "// Bytecode is the hex-encoded runtime bytecode deployed at the addresses.\nBytecode string\n")
+	fmt.Fprintf(b, "// Addresses are the addresses of the deployments by chain ID.\nAddresses map[uint64]string\n")
+	fmt.Fprintf(b, "}\n\n")
+
+	fmt.Fprintf(b, "// Address returns the address of c on the chain of chainID, empty when it isn't deployed there.\n")
+	fmt.Fprintf(b, "func (c *Contract) Address(chainID uint64) string { return c.Addresses[chainID] }\n\n")
+
+	for _, cc := range contracts {
+		chains := make([]chain, 0, len(cc.addresses))
+		for ch := range cc.addresses {
+			chains = append(chains, ch)
+		}
+		sort.Slice(chains, func(i, j int) bool { return chains[i] < chains[j] })
+
+		addresses := []string{}
+		for _, ch := range chains {
+			addresses = append(addresses, fmt.Sprintf("%d: %q", ch, cc.addresses[ch]))
+		}
+
+		fmt.Fprintf(b, "// %s is the %s contract.\n", cc.ident, cc.name)
+		fmt.Fprintf(b, "var %s = &Contract{\nName: %q,\nABI: %s,\nBytecode: %q,\nAddresses: map[uint64]string{%s},\n}\n\n",
+			cc.ident, cc.name, strconv.Quote(cc.abi), cc.bytecode, strings.Join(addresses, ", "))
+	}
+
+	return format.Source([]byte(b.String()))
+}
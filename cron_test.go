@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	at := func(s string) time.Time {
+		t.Helper()
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	for _, tt := range []struct {
+		expr string
+		from string
+		want string
+	}{
+		{"* * * * *", "2024-01-01 10:07", "2024-01-01 10:08"},
+		{"*/15 * * * *", "2024-01-01 10:07", "2024-01-01 10:15"},
+		{"0 */6 * * *", "2024-01-01 05:30", "2024-01-01 06:00"},
+		{"0 */6 * * *", "2024-01-01 18:00", "2024-01-02 00:00"},
+		{"5/20 * * * *", "2024-01-01 10:30", "2024-01-01 10:45"},
+		{"30 9 * * 1-5", "2024-01-06 10:00", "2024-01-08 09:30"}, // Saturday to Monday
+		{"0 0 * * 7", "2024-01-01 00:00", "2024-01-07 00:00"},    // 7 is Sunday
+		{"0 0 1,15 * *", "2024-01-02 00:00", "2024-01-15 00:00"},
+		{"0 0 1 */3 *", "2024-02-10 00:00", "2024-04-01 00:00"},
+		{"0 0 13 * 5", "2024-01-01 00:00", "2024-01-05 00:00"}, // either the day of month or of week
+		{"0 12 29 2 *", "2025-03-01 00:00", "2028-02-29 12:00"},
+		{"59 23 31 12 *", "2024-12-31 23:59", "2025-12-31 23:59"},
+	} {
+		s, err := parseCron(tt.expr)
+		if err != nil {
+			t.Errorf("parseCron(%q): %s", tt.expr, err)
+			continue
+		}
+		if got := s.next(at(tt.from)); !got.Equal(at(tt.want)) {
+			t.Errorf("%q after %s: %s, want %s", tt.expr, tt.from, got.Format("2006-01-02 15:04"), tt.want)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"a * * * *",
+		"5-1 * * * *",
+		"0 0 30 2 *", // never matches
+	} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) parsed, want an error", expr)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// NotifyConfig is where the daemon reports contracts whose verified sources changed.
+type NotifyConfig struct {
+	Webhook string `json:"webhook"` // URL POSTed a changeNotification as JSON
+}
+
+// changeNotification is the body of a webhook notification.
+type changeNotification struct {
+	Contract string        `json:"contract"`
+	Chain    chain         `json:"chainId"`
+	Address  string        `json:"address"`
+	Changes  []*fileChange `json:"changes"`
+	Time     time.Time     `json:"time"`
+}
+
+// scheduledContract is a contract synced by the daemon.
+type scheduledContract struct {
+	name     string
+	schedule *cronSchedule
+	next     time.Time
+}
+
+// daemonCommand runs until interrupted, syncing each contract on its schedule, or the config's schedule:
+// its sources are downloaded again when they changed on the explorer, logging and notifying the change.
+func daemonCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	flags := addDownloadFlags(fs)
+
+	return func(ctx context.Context, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		dl, err := flags.downloader(c)
+		if err != nil {
+			return err
+		}
+		ctx = dl.withClient(ctx)
+		if dl.unverified != nil {
+			// a contract verified since an earlier sync is picked up on its next schedule, not after unverifiedTTL
+			dl.unverified.refresh = true
+		}
+
+		now := time.Now()
+		scheduled := []*scheduledContract{}
+		for _, name := range c.names() {
+			key, expr := "contracts."+name+".schedule", c.Contracts[name].Schedule
+			if expr == "" {
+				key, expr = "schedule", c.Schedule
+			}
+			if expr == "" {
+				continue
+			}
+
+			s, err := parseCron(expr)
+			if err != nil {
+				return &configError{fmt.Errorf("%s: %w", key, err)}
+			}
+			scheduled = append(scheduled, &scheduledContract{name: name, schedule: s, next: s.next(now)})
+		}
+		if len(scheduled) == 0 {
+			return &configError{errors.New("no contract has a schedule, set schedule in config.json, e.g. \"schedule\": \"0 */6 * * *\"")}
+		}
+
+		log.Printf("syncing %d contracts on schedule", len(scheduled))
+
+		for {
+			next := scheduled[0].next
+			for _, sc := range scheduled {
+				if sc.next.Before(next) {
+					next = sc.next
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(time.Until(next)):
+			}
+
+			// each run fetches anew
+			dl.fetched = newRawCodeCache()
+
+			for _, sc := range scheduled {
+				if sc.next.After(time.Now()) {
+					continue
+				}
+				sc.next = sc.schedule.next(time.Now())
+
+				if err := dl.sync(ctx, c, sc.name); err != nil {
+					if errors.Is(err, context.Canceled) {
+						return nil
+					}
+					log.Printf("%s: %s", sc.name, redactAPIKeys(err.Error()))
+				}
+			}
+		}
+	}
+}
+
+// sync downloads the contract name again when its verified sources differ from the downloaded ones,
+// notifying the change.
+func (dl *downloader) sync(ctx context.Context, c *Config, name string) error {
+	d, err := c.deployment(withFetched(ctx, dl.fetched), name)
+	if err != nil {
+		return err
+	}
+
+	dir := d.dir(c.ContractDir)
+	downloaded := localStatus(dir) == "downloaded"
+
+	changes, _, err := dl.sourceDiff().diff(ctx, d, dir)
+	if err != nil {
+		return err
+	}
+	if downloaded && len(changes) == 0 {
+		log.Printf("%s: up to date", name)
+		return nil
+	}
+
+	if err := dl.download(ctx, d); err != nil {
+		return err
+	}
+
+	// like update, the files no longer verified are removed, as downloads only add and overwrite files
+	for _, change := range changes {
+		if change.Status != "D" {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, filepath.FromSlash(change.Path))); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if !downloaded {
+		log.Printf("%s: downloaded", name)
+		return nil
+	}
+
+	log.Printf("%s: %d files changed on the explorer, downloaded again", name, len(changes))
+	for _, change := range changes {
+		log.Printf("%s: %s %s", name, change.Status, change.Path)
+	}
+
+	if c.Notify != nil && c.Notify.Webhook != "" {
+		if err := notifyChange(ctx, c.Notify.Webhook, &changeNotification{Contract: name, Chain: d.Chain, Address: d.Address, Changes: changes, Time: time.Now().UTC()}); err != nil {
+			return fmt.Errorf("notify: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// notifyChange POSTs n to the webhook url.
+func notifyChange(ctx context.Context, url string, n *changeNotification) error {
+	bs, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bs))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("POST %s: %s", url, resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	decompiledDir = "decompiled"
+	decompilerLog = "decompiler.log"
+)
+
+// DecompilerConfig is a decompiler run against the bytecode of unverified contracts, e.g. heimdall.
+// In its arguments, {bytecode} is replaced by the path of bytecode.hex and {output} by the path of the decompiled directory.
+type DecompilerConfig struct {
+	Command []string `json:"command"`
+}
+
+// decompile runs the decompiler against the bytecode saved in dir, in dir/decompiled,
+// storing its combined output there as decompiler.log.
+func decompile(ctx context.Context, dir string, dc *DecompilerConfig) error {
+	if len(dc.Command) == 0 {
+		return errors.New("decompiler command is not configured")
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	out := filepath.Join(abs, decompiledDir)
+	if err := os.MkdirAll(out, dirMode); err != nil {
+		return err
+	}
+
+	r := strings.NewReplacer("{bytecode}", filepath.Join(abs, bytecodeFile), "{output}", out)
+	args := make([]string, len(dc.Command))
+	for i, arg := range dc.Command {
+		args[i] = r.Replace(arg)
+	}
+
+	log := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = out
+	cmd.Stdout = log
+	cmd.Stderr = log
+
+	runErr := cmd.Run()
+	if err := os.WriteFile(filepath.Join(out, decompilerLog), log.Bytes(), fileMode); err != nil {
+		return err
+	}
+	if runErr != nil {
+		return fmt.Errorf("decompiler: %w, see %s", runErr, filepath.Join(dir, decompiledDir, decompilerLog))
+	}
+
+	return nil
+}
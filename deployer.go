@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type Transaction struct {
+	BlockNumber     string `json:"blockNumber"`
+	To              string `json:"to"`
+	ContractAddress string `json:"contractAddress"`
+	IsError         string `json:"isError"`
+}
+
+// deployerDeployments lists the contracts deployed by the account d, named under d.Name by their address.
+// Only the contracts created by d's own transactions are listed; contracts created through factories are not.
+func deployerDeployments(ctx context.Context, d *deployment) ([]*deployment, error) {
+	explorer, ok := blockExploers[d.Chain]
+	if !ok {
+		return nil, unsupportedChain(d.Chain)
+	}
+
+	deployments := []*deployment{}
+	seen := map[string]bool{}
+	err := pageByBlock(func(start int) (int, int, error) {
+		txs := []*Transaction{}
+		params := url.Values{
+			"module":     {"account"},
+			"action":     {"txlist"},
+			"address":    {d.Address},
+			"sort":       {"asc"},
+			"startblock": {strconv.Itoa(start)},
+			"page":       {"1"},
+			"offset":     {strconv.Itoa(explorerPageSize)},
+		}
+		if err := queryExplorer(ctx, explorer, params, &txs); err != nil {
+			return 0, 0, err
+		}
+
+		last := start
+		for _, tx := range txs {
+			if n, err := strconv.Atoi(tx.BlockNumber); err == nil && n > last {
+				last = n
+			}
+
+			// contract creations are the transactions without a recipient
+			if tx.To != "" || tx.IsError != "0" || !isAddress(tx.ContractAddress) || seen[strings.ToLower(tx.ContractAddress)] {
+				continue
+			}
+			seen[strings.ToLower(tx.ContractAddress)] = true
+
+			deployments = append(deployments, &deployment{
+				Name:    filepath.Join(d.folder(), tx.ContractAddress),
+				Chain:   d.Chain,
+				Address: tx.ContractAddress,
+				OutDir:  d.OutDir,
+			})
+		}
+
+		return len(txs), last, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deployments, nil
+}
+
+// verifiedOnly returns the deployments of ds with verified sources, as a deployer's contracts are often not all verified.
+// The sources are fetched once, the download reusing them.
+func (dl *downloader) verifiedOnly(ctx context.Context, ds []*deployment) ([]*deployment, error) {
+	verified := []*deployment{}
+	for _, d := range ds {
+		rawCodes, err := dl.fetched.fetch(ctx, d)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", d.Name, err)
+		}
+
+		if isUnverified(rawCodes) {
+			fmt.Fprintf(os.Stderr, "%s: source code not verified, skipping\n", d.Name)
+			continue
+		}
+		verified = append(verified, d)
+	}
+
+	return verified, nil
+}
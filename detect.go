@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// detectChains sets the chain of the deployments of ds without one, e.g. a bare address target,
+// to the chain of the configured explorers where the address has code, preferring the one where it is verified.
+// All matches are reported; when several remain, the chain is asked for at a prompt when stdin is a terminal.
+func detectChains(ctx context.Context, ds []*deployment, fetched *rawCodeCache) error {
+	for _, d := range ds {
+		if d.Chain != 0 {
+			continue
+		}
+
+		results := probeChains(ctx, d.Address, fetched, false)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// an explorer failing to answer is no match, rather than failing the detection
+		matches := []*DiscoverResult{}
+		labels := []string{}
+		verified := []*DiscoverResult{}
+		for _, r := range results {
+			if !r.HasCode {
+				continue
+			}
+			matches = append(matches, r)
+
+			label := r.Chain
+			if r.Verified {
+				label += " (verified)"
+				verified = append(verified, r)
+			}
+			labels = append(labels, label)
+		}
+
+		switch {
+		case len(matches) == 0:
+			return fmt.Errorf("%s: %w on any configured explorer, prefix the address with its chain", d.Name, errNoCode)
+		case len(matches) == 1:
+			d.Chain = matches[0].ChainID
+		case len(verified) == 1:
+			d.Chain = verified[0].ChainID
+		case isTerminal(os.Stdin):
+			ch, err := askChain(d, labels, matches)
+			if err != nil {
+				return err
+			}
+			d.Chain = ch
+		default:
+			return fmt.Errorf("%s: found on %s, prefix the address with its chain, e.g. %s:%s", d.Name, strings.Join(labels, ", "), matches[0].ChainID, d.Address)
+		}
+
+		fmt.Fprintf(os.Stderr, "%s: found on %s, using %s\n", d.Name, strings.Join(labels, ", "), d.Chain)
+	}
+
+	return nil
+}
+
+// probeChains returns what the explorer of every configured chain knows of address, by chain id, asking them
+// concurrently, each explorer's requests limited to its rate. The code is read from the chain's rpc when configured.
+// The sources are asked for on the chains the address has code on, and with withoutCode on the others as well,
+// e.g. for a self-destructed contract; their getsourcecode results are kept in fetched.
+func probeChains(ctx context.Context, address string, fetched *rawCodeCache, withoutCode bool) []*DiscoverResult {
+	chains := []chain{}
+	for ch := range blockExploers {
+		chains = append(chains, ch)
+	}
+	sort.Slice(chains, func(i, j int) bool { return chains[i] < chains[j] })
+
+	results := make([]*DiscoverResult, len(chains))
+	wg := sync.WaitGroup{}
+	for i, ch := range chains {
+		wg.Add(1)
+		go func(i int, ch chain) {
+			defer wg.Done()
+			results[i] = probeChain(ctx, ch, address, fetched, withoutCode)
+		}(i, ch)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// probeChain returns what the explorer of ch knows of address, for probeChains.
+func probeChain(ctx context.Context, ch chain, address string, fetched *rawCodeCache, withoutCode bool) *DiscoverResult {
+	r := &DiscoverResult{ChainID: ch, Chain: ch.String()}
+	explorer := blockExploers[ch]
+	errs := []string{}
+
+	code := ""
+	var err error
+	if explorer.rpc != "" {
+		err = rpcCall(ctx, explorer.rpc, "eth_getCode", []interface{}{address, "latest"}, &code)
+	} else {
+		code, err = getCode(ctx, explorer, address)
+	}
+	if err != nil {
+		errs = append(errs, "code: "+redactAPIKeys(err.Error()))
+	}
+	r.HasCode = code != "" && code != "0x"
+
+	if r.HasCode || withoutCode {
+		rawCodes, err := fetched.fetch(ctx, &deployment{Name: address, Chain: ch, Address: address})
+		switch {
+		case err != nil:
+			errs = append(errs, "sources: "+redactAPIKeys(err.Error()))
+		case !isUnverified(rawCodes):
+			r.Verified, r.ContractName = true, rawCodes[0].ContractName
+		}
+	}
+
+	r.Error = strings.Join(errs, "; ")
+
+	return r
+}
+
+// askChain asks which of matches d is downloaded from.
+func askChain(d *deployment, labels []string, matches []*DiscoverResult) (chain, error) {
+	fmt.Fprintf(os.Stderr, "%s has code on several chains:\n", d.Address)
+	for i, label := range labels {
+		fmt.Fprintf(os.Stderr, "  %d) %s\n", i+1, label)
+	}
+
+	p := &prompter{r: bufio.NewReader(os.Stdin), w: os.Stderr}
+	answer, err := p.ask("chain", "1")
+	if err != nil {
+		return 0, err
+	}
+
+	if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(matches) {
+		return matches[i-1].ChainID, nil
+	}
+	for _, m := range matches {
+		if ch, err := parseChain(answer); err == nil && ch == m.ChainID {
+			return ch, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%s: not one of the chains: %s", d.Name, answer)
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// fileChange is a difference between the downloaded and the verified sources.
+type fileChange struct {
+	Status string `json:"status"` // "A" only verified, "D" only downloaded, "M" changed
+	Path   string `json:"path"`
+}
+
+// diffCommand prints the files which differ between the downloaded sources and those currently verified on the explorer,
+// or, with --against, between the sources committed at a git ref, e.g. an audited tag, and the verified ones.
+func diffCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	against := fs.String("against", "", "compare the sources committed at this git ref, e.g. v1.2.0, instead of the working tree")
+
+	return func(ctx context.Context, args []string) error {
+		return runDiff(ctx, args, *against)
+	}
+}
+
+func runDiff(ctx context.Context, args []string, against string) error {
+	c, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	deployments, err := c.deployments(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	drifted := 0
+	for _, d := range deployments {
+		dir := d.dir(c.ContractDir)
+		if against != "" {
+			tree, cleanup, err := checkoutGitTree(ctx, against, dir)
+			if err != nil {
+				return fmt.Errorf("%s: %w", d.Name, err)
+			}
+			defer cleanup()
+			dir = tree
+		}
+
+		changes, err := diffDeployment(ctx, c, d, dir)
+		if err != nil {
+			return fmt.Errorf("%s: %w", d.Name, err)
+		}
+
+		for _, change := range changes {
+			fmt.Printf("%s %s\n", change.Status, filepath.ToSlash(filepath.Join(d.Name, change.Path)))
+		}
+		if len(changes) > 0 {
+			drifted++
+		}
+	}
+
+	if drifted > 0 {
+		return fmt.Errorf("%w: %d of %d contracts", errDrift, drifted, len(deployments))
+	}
+
+	return nil
+}
+
+// diffDeployment compares the sources of d downloaded into dir with the verified sources, written as they were downloaded.
+func diffDeployment(ctx context.Context, c *Config, d *deployment, dir string) ([]*fileChange, error) {
+	s := &sourceDiff{fetch: fetchRawCode, layout: recordedLayout, normalize: c.Normalize, partial: true}
+	changes, _, err := s.diff(ctx, d, dir)
+
+	return changes, err
+}
+
+// sourceDiff compares the downloads of contracts with the files their download writes from the verified sources now.
+type sourceDiff struct {
+	fetch     func(ctx context.Context, d *deployment) ([]*RawCode, error)
+	layout    func(dir string) *SourceLayout
+	normalize *NormalizeConfig
+	only      []string // the globs of --only: the files of the download, the others are neither added nor removed
+	// partial compares a directory without metadata.json, written by a download with --only, by the files it has:
+	// those changed or no longer verified are reported, not the sources it doesn't have.
+	partial bool
+}
+
+// sourceDiff returns the sourceDiff of dl, comparing with the files its download writes.
+func (dl *downloader) sourceDiff() *sourceDiff {
+	return &sourceDiff{fetch: dl.fetched.fetch, layout: dl.layout, normalize: dl.normalize, only: dl.filter.only}
+}
+
+// diff compares the download of d into dir with the verified sources written by the layout of dir.
+// It returns the changes and the files written, by slash-separated path relative to dir.
+func (s *sourceDiff) diff(ctx context.Context, d *deployment, dir string) ([]*fileChange, map[string]string, error) {
+	rawCodes, err := s.fetch(ctx, d)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if isUnverified(rawCodes) {
+		return nil, nil, errNotVerified
+	}
+
+	layout := s.layout(dir)
+	if layout.CombineProxy && !d.combined && rawCodes[0].Proxy == "1" && isAddress(rawCodes[0].Implementation) {
+		return s.diffCombined(ctx, d, dir, rawCodes[0].Implementation)
+	}
+
+	sourceCodes, err := parseContractCode(rawCodes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := map[string]string{}
+	for _, sourceCode := range sourceCodes {
+		sources, _, _ := layout.sources(sourceCode.Sources, sourceCode.Settings.Remappings, rawCodes[0], s.normalize)
+		for key, source := range sources {
+			files[filepath.ToSlash(sourcePath(key))] = source.Content
+		}
+	}
+
+	only := &sourceFilter{only: s.only}
+	for path := range files {
+		if !only.selects(path) {
+			delete(files, path)
+		}
+	}
+
+	changes, err := diffSources(dir, files)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = os.Stat(filepath.Join(dir, metadataFile))
+	partial := s.partial && os.IsNotExist(err)
+	if partial {
+		// a directory without any of the files isn't a download with --only, but none
+		added := 0
+		for _, change := range changes {
+			if change.Status == "A" {
+				added++
+			}
+		}
+		partial = added < len(files) || added < len(changes)
+	}
+
+	kept := []*fileChange{}
+	for _, change := range changes {
+		if (change.Status == "D" && !only.selects(change.Path)) || (change.Status == "A" && partial) {
+			continue
+		}
+		kept = append(kept, change)
+	}
+
+	return kept, files, nil
+}
+
+// diffCombined compares the directory of the proxy d downloaded with --combine-proxy: its proxy/ part with the
+// proxy's verified sources and its implementation/ part with those of the current implementation impl.
+func (s *sourceDiff) diffCombined(ctx context.Context, d *deployment, dir string, impl string) ([]*fileChange, map[string]string, error) {
+	proxy, implementation := combinedParts(d, strings.ToLower(impl))
+
+	changes, files := []*fileChange{}, map[string]string{}
+	for _, part := range []struct {
+		d   *deployment
+		dir string
+	}{{proxy, proxyPartDir}, {implementation, implementationPartDir}} {
+		partChanges, partFiles, err := s.diff(ctx, part.d, filepath.Join(dir, part.dir))
+		if errors.Is(err, errNotVerified) && part.d == implementation {
+			// an unverified implementation has no sources to compare
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", part.dir, err)
+		}
+
+		for _, change := range partChanges {
+			changes = append(changes, &fileChange{Status: change.Status, Path: part.dir + "/" + change.Path})
+		}
+		for path, content := range partFiles {
+			files[part.dir+"/"+path] = content
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+
+	return changes, files, nil
+}
+
+// diffSources compares the source files in dir with files, the contents written by path.
+// Files in the libraries and implementations directories and the generated interface are not sources of the contract itself.
+func diffSources(dir string, files map[string]string) ([]*fileChange, error) {
+	changes := []*fileChange{}
+	for path, content := range files {
+		local, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+		if os.IsNotExist(err) {
+			changes = append(changes, &fileChange{Status: "A", Path: path})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !bytes.Equal(local, []byte(content)) {
+			changes = append(changes, &fileChange{Status: "M", Path: path})
+		}
+	}
+
+	generated := map[string]bool{}
+	if m, err := loadMetadata(filepath.Join(dir, metadataFile)); err == nil {
+		generated["I"+m.ContractName+".sol"] = true
+	}
+
+	err := filepath.WalkDir(dir, func(path string, e fs.DirEntry, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if e.IsDir() {
+			if rel == "libraries" || rel == "implementations" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !isSourceFile(path) || generated[rel] {
+			return nil
+		}
+
+		if _, ok := files[rel]; !ok {
+			changes = append(changes, &fileChange{Status: "D", Path: rel})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+
+	return changes, nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeExplorer answers getsourcecode requests with the verifications set by verify, unverified for other addresses.
+type fakeExplorer struct {
+	mu      sync.Mutex
+	codes   map[string]map[string]string
+	queries map[string]int // the getsourcecode requests of each address
+	hold    chan struct{}  // when set, the responses wait until it is closed
+}
+
+// verify verifies the standard json input of sources as the contract name at address, with extra fields of the
+// getsourcecode result, e.g. a proxy's Proxy and Implementation.
+func (e *fakeExplorer) verify(t *testing.T, address, name string, sources map[string]string, extra map[string]string) {
+	t.Helper()
+
+	input := map[string]interface{}{"language": "Solidity", "sources": map[string]interface{}{}, "settings": map[string]interface{}{}}
+	for path, content := range sources {
+		input["sources"].(map[string]interface{})[path] = map[string]string{"content": content}
+	}
+	bs, err := json.Marshal(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := map[string]string{
+		"SourceCode":       "{" + string(bs) + "}",
+		"ABI":              "[]",
+		"ContractName":     name,
+		"CompilerVersion":  "v0.8.19+commit.7dd6d404",
+		"OptimizationUsed": "0",
+		"Runs":             "200",
+		"EVMVersion":       "Default",
+		"LicenseType":      "MIT",
+		"Proxy":            "0",
+	}
+	for k, v := range extra {
+		code[k] = v
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.codes == nil {
+		e.codes = map[string]map[string]string{}
+	}
+	e.codes[strings.ToLower(address)] = code
+}
+
+func (e *fakeExplorer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("action") != "getsourcecode" {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+
+	e.mu.Lock()
+	if e.queries == nil {
+		e.queries = map[string]int{}
+	}
+	e.queries[strings.ToLower(q.Get("address"))]++
+	hold := e.hold
+	e.mu.Unlock()
+	if hold != nil {
+		<-hold
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	code, ok := e.codes[strings.ToLower(q.Get("address"))]
+	if !ok {
+		code = map[string]string{"SourceCode": "", "ABI": "Contract source code not verified"}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "1", "message": "OK", "result": []interface{}{code}})
+}
+
+// inTestProject runs the test in a temporary directory with a config.json of contracts on the chain 1,
+// whose explorer is the returned fake.
+func inTestProject(t *testing.T, contracts string) *fakeExplorer {
+	t.Helper()
+
+	e := &fakeExplorer{}
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+
+	explorers := blockExploers
+	blockExploers = map[chain]blockExplorer{}
+	for ch, explorer := range explorers {
+		blockExploers[ch] = explorer
+	}
+	t.Cleanup(func() { blockExploers = explorers })
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	config := fmt.Sprintf(`{"contractDir": "contracts", "unverifiedTTL": "0", "explorers": {"1": {"endpoint": %q, "apiKey": "test", "rateLimit": 1000}}, "contracts": %s}`, srv.URL, contracts)
+	if err := os.WriteFile(configFile, []byte(config), fileMode); err != nil {
+		t.Fatal(err)
+	}
+
+	return e
+}
+
+// runCommand runs the command line args, failing the test when it fails.
+func runCommand(t *testing.T, args ...string) {
+	t.Helper()
+
+	if err := run(context.Background(), args); err != nil {
+		t.Fatalf("%s: %s", strings.Join(args, " "), err)
+	}
+}
+
+func TestUpdateCombinedProxy(t *testing.T) {
+	const (
+		proxy = "0x1111111111111111111111111111111111111111"
+		implA = "0x2222222222222222222222222222222222222222"
+		implB = "0x3333333333333333333333333333333333333333"
+	)
+
+	e := inTestProject(t, fmt.Sprintf(`{"vault": {"chain": 1, "address": %q}}`, proxy))
+	e.verify(t, proxy, "Proxy", map[string]string{"src/Proxy.sol": "contract Proxy {}\n"}, map[string]string{"Proxy": "1", "Implementation": implA})
+	e.verify(t, implA, "VaultV1", map[string]string{"src/VaultV1.sol": "contract VaultV1 {}\n"}, nil)
+	e.verify(t, implB, "VaultV2", map[string]string{"src/VaultV2.sol": "contract VaultV2 {}\n"}, nil)
+
+	runCommand(t, "download", "--quiet", "--combine-proxy", "vault")
+
+	dir := filepath.Join("contracts", "vault")
+	want := []string{"proxy/src/Proxy.sol", "implementation/src/VaultV1.sol"}
+	for _, path := range want {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Fatalf("download: %s", err)
+		}
+	}
+
+	c, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := c.deployment(context.Background(), "vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := diffDeployment(context.Background(), c, d, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("diff after download: %d changes, want none: %v", len(changes), changes)
+	}
+
+	// an update of an up to date download must leave its files
+	runCommand(t, "update", "--quiet", "--yes", "--combine-proxy", "vault")
+	for _, path := range want {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Fatalf("update: %s", err)
+		}
+	}
+
+	// the proxy upgraded to a new implementation: its files replace the old one's
+	e.verify(t, proxy, "Proxy", map[string]string{"src/Proxy.sol": "contract Proxy {}\n"}, map[string]string{"Proxy": "1", "Implementation": implB})
+
+	changes, err = diffDeployment(context.Background(), c, d, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, change := range changes {
+		got = append(got, change.Status+" "+change.Path)
+	}
+	if strings.Join(got, ",") != "D implementation/src/VaultV1.sol,A implementation/src/VaultV2.sol" {
+		t.Fatalf("diff after the upgrade: %v", got)
+	}
+
+	runCommand(t, "update", "--quiet", "--yes", "vault")
+	if _, err := os.Stat(filepath.Join(dir, "implementation/src/VaultV1.sol")); !os.IsNotExist(err) {
+		t.Fatalf("update kept the old implementation's source: %v", err)
+	}
+	for _, path := range []string{"proxy/src/Proxy.sol", "implementation/src/VaultV2.sol"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Fatalf("update: %s", err)
+		}
+	}
+}
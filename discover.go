@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// DiscoverResult is what the explorer of a chain knows of an address.
+type DiscoverResult struct {
+	ChainID      chain  `json:"chainId"`
+	Chain        string `json:"chain"`
+	HasCode      bool   `json:"hasCode"`
+	Verified     bool   `json:"verified"`
+	ContractName string `json:"contractName,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// found reports whether the address is deployed or verified on the chain.
+func (r *DiscoverResult) found() bool {
+	return r.HasCode || r.Verified
+}
+
+// discoverCommand fans out across every known explorer to find the chains an address has code or verified sources on,
+// e.g. a protocol deployed at the same address on several chains, adding them to config.json with --add.
+func discoverCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	add := fs.String("add", "", "add the chains found to config.json under this name, as deployments when there are several")
+	output := fs.String("output", "text", "output format: text or json")
+
+	return func(ctx context.Context, args []string) error {
+		if *output != "text" && *output != "json" {
+			return &configError{fmt.Errorf("unknown output format %q, want text or json", *output)}
+		}
+		if len(args) != 1 {
+			return &configError{errors.New("usage: discover [--add <name>] <address>")}
+		}
+
+		address := args[0]
+		if !isAddress(address) {
+			return &configError{fmt.Errorf("%w: %s", errBadAddress, address)}
+		}
+
+		if _, err := loadConfig(); err != nil && (*add != "" || !errors.Is(err, os.ErrNotExist)) {
+			// the explorers of the config are searched too, an address is discovered without one
+			return err
+		}
+
+		results := discover(ctx, address)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if *output == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(results); err != nil {
+				return err
+			}
+		} else {
+			printDiscover(address, results)
+		}
+
+		failed := 0
+		for _, r := range results {
+			if r.Error != "" && !r.found() {
+				failed++
+			}
+		}
+		if failed == len(results) {
+			return fmt.Errorf("no explorer answered for %s", address)
+		}
+
+		if *add != "" {
+			return addDiscovered(*add, address, results)
+		}
+
+		return nil
+	}
+}
+
+// discover queries the explorer of every known chain for the code and the verified sources of address at once,
+// each explorer's requests limited to its rate, and returns the results by chain id.
+func discover(ctx context.Context, address string) []*DiscoverResult {
+	return probeChains(ctx, address, newRawCodeCache(), true)
+}
+
+// printDiscover prints a line per chain the address was found on, and the explorers which failed to answer.
+func printDiscover(address string, results []*DiscoverResult) {
+	found := 0
+	for _, r := range results {
+		if r.Error != "" {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", r.Chain, r.Error)
+		}
+		if !r.found() {
+			continue
+		}
+		found++
+
+		code := "no code"
+		if r.HasCode {
+			code = "code"
+		}
+		verified := "unverified"
+		if r.Verified {
+			verified = "verified as " + r.ContractName
+		}
+		fmt.Printf("%-12s %-8d %s, %s\n", r.Chain, r.ChainID, code, verified)
+	}
+
+	fmt.Printf("%s found on %d of %d chains\n", address, found, len(results))
+}
+
+// addDiscovered adds the chains address was found on to config.json as name, a contract on the only chain found,
+// or a contract with a deployment per chain.
+func addDiscovered(name string, address string, results []*DiscoverResult) error {
+	cc := ConfigContract{}
+	for _, r := range results {
+		if r.found() {
+			cc.Deployments = append(cc.Deployments, ConfigDeployment{Chain: r.ChainID, Address: address})
+		}
+	}
+
+	switch len(cc.Deployments) {
+	case 0:
+		return fmt.Errorf("%s: found on no chain, nothing to add", address)
+	case 1:
+		cc = ConfigContract{Chain: cc.Deployments[0].Chain, Address: address}
+	}
+
+	if configPath == "-" {
+		return errStdinConfig
+	}
+
+	bs, err := readConfig()
+	if err != nil {
+		return err
+	}
+
+	bs, err = addConfigContract(bs, name, cc)
+	if err != nil {
+		return err
+	}
+
+	if err := writeConfigFile(bs); err != nil {
+		return err
+	}
+
+	fmt.Println("added", name)
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// doctor collects the results of environment checks.
+type doctor struct {
+	problems int
+}
+
+func (doc *doctor) ok(format string, a ...interface{}) {
+	fmt.Printf("ok    "+format+"\n", a...)
+}
+
+func (doc *doctor) warn(fix string, format string, a ...interface{}) {
+	fmt.Printf("warn  %s\n", redactAPIKeys(fmt.Sprintf(format, a...)))
+	fmt.Printf("      -> %s\n", fix)
+}
+
+func (doc *doctor) fail(fix string, format string, a ...interface{}) {
+	doc.problems++
+	fmt.Printf("FAIL  %s\n", redactAPIKeys(fmt.Sprintf(format, a...)))
+	fmt.Printf("      -> %s\n", fix)
+}
+
+// runDoctor checks the config, the explorer API keys and endpoints, and the external tools, printing how to fix what is wrong.
+func runDoctor(ctx context.Context, args []string) error {
+	doc := &doctor{}
+
+	chains := doc.checkConfig()
+	for _, ch := range chains {
+		doc.checkExplorer(ctx, ch)
+	}
+	doc.checkTools()
+
+	if doc.problems > 0 {
+		return fmt.Errorf("doctor found %d problem(s)", doc.problems)
+	}
+
+	return nil
+}
+
+// checkConfig checks config.json and returns the chains its contracts are on.
+func (doc *doctor) checkConfig() []chain {
+	c, err := loadConfig()
+	if err != nil {
+		doc.fail("create one with `etherscan-downloader init`", "%s: %s", configPath, err)
+
+		chains := []chain{}
+		for ch := range blockExploers {
+			chains = append(chains, ch)
+		}
+		sort.Slice(chains, func(i, j int) bool { return chains[i] < chains[j] })
+
+		return chains
+	}
+	doc.ok("%s loaded (%d contracts)", configPath, len(c.Contracts))
+
+	if c.ContractDir == "" {
+		doc.fail(`set "contractDir" to the directory sources are downloaded into`, "contractDir is empty")
+	}
+
+	if c.Target != "" {
+		if _, err := c.configured(c.Target); err != nil {
+			doc.fail(`set "target" to the name of a contract in "contracts"`, "target: %s", err)
+		}
+	}
+
+	used := map[chain]bool{}
+	for _, name := range c.names() {
+		ch, address, err := c.Contracts[name].resolve()
+		switch {
+		case err != nil:
+			doc.fail(`use a 0x address with "chain", or a chain-prefixed address like eth:0x...`, "contract %s: %s", name, err)
+		case address == "" && c.Contracts[name].Registry != nil:
+			if _, err := c.Contracts[name].Registry.calldata(); err != nil {
+				doc.fail(`set "call" to the getter's signature and "args" to its arguments`, "contract %s: registry: %s", name, err)
+			}
+			if ch == 0 {
+				doc.fail(`set "chain" or use a chain-prefixed registry address`, "contract %s: no chain", name)
+			} else if _, ok := blockExploers[ch]; ok {
+				used[ch] = true
+			}
+		case !isAddress(address):
+			doc.fail("use a 20-byte hex address", "contract %s: invalid address %q", name, address)
+		case ch == 0:
+			doc.fail(`set "chain" or use a chain-prefixed address`, "contract %s: no chain", name)
+		default:
+			if _, ok := blockExploers[ch]; !ok {
+				if c.Contracts[name].Source != sourceTenderly {
+					doc.fail("use one of the supported chains", "contract %s: %s", name, unsupportedChain(ch))
+				}
+				continue
+			}
+			used[ch] = true
+		}
+	}
+
+	if c.History != nil {
+		if _, err := exec.LookPath("sqlite3"); err != nil {
+			doc.fail("install sqlite3 or remove \"history\" from config.json", "sqlite3 not found, needed to record the history")
+		} else {
+			doc.ok("sqlite3 found for the history")
+		}
+	}
+
+	if c.Tenderly != nil {
+		if _, err := c.Tenderly.accessKey(); err != nil {
+			doc.fail(fmt.Sprintf("export %s=<your access token>", firstNonEmpty(c.Tenderly.AccessKeyEnv, defaultTenderlyAccessEnv)), "%s", err)
+		} else {
+			doc.ok("tenderly: access token set for %s/%s", c.Tenderly.Account, c.Tenderly.Project)
+		}
+	}
+
+	chains := []chain{}
+	for ch := range used {
+		chains = append(chains, ch)
+	}
+	sort.Slice(chains, func(i, j int) bool { return chains[i] < chains[j] })
+
+	return chains
+}
+
+// checkExplorer makes a test call to the explorer of ch with its API key.
+func (doc *doctor) checkExplorer(ctx context.Context, ch chain) {
+	explorer := blockExploers[ch]
+
+	if explorer.api == zksyncAPI {
+		start := time.Now()
+		_, err := getZksyncSourceResponse(ctx, explorer, "0x0000000000000000000000000000000000000000")
+		if err != nil {
+			doc.fail(fmt.Sprintf("check the network and that %s is reachable", explorer.endpoint), "%s: %s", explorer.site, err)
+			return
+		}
+		doc.ok("%s: API reachable (%s)", explorer.site, time.Since(start).Round(time.Millisecond))
+		return
+	}
+
+	if !explorer.hasKey() && !explorer.keyOptional() {
+		doc.warn(fmt.Sprintf("export %s=<your key>, keyless requests are heavily rate limited", explorer.apiKeyEnv), "%s: %s is not set", explorer.site, explorer.apiKeyEnv)
+	}
+
+	params := url.Values{
+		"module":  {"account"},
+		"action":  {"balance"},
+		"address": {"0x0000000000000000000000000000000000000000"},
+		"tag":     {"latest"},
+	}
+
+	start := time.Now()
+	var balance string
+	err := queryExplorer(ctx, explorer, params, &balance)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	switch {
+	case err == nil:
+		doc.ok("%s: API reachable (%s)", explorer.site, elapsed)
+	case errors.Is(err, errInvalidAPIKey):
+		doc.fail(fmt.Sprintf("check %s, keys are created at https://%s/myapikey", explorer.apiKeyEnv, explorer.site), "%s: invalid API key", explorer.site)
+	case errors.Is(err, errRateLimited):
+		doc.warn("wait and retry, or use an API key with a higher rate limit", "%s: rate limited: %s", explorer.site, err)
+	default:
+		doc.fail(fmt.Sprintf("check the network and that %s is reachable", explorer.endpoint), "%s: %s", explorer.site, err)
+	}
+}
+
+// checkTools checks for the external tools some flags need.
+func (doc *doctor) checkTools() {
+	if _, err := exec.LookPath("abigen"); err != nil {
+		doc.warn("install it with `go install github.com/ethereum/go-ethereum/cmd/abigen@latest`", "abigen not found, needed for --gen-go-bindings")
+	} else {
+		doc.ok("abigen found")
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		doc.warn("set HOME or XDG_CACHE_HOME", "no user cache directory for solc downloads: %s", err)
+		return
+	}
+	doc.ok("solc downloads cached in %s", filepath.Join(cacheDir, "etherscan-downloader", "solc"))
+}
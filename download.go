@@ -0,0 +1,1179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// deployment is a contract deployed at Address on Chain, downloaded into a directory called Name.
+type deployment struct {
+	Name    string
+	Chain   chain
+	Address string
+	OutDir  string // directory the sources are written to instead of contractDir
+	As      string // name of the sources' directory instead of Name
+
+	PostDownload []string        // hook commands run in the directory once downloaded
+	Integrity    string          // the pinned integrity of the verified sources, if any
+	Registry     *RegistryConfig // the registry returning Address, called at download time
+	Source       string          // sourceTenderly for a contract of the Tenderly project, empty for the explorer's
+
+	combined bool // the proxy or implementation part of a download with --combine-proxy
+}
+
+// folder returns the name of the directory the sources of d are written to.
+func (d *deployment) folder() string {
+	return firstNonEmpty(d.As, d.Name)
+}
+
+// dir returns the directory the sources of d are written to.
+func (d *deployment) dir(contractDir string) string {
+	return filepath.Join(firstNonEmpty(d.OutDir, contractDir), d.folder())
+}
+
+// unresolved reports whether the chain or the address of d is left to be detected or returned by its registry.
+func (d *deployment) unresolved() bool {
+	return d.Chain == 0 || d.Address == ""
+}
+
+// downloaded sets the chain and address of the unresolved deployment d to those of its download in dir,
+// as recorded in its metadata.json.
+func (d *deployment) downloaded(dir string) error {
+	m, err := loadMetadata(filepath.Join(dir, metadataFile))
+	if err != nil {
+		return errors.New("chain detected / registry resolved at download time, and no downloaded metadata.json to take them from")
+	}
+	d.Chain, d.Address = m.Chain, m.Address
+
+	return nil
+}
+
+// downloadFlags are the flags of commands that download sources.
+type downloadFlags struct {
+	input              *string
+	factory            *bool
+	deployer           *bool
+	verifyCompiles     *bool
+	verifyBytecode     *bool
+	verifyMetadataHash *bool
+	foundryProfile     *bool
+	vscode             *bool
+	genGoBindings      *bool
+	hardhatArtifact    *bool
+	genInterface       *bool
+	genDocs            *bool
+	selectors          *bool
+	packageJSON        *bool
+	storageLayout      *bool
+	ast                *bool
+	importGraph        *bool
+	analyze            *bool
+	decompile          *bool
+	fetchMetadata      *bool
+	lookupSignatures   *bool
+	implementations    *bool
+	combineProxy       *bool
+	tokenMetadata      *bool
+	onlyReachable      *bool
+	abiOnly            *bool
+	refresh            *bool
+	rawResponse        *bool
+	include            globsFlag
+	exclude            globsFlag
+	only               globsFlag
+	spdx               *string
+	dedup              *string
+	caseCollisions     *string
+	maxFiles           *int
+	checkpoint         *string
+	report             *string
+	quiet              *bool
+	maxBytes           *int64
+	maxRequests        *int
+	unflatten          *bool
+	ensureBuilds       *bool
+	maxDailyRequests   *int
+	httpTimeout        *string
+	connectTimeout     *string
+	proxy              *string
+	caCert             *string
+	insecure           *bool
+	userAgent          *string
+	dirMode            *string
+	fileMode           *string
+	record             *string
+	replay             *string
+	debugHTTP          *string
+	failFast           *bool
+	fetchWorkers       *int
+	parseWorkers       *int
+	pipelineBuffer     *int
+	breakerThreshold   *int
+	breakerCooldown    *string
+	output             *string
+
+	fs *flag.FlagSet
+}
+
+func addDownloadFlags(fs *flag.FlagSet) *downloadFlags {
+	f := &downloadFlags{
+		input:              fs.String("input", "", "CSV or JSON file listing contracts (chain,address,name) to download"),
+		factory:            fs.Bool("factory", false, "treat the target as a factory and download every contract it created"),
+		deployer:           fs.Bool("deployer", false, "treat the target as a deployer account and download every verified contract it deployed"),
+		verifyCompiles:     fs.Bool("verify-compiles", false, "compile the downloaded sources with the verified compiler and settings"),
+		verifyBytecode:     fs.Bool("verify-bytecode", false, "compare the runtime bytecode compiled from the downloaded sources with the deployed code"),
+		verifyMetadataHash: fs.Bool("verify-metadata-hash", false, "check that the metadata hash in the deployed bytecode can be reproduced from the downloaded sources"),
+		foundryProfile:     fs.Bool("foundry-profile", false, "write a foundry.toml pinning the verified compiler settings"),
+		vscode:             fs.Bool("vscode", false, "write a .code-workspace and .vscode/settings.json pinning the Solidity extension to the verified compiler and remappings"),
+		genGoBindings:      fs.Bool("gen-go-bindings", false, "generate Go bindings from the ABI with abigen"),
+		hardhatArtifact:    fs.Bool("hardhat-artifact", false, "compile the sources and write a Hardhat artifact"),
+		genInterface:       fs.Bool("gen-interface", false, "generate a Solidity interface I<ContractName>.sol from the ABI"),
+		genDocs:            fs.Bool("gen-docs", false, "compile the sources and render their NatSpec documentation as Markdown"),
+		selectors:          fs.Bool("selectors", false, "write the 4-byte function selectors as selectors.json and signatures.txt"),
+		packageJSON:        fs.Bool("package-json", false, "write a package.json pinning the versions of the npm packages (e.g. OpenZeppelin) the sources vendor"),
+		storageLayout:      fs.Bool("storage-layout", false, "compile the sources and write the contract's storage layout"),
+		ast:                fs.Bool("ast", false, "compile the sources and write the AST of each source file into ast/"),
+		importGraph:        fs.Bool("import-graph", false, "write the import graph of the sources as imports.dot and imports.mmd"),
+		decompile:          fs.Bool("decompile", false, "run the configured decompiler against the bytecode of unverified contracts, into decompiled/"),
+		analyze:            fs.Bool("analyze", false, "run the configured analyzer against each downloaded contract"),
+		fetchMetadata:      fs.Bool("fetch-metadata", false, "recover sources of unverified contracts from the IPFS/Swarm metadata referenced by their bytecode"),
+		lookupSignatures:   fs.Bool("lookup-signatures", false, "look up the selectors of unverified contracts in the openchain and 4byte.directory signature databases"),
+		implementations:    fs.Bool("implementations", false, "also download the implementation of proxies into implementations/<address>, keeping previous implementations"),
+		combineProxy:       fs.Bool("combine-proxy", false, "download proxies and their implementation into one directory, as proxy/ and implementation/ next to the merged ABI"),
+		onlyReachable:      fs.Bool("only-reachable", false, "write only the sources imported, directly or not, by the file defining the contract"),
+		rawResponse:        fs.Bool("raw-response", false, "save the explorer's getsourcecode response as is into raw-response.json"),
+		refresh:            fs.Bool("refresh", false, "query the contracts found unverified by a recent run again, instead of skipping them for unverifiedTTL"),
+		abiOnly:            fs.Bool("abi-only", false, "write only the verified ABI of each contract as abi.json, skipping the sources"),
+		tokenMetadata:      fs.Bool("token-metadata", false, "add the name, symbol and decimals of ERC-20/721 tokens to metadata.json"),
+		spdx:               fs.String("spdx", "", "add the SPDX line of the explorer's license to sources lacking one (insert), also rewriting conflicting ones (normalize)"),
+		caseCollisions:     fs.String("case-collisions", "", "handle sources differing only by case: error, or rename them (default an error where the filesystem ignores case)"),
+		maxFiles:           fs.Int("max-files", 0, "most source files written for a contract (default 10000, or limits.maxFiles in config.json)"),
+		maxBytes:           fs.Int64("max-bytes", 0, "most bytes of sources written for a contract (default 256 MiB, or limits.maxBytes in config.json)"),
+		ensureBuilds:       fs.Bool("ensure-builds", false, "compile the written tree with the verified compiler and settings, adding the remappings its imports need to remappings.txt, and fail when it doesn't build"),
+		unflatten:          fs.Bool("unflatten", false, "split single-file verifications flattened with \"// File: <path>\" markers back into the files they were made of"),
+		maxRequests:        fs.Int("max-requests", 0, "stop after this many explorer API calls in the run (default none, or budget.perRun in config.json)"),
+		maxDailyRequests:   fs.Int("max-daily-requests", 0, "stop after this many explorer API calls today on this machine, counted across runs (default none, or budget.perDay in config.json)"),
+		quiet:              fs.Bool("quiet", false, "print only warnings and errors, without the progress messages and the summary table of the run"),
+		report:             fs.String("report", "", "write an HTML report of the run into this file, with the outcome, files written and changed of each contract"),
+		checkpoint:         fs.String("checkpoint", "", "record the contracts downloaded into this file, resuming an interrupted run of the same contracts from it"),
+		dedup:              fs.String("dedup", "", "store sources once by content under contractDir/.store and link them into each contract (hardlink or symlink)"),
+		httpTimeout:        fs.String("http-timeout", "", "timeout of each HTTP request, e.g. 30s (default 60s, or http.timeout in config.json)"),
+		connectTimeout:     fs.String("connect-timeout", "", "timeout of connecting to a server (default 10s, or http.connectTimeout in config.json)"),
+		proxy:              fs.String("proxy", "", "proxy URL (http://, https:// or socks5://), instead of HTTP_PROXY/HTTPS_PROXY"),
+		caCert:             fs.String("ca-cert", "", "PEM file of CA certificates to trust in addition to the system ones"),
+		insecure:           fs.Bool("insecure", false, "skip TLS certificate verification (lab setups only)"),
+		output:             fs.String("output", "text", "output format: text, or ndjson for one JSON event per download, skip or error on stdout"),
+		failFast:           fs.Bool("fail-fast", false, "stop at the first contract failing to download, instead of downloading the others and summarizing the failures"),
+		fetchWorkers:       fs.Int("fetch-workers", defaultFetchWorkers, "fetch this many contracts from the explorers at once, ahead of their writes, within the rate limits"),
+		parseWorkers:       fs.Int("parse-workers", defaultParseWorkers, "decode the sources of this many contracts fetched ahead at once"),
+		pipelineBuffer:     fs.Int("pipeline-buffer", defaultPipelineBuffer, "fetch at most this many contracts ahead of the one being written"),
+		breakerThreshold:   fs.Int("breaker-threshold", 5, "defer the remaining contracts of a chain after this many consecutive failures of its explorer (0 disables)"),
+		breakerCooldown:    fs.String("breaker-cooldown", "5m", "how long to defer the contracts of a chain whose explorer keeps failing"),
+		record:             fs.String("record", "", "save the explorer responses as fixtures in this directory"),
+		replay:             fs.String("replay", "", "answer explorer requests from the fixtures in this directory instead of the network"),
+		debugHTTP:          fs.String("debug-http", "", "append the URLs of the explorer requests and the responses with their bodies to this file, API keys redacted"),
+		dirMode:            fs.String("dir-mode", "", "mode of the directories written, e.g. 0750 (default 0755, or permissions.dirMode in config.json)"),
+		fileMode:           fs.String("file-mode", "", "mode of the files written, e.g. 0640 (default 0644, or permissions.fileMode in config.json)"),
+		userAgent:          fs.String("user-agent", "", "User-Agent of requests (default etherscan-downloader/<version>)"),
+		fs:                 fs,
+	}
+	fs.Var(&f.include, "include", "write only the sources whose path matches one of these globs, e.g. 'src/**' (repeatable, or comma-separated)")
+	fs.Var(&f.only, "only", "write just the sources whose path matches one of these globs, e.g. 'contracts/Vault.sol', without metadata.json or other files (repeatable, or comma-separated)")
+	fs.Var(&f.exclude, "exclude", "skip the sources whose path matches one of these globs, e.g. 'contracts/mocks/**' (repeatable, or comma-separated)")
+
+	return f
+}
+
+// downloader returns the downloader configured by c and the flags.
+func (f *downloadFlags) downloader(c *Config) (*downloader, error) {
+	if err := configureHTTP(c.HTTP, &HTTPConfig{Timeout: *f.httpTimeout, ConnectTimeout: *f.connectTimeout, Proxy: *f.proxy, CACert: *f.caCert, InsecureSkipVerify: *f.insecure, UserAgent: *f.userAgent}); err != nil {
+		return nil, err
+	}
+
+	keepRawResponses = *f.rawResponse
+
+	if err := configureBudget(c.Budget, &BudgetConfig{PerRun: *f.maxRequests, PerDay: *f.maxDailyRequests}); err != nil {
+		return nil, err
+	}
+
+	unverified, err := newUnverifiedCache(c.UnverifiedTTL, *f.refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := configurePermissions(c.Permissions, &PermissionsConfig{DirMode: *f.dirMode, FileMode: *f.fileMode}); err != nil {
+		return nil, err
+	}
+
+	if *f.factory && *f.deployer {
+		return nil, errors.New("--factory and --deployer are mutually exclusive")
+	}
+
+	var client httpDoer
+	switch {
+	case *f.record != "" && *f.replay != "":
+		return nil, errors.New("--record and --replay are mutually exclusive")
+	case *f.record != "":
+		client = &recordingClient{base: httpClient, dir: *f.record}
+	case *f.replay != "":
+		client = &replayingClient{dir: *f.replay}
+	}
+
+	if *f.debugHTTP != "" {
+		w, err := os.OpenFile(*f.debugHTTP, os.O_WRONLY|os.O_CREATE|os.O_APPEND, fileMode)
+		if err != nil {
+			return nil, fmt.Errorf("--debug-http: %w", err)
+		}
+		// the log is written until the process exits
+		if client == nil {
+			client = httpClient
+		}
+		client = &debugClient{base: client, w: w}
+	}
+
+	cooldown, err := time.ParseDuration(*f.breakerCooldown)
+	if err != nil {
+		return nil, fmt.Errorf("--breaker-cooldown: %w", err)
+	}
+
+	history, err := newFetchHistory(c.History)
+	if err != nil {
+		return nil, err
+	}
+
+	dl := &downloader{
+		client:             client,
+		contractDir:        c.ContractDir,
+		libDir:             c.LibDir,
+		similarMatchPolicy: c.SimilarMatchPolicy,
+		normalize:          c.Normalize,
+		licenses:           c.Licenses,
+		history:            history,
+		signing:            c.Signing,
+		verifyCompiles:     *f.verifyCompiles,
+		verifyBytecode:     *f.verifyBytecode,
+		verifyMetadataHash: *f.verifyMetadataHash,
+		foundryProfile:     *f.foundryProfile,
+		vscode:             *f.vscode,
+		genGoBindings:      *f.genGoBindings,
+		hardhatArtifact:    *f.hardhatArtifact,
+		genInterface:       *f.genInterface,
+		genDocs:            *f.genDocs,
+		selectors:          *f.selectors,
+		packageJSON:        *f.packageJSON,
+		storageLayout:      *f.storageLayout,
+		ast:                *f.ast,
+		importGraph:        *f.importGraph,
+		fetchMetadata:      *f.fetchMetadata,
+		lookupSignatures:   *f.lookupSignatures,
+		implementations:    *f.implementations,
+		tokenMetadata:      *f.tokenMetadata,
+		abiOnly:            *f.abiOnly,
+		unverified:         unverified,
+		filter:             sourceFilter{only: f.only},
+		failFast:           *f.failFast,
+		fetchWorkers:       *f.fetchWorkers,
+		parseWorkers:       *f.parseWorkers,
+		pipelineBuffer:     *f.pipelineBuffer,
+		breaker:            newCircuitBreaker(*f.breakerThreshold, cooldown),
+		fetched:            newRawCodeCache(),
+		claims:             newPathClaims(),
+		caseCollisions:     *f.caseCollisions,
+		checkpoint:         *f.checkpoint,
+		report:             newRunReport(*f.report),
+		quiet:              *f.quiet,
+		ensureBuilds:       *f.ensureBuilds,
+	}
+
+	if dl.limits, err = newLimits(c.Limits, &LimitsConfig{MaxFiles: *f.maxFiles, MaxBytes: *f.maxBytes}); err != nil {
+		return nil, err
+	}
+
+	switch dl.caseCollisions {
+	case "", "error", "rename":
+	default:
+		return nil, fmt.Errorf("--case-collisions: unknown policy: %s, want error or rename", dl.caseCollisions)
+	}
+
+	layout := &SourceLayout{Unflatten: *f.unflatten, Include: f.include, Exclude: f.exclude, OnlyReachable: *f.onlyReachable, CombineProxy: *f.combineProxy}
+	if *f.spdx != "" {
+		replace, err := spdxMode(*f.spdx)
+		if err != nil {
+			return nil, err
+		}
+		layout.SPDX = "insert"
+		if replace {
+			layout.SPDX = "normalize"
+		}
+	}
+	dl.setLayoutFlags(f.fs, layout)
+
+	switch *f.output {
+	case "text":
+	case "ndjson":
+		dl.events = newEventWriter(os.Stdout)
+		// keep stdout for the events
+		humanOut = os.Stderr
+	default:
+		return nil, fmt.Errorf("unknown output format: %s", *f.output)
+	}
+
+	if *f.quiet {
+		humanOut = io.Discard
+	}
+
+	if *f.analyze {
+		if c.Analyzer == nil {
+			return nil, errors.New("--analyze needs an analyzer in config.json")
+		}
+		dl.analyzer = c.Analyzer
+	}
+
+	if *f.decompile {
+		if c.Decompiler == nil {
+			return nil, errors.New("--decompile needs a decompiler in config.json")
+		}
+		dl.decompiler = c.Decompiler
+	}
+
+	if *f.dedup != "" {
+		store, err := newContentStore(c.ContractDir, *f.dedup)
+		if err != nil {
+			return nil, err
+		}
+		dl.store = store
+	}
+
+	return dl, nil
+}
+
+// downloadCommand downloads the given targets, the contracts listed in --input, or the config's target.
+func downloadCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	flags := addDownloadFlags(fs)
+	tags := fs.String("tags", "", "download the contracts tagged with any of these comma-separated tags")
+	workspace := fs.Bool("workspace", false, "download the target of every "+workspaceConfigFile+" under the current directory, each from its own directory")
+	workspaceCommands := fs.Bool("workspace-commands", false, "with --workspace, run the commands of the configs found: apiKeyCmd, hooks, analyzer, decompiler and signing")
+
+	return func(ctx context.Context, args []string) error {
+		if *workspace {
+			if len(args) > 0 || *tags != "" {
+				return &configError{errors.New("--workspace downloads the target of each config, it takes no targets or --tags")}
+			}
+
+			return runWorkspace(ctx, *flags.failFast, *workspaceCommands, func(ctx context.Context, c *Config, fetched *rawCodeCache) error {
+				return flags.download(ctx, c, nil, "", fetched)
+			})
+		}
+
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		return flags.download(ctx, c, args, *tags, nil)
+	}
+}
+
+// download downloads the targets of c, those tagged with tags as well, sharing fetched with other downloads unless it is nil.
+func (f *downloadFlags) download(ctx context.Context, c *Config, args []string, tags string, fetched *rawCodeCache) error {
+	if tags != "" {
+		names, err := c.tagged(strings.Split(tags, ","))
+		if err != nil {
+			return err
+		}
+		args = append(args, names...)
+	}
+
+	dl, err := f.downloader(c)
+	if err != nil {
+		return err
+	}
+	if fetched != nil {
+		dl.fetched = fetched
+	}
+	ctx = dl.withClient(ctx)
+
+	if *f.input != "" {
+		deployments, err := readInput(*f.input)
+		if err != nil {
+			return err
+		}
+
+		if err := resolveUnknownChains(ctx, deployments); err != nil {
+			return err
+		}
+
+		return dl.downloadAll(ctx, deployments)
+	}
+
+	deployments, err := c.deployments(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	if err := resolveUnknownChains(ctx, deployments); err != nil {
+		return err
+	}
+
+	if *f.factory {
+		created := []*deployment{}
+		for _, d := range deployments {
+			ds, err := factoryDeployments(ctx, d)
+			if err != nil {
+				return err
+			}
+			created = append(created, ds...)
+		}
+		deployments = created
+	}
+
+	if *f.deployer {
+		deployed := []*deployment{}
+		for _, d := range deployments {
+			ds, err := deployerDeployments(ctx, d)
+			if err != nil {
+				return err
+			}
+			deployed = append(deployed, ds...)
+		}
+
+		if deployments, err = dl.verifiedOnly(ctx, deployed); err != nil {
+			return err
+		}
+	}
+
+	return dl.downloadAll(ctx, deployments)
+}
+
+// downloader downloads verified sources into contractDir.
+type downloader struct {
+	client             httpDoer // sends the explorer API requests of its commands' contexts, httpClient when nil
+	contractDir        string
+	libDir             string
+	similarMatchPolicy string
+	normalize          *NormalizeConfig
+	licenses           *LicensePolicyConfig
+	history            *fetchHistory
+	verifyCompiles     bool
+	verifyBytecode     bool
+	verifyMetadataHash bool
+	foundryProfile     bool
+	vscode             bool
+	genGoBindings      bool
+	hardhatArtifact    bool
+	genInterface       bool
+	genDocs            bool
+	selectors          bool
+	packageJSON        bool
+	storageLayout      bool
+	ast                bool
+	importGraph        bool
+	fetchMetadata      bool
+	lookupSignatures   bool
+	implementations    bool
+	tokenMetadata      bool
+	abiOnly            bool
+	filter             sourceFilter
+	layoutFlags        *SourceLayout
+	layoutSet          map[string]bool
+	failFast           bool
+	fetchWorkers       int
+	parseWorkers       int
+	pipelineBuffer     int
+	breaker            *circuitBreaker
+	fetched            *rawCodeCache
+	unverified         *unverifiedCache
+	analyzer           *AnalyzerConfig
+	decompiler         *DecompilerConfig
+	signing            *SigningConfig
+	store              *contentStore
+	events             *eventWriter
+	report             *runReport
+	quiet              bool
+	claims             *pathClaims
+	caseCollisions     string
+	limits             *LimitsConfig
+	checkpoint         string
+	ensureBuilds       bool
+}
+
+// withClient returns ctx whose explorer API requests are sent by the downloader's client, recording or replaying them
+// with --record or --replay, and whose deployments are resolved with the getsourcecode results of the run.
+func (dl *downloader) withClient(ctx context.Context) context.Context {
+	return withFetched(withExplorerClient(ctx, dl.client), dl.fetched)
+}
+
+func (dl *downloader) downloadAll(ctx context.Context, deployments []*deployment) error {
+	failed := []*contractError{}
+	deferred := []*contractError{}
+	var p *pipeline
+	defer func() {
+		dl.emit(&event{Event: "summary", Total: len(deployments), Failed: len(failed), Deferred: len(deferred)})
+
+		if !dl.quiet {
+			dl.report.printSummary(humanOut, useColor(humanOut))
+			if p != nil && (p.fetchWorkers > 1 || p.parseWorkers > 1) {
+				p.printStats(humanOut)
+			}
+		}
+
+		if dl.report != nil && dl.report.path != "" {
+			if err := dl.report.write(); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: report: %s\n", err)
+			} else {
+				fmt.Fprintf(humanOut, "wrote the report of the run to %s\n", dl.report.path)
+			}
+		}
+	}()
+
+	if err := checkDirCollisions(dl.contractDir, deployments); err != nil {
+		return err
+	}
+
+	var cp *checkpoint
+	if dl.checkpoint != "" {
+		var err error
+		if cp, err = openCheckpoint(dl.checkpoint, deployments); err != nil {
+			return err
+		}
+		if n := len(cp.done); n > 0 {
+			fmt.Fprintf(humanOut, "resuming from %s: skipping %d contracts already downloaded\n", dl.checkpoint, n)
+		}
+	}
+
+	p = newPipeline(dl, deployments, func(d *deployment) bool { return cp != nil && cp.isDone(d) })
+	p.start(ctx)
+	defer p.stop()
+
+	for i, d := range deployments {
+		if cp != nil && cp.isDone(d) {
+			continue
+		}
+
+		waited := p.wait(ctx, i)
+
+		if ok, until := dl.breaker.allow(d.Chain); !ok {
+			p.release(i)
+			err := fmt.Errorf("%w: the explorer of %s is failing, retry after %s", errDeferred, d.Chain, until.Format(time.Kitchen))
+			e := deploymentEvent("deferred", d)
+			e.Reason = redactAPIKeys(err.Error())
+			dl.emit(e)
+			deferred = append(deferred, &contractError{name: d.Name, err: err})
+			continue
+		}
+
+		start := time.Now()
+		err := dl.download(ctx, d)
+		p.written(i, waited, start)
+		dl.breaker.record(d.Chain, err)
+		if err == nil {
+			e := deploymentEvent("download", d)
+			e.DurationMS = time.Since(start).Milliseconds()
+			dl.emit(e)
+			if cp != nil {
+				if err := cp.markDone(d); err != nil {
+					return fmt.Errorf("checkpoint: %w", err)
+				}
+			}
+			continue
+		}
+
+		e := deploymentEvent("error", d)
+		e.Error = redactAPIKeys(err.Error())
+		dl.emit(e)
+
+		if ctx.Err() != nil {
+			fmt.Fprintf(os.Stderr, "interrupted: downloaded %d of %d contracts\n", i-len(failed)-len(deferred), len(deployments))
+			if cp != nil {
+				fmt.Fprintf(os.Stderr, "run again with --checkpoint %s to resume\n", dl.checkpoint)
+			}
+			return fmt.Errorf("%s: %w", d.Name, err)
+		}
+
+		if errors.Is(err, errBudgetExhausted) {
+			remaining := []string{}
+			for _, d := range deployments[i:] {
+				if cp == nil || !cp.isDone(d) {
+					remaining = append(remaining, d.Name)
+				}
+			}
+			fmt.Fprintf(os.Stderr, "request budget exhausted: %d contracts remaining: %s\n", len(remaining), strings.Join(remaining, " "))
+			if cp != nil {
+				fmt.Fprintf(os.Stderr, "run again with --checkpoint %s to resume\n", dl.checkpoint)
+			}
+			return fmt.Errorf("%s: %w", d.Name, err)
+		}
+
+		if dl.failFast || len(deployments) == 1 {
+			return fmt.Errorf("%s: %w", d.Name, err)
+		}
+
+		fmt.Fprintf(os.Stderr, "%s: %s\n", d.Name, redactAPIKeys(err.Error()))
+		failed = append(failed, &contractError{name: d.Name, err: err})
+	}
+
+	if len(failed) == 0 && len(deferred) == 0 {
+		if cp != nil {
+			return cp.remove()
+		}
+		return nil
+	}
+
+	if len(failed) > 0 {
+		printFailures(os.Stderr, failed, len(deployments))
+	}
+	if len(deferred) > 0 {
+		printDeferred(os.Stderr, deferred)
+	}
+
+	return &batchError{failed: failed, deferred: deferred}
+}
+
+// download fetches the verified sources of d and writes them under contractDir/d.Name (or d.OutDir/d.Name),
+// along with the sources of its linked libraries under its libraries directory.
+func (dl *downloader) download(ctx context.Context, d *deployment) (err error) {
+	if d.unresolved() {
+		if err := resolveDeployments(withFetched(ctx, dl.fetched), []*deployment{d}); err != nil {
+			return err
+		}
+	}
+
+	ctx, span := startSpan(ctx, "download", spanInternal, "contract.name", d.Name, "contract.address", d.Address)
+	defer func() { span.end(err) }()
+
+	dir := d.dir(dl.contractDir)
+
+	sb, err := dl.sandbox(d)
+	if err != nil {
+		return err
+	}
+	if err := sb.check(dir); err != nil {
+		return err
+	}
+	if err := sb.checkTree(dir); err != nil {
+		return err
+	}
+
+	at, ok, err := dl.unverified.cachedAt(d)
+	if err != nil {
+		return err
+	}
+	if ok {
+		fmt.Fprintf(os.Stderr, "%s: source code not verified as of %s, skipped until %s (--refresh to query it again)\n", d.Name, at.Local().Format(time.RFC3339), at.Add(dl.unverified.ttl).Local().Format(time.RFC3339))
+
+		e := deploymentEvent("skip", d)
+		e.Reason = errNotVerified.Error()
+		dl.emit(e)
+
+		return nil
+	}
+
+	if d.Source != sourceTenderly {
+		// the chain of a Tenderly contract may be a fork or virtual testnet the configured RPC doesn't serve
+		if err := dl.fetched.checkHasCode(ctx, d); err != nil {
+			return err
+		}
+	}
+
+	rawCodes, err := dl.fetched.fetch(ctx, d)
+	if err != nil {
+		return err
+	}
+
+	if err := dl.unverified.record(d, isUnverified(rawCodes)); err != nil {
+		return err
+	}
+
+	// the history is a record of the runs, which don't fail on it
+	if err := dl.recordFetch(ctx, d, rawCodes); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %s: %s\n", d.Name, redactAPIKeys(err.Error()))
+	}
+
+	if err := dl.fillABI(ctx, d, rawCodes); err != nil {
+		return err
+	}
+
+	if dl.abiOnly {
+		return dl.downloadABI(ctx, dir, d, rawCodes)
+	}
+
+	layout := dl.layout(dir)
+	if layout.CombineProxy && !d.combined && !isUnverified(rawCodes) && rawCodes[0].Proxy == "1" && isAddress(rawCodes[0].Implementation) {
+		return dl.downloadCombined(ctx, dir, d, rawCodes)
+	}
+
+	if isUnverified(rawCodes) && d.Source == sourceTenderly {
+		// nor is its code the explorer's
+		fmt.Fprintf(os.Stderr, "%s: not in the Tenderly project %s/%s or without its sources, skipped\n", d.Name, tenderlyProject.Account, tenderlyProject.Project)
+
+		e := deploymentEvent("skip", d)
+		e.Reason = errNotVerified.Error()
+		dl.emit(e)
+
+		return nil
+	}
+
+	if isUnverified(rawCodes) {
+		bytecode, err := saveUnverified(ctx, dir, d)
+		if err != nil {
+			return err
+		}
+
+		if r := dl.fetched.response(d); r != nil {
+			if err := writeProvenance(dir, d, r, ""); err != nil {
+				return err
+			}
+			if err := writeRawResponse(dir, r); err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintf(os.Stderr, "%s: source code not verified, saved bytecode and heuristic ABI\n", d.Name)
+
+		e := deploymentEvent("skip", d)
+		e.Reason = errNotVerified.Error()
+		dl.emit(e)
+
+		if dl.decompiler != nil {
+			if err := decompile(ctx, dir, dl.decompiler); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(os.Stderr, "%s: decompiled the bytecode into %s\n", d.Name, decompiledDir)
+		}
+
+		if dl.lookupSignatures {
+			n, err := writeGuessedSignatures(ctx, dir, bytecode)
+			if err != nil {
+				return fmt.Errorf("look up signatures: %w", err)
+			}
+
+			fmt.Fprintf(os.Stderr, "%s: guessed %d function signatures\n", d.Name, n)
+		}
+
+		if dl.fetchMetadata {
+			if err := recoverFromMetadata(ctx, dir, bytecode); err != nil {
+				return fmt.Errorf("recover sources from metadata: %w", err)
+			}
+
+			fmt.Fprintf(os.Stderr, "%s: recovered sources from the bytecode's metadata\n", d.Name)
+		}
+
+		return nil
+	}
+
+	if err := checkSimilarMatch(d, rawCodes[0], dl.similarMatchPolicy); err != nil {
+		return err
+	}
+
+	sourceCodes, err := dl.fetched.sources(d, rawCodes)
+	if err != nil {
+		return err
+	}
+
+	integrity := sourcesIntegrity(sourceCodes)
+	if err := checkIntegrity(d, integrity); err != nil {
+		return err
+	}
+
+	if err := dl.licenses.check(d, rawCodes[0].LicenseType, sourceCodes); err != nil {
+		return err
+	}
+
+	sharedRemappings := []string{}
+	written := map[string][]byte{}
+	pending := []*pendingFile{}
+	for _, sourceCode := range sourceCodes {
+		// standard-input.json keeps the verified sources, e.g. the flattened one of the files written unflattened
+		sources, notes, warnings := layout.sources(sourceCode.Sources, sourceCode.Settings.Remappings, rawCodes[0], dl.normalize)
+		for _, note := range notes {
+			fmt.Fprintf(humanOut, "%s: %s\n", d.Name, note)
+		}
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "%s: warning: %s\n", d.Name, warning)
+		}
+
+		shared, remappings, err := dl.shareLibraries(dir, sources)
+		if err != nil {
+			return err
+		}
+		sharedRemappings = append(sharedRemappings, remappings...)
+
+		for path, source := range sources {
+			if !dl.filter.selects(path) {
+				continue
+			}
+
+			dst := filepath.Join(dir, sourcePath(path))
+			if p, ok := shared[path]; ok {
+				dst = p
+			}
+
+			if err := sb.check(dst); err != nil {
+				return err
+			}
+
+			content := source.Content
+			if _, ok := shared[path]; !ok {
+				written[sourcePath(path)] = []byte(content)
+			}
+
+			pending = append(pending, &pendingFile{path: dst, source: path, content: []byte(content)})
+		}
+	}
+
+	if err := dl.limits.check(pending); err != nil {
+		return err
+	}
+
+	if err := dl.resolveCaseCollisions(dir, d, pending, written); err != nil {
+		return err
+	}
+
+	if err := dl.claims.claim(d.Name, pending); err != nil {
+		return err
+	}
+
+	if len(dl.filter.only) > 0 && len(pending) == 0 {
+		return fmt.Errorf("no source matches --only %s", strings.Join(dl.filter.only, ","))
+	}
+
+	if err := dl.report.files(d, dir, pending); err != nil {
+		return err
+	}
+
+	if err := writeFiles(ctx, pending, dl.store); err != nil {
+		return err
+	}
+
+	if len(dl.filter.only) > 0 {
+		// just the files asked for, not a download to keep up to date
+		fmt.Fprintf(humanOut, "%s: wrote %d sources matching --only\n", d.Name, len(pending))
+		return nil
+	}
+
+	if len(rawCodes) > 0 && len(sourceCodes) > 0 {
+		if err := dl.writeArtifacts(ctx, dir, d, rawCodes[0], sourceCodes[0], layout); err != nil {
+			return err
+		}
+
+		if err := appendRemappings(dir, sharedRemappings); err != nil {
+			return err
+		}
+
+		if dl.ensureBuilds {
+			if err := ensureBuilds(ctx, dir, dl.libDir, d, rawCodes[0].CompilerVersion, sourceCodes[0]); err != nil {
+				return err
+			}
+		}
+
+		if dl.vscode {
+			remappings := append(append([]string{}, sourceCodes[0].Settings.Remappings...), sharedRemappings...)
+			if err := writeVSCodeWorkspace(dir, rawCodes[0], remappings); err != nil {
+				return err
+			}
+		}
+
+		if err := writeChecksums(dir, written); err != nil {
+			return err
+		}
+
+		if dl.signing != nil {
+			if err := dl.signing.sign(ctx, dir); err != nil {
+				return err
+			}
+		}
+	}
+
+	if dl.implementations && !d.combined && len(rawCodes) > 0 && rawCodes[0].Proxy == "1" && isAddress(rawCodes[0].Implementation) {
+		impl := implementationDeployment(d, rawCodes[0].Implementation)
+		if err := dl.download(ctx, impl); err != nil {
+			return fmt.Errorf("implementation %s: %w", rawCodes[0].Implementation, err)
+		}
+
+		implCodes, err := dl.fetched.fetch(ctx, impl)
+		if err != nil {
+			return err
+		}
+		if !isUnverified(implCodes) && hasABI(rawCodes[0].Abi) && hasABI(implCodes[0].Abi) {
+			if err := writeMergedABI(dir, rawCodes[0].Abi, implCodes[0].Abi); err != nil {
+				return fmt.Errorf("merge ABI of %s: %w", rawCodes[0].Implementation, err)
+			}
+		}
+	}
+
+	if r := dl.fetched.response(d); r != nil {
+		if err := writeProvenance(dir, d, r, integrity); err != nil {
+			return err
+		}
+		if err := writeRawResponse(dir, r); err != nil {
+			return err
+		}
+	}
+
+	for _, rawCode := range rawCodes {
+		for _, library := range linkedLibraries(d, rawCode) {
+			if err := dl.download(ctx, library); err != nil {
+				return fmt.Errorf("library %s: %w", filepath.Base(library.Name), err)
+			}
+		}
+	}
+
+	return runHooks(ctx, d, dir, "postDownload", d.PostDownload)
+}
+
+// emit writes the event e, and adds it to the report of the run.
+func (dl *downloader) emit(e *event) {
+	dl.events.emit(e)
+	dl.report.record(e)
+}
+
+// downloadABI writes just the verified ABI of d, and with --implementations the ABI of its implementation merged in,
+// for --abi-only. An unverified contract is skipped.
+func (dl *downloader) downloadABI(ctx context.Context, dir string, d *deployment, rawCodes []*RawCode) error {
+	if isUnverified(rawCodes) || !hasABI(rawCodes[0].Abi) {
+		fmt.Fprintf(os.Stderr, "%s: source code not verified, no ABI\n", d.Name)
+
+		e := deploymentEvent("skip", d)
+		e.Reason = errNotVerified.Error()
+		dl.emit(e)
+
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, dirMode); err != nil {
+		return err
+	}
+	if err := writeABI(dir, rawCodes[0]); err != nil {
+		return err
+	}
+
+	if dl.implementations && rawCodes[0].Proxy == "1" && isAddress(rawCodes[0].Implementation) {
+		impl := implementationDeployment(d, rawCodes[0].Implementation)
+		implCodes, err := dl.fetched.fetch(ctx, impl)
+		if err != nil {
+			return fmt.Errorf("implementation %s: %w", rawCodes[0].Implementation, err)
+		}
+		if err := dl.fillABI(ctx, impl, implCodes); err != nil {
+			return fmt.Errorf("implementation %s: %w", rawCodes[0].Implementation, err)
+		}
+		if !isUnverified(implCodes) && hasABI(implCodes[0].Abi) {
+			if err := writeMergedABI(dir, rawCodes[0].Abi, implCodes[0].Abi); err != nil {
+				return fmt.Errorf("merge ABI of %s: %w", rawCodes[0].Implementation, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeArtifacts writes the files derived from the verification next to the sources and runs the enabled checks.
+func (dl *downloader) writeArtifacts(ctx context.Context, dir string, d *deployment, rawCode *RawCode, sourceCode *SourceCode, layout *SourceLayout) (err error) {
+	ctx, span := startSpan(ctx, "write artifacts", spanInternal, "contract.name", d.Name)
+	defer func() { span.end(err) }()
+
+	m := newMetadata(d, rawCode, sourceCode)
+	m.Layout = layout.recorded()
+	if dl.tokenMetadata {
+		abi, err := parseABI(rawCode.Abi)
+		if err != nil {
+			return fmt.Errorf("parse ABI: %w", err)
+		}
+
+		if m.Token, err = fetchTokenMetadata(ctx, d, abi); err != nil {
+			return fmt.Errorf("token metadata: %w", err)
+		}
+	}
+
+	if err := writeMetadata(dir, m, sourceCode); err != nil {
+		return err
+	}
+
+	if err := writeCompilerPin(dir, rawCode, sourceCode, dl.foundryProfile); err != nil {
+		return err
+	}
+
+	if err := writeABI(dir, rawCode); err != nil {
+		return err
+	}
+
+	if rawCode.Proxy == "1" && isAddress(rawCode.Implementation) {
+		if err := dl.recordImplementation(ctx, dir, d, rawCode); err != nil {
+			return fmt.Errorf("implementation history: %w", err)
+		}
+	}
+
+	if dl.genGoBindings {
+		if err := generateGoBindings(ctx, dir, rawCode.ContractName); err != nil {
+			return err
+		}
+	}
+
+	if dl.selectors {
+		if err := writeSelectors(dir, rawCode); err != nil {
+			return err
+		}
+	}
+
+	if dl.packageJSON {
+		if err := writePackageJSON(dir, d, sourceCode); err != nil {
+			return err
+		}
+	}
+
+	if dl.importGraph {
+		if err := writeImportGraph(dir, sourceCode); err != nil {
+			return err
+		}
+	}
+
+	if dl.genInterface {
+		if err := writeInterface(dir, rawCode); err != nil {
+			return err
+		}
+	}
+
+	if dl.hardhatArtifact {
+		if err := writeHardhatArtifact(ctx, dir, rawCode, sourceCode); err != nil {
+			return err
+		}
+	}
+
+	if dl.genDocs {
+		if err := writeDocs(ctx, dir, rawCode, sourceCode); err != nil {
+			return err
+		}
+	}
+
+	if dl.storageLayout {
+		if err := writeStorageLayout(ctx, dir, rawCode, sourceCode); err != nil {
+			return err
+		}
+	}
+
+	if dl.ast {
+		if err := writeASTs(ctx, dir, rawCode, sourceCode); err != nil {
+			return err
+		}
+	}
+
+	if dl.analyzer != nil {
+		if err := analyze(ctx, dir, dl.analyzer); err != nil {
+			return err
+		}
+	}
+
+	checks := &verifyChecks{compiles: dl.verifyCompiles, bytecode: dl.verifyBytecode, metadataHash: dl.verifyMetadataHash}
+	return checks.run(ctx, d, rawCode.CompilerVersion, rawCode.ContractName, sourceCode)
+}
+
+// maxParallelWrites bounds the files written at once, without running out of file descriptors.
+const maxParallelWrites = 16
+
+// pendingFile is a source file to be written by writeFiles.
+type pendingFile struct {
+	path    string
+	source  string // the source key the file is written from
+	content []byte
+}
+
+// writeFiles writes files concurrently, linking them from store when it is not nil.
+// A later file of the same path wins, and files not started yet are skipped after an error or when ctx is canceled,
+// so an interrupt never leaves one half written.
+func writeFiles(ctx context.Context, files []*pendingFile, store *contentStore) error {
+	last := map[string]int{}
+	for i, f := range files {
+		last[f.path] = i
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, maxParallelWrites)
+	for i, f := range files {
+		if last[f.path] != i {
+			continue
+		}
+
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(f *pendingFile) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := writeFile(f, store); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				cancel()
+			}
+		}(f)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	// the parent's error, e.g. an interrupt, as the own cancel only follows a failure
+	return ctx.Err()
+}
+
+func writeFile(f *pendingFile, store *contentStore) error {
+	if err := os.MkdirAll(filepath.Dir(f.path), dirMode); err != nil {
+		return err
+	}
+
+	if store != nil {
+		return store.link(f.path, f.content)
+	}
+
+	return writeFileAtomic(f.path, f.content)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into place,
+// so readers never see a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := os.Chmod(f.Name(), fileMode); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
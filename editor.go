@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const vscodeSettingsFile = ".vscode/settings.json"
+
+// VSCodeWorkspace is a VS Code .code-workspace file.
+type VSCodeWorkspace struct {
+	Folders  []*VSCodeFolder        `json:"folders"`
+	Settings map[string]interface{} `json:"settings"`
+}
+
+type VSCodeFolder struct {
+	Path string `json:"path"`
+}
+
+// writeVSCodeWorkspace writes <dir name>.code-workspace and .vscode/settings.json, pinning the Solidity extension
+// (juanblanco.solidity) to the verified compiler and the remappings, so go-to-definition works on opening dir.
+func writeVSCodeWorkspace(dir string, rawCode *RawCode, remappings []string) error {
+	settings := map[string]interface{}{
+		"solidity.remappings": remappings,
+	}
+	if v := rawCode.CompilerVersion; v != "" && !strings.HasPrefix(v, "vyper") {
+		settings["solidity.compileUsingRemoteVersion"] = v
+		settings["solidity.defaultCompiler"] = "remote"
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(vscodeSettingsFile)), dirMode); err != nil {
+		return err
+	}
+	if err := writeJSON(filepath.Join(dir, vscodeSettingsFile), settings); err != nil {
+		return err
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(filepath.Join(dir, filepath.Base(abs)+".code-workspace"), &VSCodeWorkspace{
+		Folders:  []*VSCodeFolder{{Path: "."}},
+		Settings: settings,
+	})
+}
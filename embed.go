@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const embedFile = "embed.go"
+
+// embeddedContract is a downloaded contract as listed in the generated file, its paths relative to the file.
+type embeddedContract struct {
+	name     string
+	metadata *Metadata
+	path     string   // the directory of the contract
+	dir      string   // path relative to the generated file, or the name with consts
+	files    []string // the ABI, metadata.json and the sources, relative to dir
+}
+
+func embedCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	out := fs.String("out", "", "Go file to write (default <contractDir>/"+embedFile+")")
+	pkg := fs.String("pkg", "", "package of the file (default named after its directory)")
+	consts := fs.Bool("const", false, "write the files as string constants instead of embedding them with go:embed, so the file can live outside contractDir")
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 0 {
+			return errors.New("usage: embed [--out <file>] [--pkg <name>] [--const]")
+		}
+
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		dst := *out
+		if dst == "" {
+			dst = filepath.Join(c.ContractDir, embedFile)
+		}
+		abs, err := filepath.Abs(dst)
+		if err != nil {
+			return err
+		}
+		name := *pkg
+		if name == "" {
+			name = goPackageName(filepath.Base(filepath.Dir(abs)))
+		}
+
+		contracts, err := embeddedContracts(c.ContractDir, filepath.Dir(dst), *consts)
+		if err != nil {
+			return err
+		}
+		if len(contracts) == 0 {
+			return fmt.Errorf("no contract downloaded into %s", c.ContractDir)
+		}
+
+		src, err := generateEmbed(name, contracts, *consts)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dst), dirMode); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dst, src, fileMode); err != nil {
+			return err
+		}
+
+		fmt.Printf("wrote %d contracts to %s\n", len(contracts), dst)
+
+		return nil
+	}
+}
+
+// embeddedContracts returns the contracts downloaded into contractDir with their directories relative to base,
+// the directory of the generated file, which go:embed requires them to be in, or named after them with consts.
+func embeddedContracts(contractDir string, base string, consts bool) ([]*embeddedContract, error) {
+	downloaded, err := findDownloaded(contractDir)
+	if err != nil {
+		return nil, err
+	}
+
+	contracts := []*embeddedContract{}
+	for _, d := range downloaded {
+		name, err := filepath.Rel(contractDir, d.Dir)
+		if err != nil {
+			return nil, err
+		}
+		dir := name
+		if !consts {
+			if dir, err = relPath(base, d.Dir); err != nil {
+				return nil, err
+			}
+			if dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+				return nil, fmt.Errorf("%s is outside of %s, which go:embed can't reach: write the file into contractDir or use --const", d.Dir, base)
+			}
+		}
+
+		files := []string{metadataFile}
+		if _, err := os.Stat(filepath.Join(d.Dir, abiFile)); err == nil {
+			files = append(files, abiFile)
+		}
+		for _, file := range d.Files {
+			rel, err := filepath.Rel(d.Dir, file)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+
+		contracts = append(contracts, &embeddedContract{name: filepath.ToSlash(name), metadata: d.Metadata, path: d.Dir, dir: filepath.ToSlash(dir), files: files})
+	}
+
+	return contracts, nil
+}
+
+// relPath returns target relative to base, either of which may be relative to the working directory.
+func relPath(base string, target string) (string, error) {
+	base, err := filepath.Abs(base)
+	if err != nil {
+		return "", err
+	}
+	target, err = filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Rel(base, target)
+}
+
+// generateEmbed returns the Go file exposing contracts: embedding their files with go:embed,
+// or holding them as string constants when consts is set.
+func generateEmbed(pkg string, contracts []*embeddedContract, consts bool) ([]byte, error) {
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "// Code generated by etherscan-downloader embed; DO NOT EDIT.\n\n")
+	fmt.Fprintf(b, "// Package %s holds the verified sources and ABIs of the contracts downloaded by etherscan-downloader.\n", pkg)
+	fmt.Fprintf(b, "package %s\n\n", pkg)
+
+	if consts {
+		fmt.Fprintf(b, "import (\n\"os\"\n\"path\"\n)\n\n")
+	} else {
+		fmt.Fprintf(b, "import (\n\"embed\"\n\"path\"\n)\n\n")
+	}
+
+	fmt.Fprintf(b, "// Contract is a downloaded verified contract.\n")
+	fmt.Fprintf(b, "type Contract struct {\n")
+	fmt.Fprintf(b, "ContractName string\nChainID uint64\nAddress string\n")
+	fmt.Fprintf(b, "// Dir is the directory of the contract, holding abi.json, metadata.json and the Sources.\nDir string\n")
+	fmt.Fprintf(b, "// Sources are the paths of the source files, relative to Dir.\nSources []string\n")
+	fmt.Fprintf(b, "}\n\n")
+
+	fmt.Fprintf(b, "// Contracts are the downloaded contracts by name.\n")
+	fmt.Fprintf(b, "var Contracts = map[string]*Contract{\n")
+	for _, c := range contracts {
+		sources := []string{}
+		for _, file := range c.files {
+			if file != metadataFile && file != abiFile {
+				sources = append(sources, strconv.Quote(file))
+			}
+		}
+		fmt.Fprintf(b, "%q: {ContractName: %q, ChainID: %d, Address: %q, Dir: %q, Sources: []string{%s}},\n",
+			c.name, c.metadata.ContractName, c.metadata.Chain, c.metadata.Address, c.dir, strings.Join(sources, ", "))
+	}
+	fmt.Fprintf(b, "}\n\n")
+
+	fmt.Fprintf(b, "// ABI returns the verified ABI of c.\n")
+	fmt.Fprintf(b, "func (c *Contract) ABI() ([]byte, error) { return readFile(path.Join(c.Dir, \"abi.json\")) }\n\n")
+	fmt.Fprintf(b, "// Metadata returns the metadata.json of c, its compiler settings.\n")
+	fmt.Fprintf(b, "func (c *Contract) Metadata() ([]byte, error) { return readFile(path.Join(c.Dir, \"metadata.json\")) }\n\n")
+	fmt.Fprintf(b, "// Source returns the source file of c named name, one of its Sources.\n")
+	fmt.Fprintf(b, "func (c *Contract) Source(name string) ([]byte, error) { return readFile(path.Join(c.Dir, name)) }\n\n")
+
+	if !consts {
+		fmt.Fprintf(b, "// FS holds the files of the Contracts.\n//\n")
+		for _, c := range contracts {
+			patterns := []string{}
+			for _, file := range c.files {
+				patterns = append(patterns, embedPattern(path.Join(c.dir, file)))
+			}
+			fmt.Fprintf(b, "//go:embed %s\n", strings.Join(patterns, " "))
+		}
+		fmt.Fprintf(b, "var FS embed.FS\n\n")
+		fmt.Fprintf(b, "func readFile(name string) ([]byte, error) { return FS.ReadFile(name) }\n")
+	} else {
+		fmt.Fprintf(b, "func readFile(name string) ([]byte, error) {\n")
+		fmt.Fprintf(b, "content, ok := files[name]\nif !ok {\nreturn nil, &os.PathError{Op: \"open\", Path: name, Err: os.ErrNotExist}\n}\n\nreturn []byte(content), nil\n}\n\n")
+		fmt.Fprintf(b, "var files = map[string]string{\n")
+		for _, c := range contracts {
+			for _, file := range c.files {
+				bs, err := os.ReadFile(filepath.Join(c.path, filepath.FromSlash(file)))
+				if err != nil {
+					return nil, err
+				}
+				fmt.Fprintf(b, "%q: %s,\n", path.Join(c.dir, file), strconv.Quote(string(bs)))
+			}
+		}
+		fmt.Fprintf(b, "}\n")
+	}
+
+	return format.Source([]byte(b.String()))
+}
+
+// embedPattern quotes path for a go:embed directive when it has a space or a quote.
+func embedPattern(path string) string {
+	if strings.ContainsAny(path, " \t\"`") {
+		return strconv.Quote(path)
+	}
+
+	return path
+}
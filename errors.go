@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Errors the commands branch on with errors.Is, and exitCode maps to exit codes; returned errors wrap them with details.
+// They are internal: this is a command, not an importable package, and other programs branch on its exit codes.
+var (
+	errNotVerified      = errors.New("source code not verified")
+	errRateLimited      = errors.New("rate limited")
+	errInvalidAPIKey    = errors.New("invalid API key")
+	errBadAddress       = errors.New("invalid address")
+	errUnknownChain     = errors.New("unknown chain")
+	errUnsupportedChain = errors.New("unsupported chain")
+	errInvalidConfig    = errors.New("invalid config")
+	errDrift            = errors.New("drift detected")
+	errExplorerDown     = errors.New("explorer unavailable")
+	errDeferred         = errors.New("deferred")
+	errNoCode           = errors.New("no contract code")
+	errLimitExceeded    = errors.New("limit exceeded")
+	errBudgetExhausted  = errors.New("request budget exhausted")
+	errReverted         = errors.New("call reverted")
+)
+
+// configError marks err as an errInvalidConfig, keeping its message.
+type configError struct {
+	err error
+}
+
+func (e *configError) Error() string { return e.err.Error() }
+
+func (e *configError) Unwrap() error { return e.err }
+
+func (e *configError) Is(target error) bool { return target == errInvalidConfig }
+
+// rateLimitedError wraps an errRateLimited which may be retried after retryAfter,
+// or after a back-off when the explorer didn't say how long to wait.
+type rateLimitedError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *rateLimitedError) Error() string { return e.err.Error() }
+
+func (e *rateLimitedError) Unwrap() error { return e.err }
+
+// unsupportedChain is the error for chains without a known block explorer.
+func unsupportedChain(c chain) error {
+	return fmt.Errorf("%w: %d", errUnsupportedChain, c)
+}
+
+// explorerError returns the error of an explorer response whose status is not "1",
+// wrapping errRateLimited, errInvalidAPIKey or errBadAddress when the explanation in the result says so.
+func explorerError(r *explorerResponse) error {
+	reason := string(r.Result)
+	var s string
+	if err := json.Unmarshal(r.Result, &s); err == nil {
+		reason = s
+	}
+
+	err := fmt.Errorf("bad status: %s, message: %s, result: %s", r.Status, r.Message, reason)
+
+	lower := strings.ToLower(reason)
+	switch {
+	case strings.Contains(lower, "daily") && strings.Contains(lower, "limit"):
+		// e.g. "Max daily rate limit reached", which lasts until the next day, isn't retried
+		return fmt.Errorf("%w: %s", errRateLimited, err)
+	case strings.Contains(lower, "rate limit"):
+		// e.g. Etherscan's "Max rate limit reached" of more calls per second than the key's tier
+		return &rateLimitedError{err: fmt.Errorf("%w: %s", errRateLimited, err)}
+	case strings.Contains(lower, "invalid api key"), strings.Contains(lower, "missing/invalid api key"):
+		return fmt.Errorf("%w: %s", errInvalidAPIKey, err)
+	case strings.Contains(lower, "invalid address"):
+		return fmt.Errorf("%w: %s", errBadAddress, err)
+	}
+
+	return err
+}
+
+// rpcError returns the error of a JSON-RPC error message of method, wrapping errReverted when it says the call reverted.
+func rpcError(method string, message string) error {
+	if strings.Contains(strings.ToLower(message), "revert") {
+		return fmt.Errorf("%s: %w: %s", method, errReverted, message)
+	}
+
+	return fmt.Errorf("%s: %s", method, message)
+}
+
+// contractError is the failure of one contract of a batch.
+type contractError struct {
+	name string
+	err  error
+}
+
+// batchError is returned when some contracts of a batch failed or were deferred.
+// It matches the errors of all failed and deferred contracts with errors.Is.
+type batchError struct {
+	failed   []*contractError
+	deferred []*contractError
+}
+
+func (e *batchError) Error() string {
+	if len(e.deferred) == 0 {
+		return fmt.Sprintf("%d contracts failed", len(e.failed))
+	}
+
+	return fmt.Sprintf("%d contracts failed, %d deferred", len(e.failed), len(e.deferred))
+}
+
+func (e *batchError) Is(target error) bool {
+	for _, f := range append(e.failed, e.deferred...) {
+		if errors.Is(f.err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *batchError) As(target interface{}) bool {
+	for _, f := range append(e.failed, e.deferred...) {
+		if errors.As(f.err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// printFailures prints a table of the failed contracts of a batch of total contracts.
+func printFailures(w io.Writer, failed []*contractError, total int) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\n%d of %d contracts failed\n", len(failed), total)
+	fmt.Fprintln(tw, "NAME\tERROR")
+	for _, f := range failed {
+		fmt.Fprintf(tw, "%s\t%s\n", f.name, redactAPIKeys(f.err.Error()))
+	}
+	tw.Flush()
+}
+
+// printDeferred prints a table of the contracts of a batch deferred by a tripped circuit breaker.
+func printDeferred(w io.Writer, deferred []*contractError) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\n%d contracts deferred, run again later to download them\n", len(deferred))
+	fmt.Fprintln(tw, "NAME\tREASON")
+	for _, f := range deferred {
+		fmt.Fprintf(tw, "%s\t%s\n", f.name, f.err)
+	}
+	tw.Flush()
+}
+
+// exit codes of the process, for CI
+const (
+	exitError      = 1 // anything else
+	exitConfig     = 2 // bad config, arguments or flags
+	exitNetwork    = 3 // network failures, rate limits and explorer errors
+	exitUnverified = 4 // a contract is not verified
+	exitDrift      = 5 // downloaded sources differ from the verified ones
+)
+
+// exitCode returns the exit code for err.
+func exitCode(err error) int {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errDrift):
+		return exitDrift
+	case errors.Is(err, errNotVerified):
+		return exitUnverified
+	case errors.Is(err, errRateLimited), errors.Is(err, errBudgetExhausted), errors.Is(err, errExplorerDown), errors.Is(err, errDeferred), errors.Is(err, errInvalidAPIKey), errors.Is(err, errResponseTooLarge), errors.As(err, &netErr):
+		return exitNetwork
+	case errors.Is(err, errInvalidConfig), errors.Is(err, errBadAddress), errors.Is(err, errNoCode), errors.Is(err, errUnknownChain), errors.Is(err, errUnsupportedChain):
+		return exitConfig
+	}
+
+	return exitError
+}
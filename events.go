@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// event is a line of --output ndjson.
+type event struct {
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"` // download, skip, error, deferred or summary
+	Name       string    `json:"name,omitempty"`
+	Chain      chain     `json:"chain,omitempty"`
+	Address    string    `json:"address,omitempty"`
+	DurationMS int64     `json:"durationMs,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Total      int       `json:"total,omitempty"`
+	Failed     int       `json:"failed,omitempty"`
+	Deferred   int       `json:"deferred,omitempty"`
+}
+
+// eventWriter writes events as newline-delimited JSON.
+type eventWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newEventWriter(w io.Writer) *eventWriter {
+	return &eventWriter{enc: json.NewEncoder(w)}
+}
+
+// emit writes e. A nil eventWriter discards events, so callers needn't check for text output.
+func (w *eventWriter) emit(e *event) {
+	if w == nil {
+		return
+	}
+
+	e.Time = time.Now().UTC()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.enc.Encode(e)
+}
+
+// deploymentEvent returns an event of kind about d.
+func deploymentEvent(kind string, d *deployment) *event {
+	return &event{Event: kind, Name: d.Name, Chain: d.Chain, Address: d.Address}
+}
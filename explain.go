@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// explainCommand prints what a download of the targets with the same flags would do, without sending requests:
+// the explorer and RPC requests, the fixtures, checkpoint and unverified cache used, whether proxies are followed and where the files land.
+func explainCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	flags := addDownloadFlags(fs)
+
+	return func(ctx context.Context, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		dl, err := flags.downloader(c)
+		if err != nil {
+			return err
+		}
+		ctx = dl.withClient(ctx)
+
+		deployments, err := c.configuredDeployments(args)
+		if err != nil {
+			return err
+		}
+
+		var cp *checkpoint
+		if dl.checkpoint != "" {
+			if cp, err = openCheckpoint(dl.checkpoint, deployments); err != nil {
+				return err
+			}
+		}
+
+		for i, d := range deployments {
+			if i > 0 {
+				fmt.Println()
+			}
+			if err := dl.explain(d, cp, *flags.record, *flags.replay); err != nil {
+				return fmt.Errorf("%s: %w", d.Name, err)
+			}
+		}
+
+		return nil
+	}
+}
+
+// explain prints the plan of the download of d.
+func (dl *downloader) explain(d *deployment, cp *checkpoint, record string, replay string) error {
+	fmt.Println(d.Name)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	line := func(key string, format string, a ...interface{}) {
+		fmt.Fprintf(tw, "  %s\t%s\n", key, fmt.Sprintf(format, a...))
+	}
+
+	dir := d.dir(dl.contractDir)
+
+	if cp != nil && cp.isDone(d) {
+		line("checkpoint", "already downloaded in %s, skipped", dl.checkpoint)
+		return nil
+	}
+
+	at, cached, err := dl.unverified.cachedAt(d)
+	if err != nil {
+		return err
+	}
+	if cached {
+		line("unverified", "not verified as of %s, skipped until %s (--refresh to query it again)", at.Local().Format(time.RFC3339), at.Add(dl.unverified.ttl).Local().Format(time.RFC3339))
+		return nil
+	}
+
+	explorer, ok := blockExploers[d.Chain]
+	switch {
+	case d.Chain == 0:
+		line("chain", "none set, detected at download time by querying the explorer of every chain")
+	case !ok && d.Source != sourceTenderly:
+		return unsupportedChain(d.Chain)
+	default:
+		line("chain", "%s (%d)", d.Chain, uint64(d.Chain))
+
+		if d.Registry != nil {
+			line("registry", "%s of %s, resolved at download time for the address of the contract", d.Registry.Call, d.Registry.Address)
+			break
+		}
+
+		if explorer.rpc != "" {
+			line("rpc", "eth_getCode %s, checking there is code at %s", explorer.rpc, d.Address)
+		}
+
+		// the key is redacted from the URL printed, and one logged in to the keychain isn't looked up
+		apiKey := explorer.apiKey
+		if apiKey == "" && explorer.keychain != "" {
+			apiKey = "REDACTED"
+		}
+		u := getContractURL(explorer.endpoint, d.Address, apiKey)
+		key := "with an API key"
+		switch {
+		case !explorer.hasKey() && explorer.api == routescanAPI:
+			key = "without an API key, at the rate limit Routescan shares across its chains"
+		case !explorer.hasKey():
+			key = "without an API key, at the keyless rate limit"
+		}
+		if explorer.api == zksyncAPI {
+			u = strings.TrimSuffix(explorer.endpoint, "/") + "/contract_verification/info/" + url.PathEscape(d.Address)
+			key = "the zkSync explorer takes no API key"
+		}
+		source := "explorer"
+		if d.Source == sourceTenderly {
+			u, source = tenderlyProject.contractURL(d.Chain, d.Address), sourceTenderly
+			key = "with the access token in the X-Access-Key header"
+		}
+
+		queried, err := url.Parse(u)
+		if err != nil {
+			return err
+		}
+		line(source, "GET %s (%s)", redactedURL(queried), key)
+
+		req, err := http.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			return err
+		}
+		switch {
+		case replay != "":
+			path, err := fixturePath(replay, req)
+			if err != nil {
+				return err
+			}
+			if _, err := os.Stat(path); err != nil {
+				line("fixture", "%s missing, the download fails", path)
+			} else {
+				line("fixture", "%s, replayed without network access", path)
+			}
+		case record != "":
+			path, err := fixturePath(record, req)
+			if err != nil {
+				return err
+			}
+			line("fixture", "recorded into %s", path)
+		}
+	}
+
+	if dl.abiOnly {
+		line("output", "%s", filepath.Join(dir, abiFile))
+		return nil
+	}
+
+	// a proxy downloaded with --combine-proxy has its metadata.json in proxy/
+	var m *Metadata
+	for _, path := range []string{filepath.Join(dir, metadataFile), filepath.Join(dir, proxyPartDir, metadataFile)} {
+		if m, err = loadMetadata(path); err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	switch {
+	case m == nil:
+		line("proxy", "unknown until the sources are fetched")
+	case !m.Proxy || !isAddress(m.Implementation):
+		line("proxy", "no, as of the last download")
+	case dl.layout(dir).CombineProxy:
+		line("proxy", "yes, with the implementation at %s as of the last download, downloaded into %s", m.Implementation, filepath.Join(dir, implementationPartDir))
+	case dl.implementations:
+		line("proxy", "yes, with the implementation at %s as of the last download, downloaded into %s", m.Implementation, implementationDeployment(d, m.Implementation).dir(dl.contractDir))
+	default:
+		line("proxy", "yes, with the implementation at %s as of the last download, not followed without --implementations or --combine-proxy", m.Implementation)
+	}
+
+	if m != nil && dl.compiles() && !strings.HasPrefix(m.CompilerVersion, "vyper") {
+		version := strings.TrimPrefix(m.CompilerVersion, "v")
+		path, err := cachedSolcPath(version)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); err != nil {
+			line("compiler", "solc %s, downloaded from %s into %s", version, solcBinURL, path)
+		} else {
+			line("compiler", "solc %s, cached at %s", version, path)
+		}
+	}
+
+	n, err := countFiles(dir)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		line("output", "%s, new", dir)
+	} else {
+		line("output", "%s, over the %d files already there", dir, n)
+	}
+
+	if dl.libDir != "" {
+		line("libraries", "shared packages written into %s", dl.libDir)
+	}
+
+	for _, command := range d.PostDownload {
+		line("hook", "postDownload: %s", command)
+	}
+
+	return nil
+}
+
+// compiles reports whether the download compiles the sources, with the solc of their verification.
+func (dl *downloader) compiles() bool {
+	return dl.verifyCompiles || dl.ensureBuilds || dl.hardhatArtifact || dl.genDocs || dl.storageLayout || dl.ast
+}
+
+// countFiles returns the number of files under dir, none when it doesn't exist.
+func countFiles(dir string) (int, error) {
+	n := 0
+	err := filepath.WalkDir(dir, func(path string, e fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if !e.IsDir() {
+			n++
+		}
+		return nil
+	})
+
+	return n, err
+}
@@ -0,0 +1,598 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func getContractURL(endpoint string, address string, apikey string) string {
+	const url = "%s/api?module=contract&action=getsourcecode&address=%s"
+	u := fmt.Sprintf(url, endpoint, address)
+	if apikey != "" {
+		u += "&apikey=" + apikey
+	}
+
+	return u
+}
+
+func getRawContractCode(ctx context.Context, explorer blockExplorer, address string) ([]*RawCode, error) {
+	r, err := getSourceResponse(ctx, explorer, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.codes, nil
+}
+
+// sourceResponse is a getsourcecode result with where and when it was fetched.
+type sourceResponse struct {
+	codes     []*RawCode
+	url       string // the URL queried, with the API key redacted
+	fetchedAt time.Time
+	sha256    string // of the raw response
+	raw       []byte // the raw response, kept with --raw-response
+	recorded  bool   // added to the fetch history, guarded by the mutex of the rawCodeCache holding it
+}
+
+// keepRawResponses is set by --raw-response to keep the raw getsourcecode responses, written as raw-response.json.
+var keepRawResponses bool
+
+// readResponse returns body hashed as it is read, and the buffer it is also copied to when keepRawResponses is set.
+func readResponse(body io.Reader) (io.Reader, hash.Hash, *bytes.Buffer) {
+	h := sha256.New()
+	if !keepRawResponses {
+		return io.TeeReader(body, h), h, nil
+	}
+
+	raw := &bytes.Buffer{}
+	return io.TeeReader(body, io.MultiWriter(h, raw)), h, raw
+}
+
+func getSourceResponse(ctx context.Context, explorer blockExplorer, address string) (*sourceResponse, error) {
+	var r *sourceResponse
+	err := retryRateLimited(ctx, explorer.endpoint, !explorer.hasKey(), func() (err error) {
+		r, err = getSourceResponseOnce(ctx, explorer, address)
+		return err
+	})
+
+	return r, err
+}
+
+func getSourceResponseOnce(ctx context.Context, explorer blockExplorer, address string) (*sourceResponse, error) {
+	u := getContractURL(explorer.endpoint, address, explorer.key())
+	resp, err := explorerGet(ctx, explorer, u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	bs, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &explorerResponse{}
+	if err := json.Unmarshal(bs, r); err != nil {
+		return nil, fmt.Errorf("decode getsourcecode response: %w", err)
+	}
+
+	if r.Status != "1" {
+		return nil, explorerError(r)
+	}
+
+	codes := []*RawCode{}
+	if err := json.Unmarshal(r.Result, &codes); err != nil {
+		return nil, fmt.Errorf("decode getsourcecode result: %w", err)
+	}
+
+	queried, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(bs)
+	sr := &sourceResponse{
+		codes:     codes,
+		url:       redactedURL(queried),
+		fetchedAt: time.Now().UTC(),
+		sha256:    hex.EncodeToString(sum[:]),
+	}
+	if keepRawResponses {
+		sr.raw = bs
+	}
+
+	return sr, nil
+}
+
+// explorerGet sends a GET request to the API of explorer, recording it in the metrics and as a span of ctx's trace.
+// The requests to a host share its rate limit, but how the key is sent is the explorer's, as the chains of an
+// aggregated API like Routescan's share a host.
+func explorerGet(ctx context.Context, explorer blockExplorer, u string) (*http.Response, error) {
+	return explorerSend(ctx, explorer, http.MethodGet, u, nil)
+}
+
+// explorerPost sends form to the explorer API like explorerGet, e.g. a verification.
+// The API key stays in u's query, where the rate limiter and the redaction look for it.
+func explorerPost(ctx context.Context, explorer blockExplorer, u string, form url.Values) (*http.Response, error) {
+	return explorerSend(ctx, explorer, http.MethodPost, u, form)
+}
+
+func explorerSend(ctx context.Context, explorer blockExplorer, method string, u string, form url.Values) (*http.Response, error) {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	host := pu.Host
+
+	// fixtures answer without spending the budget or waiting for the explorer's rate
+	offline := replaying(ctx)
+
+	if !offline {
+		if err := requestBudget.take(); err != nil {
+			return nil, err
+		}
+	}
+
+	keyless := pu.Query().Get("apikey") == ""
+	if explorer.keyOptional() {
+		// the API takes no key, and isn't throttled like keyless Etherscan requests
+		keyless = false
+	}
+
+	var keyHeader http.Header
+	if !keyless {
+		u, method, form, keyHeader = explorer.moveAPIKey(pu, method, form)
+	}
+
+	release := func() {}
+	if !offline {
+		if err := explorerLimiter(host, keyless).wait(ctx); err != nil {
+			return nil, err
+		}
+
+		release, err = acquireSlot(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	ctx, span := startSpan(ctx, "explorer "+method, spanClient, "server.address", host)
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		release()
+		span.end(err)
+		return nil, err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for name, values := range keyHeader {
+		req.Header[name] = values
+	}
+	injectTraceparent(ctx, req.Header)
+
+	start := time.Now()
+	resp, err := explorerDoer(ctx).Do(req)
+	explorerDuration.since(start, host)
+	if err != nil {
+		err = redactURLError(err)
+		release()
+		explorerRequests.inc(host, "error")
+		span.end(err)
+		return nil, err
+	}
+	explorerRequests.inc(host, strconv.Itoa(resp.StatusCode))
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		resp.Body.Close()
+		release()
+		span.end(errRateLimited)
+		return nil, &rateLimitedError{err: fmt.Errorf("%w: %s", errRateLimited, resp.Status), retryAfter: retryAfter(resp.Header, time.Now())}
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		resp.Body.Close()
+		release()
+		span.end(errExplorerDown)
+		return nil, fmt.Errorf("%w: %s", errExplorerDown, resp.Status)
+	}
+
+	resp.Body = &releasingBody{limitBody(resp.Body), release}
+
+	span.set("http.response.status_code", strconv.Itoa(resp.StatusCode))
+	span.end(nil)
+
+	return resp, nil
+}
+
+// fetchRawCode returns the explorer's getsourcecode result for d.
+func fetchRawCode(ctx context.Context, d *deployment) ([]*RawCode, error) {
+	r, err := fetchSourceResponse(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.codes, nil
+}
+
+// fetchSourceResponse returns the explorer's getsourcecode response for d.
+func fetchSourceResponse(ctx context.Context, d *deployment) (*sourceResponse, error) {
+	if d.Source == sourceTenderly {
+		return getTenderlySourceResponse(ctx, tenderlyProject, d.Chain, d.Address)
+	}
+
+	explorer, ok := blockExploers[d.Chain]
+	if !ok {
+		return nil, unsupportedChain(d.Chain)
+	}
+
+	if explorer.api == zksyncAPI {
+		return getZksyncSourceResponse(ctx, explorer, d.Address)
+	}
+
+	return getSourceResponse(ctx, explorer, d.Address)
+}
+
+// rawCodeCache memoizes the getsourcecode responses of a run,
+// so a contract listed several times, e.g. an implementation shared by proxies, is fetched once.
+type rawCodeCache struct {
+	mu        sync.Mutex
+	responses map[string]*sourceResponse
+	fetching  map[string]*rawCodeFetch // the fetches in flight, which concurrent callers wait for
+	parsed    map[string][]*SourceCode // decoded ahead of their download by the pipeline, until taken by sources
+	hasCode   map[string]bool          // the contracts checkHasCode found code for
+}
+
+// rawCodeFetch is a getsourcecode request in flight, its result set once done is closed.
+type rawCodeFetch struct {
+	done chan struct{}
+	r    *sourceResponse
+	err  error
+}
+
+func newRawCodeCache() *rawCodeCache {
+	return &rawCodeCache{
+		responses: map[string]*sourceResponse{},
+		fetching:  map[string]*rawCodeFetch{},
+		parsed:    map[string][]*SourceCode{},
+		hasCode:   map[string]bool{},
+	}
+}
+
+// fetchedKey is the context key of the rawCodeCache of the run resolving deployments.
+type fetchedKey struct{}
+
+// withFetched returns ctx whose deployments are resolved keeping the getsourcecode results in fetched,
+// so the run downloading them doesn't fetch them again.
+func withFetched(ctx context.Context, fetched *rawCodeCache) context.Context {
+	return context.WithValue(ctx, fetchedKey{}, fetched)
+}
+
+// fetchedCache returns the rawCodeCache of ctx, a new one when it has none.
+func fetchedCache(ctx context.Context) *rawCodeCache {
+	if fetched, ok := ctx.Value(fetchedKey{}).(*rawCodeCache); ok {
+		return fetched
+	}
+
+	return newRawCodeCache()
+}
+
+// rawCodeKey returns the key of d in a rawCodeCache, which tells apart its sources, the explorers of the same chain
+// configured by the sub-projects of a workspace and the Tenderly project.
+func rawCodeKey(d *deployment) string {
+	source := blockExploers[d.Chain].endpoint
+	if d.Source == sourceTenderly {
+		source = tenderlyProject.contractURL(d.Chain, d.Address)
+	}
+
+	return fmt.Sprintf("%s:%d:%s", source, d.Chain, strings.ToLower(d.Address))
+}
+
+// fetch returns the getsourcecode result for d, fetching it unless it was already. Concurrent calls for the same
+// contract share one request, e.g. the pipeline fetching it ahead and the download waiting for it.
+func (c *rawCodeCache) fetch(ctx context.Context, d *deployment) ([]*RawCode, error) {
+	key := rawCodeKey(d)
+
+	for {
+		c.mu.Lock()
+		if r, ok := c.responses[key]; ok {
+			c.mu.Unlock()
+			return r.codes, nil
+		}
+		f, ok := c.fetching[key]
+		if !ok {
+			f = &rawCodeFetch{done: make(chan struct{})}
+			c.fetching[key] = f
+		}
+		c.mu.Unlock()
+
+		if !ok {
+			f.r, f.err = fetchSourceResponse(ctx, d)
+
+			c.mu.Lock()
+			if f.err == nil {
+				c.responses[key] = f.r
+			}
+			delete(c.fetching, key)
+			c.mu.Unlock()
+			close(f.done)
+		}
+
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		// a fetch canceled by its caller, e.g. the pipeline stopping, is sent again for the callers still waiting
+		if errors.Is(f.err, context.Canceled) && ctx.Err() == nil {
+			continue
+		}
+		if f.err != nil {
+			return nil, f.err
+		}
+
+		return f.r.codes, nil
+	}
+}
+
+// checkHasCode checks there is code at the address of d as checkHasCode does, once per contract of the run.
+func (c *rawCodeCache) checkHasCode(ctx context.Context, d *deployment) error {
+	key := rawCodeKey(d)
+
+	c.mu.Lock()
+	ok := c.hasCode[key]
+	c.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	if err := checkHasCode(ctx, d); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.hasCode[key] = true
+	c.mu.Unlock()
+
+	return nil
+}
+
+// parse decodes the fetched sources of d for its download, if they were fetched and verified.
+func (c *rawCodeCache) parse(d *deployment) {
+	r := c.response(d)
+	if r == nil || isUnverified(r.codes) {
+		return
+	}
+
+	sourceCodes, err := parseContractCode(r.codes)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.parsed[rawCodeKey(d)] = sourceCodes
+}
+
+// sources returns the sources rawCodes of d decode to, those decoded by parse if any, which are taken
+// so the cache holds the decoded sources of the contracts fetched ahead only.
+func (c *rawCodeCache) sources(d *deployment, rawCodes []*RawCode) ([]*SourceCode, error) {
+	key := rawCodeKey(d)
+
+	c.mu.Lock()
+	sourceCodes, ok := c.parsed[key]
+	delete(c.parsed, key)
+	c.mu.Unlock()
+	if ok {
+		return sourceCodes, nil
+	}
+
+	return parseContractCode(rawCodes)
+}
+
+// peekSources returns the sources rawCodes of d decode to as sources does, but leaves those decoded by parse
+// in the cache for the sources call writing them.
+func (c *rawCodeCache) peekSources(d *deployment, rawCodes []*RawCode) ([]*SourceCode, error) {
+	c.mu.Lock()
+	sourceCodes, ok := c.parsed[rawCodeKey(d)]
+	c.mu.Unlock()
+	if ok {
+		return sourceCodes, nil
+	}
+
+	return parseContractCode(rawCodes)
+}
+
+// evict forgets the fetched response of d, its raw bytes and its decoded sources, once they are no longer needed.
+func (c *rawCodeCache) evict(d *deployment) {
+	key := rawCodeKey(d)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.responses, key)
+	delete(c.parsed, key)
+}
+
+// record returns the fetched getsourcecode response for d the first time it is called for it, and nil afterwards
+// or when it wasn't fetched, so each request sent is recorded once.
+func (c *rawCodeCache) record(d *deployment) *sourceResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r := c.responses[rawCodeKey(d)]
+	if r == nil || r.recorded {
+		return nil
+	}
+	r.recorded = true
+
+	return r
+}
+
+// response returns the fetched getsourcecode response for d, or nil when it wasn't fetched.
+func (c *rawCodeCache) response(d *deployment) *sourceResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.responses[rawCodeKey(d)]
+}
+
+type RawCode struct {
+	SourceCode           string `json:"SourceCode"`
+	Abi                  string `json:"ABI"`
+	ContractName         string `json:"ContractName"`
+	CompilerVersion      string `json:"CompilerVersion"`
+	OptimizationUsed     string `json:"OptimizationUsed"`
+	Runs                 string `json:"Runs"`
+	ConstructorArguments string `json:"ConstructorArguments"`
+	EVMVersion           string `json:"EVMVersion"`
+	Library              string `json:"Library"`
+	LicenseType          string `json:"LicenseType"`
+	Proxy                string `json:"Proxy"`
+	Implementation       string `json:"Implementation"`
+	SwarmSource          string `json:"SwarmSource"`
+	SimilarMatch         string `json:"SimilarMatch"`
+
+	// CompilerZksolcVersion is the zksolc version of the contracts of the zkSync Era explorer, compiled for EraVM.
+	CompilerZksolcVersion string `json:"CompilerZksolcVersion,omitempty"`
+}
+
+// explorerResponse is the envelope shared by all explorer API responses.
+type explorerResponse struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// queryExplorer calls the explorer API with params and decodes the result into result.
+// Empty listings (e.g. "No transactions found") are not treated as errors.
+func queryExplorer(ctx context.Context, explorer blockExplorer, params url.Values, result interface{}) error {
+	return retryRateLimited(ctx, explorer.endpoint, !explorer.hasKey(), func() error {
+		return queryExplorerOnce(ctx, explorer, params, result)
+	})
+}
+
+func queryExplorerOnce(ctx context.Context, explorer blockExplorer, params url.Values, result interface{}) error {
+	if explorer.api == zksyncAPI {
+		return fmt.Errorf("%s.%s: not supported by zkSync explorers", params.Get("module"), params.Get("action"))
+	}
+
+	if key := explorer.key(); key != "" {
+		params.Set("apikey", key)
+	}
+	u := strings.TrimSuffix(explorer.endpoint, "/") + "/api?" + params.Encode()
+
+	resp, err := explorerGet(ctx, explorer, u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	r := &explorerResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(r); err != nil {
+		return fmt.Errorf("decode %s response: %w", params.Get("action"), err)
+	}
+
+	if r.Status != "1" && !strings.HasPrefix(r.Message, "No ") {
+		return explorerError(r)
+	}
+
+	return json.Unmarshal(r.Result, result)
+}
+
+// proxyResponse is the JSON-RPC style response of the explorer's proxy module.
+type proxyResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// getCode returns the runtime bytecode at address through the explorer's eth_getCode proxy.
+func getCode(ctx context.Context, explorer blockExplorer, address string) (string, error) {
+	return explorerProxy(ctx, explorer, url.Values{
+		"action":  {"eth_getCode"},
+		"address": {address},
+		"tag":     {"latest"},
+	})
+}
+
+// ethCall returns the result of calling address with data through the explorer's eth_call proxy.
+func ethCall(ctx context.Context, explorer blockExplorer, address string, data string) (string, error) {
+	return explorerProxy(ctx, explorer, url.Values{
+		"action": {"eth_call"},
+		"to":     {address},
+		"data":   {data},
+		"tag":    {"latest"},
+	})
+}
+
+// explorerProxy calls the JSON-RPC method params["action"] through the explorer's proxy module and returns its hex result.
+func explorerProxy(ctx context.Context, explorer blockExplorer, params url.Values) (string, error) {
+	var result string
+	err := retryRateLimited(ctx, explorer.endpoint, !explorer.hasKey(), func() (err error) {
+		result, err = explorerProxyOnce(ctx, explorer, params)
+		return err
+	})
+
+	return result, err
+}
+
+func explorerProxyOnce(ctx context.Context, explorer blockExplorer, params url.Values) (string, error) {
+	if explorer.api == zksyncAPI {
+		return zksyncRPC(ctx, explorer, params)
+	}
+
+	action := params.Get("action")
+	params.Set("module", "proxy")
+	if key := explorer.key(); key != "" {
+		params.Set("apikey", key)
+	}
+	u := strings.TrimSuffix(explorer.endpoint, "/") + "/api?" + params.Encode()
+
+	resp, err := explorerGet(ctx, explorer, u)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	r := &proxyResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(r); err != nil {
+		return "", fmt.Errorf("decode %s response: %w", action, err)
+	}
+
+	if r.Error != nil {
+		return "", rpcError(action, r.Error.Message)
+	}
+
+	// the proxy reports errors like rate limits as a plain string result in the status envelope
+	result := ""
+	if err := json.Unmarshal(r.Result, &result); err != nil || !strings.HasPrefix(result, "0x") {
+		if strings.Contains(strings.ToLower(result), "rate limit") {
+			return "", explorerError(&explorerResponse{Status: "0", Message: "NOTOK", Result: r.Result})
+		}
+		return "", fmt.Errorf("%s: %s", action, r.Result)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRawCodeCacheFetchShared(t *testing.T) {
+	const address = "0x1111111111111111111111111111111111111111"
+
+	e := inTestProject(t, `{}`)
+	e.verify(t, address, "Token", map[string]string{"src/Token.sol": "contract Token {}\n"}, nil)
+	if _, err := loadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	e.hold = make(chan struct{})
+
+	fetched := newRawCodeCache()
+	d := &deployment{Name: "token", Chain: 1, Address: address}
+	wg := sync.WaitGroup{}
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = fetched.fetch(context.Background(), d)
+		}(i)
+	}
+
+	// the callers after the first wait for its request
+	time.Sleep(50 * time.Millisecond)
+	close(e.hold)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := e.queries[address]; n != 1 {
+		t.Errorf("%d getsourcecode requests, want 1", n)
+	}
+
+	if _, err := fetched.fetch(context.Background(), d); err != nil || e.queries[address] != 1 {
+		t.Errorf("fetch again: %v, %d requests", err, e.queries[address])
+	}
+}
+
+func TestRawCodeCacheFetchCanceled(t *testing.T) {
+	const address = "0x1111111111111111111111111111111111111111"
+
+	e := inTestProject(t, `{}`)
+	e.verify(t, address, "Token", map[string]string{"src/Token.sol": "contract Token {}\n"}, nil)
+	if _, err := loadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	e.hold = make(chan struct{})
+
+	fetched := newRawCodeCache()
+	d := &deployment{Name: "token", Chain: 1, Address: address}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := fetched.fetch(ctx, d)
+		first <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	second := make(chan error)
+	go func() {
+		_, err := fetched.fetch(context.Background(), d)
+		second <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// the waiting caller sends the request again once the first is canceled
+	cancel()
+	if err := <-first; err == nil {
+		t.Fatal("canceled fetch succeeded")
+	}
+	close(e.hold)
+	if err := <-second; err != nil {
+		t.Fatalf("waiting fetch: %s", err)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExplorerConfig tunes the requests to the explorer of one chain, or adds an explorer for a chain without a built-in one.
+type ExplorerConfig struct {
+	// Endpoint is the base URL of the explorer's Etherscan-compatible API.
+	Endpoint string `json:"endpoint,omitempty"`
+	// API is the API served at Endpoint: "etherscan" (the default), "zksync" for a zkSync Era block explorer,
+	// or "routescan" for Routescan's aggregated API, whose endpoint for the chain is the default.
+	API string `json:"api,omitempty"`
+	// Network is the Routescan network of the chain with "api": "routescan": "mainnet" (the default) or "testnet".
+	Network string `json:"network,omitempty"`
+	// Site is the host of the explorer's web UI.
+	Site string `json:"site,omitempty"`
+	// APIKeyEnv is the environment variable the API key is read from.
+	APIKeyEnv string `json:"apiKeyEnv,omitempty"`
+	// APIKeyCmd is a shell command printing the API key when the environment variable is not set,
+	// e.g. "op read op://vault/etherscan/key", so the key is kept in a secret manager.
+	APIKeyCmd string `json:"apiKeyCmd,omitempty"`
+	// APIKey is the API key used when the environment variable is not set.
+	APIKey string `json:"apiKey,omitempty"`
+	// Tier is the plan of the API key, which sets its rate limit only: "free" (the default) or "pro".
+	// The concurrent requests are MaxInFlight's, and the tool calls no pro-only endpoint.
+	Tier string `json:"tier,omitempty"`
+	// RateLimit is the number of requests per second allowed, overriding the tier's.
+	RateLimit float64 `json:"rateLimit,omitempty"`
+	// RPC is the JSON-RPC endpoint of a node of the chain, used to check addresses have code before querying the explorer.
+	RPC string `json:"rpc,omitempty"`
+	// MaxInFlight caps the requests in flight to the explorer at once, unlimited when 0.
+	MaxInFlight int `json:"maxInFlight,omitempty"`
+	// APIKeyIn is where the API key is sent, for explorers accepting it out of the URL: "query" (the default),
+	// "header" or "body", a form field of a POST request, so it stays out of proxy logs and printed URLs.
+	APIKeyIn string `json:"apiKeyIn,omitempty"`
+	// APIKeyHeader is the header the API key is sent in with "apiKeyIn": "header", X-API-Key by default.
+	APIKeyHeader string `json:"apiKeyHeader,omitempty"`
+}
+
+const (
+	apiKeyInHeader = "header"
+	apiKeyInBody   = "body"
+
+	defaultAPIKeyHeader = "X-API-Key"
+)
+
+const (
+	freeTierRate = 5  // requests per second of a free API key
+	proTierRate  = 30 // requests per second of the highest Etherscan plans
+)
+
+// apiKeyCmdTimeout bounds an apiKeyCmd, e.g. waiting for a secret manager's unlock prompt.
+const apiKeyCmdTimeout = time.Minute
+
+var (
+	apiKeyCmdMu      sync.Mutex
+	apiKeyCmdResults = map[string]string{}
+	// configCommandsAllowed is false while loading the configs of --workspace, whose commands run only with --workspace-commands.
+	configCommandsAllowed = true
+)
+
+// apiKeyFromCommand runs the shell command cmd and returns its output, trimmed, as an API key.
+// Each command is run once, as explorers often share a key.
+func apiKeyFromCommand(cmd string) (string, error) {
+	apiKeyCmdMu.Lock()
+	defer apiKeyCmdMu.Unlock()
+
+	if key, ok := apiKeyCmdResults[cmd]; ok {
+		return key, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiKeyCmdTimeout)
+	defer cancel()
+
+	shell := shellCommand(ctx, cmd)
+	// stdin and stderr are the terminal's, for the prompts of secret managers
+	shell.Stdin = os.Stdin
+	shell.Stderr = os.Stderr
+
+	out, err := shell.Output()
+	if err != nil {
+		return "", err
+	}
+
+	key := strings.TrimSpace(string(out))
+	if key == "" {
+		return "", errors.New("printed no key")
+	}
+	apiKeyCmdResults[cmd] = key
+
+	return key, nil
+}
+
+// configureExplorers applies the explorer settings of config.json, keyed by chain id or short name.
+func configureExplorers(explorers map[string]*ExplorerConfig) error {
+	for key, ec := range explorers {
+		ch, err := parseChain(key)
+		if err != nil {
+			return fmt.Errorf("explorers: %w", err)
+		}
+
+		explorer, ok := blockExploers[ch]
+		if !ok && ec.Endpoint == "" && ec.API != routescanAPI {
+			return fmt.Errorf("explorers: %s: %w, set its endpoint or \"api\": \"routescan\"", key, unsupportedChain(ch))
+		}
+
+		if ec.Endpoint != "" {
+			explorer.endpoint = ec.Endpoint
+		}
+		switch ec.API {
+		case "":
+		case "etherscan":
+			explorer.api = ""
+		case zksyncAPI:
+			explorer.api = zksyncAPI
+		case routescanAPI:
+			if explorer.api != routescanAPI && ec.Endpoint == "" {
+				explorer.endpoint = routescanEndpoint(routescanMainnet, ch)
+			}
+			if explorer.api != routescanAPI && ec.APIKeyEnv == "" {
+				// the key of another explorer isn't Routescan's
+				explorer.apiKeyEnv, explorer.apiKey = "ROUTESCAN_APIKEY", os.Getenv("ROUTESCAN_APIKEY")
+			}
+			explorer.api = routescanAPI
+		default:
+			return fmt.Errorf("explorers: %s: unknown api %q, want etherscan, zksync or routescan", key, ec.API)
+		}
+		switch ec.Network {
+		case "":
+		case routescanMainnet, routescanTestnet:
+			if explorer.api != routescanAPI {
+				return fmt.Errorf("explorers: %s: network is a Routescan setting, set \"api\": \"routescan\"", key)
+			}
+			if ec.Endpoint == "" {
+				explorer.endpoint = routescanEndpoint(ec.Network, ch)
+			}
+		default:
+			return fmt.Errorf("explorers: %s: unknown network %q, want mainnet or testnet", key, ec.Network)
+		}
+		if ec.Site != "" {
+			explorer.site = ec.Site
+		}
+		if explorer.site == "" {
+			explorer.site = explorer.host()
+		}
+		if ec.APIKeyEnv != "" {
+			explorer.apiKeyEnv = ec.APIKeyEnv
+			explorer.apiKey = os.Getenv(ec.APIKeyEnv)
+		}
+		if explorer.apiKey == "" && ec.APIKeyCmd != "" {
+			if !configCommandsAllowed {
+				return fmt.Errorf("explorers: %s: apiKeyCmd of a workspace config: not run without --workspace-commands", key)
+			}
+			if explorer.apiKey, err = apiKeyFromCommand(ec.APIKeyCmd); err != nil {
+				return fmt.Errorf("explorers: %s: apiKeyCmd: %w", key, err)
+			}
+		}
+		if explorer.apiKey == "" {
+			explorer.apiKey = ec.APIKey
+		}
+		if ec.RPC != "" {
+			explorer.rpc = ec.RPC
+		}
+
+		switch ec.Tier {
+		case "", "free":
+			explorer.rate = freeTierRate
+			if explorer.api == routescanAPI {
+				explorer.rate = routescanRate
+			}
+		case "pro":
+			explorer.rate = proTierRate
+		default:
+			return fmt.Errorf("explorers: %s: unknown tier %q, want free or pro", key, ec.Tier)
+		}
+
+		if ec.RateLimit < 0 {
+			return fmt.Errorf("explorers: %s: rateLimit must be positive", key)
+		}
+		if ec.RateLimit > 0 {
+			explorer.rate = ec.RateLimit
+		}
+
+		switch ec.APIKeyIn {
+		case "":
+		case "query":
+			explorer.keyIn = ""
+		case apiKeyInHeader, apiKeyInBody:
+			explorer.keyIn = ec.APIKeyIn
+		default:
+			return fmt.Errorf("explorers: %s: unknown apiKeyIn %q, want query, header or body", key, ec.APIKeyIn)
+		}
+		if ec.APIKeyHeader != "" {
+			explorer.keyHeader = ec.APIKeyHeader
+		}
+
+		if ec.MaxInFlight < 0 {
+			return fmt.Errorf("explorers: %s: maxInFlight must be positive", key)
+		}
+		explorer.inFlight = ec.MaxInFlight
+
+		blockExploers[ch] = explorer
+	}
+
+	return nil
+}
+
+// moveAPIKey moves the apikey parameter of the request to u where the explorer takes it, a header or the form of a POST,
+// returning the request's URL, method, form and the header to add.
+func (e blockExplorer) moveAPIKey(u *url.URL, method string, form url.Values) (string, string, url.Values, http.Header) {
+	q := u.Query()
+	key := q.Get("apikey")
+	if e.keyIn == "" || key == "" {
+		return u.String(), method, form, nil
+	}
+
+	q.Del("apikey")
+	stripped := *u
+	stripped.RawQuery = q.Encode()
+
+	if e.keyIn == apiKeyInHeader {
+		header := http.Header{}
+		header.Set(firstNonEmpty(e.keyHeader, defaultAPIKeyHeader), key)
+		return stripped.String(), method, form, header
+	}
+
+	// the other parameters stay in the URL, which keeps fixtures of different requests apart
+	body := url.Values{}
+	for name, values := range form {
+		body[name] = values
+	}
+	body.Set("apikey", key)
+
+	return stripped.String(), http.MethodPost, body, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// exportCommand packages the download of a contract, its sources with the ABI, metadata, provenance and checksums, into a zip.
+func exportCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	out := fs.String("out", "", "zip file to write (default <target>.zip)")
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return errors.New("usage: export [--out <file>] <target>")
+		}
+
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		deployments, err := c.deployments(ctx, args)
+		if err != nil {
+			return err
+		}
+		if len(deployments) != 1 {
+			return fmt.Errorf("%s matches %d contracts, export one at a time", args[0], len(deployments))
+		}
+		d := deployments[0]
+
+		dir := d.dir(c.ContractDir)
+		if _, err := os.Stat(filepath.Join(dir, metadataFile)); err != nil {
+			return fmt.Errorf("%s is not downloaded: %w", d.Name, err)
+		}
+
+		dst := *out
+		if dst == "" {
+			dst = filepath.Base(d.folder()) + ".zip"
+		}
+
+		n, err := exportZip(dst, dir, filepath.Base(d.folder()))
+		if err != nil {
+			os.Remove(dst)
+			return err
+		}
+
+		fmt.Printf("wrote %d files of %s to %s\n", n, d.Name, dst)
+
+		return nil
+	}
+}
+
+// exportZip writes the files under dir into the zip dst, below the directory root, returning the number of files.
+// Symlinks, e.g. of --dedup symlink, are stored as the files they point to.
+func exportZip(dst string, dir string, root string) (int, error) {
+	f, err := os.Create(dst)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+
+	abs, err := filepath.Abs(dst)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	err = filepath.WalkDir(dir, func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if e.IsDir() {
+			return nil
+		}
+
+		// the zip may be written into the tree it bundles
+		if pAbs, err := filepath.Abs(p); err == nil && pAbs == abs {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		bs, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		info, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: path.Join(root, filepath.ToSlash(rel)), Method: zip.Deflate, Modified: info.ModTime()})
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(bs); err != nil {
+			return err
+		}
+		n++
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+
+	return n, f.Close()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// explorerPageSize is the maximum number of records the explorer returns per page.
+const explorerPageSize = 10000
+
+type InternalTransaction struct {
+	BlockNumber     string `json:"blockNumber"`
+	Type            string `json:"type"`
+	ContractAddress string `json:"contractAddress"`
+	IsError         string `json:"isError"`
+}
+
+// factoryDeployments lists the contracts created by the factory d, named under d.Name by their address.
+func factoryDeployments(ctx context.Context, d *deployment) ([]*deployment, error) {
+	explorer, ok := blockExploers[d.Chain]
+	if !ok {
+		return nil, unsupportedChain(d.Chain)
+	}
+
+	deployments := []*deployment{}
+	seen := map[string]bool{}
+	err := pageByBlock(func(start int) (int, int, error) {
+		txs := []*InternalTransaction{}
+		params := url.Values{
+			"module":     {"account"},
+			"action":     {"txlistinternal"},
+			"address":    {d.Address},
+			"sort":       {"asc"},
+			"startblock": {strconv.Itoa(start)},
+			"page":       {"1"},
+			"offset":     {strconv.Itoa(explorerPageSize)},
+		}
+		if err := queryExplorer(ctx, explorer, params, &txs); err != nil {
+			return 0, 0, err
+		}
+
+		last := start
+		for _, tx := range txs {
+			if n, err := strconv.Atoi(tx.BlockNumber); err == nil && n > last {
+				last = n
+			}
+
+			if !strings.HasPrefix(tx.Type, "create") || tx.IsError != "0" || !isAddress(tx.ContractAddress) || seen[strings.ToLower(tx.ContractAddress)] {
+				continue
+			}
+			seen[strings.ToLower(tx.ContractAddress)] = true
+
+			deployments = append(deployments, &deployment{
+				Name:    filepath.Join(d.folder(), tx.ContractAddress),
+				Chain:   d.Chain,
+				Address: tx.ContractAddress,
+				OutDir:  d.OutDir,
+			})
+		}
+
+		return len(txs), last, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deployments, nil
+}
+
+// pageByBlock runs query for the pages of an account's listing from the oldest record, from the block start on,
+// until a page has fewer than explorerPageSize records. The explorer serves no more than explorerPageSize records
+// of a listing however it is paged, so each page starts at the block of the last record of the previous one,
+// the block whose records query sees twice, instead of at the next page number.
+func pageByBlock(query func(start int) (records int, last int, err error)) error {
+	for start := 0; ; {
+		records, last, err := query(start)
+		if err != nil {
+			return err
+		}
+		if records < explorerPageSize {
+			return nil
+		}
+		if last <= start {
+			return fmt.Errorf("more than %d records in block %d, which the explorer can't page", explorerPageSize, start)
+		}
+		start = last
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// httpDoer sends HTTP requests, like *http.Client.
+type httpDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// explorerClientKey is the context key of the client sending the explorer API requests.
+type explorerClientKey struct{}
+
+// withExplorerClient returns ctx whose explorer API requests are sent by client, httpClient when nil,
+// e.g. to record or replay explorer responses, or by applications embedding the downloader.
+func withExplorerClient(ctx context.Context, client httpDoer) context.Context {
+	if client == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, explorerClientKey{}, client)
+}
+
+// explorerDoer returns the client sending the explorer API requests of ctx.
+func explorerDoer(ctx context.Context) httpDoer {
+	if client, ok := ctx.Value(explorerClientKey{}).(httpDoer); ok {
+		return client
+	}
+
+	return httpClient
+}
+
+// replaying reports whether the explorer requests of ctx are answered by --replay fixtures.
+func replaying(ctx context.Context) bool {
+	client := explorerDoer(ctx)
+	if debug, ok := client.(*debugClient); ok {
+		client = debug.base
+	}
+	_, ok := client.(*replayingClient)
+
+	return ok
+}
+
+// fixturePath returns the file of the fixture of req in dir. The body of a POST is part of the key, so verifications
+// submitted to the same URL don't overwrite each other. The API key is left out of it, so fixtures recorded with a key
+// replay without one.
+func fixturePath(dir string, req *http.Request) (string, error) {
+	u := *req.URL
+	q := u.Query()
+	q.Del("apikey")
+	u.RawQuery = q.Encode()
+
+	key := req.Method + " " + u.String()
+
+	body, err := requestBody(req)
+	if err != nil {
+		return "", err
+	}
+	if len(body) > 0 {
+		if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			if form, err := url.ParseQuery(string(body)); err == nil {
+				form.Del("apikey")
+				body = []byte(form.Encode())
+			}
+		}
+		key += "\n" + string(body)
+	}
+
+	h := sha256.Sum256([]byte(key))
+
+	return filepath.Join(dir, hex.EncodeToString(h[:8])+".http"), nil
+}
+
+// requestBody returns the body of req, leaving it to be sent.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+
+		return io.ReadAll(body)
+	}
+
+	bs, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(bs))
+
+	return bs, nil
+}
+
+// recordingClient sends requests with base and saves the responses as fixtures in dir.
+type recordingClient struct {
+	base httpDoer
+	dir  string
+}
+
+func (c *recordingClient) Do(req *http.Request) (*http.Response, error) {
+	path, err := fixturePath(c.dir, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.base.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	dump, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(c.dir, dirMode); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(path, dump, fileMode); err != nil {
+		return nil, err
+	}
+
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(dump)), req)
+}
+
+// replayingClient answers requests from the fixtures in dir, without network access.
+type replayingClient struct {
+	dir string
+}
+
+func (c *replayingClient) Do(req *http.Request) (*http.Response, error) {
+	path, err := fixturePath(c.dir, req)
+	if err != nil {
+		return nil, err
+	}
+
+	dump, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("replay: no fixture for %s %s", req.Method, redactedURL(req.URL))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(dump)), req)
+}
+
+// redactedURL returns u without its API key.
+func redactedURL(u *url.URL) string {
+	r := *u
+	q := r.Query()
+	if q.Has("apikey") {
+		q.Set("apikey", "REDACTED")
+	}
+	r.RawQuery = q.Encode()
+
+	return r.String()
+}
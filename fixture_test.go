@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFixturePath(t *testing.T) {
+	request := func(method, u string, form url.Values) *http.Request {
+		t.Helper()
+
+		var req *http.Request
+		var err error
+		if form == nil {
+			req, err = http.NewRequest(method, u, nil)
+		} else {
+			req, err = http.NewRequest(method, u, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return req
+	}
+	path := func(req *http.Request) string {
+		t.Helper()
+
+		p, err := fixturePath("fixtures", req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return p
+	}
+
+	const endpoint = "https://api.etherscan.io/api"
+	get := path(request(http.MethodGet, endpoint+"?module=contract&action=getsourcecode&address=0x1", nil))
+	if p := path(request(http.MethodGet, endpoint+"?module=contract&action=getsourcecode&address=0x1&apikey=KEY", nil)); p != get {
+		t.Errorf("the API key changes the fixture of a GET: %s, want %s", p, get)
+	}
+	if p := path(request(http.MethodGet, endpoint+"?module=contract&action=getsourcecode&address=0x2", nil)); p == get {
+		t.Error("two GETs of other addresses share a fixture")
+	}
+
+	first := path(request(http.MethodPost, endpoint, url.Values{"action": {"verifysourcecode"}, "contractaddress": {"0x1"}}))
+	if p := path(request(http.MethodPost, endpoint, url.Values{"action": {"verifysourcecode"}, "contractaddress": {"0x2"}})); p == first {
+		t.Error("two POSTs of other bodies to the same URL share a fixture")
+	}
+	if p := path(request(http.MethodPost, endpoint, url.Values{"action": {"verifysourcecode"}, "contractaddress": {"0x1"}, "apikey": {"KEY"}})); p != first {
+		t.Errorf("the API key of the form changes the fixture of a POST: %s, want %s", p, first)
+	}
+
+	// the body is still sent once hashed
+	req := request(http.MethodPost, endpoint, url.Values{"action": {"verifysourcecode"}})
+	req.GetBody = nil
+	path(req)
+	if body, err := requestBody(req); err != nil || string(body) != "action=verifysourcecode" {
+		t.Errorf("body after fixturePath: %q, %v", body, err)
+	}
+}
+
+func TestDownloadReplay(t *testing.T) {
+	const address = "0x1111111111111111111111111111111111111111"
+
+	e := inTestProject(t, fmt.Sprintf(`{"token": {"chain": 1, "address": %q}}`, address))
+	e.verify(t, address, "Token", map[string]string{"src/Token.sol": "contract Token {}\n"}, nil)
+
+	runCommand(t, "download", "--quiet", "--record", "fixtures", "token")
+	recorded, err := os.ReadFile(filepath.Join("contracts", "token", "src", "Token.sol"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures, err := filepath.Glob(filepath.Join("fixtures", "*.http"))
+	if err != nil || len(fixtures) != 1 {
+		t.Fatalf("fixtures: %q, %v", fixtures, err)
+	}
+	queries := e.queries[address]
+
+	// the replayed download sends no request, and takes the sources recorded rather than those verified since
+	if err := os.RemoveAll("contracts"); err != nil {
+		t.Fatal(err)
+	}
+	e.verify(t, address, "Token", map[string]string{"src/Token.sol": "contract TokenV2 {}\n"}, nil)
+	runCommand(t, "download", "--quiet", "--replay", "fixtures", "token")
+
+	replayed, err := os.ReadFile(filepath.Join("contracts", "token", "src", "Token.sol"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(replayed) != string(recorded) {
+		t.Errorf("replayed %q, want the recorded %q", replayed, recorded)
+	}
+	if e.queries[address] != queries {
+		t.Errorf("the replay sent %d getsourcecode requests", e.queries[address]-queries)
+	}
+
+	// a request without a fixture fails rather than reaching the explorer
+	if err := run(context.Background(), []string{"download", "--quiet", "--replay", t.TempDir(), "token"}); err == nil || !strings.Contains(err.Error(), "no fixture") {
+		t.Errorf("replay without fixtures: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// checkoutGitTree extracts dir as committed at ref of its git repository into a temporary directory,
+// returning the extracted dir and a function removing it.
+func checkoutGitTree(ctx context.Context, ref string, dir string) (string, func(), error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// dir may not exist in the working tree, so look for the repository from its closest existing parent
+	existing := abs
+	for {
+		if _, err := os.Stat(existing); err == nil || filepath.Dir(existing) == existing {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+
+	top, err := git(ctx, existing, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", nil, err
+	}
+	top = strings.TrimSpace(top)
+
+	// the toplevel is reported with symlinks resolved
+	resolved, err := resolvePath(abs)
+	if err != nil {
+		return "", nil, err
+	}
+	rel, err := filepath.Rel(top, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", nil, fmt.Errorf("%s is not in the git repository %s", dir, top)
+	}
+	rel = filepath.ToSlash(rel)
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, "git", "archive", "--format=tar", ref, "--", rel)
+	cmd.Dir = top
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return "", nil, fmt.Errorf("git archive %s: %w: %s", ref, err, strings.TrimSpace(stderr.String()))
+	}
+
+	tmp, err := os.MkdirTemp("", "etherscan-downloader-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+
+	if err := extractTar(tmp, stdout); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("extract %s at %s: %w", rel, ref, err)
+	}
+
+	return filepath.Join(tmp, filepath.FromSlash(rel)), cleanup, nil
+}
+
+// extractTar writes the regular files of the tar r into dir.
+func extractTar(dir string, r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		dst := filepath.Join(dir, filepath.FromSlash(h.Name))
+		if rel, err := filepath.Rel(dir, dst); err != nil || strings.HasPrefix(rel, "..") {
+			return fmt.Errorf("bad path in archive: %s", h.Name)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dst), dirMode); err != nil {
+			return err
+		}
+
+		bs, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(dst, bs, fileMode); err != nil {
+			return err
+		}
+	}
+}
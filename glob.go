@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// matchGlob reports whether the slash-separated name matches pattern,
+// where ** matches any number of path segments and the other segments are matched by path.Match.
+func matchGlob(pattern, name string) (bool, error) {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if ok, err := matchSegments(pattern[1:], name[i:]); ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+
+		if len(name) == 0 {
+			return false, nil
+		}
+
+		ok, err := path.Match(pattern[0], name[0])
+		if err != nil || !ok {
+			return false, err
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0, nil
+}
+
+// globsFlag is a flag of glob patterns, given repeatedly or comma-separated.
+type globsFlag []string
+
+func (g *globsFlag) String() string { return strings.Join(*g, ",") }
+
+func (g *globsFlag) Set(s string) error {
+	for _, pattern := range strings.Split(s, ",") {
+		if pattern == "" {
+			// e.g. --include '' for none, overriding the recorded globs
+			continue
+		}
+		for _, segment := range strings.Split(pattern, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("bad pattern %s: %w", pattern, err)
+			}
+		}
+		*g = append(*g, pattern)
+	}
+	return nil
+}
+
+// sourceFilter selects the source keys written by their paths.
+type sourceFilter struct {
+	include []string
+	exclude []string
+	only    []string // like include, but only the matching files are written, without the metadata and artifacts
+}
+
+// selects reports whether the source at key is written: it matches one of the includes and of the onlys, if any,
+// and none of the excludes.
+func (f *sourceFilter) selects(key string) bool {
+	key = strings.TrimPrefix(key, "/")
+	if len(f.include) > 0 && !matchAny(f.include, key) {
+		return false
+	}
+	if len(f.only) > 0 && !matchAny(f.only, key) {
+		return false
+	}
+
+	return !matchAny(f.exclude, key)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		// the patterns are checked when the flags are parsed
+		if ok, _ := matchGlob(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
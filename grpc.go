@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// grpcService is the path prefix of the methods of the Downloader service of proto/downloader.proto.
+const grpcService = "/etherscandownloader.v1.Downloader/"
+
+// status codes of gRPC
+const (
+	grpcOK              = 0
+	grpcInvalidArgument = 3
+	grpcNotFound        = 5
+	grpcUnimplemented   = 12
+	grpcInternal        = 13
+	grpcUnavailable     = 14
+)
+
+// grpcError is a failed call, sent as its status and message.
+type grpcError struct {
+	code    int
+	message string
+}
+
+func (e *grpcError) Error() string { return e.message }
+
+// grpcHandler serves the Downloader service over HTTP/2 from the same source service as GET /source,
+// with the protobuf messages encoded by hand, as the tool has no dependencies.
+type grpcHandler struct {
+	service *sourceService
+}
+
+func (h *grpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor != 2 {
+		// the server speaks HTTP/2 only over TLS
+		http.Error(w, "gRPC needs HTTP/2: serve with --tls-cert and --tls-key", http.StatusHTTPVersionNotSupported)
+		return
+	}
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "want a gRPC call", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+	method := strings.TrimPrefix(r.URL.Path, grpcService)
+	ctx, span := startSpan(extractTraceparent(r.Context(), r.Header), "gRPC "+method, spanServer)
+	err := h.call(ctx, w, r.Body, method)
+	span.end(err)
+
+	code, message := grpcOK, ""
+	var callErr *grpcError
+	switch {
+	case errors.As(err, &callErr):
+		code, message = callErr.code, callErr.message
+	case err != nil:
+		code, message = grpcInternal, "internal error"
+		log.Printf("gRPC %s: %s", method, redactAPIKeys(err.Error()))
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	w.Header().Set("Grpc-Message", message)
+}
+
+// call runs method with the request read from body, writing its responses to w.
+func (h *grpcHandler) call(ctx context.Context, w http.ResponseWriter, body io.Reader, method string) error {
+	switch method {
+	case "FetchSource", "GetMetadata", "StreamFiles":
+	default:
+		return &grpcError{grpcUnimplemented, "unknown method " + method}
+	}
+
+	req, err := readGRPCMessage(body)
+	if err != nil {
+		return err
+	}
+	ch, address, err := decodeContractRef(req)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := blockExploers[ch]; !ok {
+		return &grpcError{grpcNotFound, unsupportedChain(ch).Error()}
+	}
+	if !isAddress(address) {
+		return &grpcError{grpcInvalidArgument, "invalid address: " + address}
+	}
+
+	src, err := h.service.source(ctx, ch, address)
+	if errors.Is(err, errNotVerified) {
+		return &grpcError{grpcNotFound, err.Error()}
+	}
+	if err != nil {
+		// errors may contain the request URL and with it the API key
+		log.Printf("%s: %s", address, redactAPIKeys(err.Error()))
+		return &grpcError{grpcUnavailable, "explorer request failed"}
+	}
+
+	switch method {
+	case "FetchSource":
+		tree := &protoBuffer{}
+		tree.message(1, encodeMetadata(src.Metadata))
+		for _, p := range sourcePaths(src.Sources) {
+			tree.message(2, encodeSourceFile(p, src.Sources[p]))
+		}
+		return writeGRPCMessage(w, tree.bs)
+	case "GetMetadata":
+		return writeGRPCMessage(w, encodeMetadata(src.Metadata))
+	default:
+		for _, p := range sourcePaths(src.Sources) {
+			if err := writeGRPCMessage(w, encodeSourceFile(p, src.Sources[p])); err != nil {
+				return err
+			}
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+		return nil
+	}
+}
+
+// readGRPCMessage reads the single length-prefixed message of a unary or server-streaming call.
+func readGRPCMessage(r io.Reader) ([]byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, &grpcError{grpcInvalidArgument, "reading the request: " + err.Error()}
+	}
+	if header[0] != 0 {
+		return nil, &grpcError{grpcUnimplemented, "compressed requests are not supported"}
+	}
+
+	n := binary.BigEndian.Uint32(header[1:])
+	if n > maxGRPCRequest {
+		return nil, &grpcError{grpcInvalidArgument, "request too large"}
+	}
+	bs := make([]byte, n)
+	if _, err := io.ReadFull(r, bs); err != nil {
+		return nil, &grpcError{grpcInvalidArgument, "reading the request: " + err.Error()}
+	}
+
+	return bs, nil
+}
+
+// maxGRPCRequest bounds the ContractRef of a request, which is a chain id and an address.
+const maxGRPCRequest = 1 << 10
+
+// writeGRPCMessage writes msg with the length prefix of an uncompressed gRPC message.
+func writeGRPCMessage(w io.Writer, msg []byte) error {
+	if uint64(len(msg)) > math.MaxUint32 {
+		return errors.New("gRPC message too large")
+	}
+
+	header := make([]byte, 5)
+	binary.BigEndian.PutUint32(header[1:], uint32(len(msg)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(msg)
+
+	return err
+}
+
+// decodeContractRef decodes a ContractRef message, skipping unknown fields like protobuf decoders do.
+func decodeContractRef(bs []byte) (chain, string, error) {
+	var ch chain
+	address := ""
+
+	for len(bs) > 0 {
+		key, n := binary.Uvarint(bs)
+		if n <= 0 {
+			return 0, "", &grpcError{grpcInvalidArgument, "malformed ContractRef"}
+		}
+		bs = bs[n:]
+
+		field, wireType := key>>3, key&7
+		switch wireType {
+		case 0: // varint
+			v, n := binary.Uvarint(bs)
+			if n <= 0 {
+				return 0, "", &grpcError{grpcInvalidArgument, "malformed ContractRef"}
+			}
+			bs = bs[n:]
+			if field == 1 {
+				ch = chain(v)
+			}
+		case 2: // length-delimited
+			l, n := binary.Uvarint(bs)
+			if n <= 0 || l > uint64(len(bs)-n) {
+				return 0, "", &grpcError{grpcInvalidArgument, "malformed ContractRef"}
+			}
+			v := bs[n : n+int(l)]
+			bs = bs[n+int(l):]
+			if field == 2 {
+				address = string(v)
+			}
+		case 1, 5: // fixed 64 and 32 bits
+			size := 8
+			if wireType == 5 {
+				size = 4
+			}
+			if len(bs) < size {
+				return 0, "", &grpcError{grpcInvalidArgument, "malformed ContractRef"}
+			}
+			bs = bs[size:]
+		default:
+			return 0, "", &grpcError{grpcInvalidArgument, fmt.Sprintf("malformed ContractRef: wire type %d", wireType)}
+		}
+	}
+
+	return ch, address, nil
+}
+
+// encodeMetadata encodes m as the Metadata message.
+func encodeMetadata(m *Metadata) []byte {
+	b := &protoBuffer{}
+	b.string(1, m.ContractName)
+	b.varint(2, uint64(m.Chain))
+	b.string(3, m.Address)
+	b.string(4, m.CompilerVersion)
+	b.bool(5, m.OptimizationUsed)
+	b.varint(6, uint64(int64(m.Runs)))
+	b.string(7, m.EVMVersion)
+	b.string(8, m.ConstructorArguments)
+	b.string(9, m.LicenseType)
+	b.bool(10, m.Proxy)
+	b.string(11, m.Implementation)
+	b.string(12, m.SimilarMatch)
+
+	libraries := map[string]string{}
+	for source, libs := range m.Libraries {
+		for name, address := range libs {
+			libraries[source+":"+name] = address
+		}
+	}
+	for _, k := range sourcePaths(libraries) {
+		entry := &protoBuffer{}
+		entry.string(1, k)
+		entry.string(2, libraries[k])
+		b.message(13, entry.bs)
+	}
+
+	for _, r := range m.Remappings {
+		b.bytes(14, []byte(r))
+	}
+
+	return b.bs
+}
+
+// encodeSourceFile encodes the SourceFile message of the file at path.
+func encodeSourceFile(path string, content string) []byte {
+	b := &protoBuffer{}
+	b.string(1, path)
+	b.string(2, content)
+
+	return b.bs
+}
+
+// protoBuffer encodes the fields of a protobuf message, leaving out proto3's default values.
+type protoBuffer struct {
+	bs []byte
+}
+
+func (b *protoBuffer) key(field int, wireType int) {
+	b.uvarint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (b *protoBuffer) uvarint(v uint64) {
+	b.bs = appendUvarint(b.bs, v)
+}
+
+func (b *protoBuffer) varint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.key(field, 0)
+	b.uvarint(v)
+}
+
+func (b *protoBuffer) bool(field int, v bool) {
+	if v {
+		b.varint(field, 1)
+	}
+}
+
+func (b *protoBuffer) string(field int, v string) {
+	if v != "" {
+		b.bytes(field, []byte(v))
+	}
+}
+
+// bytes encodes a length-delimited field, even when empty, as the elements of repeated fields are.
+func (b *protoBuffer) bytes(field int, v []byte) {
+	b.key(field, 2)
+	b.uvarint(uint64(len(v)))
+	b.bs = append(b.bs, v...)
+}
+
+func (b *protoBuffer) message(field int, v []byte) {
+	b.bytes(field, v)
+}
+
+// sortedKeys returns the keys of m in order.
+func sourcePaths(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// grpcCall calls method of the Downloader service at srv with a ContractRef, returning the response messages and status.
+func grpcCall(t *testing.T, srv *httptest.Server, method string, ch chain, address string) ([][]byte, string) {
+	t.Helper()
+
+	ref := &protoBuffer{}
+	ref.varint(1, uint64(ch))
+	ref.string(2, address)
+	body := &bytes.Buffer{}
+	if err := writeGRPCMessage(body, ref.bs); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+grpcService+method, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.ProtoMajor != 2 || resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s: %s %s", method, resp.Proto, resp.Status)
+	}
+
+	messages := [][]byte{}
+	for {
+		header := make([]byte, 5)
+		if _, err := io.ReadFull(resp.Body, header); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		msg := make([]byte, binary.BigEndian.Uint32(header[1:]))
+		if _, err := io.ReadFull(resp.Body, msg); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, msg)
+	}
+
+	status := resp.Trailer.Get("Grpc-Status")
+	if status == "" {
+		// a trailers-only response
+		status = resp.Header.Get("Grpc-Status")
+	}
+
+	return messages, status
+}
+
+// protoStrings returns the length-delimited fields of a protobuf message by field number.
+func protoStrings(t *testing.T, bs []byte) map[uint64][]string {
+	t.Helper()
+
+	fields := map[uint64][]string{}
+	for len(bs) > 0 {
+		key, n := binary.Uvarint(bs)
+		bs = bs[n:]
+		switch key & 7 {
+		case 0:
+			_, n := binary.Uvarint(bs)
+			bs = bs[n:]
+		case 2:
+			l, n := binary.Uvarint(bs)
+			fields[key>>3] = append(fields[key>>3], string(bs[n:n+int(l)]))
+			bs = bs[n+int(l):]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+	}
+
+	return fields
+}
+
+func TestGRPC(t *testing.T) {
+	const address = "0x1111111111111111111111111111111111111111"
+
+	e := inTestProject(t, `{}`)
+	e.verify(t, address, "Token", map[string]string{"src/Token.sol": "contract Token {}\n", "src/Base.sol": "contract Base {}\n"}, nil)
+	// the explorers of config.json
+	if _, err := loadConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(&grpcHandler{service: newSourceService(1000, newMemoryCache(0))})
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	messages, status := grpcCall(t, srv, "GetMetadata", 1, address)
+	if status != "0" || len(messages) != 1 {
+		t.Fatalf("GetMetadata: status %s, %d messages", status, len(messages))
+	}
+	if m := protoStrings(t, messages[0]); m[1][0] != "Token" || m[3][0] != address || m[4][0] != "v0.8.19+commit.7dd6d404" {
+		t.Errorf("GetMetadata: %q", m)
+	}
+
+	messages, status = grpcCall(t, srv, "FetchSource", 1, address)
+	if status != "0" || len(messages) != 1 {
+		t.Fatalf("FetchSource: status %s, %d messages", status, len(messages))
+	}
+	files := protoStrings(t, messages[0])[2]
+	if len(files) != 2 || protoStrings(t, []byte(files[1]))[1][0] != "src/Token.sol" {
+		t.Errorf("FetchSource: files %q", files)
+	}
+
+	messages, status = grpcCall(t, srv, "StreamFiles", 1, address)
+	if status != "0" || len(messages) != 2 {
+		t.Fatalf("StreamFiles: status %s, %d messages", status, len(messages))
+	}
+	if f := protoStrings(t, messages[0]); f[1][0] != "src/Base.sol" || f[2][0] != "contract Base {}\n" {
+		t.Errorf("StreamFiles: %q", f)
+	}
+
+	for _, tt := range []struct {
+		method  string
+		ch      chain
+		address string
+		status  string
+	}{
+		{"GetMetadata", 1, "0x2222222222222222222222222222222222222222", "5"}, // unverified
+		{"GetMetadata", 1, "0x22", "3"},
+		{"GetMetadata", 424242, address, "5"},
+		{"Download", 1, address, "12"},
+	} {
+		if _, status := grpcCall(t, srv, tt.method, tt.ch, tt.address); status != tt.status {
+			t.Errorf("%s %d %s: status %s, want %s", tt.method, tt.ch, tt.address, status, tt.status)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const historyFile = "history.db"
+
+// historySchema creates the table of the fetches, a row per getsourcecode response of a download.
+const historySchema = `CREATE TABLE IF NOT EXISTS fetches (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	chain INTEGER NOT NULL,
+	address TEXT NOT NULL,
+	fetched_at TEXT NOT NULL,
+	source_hash TEXT NOT NULL,
+	implementation TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS fetches_address ON fetches (chain, address, fetched_at);
+CREATE INDEX IF NOT EXISTS fetches_name ON fetches (name, fetched_at);
+`
+
+// HistoryConfig records every fetch of the downloads in a SQLite database, read by the history command
+// to tell when a contract changed across scheduled runs. It needs the sqlite3 command.
+type HistoryConfig struct {
+	// Path is the database, <user cache>/etherscan-downloader/history.db by default.
+	Path string `json:"path,omitempty"`
+}
+
+// fetchHistory is the SQLite database of the fetches, written through the sqlite3 command.
+type fetchHistory struct {
+	path string
+
+	mu      sync.Mutex
+	created bool
+}
+
+// newFetchHistory returns the history configured by c, nil when c is nil for no history.
+func newFetchHistory(c *HistoryConfig) (*fetchHistory, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		return nil, fmt.Errorf("history: needs the sqlite3 command: %w", err)
+	}
+
+	path := c.Path
+	if path == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("history: set its path, there is no cache directory: %w", err)
+		}
+		path = filepath.Join(cacheDir, "etherscan-downloader", historyFile)
+	}
+
+	return &fetchHistory{path: path}, nil
+}
+
+// fetchRecord is a fetch of a contract in the history.
+type fetchRecord struct {
+	Name           string    `json:"name"`
+	ChainID        chain     `json:"chainId"`
+	Address        string    `json:"address"`
+	FetchedAt      time.Time `json:"fetchedAt"`
+	SourceHash     string    `json:"sourceHash,omitempty"` // the integrity of the verified sources, empty when unverified
+	Implementation string    `json:"implementation,omitempty"`
+}
+
+// record adds the fetch r to the history.
+func (h *fetchHistory) record(ctx context.Context, r *fetchRecord) error {
+	if h == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sql := fmt.Sprintf("INSERT INTO fetches (name, chain, address, fetched_at, source_hash, implementation) VALUES (%s, %d, %s, %s, %s, %s);\n",
+		sqlQuote(r.Name), r.ChainID, sqlQuote(strings.ToLower(r.Address)), sqlQuote(r.FetchedAt.UTC().Format(time.RFC3339)), sqlQuote(r.SourceHash), sqlQuote(strings.ToLower(r.Implementation)))
+	if !h.created {
+		if err := os.MkdirAll(filepath.Dir(h.path), dirMode); err != nil {
+			return fmt.Errorf("history: %w", err)
+		}
+		sql = historySchema + sql
+	}
+
+	if _, err := h.exec(ctx, sql); err != nil {
+		return err
+	}
+	h.created = true
+
+	return nil
+}
+
+// recordFetch adds the fetch of the sources of d to the history, hashed by their integrity, once per request sent:
+// a contract served from the run's cache, e.g. listed twice or the implementation of several proxies, isn't.
+func (dl *downloader) recordFetch(ctx context.Context, d *deployment, rawCodes []*RawCode) error {
+	if dl.history == nil {
+		return nil
+	}
+
+	resp := dl.fetched.record(d)
+	if resp == nil {
+		return nil
+	}
+
+	r := &fetchRecord{Name: d.Name, ChainID: d.Chain, Address: d.Address, FetchedAt: resp.fetchedAt}
+
+	if !isUnverified(rawCodes) {
+		sourceCodes, err := dl.fetched.peekSources(d, rawCodes)
+		if err != nil {
+			return err
+		}
+		r.SourceHash = sourcesIntegrity(sourceCodes)
+
+		if rawCodes[0].Proxy == "1" && isAddress(rawCodes[0].Implementation) {
+			r.Implementation = rawCodes[0].Implementation
+		}
+	}
+
+	return dl.history.record(ctx, r)
+}
+
+// fetches returns the fetches of d in the history, oldest first: those of its name, and those of its address
+// when it has one, e.g. fetched under another name or before being renamed.
+func (h *fetchHistory) fetches(ctx context.Context, d *deployment) ([]*fetchRecord, error) {
+	if _, err := os.Stat(h.path); err != nil {
+		if os.IsNotExist(err) {
+			return []*fetchRecord{}, nil
+		}
+		return nil, fmt.Errorf("history: %w", err)
+	}
+
+	where := "name = " + sqlQuote(d.Name)
+	if d.Address != "" {
+		where += fmt.Sprintf(" OR (chain = %d AND address = %s)", d.Chain, sqlQuote(strings.ToLower(d.Address)))
+	}
+
+	out, err := h.exec(ctx, "SELECT name, chain, address, fetched_at, source_hash, implementation FROM fetches WHERE "+where+" ORDER BY fetched_at, id;\n")
+	if err != nil {
+		return nil, err
+	}
+
+	records := []*fetchRecord{}
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("history: unexpected row %q", line)
+		}
+
+		ch, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("history: bad chain %q", fields[1])
+		}
+		at, err := time.Parse(time.RFC3339, fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("history: %w", err)
+		}
+
+		records = append(records, &fetchRecord{Name: fields[0], ChainID: chain(ch), Address: fields[2], FetchedAt: at, SourceHash: fields[4], Implementation: fields[5]})
+	}
+
+	return records, nil
+}
+
+// exec runs sql on the database with the sqlite3 command, returning its tab-separated rows.
+// Writes of concurrent runs, e.g. the daemon and a manual download, wait for each other.
+func (h *fetchHistory) exec(ctx context.Context, sql string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "sqlite3", "-batch", "-bail", "-noheader", "-separator", "\t", h.path)
+	cmd.Stdin = strings.NewReader(".timeout 5000\n" + sql)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("history: sqlite3: %s", msg)
+		}
+		return nil, fmt.Errorf("history: sqlite3: %w", err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// sqlQuote returns s as an SQL string literal, its tabs and newlines replaced since they separate the rows read back.
+func sqlQuote(s string) string {
+	s = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(s)
+
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// historyVersion is a version of a contract in its history: consecutive fetches of the same sources and implementation.
+type historyVersion struct {
+	Address        string    `json:"address"`
+	SourceHash     string    `json:"sourceHash,omitempty"`
+	Implementation string    `json:"implementation,omitempty"`
+	FirstSeen      time.Time `json:"firstSeen"`
+	LastSeen       time.Time `json:"lastSeen"`
+	Fetches        int       `json:"fetches"`
+}
+
+// historyVersions groups the fetches, oldest first, into the versions they saw.
+func historyVersions(records []*fetchRecord) []*historyVersion {
+	versions := []*historyVersion{}
+	for _, r := range records {
+		if n := len(versions); n > 0 {
+			last := versions[n-1]
+			if last.Address == r.Address && last.SourceHash == r.SourceHash && last.Implementation == r.Implementation {
+				last.LastSeen = r.FetchedAt
+				last.Fetches++
+				continue
+			}
+		}
+
+		versions = append(versions, &historyVersion{Address: r.Address, SourceHash: r.SourceHash, Implementation: r.Implementation, FirstSeen: r.FetchedAt, LastSeen: r.FetchedAt, Fetches: 1})
+	}
+
+	return versions
+}
+
+// historyCommand prints the versions a contract was seen in by the fetches recorded in the history database,
+// when each was first and last fetched and the hash of its sources, or every fetch with --fetches.
+func historyCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	all := fs.Bool("fetches", false, "print every fetch instead of the versions they saw")
+	output := fs.String("output", "text", "output format: text or json")
+
+	return func(ctx context.Context, args []string) error {
+		if *output != "text" && *output != "json" {
+			return &configError{fmt.Errorf("unknown output format %q, want text or json", *output)}
+		}
+		if len(args) != 1 {
+			return &configError{errors.New("history takes a target")}
+		}
+
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if c.History == nil {
+			return &configError{errors.New(`no history recorded: set "history" in config.json, e.g. "history": {}`)}
+		}
+
+		h, err := newFetchHistory(c.History)
+		if err != nil {
+			return err
+		}
+
+		d, err := c.deployment(ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		records, err := h.fetches(ctx, d)
+		if err != nil {
+			return err
+		}
+
+		if *output == "json" {
+			var v interface{} = historyVersions(records)
+			if *all {
+				v = records
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(v)
+		}
+
+		if len(records) == 0 {
+			fmt.Fprintf(os.Stderr, "%s: no fetch recorded in %s\n", d.Name, h.path)
+			return nil
+		}
+
+		if *all {
+			for _, r := range records {
+				fmt.Printf("%s  %s  %s%s\n", r.FetchedAt.Local().Format(time.RFC3339), firstNonEmpty(r.SourceHash, "unverified"), r.Address, implementationSuffix(r.Implementation))
+			}
+			return nil
+		}
+
+		for _, v := range historyVersions(records) {
+			noun := "fetches"
+			if v.Fetches == 1 {
+				noun = "fetch"
+			}
+			fmt.Printf("%s .. %s  %s  %s%s  (%d %s)\n", v.FirstSeen.Local().Format(time.RFC3339), v.LastSeen.Local().Format(time.RFC3339), firstNonEmpty(v.SourceHash, "unverified"), v.Address, implementationSuffix(v.Implementation), v.Fetches, noun)
+		}
+
+		return nil
+	}
+}
+
+func implementationSuffix(implementation string) string {
+	if implementation == "" {
+		return ""
+	}
+
+	return " -> " + implementation
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"sync"
+)
+
+// Hooks are shell commands run in the directory of a contract at points of its download.
+type Hooks struct {
+	PostDownload []string `json:"postDownload,omitempty"` // after the contract was downloaded successfully, e.g. "forge build"
+}
+
+// shellCommand returns the command running cmd with the shell of the OS.
+func shellCommand(ctx context.Context, cmd string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", cmd)
+	}
+
+	return exec.CommandContext(ctx, "sh", "-c", cmd)
+}
+
+// runHooks runs commands in dir one after the other, writing their output line by line to the run's log
+// prefixed with the contract and the command. The first failing command stops the others.
+func runHooks(ctx context.Context, d *deployment, dir string, stage string, commands []string) error {
+	for _, command := range commands {
+		w := &prefixWriter{w: humanOut, prefix: fmt.Sprintf("%s: %s: ", d.Name, command)}
+
+		cmd := shellCommand(ctx, command)
+		cmd.Dir = dir
+		cmd.Stdout = w
+		cmd.Stderr = w
+
+		err := cmd.Run()
+		w.flush()
+		if err != nil {
+			return fmt.Errorf("%s hook %q: %w", stage, command, err)
+		}
+	}
+
+	return nil
+}
+
+// prefixWriter writes each line written to it to w with prefix.
+// stdout and stderr of a command share it, so it is safe for concurrent use.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+
+	mu      sync.Mutex
+	partial []byte
+}
+
+func (p *prefixWriter) Write(bs []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.partial = append(p.partial, bs...)
+	for {
+		i := bytes.IndexByte(p.partial, '\n')
+		if i < 0 {
+			break
+		}
+		if _, err := fmt.Fprintf(p.w, "%s%s\n", p.prefix, p.partial[:i]); err != nil {
+			return 0, err
+		}
+		p.partial = p.partial[i+1:]
+	}
+
+	return len(bs), nil
+}
+
+// flush writes the last line when the output didn't end with a newline.
+func (p *prefixWriter) flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.partial) > 0 {
+		fmt.Fprintf(p.w, "%s%s\n", p.prefix, p.partial)
+		p.partial = nil
+	}
+}
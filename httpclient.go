@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// HTTPConfig tunes the HTTP client used for all requests. Durations are Go durations like "30s".
+type HTTPConfig struct {
+	Timeout         string `json:"timeout,omitempty"`         // whole request including the body, default 60s
+	ConnectTimeout  string `json:"connectTimeout,omitempty"`  // TCP connect, default 10s
+	KeepAlive       string `json:"keepAlive,omitempty"`       // TCP keep-alive period, default 30s
+	MaxIdleConns    int    `json:"maxIdleConns,omitempty"`    // default 100
+	MaxConnsPerHost int    `json:"maxConnsPerHost,omitempty"` // default unlimited
+
+	// Proxy is an http://, https:// or socks5:// proxy URL, overriding HTTP_PROXY and HTTPS_PROXY.
+	Proxy string `json:"proxy,omitempty"`
+
+	// CACert is a PEM file of CA certificates trusted in addition to the system ones.
+	CACert string `json:"caCert,omitempty"`
+	// InsecureSkipVerify disables TLS certificate verification, for lab setups only.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+
+	// UserAgent replaces the default etherscan-downloader/<version>.
+	UserAgent string `json:"userAgent,omitempty"`
+	// Headers are sent with every request, and HostHeaders with the requests to a host such as "api.etherscan.io".
+	Headers     map[string]string            `json:"headers,omitempty"`
+	HostHeaders map[string]map[string]string `json:"hostHeaders,omitempty"`
+
+	// MaxResponseSize is the largest explorer or IPFS response read, in bytes, default 64 MiB.
+	MaxResponseSize int64 `json:"maxResponseSize,omitempty"`
+
+	// DisableCompression stops asking for gzip or deflate responses, for servers or proxies mangling them.
+	DisableCompression bool `json:"disableCompression,omitempty"`
+}
+
+const (
+	defaultHTTPTimeout    = 60 * time.Second
+	defaultConnectTimeout = 10 * time.Second
+	defaultKeepAlive      = 30 * time.Second
+	defaultMaxIdleConns   = 100
+
+	defaultMaxResponseSize = 64 << 20
+)
+
+// httpClient is the client of all outgoing requests. Unlike http.DefaultClient, it has timeouts.
+var httpClient, _ = newHTTPClient(&HTTPConfig{})
+
+// newHTTPClient returns a client configured by hc, with defaults for the fields not set.
+func newHTTPClient(hc *HTTPConfig) (*http.Client, error) {
+	timeout, err := parseDuration("timeout", hc.Timeout, defaultHTTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	connectTimeout, err := parseDuration("connectTimeout", hc.ConnectTimeout, defaultConnectTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	keepAlive, err := parseDuration("keepAlive", hc.KeepAlive, defaultKeepAlive)
+	if err != nil {
+		return nil, err
+	}
+
+	maxIdleConns := hc.MaxIdleConns
+	if maxIdleConns == 0 {
+		maxIdleConns = defaultMaxIdleConns
+	}
+
+	dialer := &net.Dialer{Timeout: connectTimeout, KeepAlive: keepAlive}
+
+	// the cloned transport takes proxies from HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.MaxIdleConns = maxIdleConns
+	transport.MaxIdleConnsPerHost = maxIdleConns
+	transport.MaxConnsPerHost = hc.MaxConnsPerHost
+	transport.TLSHandshakeTimeout = connectTimeout
+	transport.ResponseHeaderTimeout = timeout
+
+	if hc.Proxy != "" {
+		proxy, err := url.Parse(hc.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("http proxy: %w", err)
+		}
+
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("http proxy: unsupported scheme %q, want http, https or socks5", proxy.Scheme)
+		}
+
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if hc.CACert != "" || hc.InsecureSkipVerify {
+		tlsConfig, err := newTLSConfig(hc.CACert, hc.InsecureSkipVerify)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	userAgent := hc.UserAgent
+	if userAgent == "" {
+		userAgent = "etherscan-downloader/" + version
+	}
+
+	// compressionTransport asks for gzip and deflate instead of the transport asking for gzip alone
+	transport.DisableCompression = true
+	var base http.RoundTripper = transport
+	if !hc.DisableCompression {
+		base = &compressionTransport{base: transport}
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &headerTransport{base: base, userAgent: userAgent, headers: hc.Headers, hostHeaders: hc.HostHeaders},
+	}, nil
+}
+
+// headerTransport sets the configured User-Agent and headers on requests.
+type headerTransport struct {
+	base        http.RoundTripper
+	userAgent   string
+	headers     map[string]string
+	hostHeaders map[string]map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+
+	req.Header.Set("User-Agent", t.userAgent)
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range t.hostHeaders[req.URL.Host] {
+		req.Header.Set(k, v)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+func newTLSConfig(caCert string, insecureSkipVerify bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+
+	if caCert != "" {
+		pem, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("http caCert: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("http caCert: no certificates in %s", caCert)
+		}
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
+
+// configureHTTP replaces httpClient with one configured by hc, with the non-empty fields of override taking precedence.
+func configureHTTP(hc *HTTPConfig, override *HTTPConfig) error {
+	merged := &HTTPConfig{}
+	if hc != nil {
+		*merged = *hc
+	}
+
+	if override != nil {
+		merged.Timeout = firstNonEmpty(override.Timeout, merged.Timeout)
+		merged.ConnectTimeout = firstNonEmpty(override.ConnectTimeout, merged.ConnectTimeout)
+		merged.Proxy = firstNonEmpty(override.Proxy, merged.Proxy)
+		merged.CACert = firstNonEmpty(override.CACert, merged.CACert)
+		merged.InsecureSkipVerify = merged.InsecureSkipVerify || override.InsecureSkipVerify
+		merged.UserAgent = firstNonEmpty(override.UserAgent, merged.UserAgent)
+	}
+
+	client, err := newHTTPClient(merged)
+	if err != nil {
+		return err
+	}
+	httpClient = client
+
+	maxResponseSize = merged.MaxResponseSize
+	if maxResponseSize <= 0 {
+		maxResponseSize = defaultMaxResponseSize
+	}
+
+	return nil
+}
+
+// maxResponseSize is the limit of limitBody.
+var maxResponseSize int64 = defaultMaxResponseSize
+
+// errResponseTooLarge is returned when reading past maxResponseSize.
+var errResponseTooLarge = errors.New("response too large")
+
+// limitBody limits body to maxResponseSize bytes, failing with errResponseTooLarge past it.
+func limitBody(body io.ReadCloser) io.ReadCloser {
+	return &limitedBody{ReadCloser: body, remaining: maxResponseSize}
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// read one more byte to tell a body of exactly the limit from a larger one
+		n, err := b.ReadCloser.Read(make([]byte, 1))
+		if n > 0 {
+			return 0, fmt.Errorf("%w: over %d bytes", errResponseTooLarge, maxResponseSize)
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+
+	return n, err
+}
+
+func parseDuration(name string, s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("http %s: %w", name, err)
+	}
+
+	return d, nil
+}
+
+func firstNonEmpty(ss ...string) string {
+	for _, s := range ss {
+		if s != "" {
+			return s
+		}
+	}
+
+	return ""
+}
+
+// httpGet GETs url with httpClient, canceled with ctx.
+func httpGet(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return httpClient.Do(req)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// debugClient sends requests with base and logs their URLs and the responses with their bodies to w,
+// for troubleshooting explorer quirks. The API keys are redacted from the log.
+type debugClient struct {
+	base httpDoer
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (c *debugClient) Do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.base.Do(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s %s %s\n", start.UTC().Format(time.RFC3339), req.Method, redactedURL(req.URL))
+	if err != nil {
+		fmt.Fprintf(&b, "error after %s: %v\n\n", elapsed, err)
+		c.write(b.String())
+		return nil, err
+	}
+
+	body, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	fmt.Fprintf(&b, "%s in %s\n", resp.Status, elapsed)
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s: %s\n", name, strings.Join(resp.Header[name], ", "))
+	}
+	fmt.Fprintf(&b, "\n%s\n", body)
+	if readErr != nil {
+		fmt.Fprintf(&b, "error reading the body: %v\n", readErr)
+	}
+	b.WriteString("\n")
+	c.write(b.String())
+
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	return resp, nil
+}
+
+func (c *debugClient) write(s string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	io.WriteString(c.w, redactAPIKeys(s))
+}
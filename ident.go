@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// identWords returns the words of name, its runs of ASCII letters and digits, e.g. "uniswap", "v3" and "pool" for "uniswap-v3/pool".
+func identWords(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return r >= unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+}
+
+// identifier joins words with sep into an identifier of Go and Solidity, prefixed with prefix when it would be empty
+// or start with a digit.
+func identifier(prefix string, sep string, words []string) string {
+	ident := strings.Join(words, sep)
+	if ident == "" || unicode.IsDigit(rune(ident[0])) {
+		ident = prefix + ident
+	}
+
+	return ident
+}
+
+// goExportedName returns an exported Go identifier for name, e.g. "UniswapV3Pool" for "uniswap-v3/pool".
+func goExportedName(name string) string {
+	words := identWords(name)
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+
+	return identifier("Contract", "", words)
+}
+
+// goPackageName returns a Go package name for contractName, e.g. "uniswapv3pool" for "UniswapV3Pool".
+func goPackageName(contractName string) string {
+	return identifier("contract", "", identWords(strings.ToLower(contractName)))
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// interfaceWriter renders a Solidity interface, collecting the struct types used by its members.
+type interfaceWriter struct {
+	structs     []string
+	structNames map[string]string // the name of each struct declared, by its qualified type and components
+	declared    map[string]bool
+}
+
+// writeInterface generates I<ContractName>.sol from abi.json.
+func writeInterface(dir string, rawCode *RawCode) error {
+	entries, err := parseABI(rawCode.Abi)
+	if err != nil {
+		return fmt.Errorf("parse ABI: %w", err)
+	}
+
+	name := "I" + rawCode.ContractName
+	w := &interfaceWriter{structNames: map[string]string{}, declared: map[string]bool{}}
+
+	members := []string{}
+	for _, e := range entries {
+		if m := w.member(e); m != "" {
+			members = append(members, m)
+		}
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintln(b, "// SPDX-License-Identifier: UNLICENSED")
+	fmt.Fprintln(b, "pragma solidity ^0.8.0;")
+	fmt.Fprintln(b)
+	fmt.Fprintf(b, "/// @title %s\n", name)
+	fmt.Fprintf(b, "/// @notice Interface of %s, generated from its verified ABI\n", rawCode.ContractName)
+	fmt.Fprintf(b, "interface %s {\n", name)
+	for _, s := range w.structs {
+		fmt.Fprint(b, s)
+		fmt.Fprintln(b)
+	}
+	fmt.Fprint(b, strings.Join(members, "\n"))
+	fmt.Fprintln(b, "}")
+
+	return os.WriteFile(filepath.Join(dir, name+".sol"), []byte(b.String()), fileMode)
+}
+
+func (w *interfaceWriter) member(e *ABIEntry) string {
+	b := &strings.Builder{}
+
+	switch e.Type {
+	case "function":
+		fmt.Fprintln(b, "    /// @notice")
+		for _, p := range e.Inputs {
+			if p.Name != "" {
+				fmt.Fprintf(b, "    /// @param %s\n", p.Name)
+			}
+		}
+		for _, p := range e.Outputs {
+			fmt.Fprintf(b, "    /// @return %s\n", w.paramType(p))
+		}
+
+		fmt.Fprintf(b, "    function %s(%s) external", e.Name, w.params(e.Inputs, "calldata", false))
+		if e.StateMutability != "" && e.StateMutability != "nonpayable" {
+			fmt.Fprintf(b, " %s", e.StateMutability)
+		}
+		if len(e.Outputs) > 0 {
+			fmt.Fprintf(b, " returns (%s)", w.params(e.Outputs, "memory", false))
+		}
+		fmt.Fprintln(b, ";")
+	case "event":
+		fmt.Fprintf(b, "    event %s(%s)", e.Name, w.params(e.Inputs, "", true))
+		if e.Anonymous {
+			fmt.Fprint(b, " anonymous")
+		}
+		fmt.Fprintln(b, ";")
+	case "error":
+		fmt.Fprintf(b, "    error %s(%s);\n", e.Name, w.params(e.Inputs, "", false))
+	case "fallback":
+		if e.StateMutability == "payable" {
+			fmt.Fprintln(b, "    fallback() external payable;")
+		} else {
+			fmt.Fprintln(b, "    fallback() external;")
+		}
+	case "receive":
+		fmt.Fprintln(b, "    receive() external payable;")
+	}
+
+	return b.String()
+}
+
+func (w *interfaceWriter) params(ps []*ABIParam, location string, event bool) string {
+	params := make([]string, 0, len(ps))
+	for _, p := range ps {
+		param := w.paramType(p)
+		if location != "" && isReferenceType(p.Type) {
+			param += " " + location
+		}
+		if event && p.Indexed {
+			param += " indexed"
+		}
+		if p.Name != "" {
+			param += " " + p.Name
+		}
+		params = append(params, param)
+	}
+
+	return strings.Join(params, ", ")
+}
+
+// paramType returns the Solidity type of p, declaring the structs it refers to.
+func (w *interfaceWriter) paramType(p *ABIParam) string {
+	if !strings.HasPrefix(p.Type, "tuple") {
+		return p.Type
+	}
+
+	suffix := strings.TrimPrefix(p.Type, "tuple")
+	qualified := structType(p)
+	key := qualified + " " + tupleType(p)
+
+	name, ok := w.structNames[key]
+	if !ok {
+		name = w.structName(qualified)
+		w.structNames[key] = name
+		w.declared[name] = true
+
+		b := &strings.Builder{}
+		fmt.Fprintf(b, "    struct %s {\n", name)
+		for _, c := range p.Components {
+			fmt.Fprintf(b, "        %s %s;\n", w.paramType(c), c.Name)
+		}
+		fmt.Fprintln(b, "    }")
+		w.structs = append(w.structs, b.String())
+	}
+
+	return name + suffix
+}
+
+// structName returns the name to declare the struct of the qualified type as: its bare name, e.g. "Pool" for "IPool.Pool",
+// or when another struct is declared as that, the qualified one, e.g. "IPool_Pool", numbered if it is taken too.
+func (w *interfaceWriter) structName(qualified string) string {
+	name := qualified[strings.LastIndex(qualified, ".")+1:]
+	if !w.declared[name] {
+		return name
+	}
+
+	name = strings.Join(identWords(qualified), "_")
+	for n := 2; w.declared[name]; n++ {
+		name = fmt.Sprintf("%s_%d", strings.Join(identWords(qualified), "_"), n)
+	}
+
+	return name
+}
+
+// structType returns the qualified struct type of a tuple parameter from its internal type, e.g. "IPool.Pool" for "struct IPool.Pool[]".
+func structType(p *ABIParam) string {
+	name := strings.TrimPrefix(p.InternalType, "struct ")
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i]
+	}
+
+	if name == "" || strings.HasPrefix(name, "tuple") {
+		// ABIs from old compilers lack internal types
+		return "Tuple" + strings.Join(identWords(strings.ReplaceAll(tupleType(p), "[]", "Array")), "_")
+	}
+
+	return name
+}
+
+// tupleType returns the canonical type of a tuple parameter without its array suffix, e.g. "(address,uint256)".
+func tupleType(p *ABIParam) string {
+	t := p.canonicalType()
+
+	return t[:strings.LastIndex(t, ")")+1]
+}
+
+func isReferenceType(t string) bool {
+	return t == "string" || t == "bytes" || strings.HasSuffix(t, "]") || strings.HasPrefix(t, "tuple")
+}
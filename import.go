@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+func runImport(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: import foundry-broadcast <file> | import hardhat-deployments <dir>")
+	}
+
+	var importer func(path string) ([]*deployment, error)
+	switch args[0] {
+	case "foundry-broadcast":
+		importer = importFoundryBroadcast
+	case "hardhat-deployments":
+		importer = importHardhatDeployments
+	default:
+		return fmt.Errorf("unknown import source: %s", args[0])
+	}
+
+	contracts, err := importer(args[1])
+	if err != nil {
+		return err
+	}
+
+	c, err := loadProjectConfig()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range c.addImported(contracts) {
+		fmt.Println("added", name)
+	}
+
+	return saveConfig(c)
+}
+
+// addImported registers contracts not yet in the config and returns the names they were added under.
+func (c *Config) addImported(contracts []*deployment) []string {
+	if c.Contracts == nil {
+		c.Contracts = map[string]ConfigContract{}
+	}
+
+	known := map[string]bool{}
+	for _, cc := range c.Contracts {
+		if ch, address, err := cc.resolve(); err == nil {
+			known[contractKey(ch, address)] = true
+		}
+	}
+
+	added := []string{}
+	for _, contract := range contracts {
+		key := contractKey(contract.Chain, contract.Address)
+		if known[key] {
+			continue
+		}
+		known[key] = true
+
+		name := uniqueName(c.Contracts, snakeCase(contract.Name))
+		c.Contracts[name] = ConfigContract{Chain: contract.Chain, Address: contract.Address}
+		added = append(added, name)
+	}
+
+	return added
+}
+
+func contractKey(c chain, address string) string {
+	return strconv.FormatUint(uint64(c), 10) + ":" + strings.ToLower(address)
+}
+
+func uniqueName(contracts map[string]ConfigContract, name string) string {
+	if _, ok := contracts[name]; !ok {
+		return name
+	}
+
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s_%d", name, i)
+		if _, ok := contracts[candidate]; !ok {
+			return candidate
+		}
+	}
+}
+
+// snakeCase converts a contract name like "UniswapV3Pool" to a config key like "uniswap_v3_pool".
+func snakeCase(s string) string {
+	rs := []rune(s)
+	b := strings.Builder{}
+	for i, r := range rs {
+		if unicode.IsUpper(r) && i > 0 && (unicode.IsLower(rs[i-1]) || (i+1 < len(rs) && unicode.IsLower(rs[i+1]))) {
+			b.WriteRune('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
+
+// FoundryBroadcast is the part of forge script's broadcast output (run-latest.json) needed to find deployments.
+type FoundryBroadcast struct {
+	Chain        chain                          `json:"chain"`
+	Transactions []*FoundryBroadcastTransaction `json:"transactions"`
+}
+
+type FoundryBroadcastTransaction struct {
+	TransactionType string `json:"transactionType"`
+	ContractName    string `json:"contractName"`
+	ContractAddress string `json:"contractAddress"`
+}
+
+func importFoundryBroadcast(path string) ([]*deployment, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	broadcast := &FoundryBroadcast{}
+	if err := json.Unmarshal(bs, broadcast); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	contracts := []*deployment{}
+	for _, tx := range broadcast.Transactions {
+		if tx.TransactionType != "CREATE" && tx.TransactionType != "CREATE2" {
+			continue
+		}
+
+		if tx.ContractName == "" || !isAddress(tx.ContractAddress) {
+			continue
+		}
+
+		contracts = append(contracts, &deployment{
+			Name:    tx.ContractName,
+			Chain:   broadcast.Chain,
+			Address: tx.ContractAddress,
+		})
+	}
+
+	return contracts, nil
+}
+
+// HardhatDeployment is the part of a hardhat-deploy deployment file (deployments/<network>/<Name>.json) needed to track it.
+type HardhatDeployment struct {
+	Address string `json:"address"`
+}
+
+// importHardhatDeployments reads every deployment in a hardhat-deploy network directory.
+// The chain is taken from the directory's .chainId file.
+func importHardhatDeployments(dir string) ([]*deployment, error) {
+	bs, err := os.ReadFile(filepath.Join(dir, ".chainId"))
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.ParseUint(strings.TrimSpace(string(bs)), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain id in %s: %w", filepath.Join(dir, ".chainId"), err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	contracts := []*deployment{}
+	for _, path := range paths {
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		hd := &HardhatDeployment{}
+		if err := json.Unmarshal(bs, hd); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		if !isAddress(hd.Address) {
+			continue
+		}
+
+		contracts = append(contracts, &deployment{
+			Name:    strings.TrimSuffix(filepath.Base(path), ".json"),
+			Chain:   chain(id),
+			Address: hd.Address,
+		})
+	}
+
+	return contracts, nil
+}
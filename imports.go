@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	importsDotFile     = "imports.dot"
+	importsMermaidFile = "imports.mmd"
+)
+
+var (
+	importPattern  = regexp.MustCompile(`(?m)^\s*import\s+(?:[^"';]*?\bfrom\s+)?["']([^"']+)["']`)
+	commentPattern = regexp.MustCompile(`(?s)//[^\n]*|/\*.*?\*/`)
+)
+
+// importGraph maps each source file to the source files it imports.
+type importGraph map[string][]string
+
+// parseImports builds the import graph of sources, resolving relative imports and remappings to source keys.
+func parseImports(sources Sources, remappings []string) importGraph {
+	graph := importGraph{}
+	for file, source := range sources {
+		imports := []string{}
+		for _, m := range importPattern.FindAllStringSubmatch(commentPattern.ReplaceAllString(source.Content, ""), -1) {
+			imports = append(imports, resolveImport(file, m[1], remappings))
+		}
+		sort.Strings(imports)
+		graph[file] = imports
+	}
+
+	return graph
+}
+
+// resolveImport returns the source key imported as imported from file.
+func resolveImport(file string, imported string, remappings []string) string {
+	if strings.HasPrefix(imported, "./") || strings.HasPrefix(imported, "../") {
+		return path.Join(path.Dir(file), imported)
+	}
+
+	longest := ""
+	target := ""
+	for _, r := range remappings {
+		// context:prefix=target
+		if i := strings.Index(r, ":"); i >= 0 && i < strings.Index(r, "=") {
+			if !strings.HasPrefix(file, r[:i]) {
+				continue
+			}
+			r = r[i+1:]
+		}
+
+		prefix, t, ok := strings.Cut(r, "=")
+		if ok && strings.HasPrefix(imported, prefix) && len(prefix) > len(longest) {
+			longest, target = prefix, t
+		}
+	}
+
+	if longest != "" {
+		return path.Clean(target + strings.TrimPrefix(imported, longest))
+	}
+
+	return path.Clean(imported)
+}
+
+func (g importGraph) files() []string {
+	files := make([]string, 0, len(g))
+	for file := range g {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	return files
+}
+
+func (g importGraph) dot() string {
+	b := &strings.Builder{}
+	fmt.Fprintln(b, "digraph imports {")
+	fmt.Fprintln(b, "  rankdir=LR;")
+	fmt.Fprintln(b, "  node [shape=box];")
+	for _, file := range g.files() {
+		fmt.Fprintf(b, "  %q;\n", file)
+		for _, imported := range g[file] {
+			fmt.Fprintf(b, "  %q -> %q;\n", file, imported)
+		}
+	}
+	fmt.Fprintln(b, "}")
+
+	return b.String()
+}
+
+func (g importGraph) mermaid() string {
+	ids := map[string]string{}
+	id := func(file string) string {
+		if _, ok := ids[file]; !ok {
+			ids[file] = fmt.Sprintf("f%d", len(ids))
+		}
+		return ids[file]
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintln(b, "graph LR")
+	for _, file := range g.files() {
+		fmt.Fprintf(b, "  %s[%q]\n", id(file), file)
+	}
+	for _, file := range g.files() {
+		for _, imported := range g[file] {
+			if _, ok := ids[imported]; !ok {
+				fmt.Fprintf(b, "  %s[%q]\n", id(imported), imported)
+			}
+			fmt.Fprintf(b, "  %s --> %s\n", id(file), id(imported))
+		}
+	}
+
+	return b.String()
+}
+
+// writeImportGraph writes the import graph of the sources as imports.dot and imports.mmd (Mermaid).
+func writeImportGraph(dir string, sourceCode *SourceCode) error {
+	graph := parseImports(sourceCode.Sources, sourceCode.Settings.Remappings)
+
+	if err := os.WriteFile(filepath.Join(dir, importsDotFile), []byte(graph.dot()), fileMode); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, importsMermaidFile), []byte(graph.mermaid()), fileMode)
+}
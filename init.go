@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// initCommand writes a starter config.json.
+// Values not given as flags are asked for when stdin is a terminal.
+func initCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	contractDir := fs.String("contract-dir", "", "directory sources are downloaded into (default contracts)")
+	name := fs.String("name", "", "name of the first contract")
+	address := fs.String("address", "", "address of the first contract, chain-prefixed (e.g. eth:0x...) unless --chain is given")
+	chainFlag := fs.String("chain", "", "chain id or short name of the first contract")
+	force := fs.Bool("force", false, "overwrite an existing config.json")
+
+	return func(ctx context.Context, args []string) error {
+		if _, err := os.Stat(configPath); err == nil && !*force {
+			return fmt.Errorf("%s already exists, use --force to overwrite it", configPath)
+		}
+
+		if isTerminal(os.Stdin) {
+			p := &prompter{r: bufio.NewReader(os.Stdin), w: os.Stderr}
+			for _, q := range []struct {
+				value *string
+				label string
+				def   string
+			}{
+				{contractDir, "contract directory", "contracts"},
+				{name, "contract name", ""},
+				{address, "contract address (e.g. eth:0x... or an explorer URL)", ""},
+			} {
+				if *q.value != "" {
+					continue
+				}
+
+				v, err := p.ask(q.label, q.def)
+				if err != nil {
+					return err
+				}
+				*q.value = v
+			}
+		}
+
+		if *contractDir == "" {
+			*contractDir = "contracts"
+		}
+
+		c := &Config{Version: configVersion, ContractDir: *contractDir, Contracts: map[string]ConfigContract{}}
+
+		if *name != "" || *address != "" {
+			if *name == "" || *address == "" {
+				return errors.New("both --name and --address are needed for the first contract")
+			}
+
+			cc := ConfigContract{Address: *address}
+			if *chainFlag != "" {
+				ch, err := parseChain(*chainFlag)
+				if err != nil {
+					return err
+				}
+				cc.Chain = ch
+			}
+
+			if _, _, err := cc.resolve(); err != nil {
+				return err
+			}
+			if cc.Chain == 0 && !strings.Contains(cc.Address, ":") {
+				return fmt.Errorf("%s: chain is unknown, use --chain or a chain-prefixed address", *name)
+			}
+
+			c.Target = *name
+			c.Contracts[*name] = cc
+		}
+
+		if err := saveConfig(c); err != nil {
+			return err
+		}
+
+		fmt.Printf(`wrote %s
+
+	contracts are entries of "contracts", e.g.
+	  "weth": {"address": "eth:0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"}
+	  "usdc": {"chain": 137, "address": "0x3c499c542cEF5E3811e1192ce70d8cC03d5c3359"}
+
+	set the explorer API keys (ETHERSCAN_APIKEY, POLYGONSCAN_APIKEY, ARBISCAN_APIKEY) and run
+	  etherscan-downloader download
+	`, configPath)
+
+		return nil
+	}
+}
+
+// isTerminal reports whether f is a character device rather than a pipe or a file.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+type prompter struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+// ask prints label and returns the answered line, or def when the answer is empty.
+func (p *prompter) ask(label string, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.w, "%s [%s]: ", label, def)
+	} else {
+		fmt.Fprintf(p.w, "%s: ", label)
+	}
+
+	line, err := p.r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	if line = strings.TrimSpace(line); line == "" {
+		return def, nil
+	}
+
+	return line, nil
+}
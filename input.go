@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// InputContract is an entry of a JSON --input file.
+type InputContract struct {
+	Chain   string `json:"chain"`
+	Address string `json:"address"`
+	Name    string `json:"name"`
+}
+
+// readInput reads the contracts listed in a CSV (chain,address,name) or JSON file.
+// Contracts without a name are named by their address.
+func readInput(path string) ([]*deployment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []*InputContract
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		entries, err = readJSONInput(f)
+	} else {
+		entries, err = readCSVInput(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	deployments := make([]*deployment, 0, len(entries))
+	for i, entry := range entries {
+		c, err := parseChain(entry.Chain)
+		if err != nil {
+			return nil, fmt.Errorf("%s: entry %d: %w", path, i+1, err)
+		}
+
+		if !isAddress(entry.Address) {
+			return nil, fmt.Errorf("%s: entry %d: invalid address: %s", path, i+1, entry.Address)
+		}
+
+		name := entry.Name
+		if name == "" {
+			name = entry.Address
+		}
+		if err := checkFolder(name); err != nil {
+			return nil, fmt.Errorf("%s: entry %d: %w", path, i+1, err)
+		}
+
+		deployments = append(deployments, &deployment{Name: name, Chain: c, Address: entry.Address})
+	}
+
+	return deployments, nil
+}
+
+func readJSONInput(r io.Reader) ([]*InputContract, error) {
+	entries := []*InputContract{}
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func readCSVInput(r io.Reader) ([]*InputContract, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// header row is optional
+	if len(records) > 0 && strings.EqualFold(records[0][0], "chain") {
+		records = records[1:]
+	}
+
+	entries := make([]*InputContract, 0, len(records))
+	for i, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: want chain,address[,name]", i+1)
+		}
+
+		entry := &InputContract{Chain: record[0], Address: record[1]}
+		if len(record) > 2 {
+			entry.Name = record[2]
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
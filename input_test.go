@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadInputNames(t *testing.T) {
+	const address = "0x1111111111111111111111111111111111111111"
+
+	for _, tt := range []struct {
+		file    string
+		input   string
+		name    string
+		invalid bool
+	}{
+		{"in.csv", "chain,address,name\n1," + address + ",token\n", "token", false},
+		{"in.csv", "1," + address + "\n", address, false},
+		{"in.csv", "1," + address + ",uniswap/pool\n", "uniswap/pool", false},
+		{"in.csv", "1," + address + ",../x\n", "", true},
+		{"in.csv", "1," + address + ",a/../../x\n", "", true},
+		{"in.csv", "1," + address + ",/etc/x\n", "", true},
+		{"in.csv", "1," + address + `,..\x` + "\n", "", true},
+		{"in.json", `[{"chain": "1", "address": "` + address + `", "name": ".."}]`, "", true},
+		{"in.json", `[{"chain": "1", "address": "` + address + `", "name": "a//b"}]`, "", true},
+	} {
+		path := filepath.Join(t.TempDir(), tt.file)
+		if err := os.WriteFile(path, []byte(tt.input), fileMode); err != nil {
+			t.Fatal(err)
+		}
+
+		ds, err := readInput(path)
+		switch {
+		case tt.invalid && err == nil:
+			t.Errorf("readInput(%q) = %s, want an error", tt.input, ds[0].Name)
+		case tt.invalid && !strings.Contains(err.Error(), "entry 1"):
+			t.Errorf("readInput(%q): %s, want the entry", tt.input, err)
+		case !tt.invalid && err != nil:
+			t.Errorf("readInput(%q): %s", tt.input, err)
+		case !tt.invalid && ds[0].Name != tt.name:
+			t.Errorf("readInput(%q) = %s, want %s", tt.input, ds[0].Name, tt.name)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// integrityPrefix is the algorithm of integrities, as in subresource integrity.
+const integrityPrefix = "sha256-"
+
+// sourcesIntegrity returns the integrity of the verified sources of sourceCodes, before any normalization or filter,
+// so it doesn't depend on the flags of the download.
+func sourcesIntegrity(sourceCodes []*SourceCode) string {
+	return integrityPrefix + sourcesHash(sourceCodes)
+}
+
+// checkIntegrity fails with errDrift when d pins an integrity other than that of its verified sources,
+// e.g. after a compromise of the explorer or a re-verification swapping the sources.
+func checkIntegrity(d *deployment, integrity string) error {
+	if d.Integrity == "" {
+		return nil
+	}
+
+	if !strings.EqualFold(d.Integrity, integrity) {
+		return fmt.Errorf("%w: the verified sources have the integrity %s, not the pinned %s: check what changed before updating the integrity in config.json", errDrift, integrity, d.Integrity)
+	}
+
+	return nil
+}
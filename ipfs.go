@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	defaultIPFSGateway  = "https://ipfs.io"
+	defaultSwarmGateway = "https://swarm-gateways.net"
+
+	solcMetadataFile = "solc-metadata.json"
+)
+
+// SolcMetadata is the metadata JSON solc references from the bytecode's CBOR trailer.
+type SolcMetadata struct {
+	Compiler struct {
+		Version string `json:"version"`
+	} `json:"compiler"`
+	Language string                         `json:"language"`
+	Settings map[string]json.RawMessage     `json:"settings"`
+	Sources  map[string]*SolcMetadataSource `json:"sources"`
+}
+
+type SolcMetadataSource struct {
+	Keccak256 string   `json:"keccak256"`
+	Content   string   `json:"content"`
+	URLs      []string `json:"urls"`
+}
+
+func ipfsGateway() string {
+	if g := os.Getenv("IPFS_GATEWAY"); g != "" {
+		return strings.TrimSuffix(g, "/")
+	}
+
+	return defaultIPFSGateway
+}
+
+func swarmGateway() string {
+	if g := os.Getenv("SWARM_GATEWAY"); g != "" {
+		return strings.TrimSuffix(g, "/")
+	}
+
+	return defaultSwarmGateway
+}
+
+// metadataURLs returns the gateway URLs of the metadata JSON referenced by the CBOR trailer of code.
+func metadataURLs(code []byte) ([]string, error) {
+	m, err := bytecodeMetadata(code)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := []string{}
+	if hash, ok := m["ipfs"].([]byte); ok {
+		urls = append(urls, ipfsGateway()+"/ipfs/"+base58(hash))
+	}
+
+	for _, key := range []string{"bzzr1", "bzzr0"} {
+		if hash, ok := m[key].([]byte); ok {
+			urls = append(urls, swarmGateway()+"/bzz-raw:/"+hex.EncodeToString(hash))
+		}
+	}
+
+	if len(urls) == 0 {
+		return nil, errors.New("no IPFS or Swarm hash in bytecode metadata")
+	}
+
+	return urls, nil
+}
+
+// sourceURL returns the gateway URL of a metadata source URL like "dweb:/ipfs/<cid>" or "bzz-raw://<hash>".
+func sourceURL(u string) (string, bool) {
+	switch {
+	case strings.HasPrefix(u, "dweb:/ipfs/"):
+		return ipfsGateway() + "/ipfs/" + strings.TrimPrefix(u, "dweb:/ipfs/"), true
+	case strings.HasPrefix(u, "ipfs://"):
+		return ipfsGateway() + "/ipfs/" + strings.TrimPrefix(u, "ipfs://"), true
+	case strings.HasPrefix(u, "bzz-raw://"):
+		return swarmGateway() + "/bzz-raw:/" + strings.TrimPrefix(u, "bzz-raw://"), true
+	}
+
+	return "", false
+}
+
+// recoverFromMetadata fetches the metadata JSON referenced by code and the sources it lists, writing them to dir.
+// Sources are checked against their keccak256 from the metadata.
+func recoverFromMetadata(ctx context.Context, dir string, code []byte) error {
+	urls, err := metadataURLs(code)
+	if err != nil {
+		return err
+	}
+
+	var raw []byte
+	for _, u := range urls {
+		if raw, err = fetch(ctx, u); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("fetch metadata: %w", err)
+	}
+
+	metadata := &SolcMetadata{}
+	if err := json.Unmarshal(raw, metadata); err != nil {
+		return fmt.Errorf("metadata: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, solcMetadataFile), raw, fileMode); err != nil {
+		return err
+	}
+
+	sources := Sources{}
+	for path, source := range metadata.Sources {
+		content, err := metadataSourceContent(ctx, source)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		sources[path] = &Contract{Content: content}
+
+		dst := filepath.Join(dir, sourcePath(path))
+		if err := os.MkdirAll(filepath.Dir(dst), dirMode); err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(dst, []byte(content), fileMode); err != nil {
+			return err
+		}
+	}
+
+	input, err := metadataStandardInput(metadata, sources)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(filepath.Join(dir, standardInputFile), input)
+}
+
+func metadataSourceContent(ctx context.Context, source *SolcMetadataSource) (string, error) {
+	if source.Content != "" {
+		return source.Content, nil
+	}
+
+	var err error = errors.New("no source URL")
+	for _, u := range source.URLs {
+		gatewayURL, ok := sourceURL(u)
+		if !ok {
+			continue
+		}
+
+		var content []byte
+		if content, err = fetch(ctx, gatewayURL); err != nil {
+			continue
+		}
+
+		if source.Keccak256 != "" && "0x"+hex.EncodeToString(keccak256(content)) != source.Keccak256 {
+			err = fmt.Errorf("keccak256 mismatch from %s", gatewayURL)
+			continue
+		}
+
+		return string(content), nil
+	}
+
+	return "", err
+}
+
+// metadataStandardInput builds the standard-json input solc compiled from the metadata, which records
+// the compiled contract as compilationTarget instead of an outputSelection.
+func metadataStandardInput(metadata *SolcMetadata, sources Sources) (*SourceCode, error) {
+	settings := map[string]json.RawMessage{}
+	for k, v := range metadata.Settings {
+		if k != "compilationTarget" {
+			settings[k] = v
+		}
+	}
+
+	selection, err := json.Marshal(OutputSelection{"*": {"*": {"abi", "evm.bytecode", "evm.deployedBytecode"}}})
+	if err != nil {
+		return nil, err
+	}
+	settings["outputSelection"] = selection
+
+	bs, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &SourceCode{Language: metadata.Language, Sources: sources}
+	if err := json.Unmarshal(bs, &input.Settings); err != nil {
+		return nil, err
+	}
+
+	return input, nil
+}
+
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	resp, err := httpGet(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	return io.ReadAll(limitBody(resp.Body))
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58 encodes bs with the bitcoin alphabet, as used by IPFS CIDv0.
+func base58(bs []byte) string {
+	n := new(big.Int).SetBytes(bs)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+
+	out := []byte{}
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+
+	for _, b := range bs {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+
+	return string(out)
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestBase58(t *testing.T) {
+	for _, tt := range []struct {
+		hex  string
+		want string
+	}{
+		{"", ""},
+		{"00", "1"},
+		{"0000287fb4cd", "11233QC4"},
+		{hex.EncodeToString([]byte("Hello World!")), "2NEpo7TZRRrLZSi2U"},
+		{hex.EncodeToString([]byte("The quick brown fox jumps over the lazy dog.")), "USm3fpXnKG5EUBx2ndxBDMPVciP5hGey2Jh4NDv6gmeo1LkMeiKrLJUUBk6Z"},
+	} {
+		bs, _ := hex.DecodeString(tt.hex)
+		if got := base58(bs); got != tt.want {
+			t.Errorf("base58(%s) = %q, want %q", tt.hex, got, tt.want)
+		}
+	}
+}
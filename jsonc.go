@@ -0,0 +1,79 @@
+package main
+
+// stripJSONC returns the JSON with comments (JSONC) bs as plain JSON: its // and /* */ comments
+// and the commas trailing the last member of objects and arrays replaced by spaces, so a config can annotate
+// why its contracts are vendored and who owns them. The result has the length and line breaks of bs,
+// keeping the offsets into it valid in bs, for the edits of the config file and the positions of syntax errors.
+func stripJSONC(bs []byte) []byte {
+	out := append([]byte{}, bs...)
+
+	blank := func(from, to int) {
+		for i := from; i < to; i++ {
+			if out[i] != '\n' && out[i] != '\r' {
+				out[i] = ' '
+			}
+		}
+	}
+
+	// comments first, so the commas are followed by whitespace only when trailing
+	for i := 0; i < len(out); i++ {
+		switch {
+		case out[i] == '"':
+			i = stringEnd(out, i)
+		case out[i] == '/' && i+1 < len(out) && out[i+1] == '/':
+			end := i
+			for end < len(out) && out[end] != '\n' {
+				end++
+			}
+			blank(i, end)
+			i = end
+		case out[i] == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := i + 2
+			for end < len(out) && !(out[end] == '*' && end+1 < len(out) && out[end+1] == '/') {
+				end++
+			}
+			// an unterminated comment runs to the end
+			end += 2
+			if end > len(out) {
+				end = len(out)
+			}
+			blank(i, end)
+			i = end - 1
+		}
+	}
+
+	comma := -1
+	for i := 0; i < len(out); i++ {
+		switch out[i] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '"':
+			i = stringEnd(out, i)
+		case '}', ']':
+			if comma >= 0 {
+				out[comma] = ' '
+			}
+		}
+
+		comma = -1
+		if out[i] == ',' {
+			comma = i
+		}
+	}
+
+	return out
+}
+
+// stringEnd returns the offset of the closing quote of the JSON string opening at start, the last one when unterminated.
+func stringEnd(bs []byte, start int) int {
+	for i := start + 1; i < len(bs); i++ {
+		switch bs[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+
+	return len(bs) - 1
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// spaces are the n spaces a comment or trailing comma is blanked with.
+func spaces(n int) string { return strings.Repeat(" ", n) }
+
+func TestStripJSONC(t *testing.T) {
+	for _, tt := range []struct {
+		jsonc string
+		want  string
+	}{
+		{`{"a": 1}`, `{"a": 1}`},
+		{"{\"a\": 1 // vendored for the audit\n}", "{\"a\": 1" + spaces(26) + "\n}"},
+		{`{/* owner: core */ "a": 1}`, "{" + spaces(18) + `"a": 1}`},
+		{"{\"a\": /* multi\nline */ 1}", "{\"a\":" + spaces(9) + "\n" + spaces(8) + "1}"},
+		{`{"a": [1, 2,], "b": {"c": 3,},}`, `{"a": [1, 2 ], "b": {"c": 3 } }`},
+		{"{\"a\": 1, // trailing\n}", "{\"a\": 1" + spaces(13) + "\n}"},
+		// comment and comma lookalikes in strings are kept
+		{`{"url": "https://example.com/*x*/", "s": ",}"}`, `{"url": "https://example.com/*x*/", "s": ",}"}`},
+		{`{"q": "a \"// b\"", "n": 1}`, `{"q": "a \"// b\"", "n": 1}`},
+		{`{"a": 1} /* unterminated`, `{"a": 1}` + spaces(16)},
+	} {
+		got := string(stripJSONC([]byte(tt.jsonc)))
+		if got != tt.want {
+			t.Errorf("stripJSONC(%q) = %q, want %q", tt.jsonc, got, tt.want)
+		}
+		if len(got) != len(tt.jsonc) {
+			t.Errorf("stripJSONC(%q): %d bytes, want the %d of the input", tt.jsonc, len(got), len(tt.jsonc))
+		}
+		if !json.Valid([]byte(got)) {
+			t.Errorf("stripJSONC(%q) = %q, not valid JSON", tt.jsonc, got)
+		}
+	}
+}
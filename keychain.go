@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keychainService is the service the API keys are stored under in the OS keychain.
+const keychainService = "etherscan-downloader"
+
+// keychainTimeout bounds a call of the keychain tool, e.g. waiting for an unlock prompt.
+const keychainTimeout = time.Minute
+
+// keychainIndexPath is the file listing the accounts logged in, in the user config directory,
+// so the keychain is only asked for the keys stored in it.
+func keychainIndexPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "etherscan-downloader", "keychain.json"), nil
+}
+
+func loadKeychainIndex() ([]string, error) {
+	path, err := keychainIndexPath()
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := []string{}
+	if err := json.Unmarshal(bs, &accounts); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return accounts, nil
+}
+
+func saveKeychainIndex(accounts []string) error {
+	path, err := keychainIndexPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), dirMode); err != nil {
+		return err
+	}
+	sort.Strings(accounts)
+
+	return writeJSON(path, accounts)
+}
+
+// keychainAccount is the account the API key of explorer is stored as, its environment variable,
+// so the explorers sharing a key share the entry.
+func keychainAccount(ch chain, explorer blockExplorer) string {
+	if explorer.apiKeyEnv != "" {
+		return explorer.apiKeyEnv
+	}
+
+	return fmt.Sprintf("chain-%d", ch)
+}
+
+// keychainCommand returns the command of the OS keychain tool doing op, "get", "set" or "delete", for account.
+// The key to set is passed on the command's stdin, never as an argument, and the key got is read from its stdout.
+func keychainCommand(ctx context.Context, op string, account string, key string) (*exec.Cmd, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		if op == "set" {
+			// security's interactive mode reads the command from stdin
+			cmd := exec.CommandContext(ctx, "security", "-i")
+			cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %q -a %q -w %q\n", keychainService, account, key))
+			return cmd, nil
+		}
+		args := map[string][]string{
+			"get":    {"find-generic-password", "-s", keychainService, "-a", account, "-w"},
+			"delete": {"delete-generic-password", "-s", keychainService, "-a", account},
+		}[op]
+		return exec.CommandContext(ctx, "security", args...), nil
+	case "windows":
+		vault := `[void][Windows.Security.Credentials.PasswordVault,Windows.Security.Credentials,ContentType=WindowsRuntime];$v=New-Object Windows.Security.Credentials.PasswordVault;`
+		script := map[string]string{
+			"get":    vault + `$c=$v.Retrieve($env:ED_SERVICE,$env:ED_ACCOUNT);$c.RetrievePassword();$c.Password`,
+			"set":    vault + `$k=[Console]::In.ReadLine();$v.Add((New-Object Windows.Security.Credentials.PasswordCredential($env:ED_SERVICE,$env:ED_ACCOUNT,$k)))`,
+			"delete": vault + `$v.Remove($v.Retrieve($env:ED_SERVICE,$env:ED_ACCOUNT))`,
+		}[op]
+		cmd := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+		cmd.Env = append(os.Environ(), "ED_SERVICE="+keychainService, "ED_ACCOUNT="+account)
+		cmd.Stdin = strings.NewReader(key + "\n")
+		return cmd, nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		attrs := []string{"service", keychainService, "account", account}
+		args := map[string][]string{
+			"get":    append([]string{"lookup"}, attrs...),
+			"set":    append([]string{"store", "--label", keychainService + " " + account}, attrs...),
+			"delete": append([]string{"clear"}, attrs...),
+		}[op]
+		// the Secret Service, e.g. GNOME Keyring or KWallet, through libsecret's secret-tool, which reads the key from stdin
+		cmd := exec.CommandContext(ctx, "secret-tool", args...)
+		cmd.Stdin = strings.NewReader(key)
+		return cmd, nil
+	}
+
+	return nil, fmt.Errorf("no OS keychain support on %s, use apiKeyCmd instead", runtime.GOOS)
+}
+
+func keychainGet(account string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), keychainTimeout)
+	defer cancel()
+
+	cmd, err := keychainCommand(ctx, "get", account, "")
+	if err != nil {
+		return "", err
+	}
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("keychain: %s: %w", account, err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+func keychainSet(account string, key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), keychainTimeout)
+	defer cancel()
+
+	cmd, err := keychainCommand(ctx, "set", account, key)
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = os.Stderr
+
+	if out, err := cmd.Output(); err != nil {
+		return fmt.Errorf("keychain: %s: %w: %s", account, err, out)
+	}
+
+	return nil
+}
+
+func keychainDelete(account string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), keychainTimeout)
+	defer cancel()
+
+	cmd, err := keychainCommand(ctx, "delete", account, "")
+	if err != nil {
+		return err
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("keychain: %s: %w: %s", account, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// keychainKeys are the API keys looked up in the OS keychain, by account.
+var keychainKeys = struct {
+	sync.Mutex
+	keys map[string]string
+}{keys: map[string]string{}}
+
+// key returns the API key of e, looking it up in the OS keychain the first time one of the explorers logged in
+// to it needs it, so the commands sending no explorer request don't run the keychain tool.
+// A key failing to be looked up is reported once, and the requests are sent without it.
+func (e blockExplorer) key() string {
+	if e.apiKey != "" || e.keychain == "" {
+		return e.apiKey
+	}
+
+	keychainKeys.Lock()
+	defer keychainKeys.Unlock()
+
+	key, ok := keychainKeys.keys[e.keychain]
+	if !ok {
+		var err error
+		if key, err = keychainGet(e.keychain); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %s, sending the requests of %s without an API key\n", err, e.site)
+		}
+		keychainKeys.keys[e.keychain] = key
+	}
+
+	return key
+}
+
+// hasKey reports whether e has an API key, set or logged in to the keychain, without looking it up.
+func (e blockExplorer) hasKey() bool {
+	return e.apiKey != "" || e.keychain != ""
+}
+
+// configureKeychainKeys sets the explorers without an API key whose account is logged in to the OS keychain
+// to look it up there when sending their first request.
+func configureKeychainKeys() error {
+	accounts, err := loadKeychainIndex()
+	if err != nil || len(accounts) == 0 {
+		return err
+	}
+
+	loggedIn := map[string]bool{}
+	for _, account := range accounts {
+		loggedIn[account] = true
+	}
+
+	for ch, explorer := range blockExploers {
+		account := keychainAccount(ch, explorer)
+		if explorer.apiKey != "" || !loggedIn[account] {
+			continue
+		}
+
+		explorer.keychain = account
+		blockExploers[ch] = explorer
+	}
+
+	return nil
+}
+
+// loginTarget returns the chain and explorer of the only argument of login and logout.
+func loginTarget(args []string, usage string) (chain, blockExplorer, error) {
+	if len(args) != 1 {
+		return 0, blockExplorer{}, errors.New(usage)
+	}
+
+	// explorers of config.json can be logged in to as well
+	if _, err := loadConfig(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, blockExplorer{}, err
+	}
+
+	ch, err := parseChain(args[0])
+	if err != nil {
+		return 0, blockExplorer{}, err
+	}
+
+	explorer, ok := blockExploers[ch]
+	if !ok {
+		return 0, blockExplorer{}, unsupportedChain(ch)
+	}
+
+	return ch, explorer, nil
+}
+
+// runLogin stores the API key of the explorer of a chain, read from stdin, in the OS keychain.
+func runLogin(ctx context.Context, args []string) error {
+	ch, explorer, err := loginTarget(args, "usage: login <chain>")
+	if err != nil {
+		return err
+	}
+	account := keychainAccount(ch, explorer)
+
+	if isTerminal(os.Stdin) {
+		fmt.Fprintf(os.Stderr, "API key of %s (%s): ", explorer.site, account)
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return fmt.Errorf("read the API key: %w", err)
+	}
+	key := strings.TrimSpace(line)
+	if key == "" {
+		return errors.New("empty API key")
+	}
+
+	if err := keychainSet(account, key); err != nil {
+		return err
+	}
+
+	accounts, err := loadKeychainIndex()
+	if err != nil {
+		return err
+	}
+	for _, a := range accounts {
+		if a == account {
+			fmt.Printf("updated the key of %s in the keychain\n", account)
+			return nil
+		}
+	}
+	if err := saveKeychainIndex(append(accounts, account)); err != nil {
+		return err
+	}
+
+	fmt.Printf("stored the key of %s in the keychain\n", account)
+
+	return nil
+}
+
+// runLogout removes the API key of the explorer of a chain from the OS keychain.
+func runLogout(ctx context.Context, args []string) error {
+	ch, explorer, err := loginTarget(args, "usage: logout <chain>")
+	if err != nil {
+		return err
+	}
+	account := keychainAccount(ch, explorer)
+
+	accounts, err := loadKeychainIndex()
+	if err != nil {
+		return err
+	}
+	kept := []string{}
+	for _, a := range accounts {
+		if a != account {
+			kept = append(kept, a)
+		}
+	}
+	if len(kept) == len(accounts) {
+		return fmt.Errorf("%s is not logged in", account)
+	}
+
+	if err := keychainDelete(account); err != nil {
+		return err
+	}
+
+	if err := saveKeychainIndex(kept); err != nil {
+		return err
+	}
+
+	fmt.Printf("removed the key of %s from the keychain\n", account)
+
+	return nil
+}
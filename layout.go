@@ -0,0 +1,134 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SourceLayout is how the verified sources of a contract were written, recorded in its metadata.json:
+// diff, check and status compare the directory with the files written that way, and a download again,
+// update and the daemon write them the same way unless the flags say otherwise.
+type SourceLayout struct {
+	Unflatten     bool     `json:"unflatten,omitempty"`     // flattened single-file verifications split back into their files
+	Include       []string `json:"include,omitempty"`       // the globs of --include, of the sources written
+	Exclude       []string `json:"exclude,omitempty"`       // the globs of --exclude, of the sources skipped
+	OnlyReachable bool     `json:"onlyReachable,omitempty"` // only the sources imported by the file defining the contract
+	SPDX          string   `json:"spdx,omitempty"`          // the --spdx mode, insert or normalize, empty when the SPDX lines are kept as verified
+
+	// CombineProxy downloads a proxy and its implementation into proxy/ and implementation/, recorded by proxy.json.
+	CombineProxy bool `json:"-"`
+}
+
+// layoutFlags are the download flags changing the files written from the verified sources.
+var layoutFlags = []string{"unflatten", "include", "exclude", "only-reachable", "spdx", "combine-proxy"}
+
+// recordedLayout returns the layout recorded by the download into dir, the default one when there is none.
+func recordedLayout(dir string) *SourceLayout {
+	l := &SourceLayout{}
+	if m, err := loadMetadata(filepath.Join(dir, metadataFile)); err == nil && m.Layout != nil {
+		*l = *m.Layout
+	}
+	if _, err := os.Stat(filepath.Join(dir, combinedProxyFile)); err == nil {
+		l.CombineProxy = true
+	}
+
+	return l
+}
+
+// layout returns how the download into dir writes the sources: as recorded by its previous download,
+// but for the layout flags given.
+func (dl *downloader) layout(dir string) *SourceLayout {
+	l := recordedLayout(dir)
+	if dl.layoutSet["unflatten"] {
+		l.Unflatten = dl.layoutFlags.Unflatten
+	}
+	if dl.layoutSet["include"] {
+		l.Include = dl.layoutFlags.Include
+	}
+	if dl.layoutSet["exclude"] {
+		l.Exclude = dl.layoutFlags.Exclude
+	}
+	if dl.layoutSet["only-reachable"] {
+		l.OnlyReachable = dl.layoutFlags.OnlyReachable
+	}
+	if dl.layoutSet["spdx"] {
+		l.SPDX = dl.layoutFlags.SPDX
+	}
+	if dl.layoutSet["combine-proxy"] {
+		l.CombineProxy = dl.layoutFlags.CombineProxy
+	}
+
+	return l
+}
+
+// setLayoutFlags records the layout flags given to fs, which override the recorded layout of each download.
+func (dl *downloader) setLayoutFlags(fs *flag.FlagSet, l *SourceLayout) {
+	dl.layoutFlags, dl.layoutSet = l, map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		for _, name := range layoutFlags {
+			if f.Name == name {
+				dl.layoutSet[name] = true
+			}
+		}
+	})
+}
+
+// recorded returns l as recorded in metadata.json, nil when it is the default one.
+func (l *SourceLayout) recorded() *SourceLayout {
+	if l == nil || !l.Unflatten && len(l.Include) == 0 && len(l.Exclude) == 0 && !l.OnlyReachable && l.SPDX == "" {
+		return nil
+	}
+
+	return l
+}
+
+// sources returns the verified sources as l writes them, by source key: with --unflatten split back into the files
+// they were flattened from, those matching --include and --exclude
+// and with --only-reachable imported by the file defining the contract, normalized by normalize and, with --spdx, their SPDX lines added.
+// It also returns what was done and the warnings, printed by download.
+func (l *SourceLayout) sources(sources Sources, remappings []string, rawCode *RawCode, normalize *NormalizeConfig) (Sources, []string, []string) {
+	notes, warnings := []string{}, []string{}
+
+	if l.Unflatten {
+		if split, ok := unflatten(sources); ok {
+			notes = append(notes, fmt.Sprintf("unflattened into %d files", len(split)))
+			sources = split
+		}
+	}
+
+	var reachable map[string]bool
+	if l.OnlyReachable {
+		if main, ok := mainSource(sources, rawCode.ContractName); ok {
+			reachable = parseImports(sources, remappings).reachable(main)
+			notes = append(notes, fmt.Sprintf("writing %d of %d sources reachable from %s", len(reachable), len(sources), main))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("no source defines %s, writing all sources", rawCode.ContractName))
+		}
+	}
+
+	id := spdxID(rawCode.LicenseType)
+	replace := strings.EqualFold(l.SPDX, "normalize")
+
+	filter := &sourceFilter{include: l.Include, exclude: l.Exclude}
+	written := Sources{}
+	for path, source := range sources {
+		if (reachable != nil && !reachable[path]) || !filter.selects(path) {
+			continue
+		}
+
+		content := normalize.apply(source.Content)
+		if l.SPDX != "" && id != "" && strings.HasSuffix(path, ".sol") {
+			var change string
+			if content, change = normalizeSPDX(content, id, replace); change != "" {
+				notes = append(notes, fmt.Sprintf("spdx: %s in %s", change, path))
+			}
+		}
+
+		written[path] = &Contract{Content: content}
+	}
+
+	return written, notes, warnings
+}
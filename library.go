@@ -0,0 +1,60 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// flatLibraries returns the libraries listed in the explorer's Library field as linked into file.
+func flatLibraries(file string, library string) Libraries {
+	libraries := map[string]string{}
+	for _, pair := range strings.Split(library, ";") {
+		name, address, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			continue
+		}
+
+		libraries[name] = normalizeLibraryAddress(address)
+	}
+
+	if len(libraries) == 0 {
+		return nil
+	}
+
+	return Libraries{file: libraries}
+}
+
+func normalizeLibraryAddress(address string) string {
+	if !strings.HasPrefix(address, "0x") {
+		return "0x" + address
+	}
+
+	return address
+}
+
+// linkedLibraries returns the externally linked libraries listed in rawCode.Library,
+// which the explorer formats as "Name:address" pairs separated by ';'.
+func linkedLibraries(parent *deployment, rawCode *RawCode) []*deployment {
+	libraries := []*deployment{}
+	for _, pair := range strings.Split(rawCode.Library, ";") {
+		name, address, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			continue
+		}
+
+		address = normalizeLibraryAddress(address)
+		if !isAddress(address) {
+			continue
+		}
+
+		libraries = append(libraries, &deployment{
+			Name:    filepath.Join(parent.folder(), "libraries", name),
+			Chain:   parent.Chain,
+			Address: address,
+			OutDir:  parent.OutDir,
+			Source:  parent.Source,
+		})
+	}
+
+	return libraries
+}
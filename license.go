@@ -0,0 +1,74 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var spdxPattern = regexp.MustCompile(`SPDX-License-Identifier:\s*([^\s*]+(?:\s+(?:AND|OR|WITH)\s+[^\s*]+)*)`)
+
+// explorerLicenses maps the explorer's LicenseType values to SPDX identifiers.
+var explorerLicenses = map[string]string{
+	"none":         "",
+	"unlicense":    "Unlicense",
+	"mit":          "MIT",
+	"gnu gplv2":    "GPL-2.0",
+	"gnu gplv3":    "GPL-3.0",
+	"gnu lgplv2.1": "LGPL-2.1",
+	"gnu lgplv3":   "LGPL-3.0",
+	"bsd-2-clause": "BSD-2-Clause",
+	"bsd-3-clause": "BSD-3-Clause",
+	"mpl-2.0":      "MPL-2.0",
+	"osl-3.0":      "OSL-3.0",
+	"apache-2.0":   "Apache-2.0",
+	"gnu agplv3":   "AGPL-3.0",
+	"bsl 1.1":      "BUSL-1.1",
+	"busl-1.1":     "BUSL-1.1",
+	"unlicensed":   "UNLICENSED",
+}
+
+// explorerLicenseCodes are the explorer's LicenseType values in the order of the numbers its verification API takes.
+var explorerLicenseCodes = []string{
+	"none", "unlicense", "mit", "gnu gplv2", "gnu gplv3", "gnu lgplv2.1", "gnu lgplv3",
+	"bsd-2-clause", "bsd-3-clause", "mpl-2.0", "osl-3.0", "apache-2.0", "gnu agplv3", "bsl 1.1",
+}
+
+// explorerLicenseCode returns the number of the verification API for a LicenseType, an SPDX identifier or the number itself.
+func explorerLicenseCode(license string) (int, bool) {
+	if n, err := strconv.Atoi(license); err == nil {
+		return n, n >= 1 && n <= len(explorerLicenseCodes)
+	}
+
+	// e.g. "MIT" and "mit", "GNU GPLv3" and "GPL-3.0", or "UNLICENSED" for "None"
+	id := strings.ToLower(firstNonEmpty(spdxLicense(license), "none"))
+	if id == "unlicensed" {
+		id = "none"
+	}
+	for i, name := range explorerLicenseCodes {
+		if id == name || id == strings.ToLower(explorerLicenses[name]) {
+			return i + 1, true
+		}
+	}
+
+	return 0, false
+}
+
+// spdxLicense returns the SPDX identifier of an explorer LicenseType, or "" when it has none.
+func spdxLicense(licenseType string) string {
+	if id, ok := explorerLicenses[strings.ToLower(strings.TrimSpace(licenseType))]; ok {
+		return id
+	}
+
+	return licenseType
+}
+
+// sourceLicense returns the SPDX license expression declared in a source file, or "" when it has none.
+func sourceLicense(content string) string {
+	m := spdxPattern.FindStringSubmatch(content)
+	if m == nil {
+		return ""
+	}
+
+	return m[1]
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// LicensePolicyConfig restricts the licenses of the contracts downloaded, e.g. to keep GPL code out of a proprietary repo.
+// Licenses are SPDX identifiers matched case-insensitively, with * wildcards, e.g. "GPL-*".
+type LicensePolicyConfig struct {
+	// Allow are the only licenses allowed, all but those denied when empty.
+	Allow []string `json:"allow,omitempty"`
+	// Deny are the licenses not allowed.
+	Deny []string `json:"deny,omitempty"`
+	// Policy is what to do with a contract or source file of a license not allowed: warn (default) or fail.
+	Policy string `json:"policy,omitempty"`
+}
+
+// validate checks the settings of p, which may be nil for no policy.
+func (p *LicensePolicyConfig) validate() error {
+	if p == nil {
+		return nil
+	}
+
+	for _, pattern := range append(append([]string{}, p.Allow...), p.Deny...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("licenses: %q: %w", pattern, err)
+		}
+	}
+
+	switch p.Policy {
+	case "", policyWarn, policyFail:
+		return nil
+	}
+
+	return fmt.Errorf("licenses: unknown policy %q, want warn or fail", p.Policy)
+}
+
+// permits reports whether the SPDX identifier id is allowed.
+func (p *LicensePolicyConfig) permits(id string) bool {
+	matches := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(id)); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	if matches(p.Deny) {
+		return false
+	}
+
+	return len(p.Allow) == 0 || matches(p.Allow)
+}
+
+// allows reports whether the SPDX license expression expr is allowed: any of its OR alternatives having only
+// licenses allowed, the exceptions after WITH aside, e.g. "MIT OR GPL-3.0" when MIT is.
+func (p *LicensePolicyConfig) allows(expr string) bool {
+	expr = strings.NewReplacer("(", " ", ")", " ").Replace(expr)
+
+	for _, alternative := range strings.Split(expr, " OR ") {
+		allowed := true
+		for _, term := range strings.Split(alternative, " AND ") {
+			id := strings.TrimSpace(term)
+			if i := strings.Index(id, " WITH "); i >= 0 {
+				id = strings.TrimSpace(id[:i])
+			}
+			if id != "" && !p.permits(id) {
+				allowed = false
+			}
+		}
+		if allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// check applies the policy p to the license d is verified under and those declared by its source files,
+// warning about or failing on those not allowed.
+func (p *LicensePolicyConfig) check(d *deployment, licenseType string, sourceCodes []*SourceCode) error {
+	if p == nil {
+		return nil
+	}
+
+	violations := []string{}
+	if id := spdxLicense(licenseType); id != "" && !p.allows(id) {
+		violations = append(violations, fmt.Sprintf("verified under %s", id))
+	}
+
+	files := map[string][]string{}
+	for _, sourceCode := range sourceCodes {
+		for file, source := range sourceCode.Sources {
+			if id := sourceLicense(source.Content); id != "" && !p.allows(id) {
+				files[id] = append(files[id], file)
+			}
+		}
+	}
+
+	ids := []string{}
+	for id := range files {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		sort.Strings(files[id])
+		violations = append(violations, fmt.Sprintf("%s in %s", id, strings.Join(files[id], ", ")))
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	msg := "license not allowed: " + strings.Join(violations, "; ")
+	if p.Policy == policyFail {
+		return fmt.Errorf("%s", msg)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: warning: %s\n", d.Name, msg)
+
+	return nil
+}
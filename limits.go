@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// LimitsConfig bounds what the verification of a single contract may write,
+// so a malicious or pathological payload can't fill the disk.
+type LimitsConfig struct {
+	// MaxFiles is the most source files written for a contract, default 10000.
+	MaxFiles int `json:"maxFiles,omitempty"`
+	// MaxBytes is the most bytes of sources written for a contract, default 256 MiB.
+	MaxBytes int64 `json:"maxBytes,omitempty"`
+}
+
+const (
+	defaultMaxFiles = 10000
+	defaultMaxBytes = 256 << 20
+)
+
+// newLimits returns the limits of config, overridden by the fields set in flags, with defaults for the others.
+func newLimits(config *LimitsConfig, flags *LimitsConfig) (*LimitsConfig, error) {
+	l := &LimitsConfig{}
+	for _, c := range []*LimitsConfig{config, flags} {
+		if c == nil {
+			continue
+		}
+		if c.MaxFiles < 0 || c.MaxBytes < 0 {
+			return nil, errors.New("limits: maxFiles and maxBytes can't be negative")
+		}
+		if c.MaxFiles != 0 {
+			l.MaxFiles = c.MaxFiles
+		}
+		if c.MaxBytes != 0 {
+			l.MaxBytes = c.MaxBytes
+		}
+	}
+
+	if l.MaxFiles == 0 {
+		l.MaxFiles = defaultMaxFiles
+	}
+	if l.MaxBytes == 0 {
+		l.MaxBytes = defaultMaxBytes
+	}
+
+	return l, nil
+}
+
+// check fails when files are more, or larger in total, than the limits.
+func (l *LimitsConfig) check(files []*pendingFile) error {
+	if len(files) > l.MaxFiles {
+		return fmt.Errorf("%w: %d source files, more than maxFiles %d: raise limits.maxFiles in config.json or --max-files if the contract is legitimate", errLimitExceeded, len(files), l.MaxFiles)
+	}
+
+	var total int64
+	for _, f := range files {
+		total += int64(len(f.content))
+	}
+	if total > l.MaxBytes {
+		return fmt.Errorf("%w: %d bytes of sources, more than maxBytes %d: raise limits.maxBytes in config.json or --max-bytes if the contract is legitimate", errLimitExceeded, total, l.MaxBytes)
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+)
+
+// listCommand prints a table of the configured contracts and whether they are downloaded.
+func listCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	namesOnly := fs.Bool("names", false, "only print the names, one per line")
+
+	return func(ctx context.Context, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		if *namesOnly {
+			for _, name := range c.names() {
+				fmt.Println(name)
+			}
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tCHAIN\tADDRESS\tLOCAL")
+		for _, name := range c.names() {
+			ch, address, err := c.Contracts[name].resolve()
+			if err != nil {
+				fmt.Fprintf(w, "%s\t-\t%s\t%s\n", name, c.Contracts[name].Address, err)
+				continue
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, ch, address, localStatus(c.dir(name)))
+		}
+
+		return w.Flush()
+	}
+}
+
+// statusCommand reports, for each configured contract, whether its downloaded sources are up to date with the explorer.
+func statusCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	offline := fs.Bool("offline", false, "only check which contracts are downloaded, without querying the explorer")
+
+	return func(ctx context.Context, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSTATUS\tDETAIL")
+		counts := map[string]int{}
+		for _, name := range c.names() {
+			status, detail := contractStatus(ctx, c, name, *offline)
+			fmt.Fprintf(w, "%s\t%s\t%s\n", name, status, detail)
+			counts[status]++
+		}
+
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		switch {
+		case counts["drifted"] > 0:
+			return fmt.Errorf("%w: %d contracts", errDrift, counts["drifted"])
+		case counts["unverified"] > 0:
+			return fmt.Errorf("%w: %d contracts", errNotVerified, counts["unverified"])
+		}
+
+		return nil
+	}
+}
+
+// contractStatus returns "up-to-date", "drifted", "missing", "unverified" or "error" for the contract name,
+// with a detail of what drifted or failed. A contract whose chain or address is left to the download is checked at those
+// of its download, without resolving them.
+func contractStatus(ctx context.Context, c *Config, name string, offline bool) (string, string) {
+	local := localStatus(c.dir(name))
+	if local == "missing" || offline {
+		return local, ""
+	}
+
+	d, err := c.configured(name)
+	if err != nil {
+		return "error", err.Error()
+	}
+	if d.unresolved() {
+		if err := d.downloaded(d.dir(c.ContractDir)); err != nil {
+			return "error", err.Error()
+		}
+	}
+
+	if local == "unverified" {
+		rawCodes, err := fetchRawCode(ctx, d)
+		if err != nil {
+			return "error", redactAPIKeys(err.Error())
+		}
+
+		if isUnverified(rawCodes) {
+			return "unverified", ""
+		}
+
+		return "drifted", "verified since download"
+	}
+
+	changes, err := diffDeployment(ctx, c, d, d.dir(c.ContractDir))
+	if err != nil {
+		return "error", redactAPIKeys(err.Error())
+	}
+
+	if len(changes) == 0 {
+		return "up-to-date", ""
+	}
+
+	return "drifted", fmt.Sprintf("%d files differ, see diff %s", len(changes), name)
+}
+
+// localStatus returns "downloaded", "unverified" or "missing" for the contract directory dir.
+// The directory of a proxy downloaded with --combine-proxy, and one of sources without metadata.json,
+// written with --only, are downloaded.
+func localStatus(dir string) string {
+	if _, err := os.Stat(filepath.Join(dir, unverifiedFile)); err == nil {
+		return "unverified"
+	}
+
+	for _, file := range []string{metadataFile, combinedProxyFile} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err == nil {
+			return "downloaded"
+		}
+	}
+
+	if hasSourceFile(dir) {
+		return "downloaded"
+	}
+
+	return "missing"
+}
+
+// hasSourceFile reports whether there is a source file under dir.
+func hasSourceFile(dir string) bool {
+	errFound := errors.New("found")
+	err := filepath.WalkDir(dir, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !e.IsDir() && isSourceFile(path) {
+			return errFound
+		}
+		return nil
+	})
+
+	return err == errFound
+}
@@ -1,210 +1,146 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
 )
 
-type Config struct {
-	Target      string                    `json:"target"`
-	ContractDir string                    `json:"contractDir"`
-	Contracts   map[string]ConfigContract `json:"contracts"`
+// command is a subcommand of the CLI.
+type command struct {
+	usage string
+	// define registers the command's flags on fs and returns the function running it with the remaining arguments.
+	define func(fs *flag.FlagSet) func(ctx context.Context, args []string) error
 }
 
-type ConfigContract struct {
-	Chain   chain  `json:"chain"`
-	Address string `json:"address"`
-}
-
-const (
-	ethereum chain = 1
-	polygon  chain = 137
-)
-
-var blockExploers = map[chain]blockExplorer{
-	ethereum: {endpoint: "https://api.etherscan.io/", apiKey: os.Getenv("ETHERSCAN_APIKEY")},
-	polygon:  {endpoint: "https://api.polygonscan.com/", apiKey: os.Getenv("POLYGONSCAN_APIKEY")},
-}
-
-type chain uint
-
-type blockExplorer struct {
-	endpoint string
-	apiKey   string
+// noFlags is the define of commands without flags.
+func noFlags(run func(ctx context.Context, args []string) error) func(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	return func(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+		return run
+	}
 }
 
-func main() {
-	if err := run(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+var commands map[string]*command
+
+func init() {
+	// completion reads the table, so it can't be part of the table's initializer
+	commands = map[string]*command{
+		"init":          {usage: "init [flags]                  write a starter config.json", define: initCommand},
+		"add":           {usage: "add [--chain <chain>] <name> <address>  add a contract to config.json", define: addCommand},
+		"remove":        {usage: "remove <name>...              remove contracts from config.json", define: noFlags(runRemove)},
+		"download":      {usage: "download [flags] [target...]  download verified sources (default command)", define: downloadCommand},
+		"explain":       {usage: "explain [flags] [target...]   print the requests, fixtures and outputs of a download without running it", define: explainCommand},
+		"discover":      {usage: "discover [--add <name>] [--output text|json] <address>  find the chains an address has code or verified sources on", define: discoverCommand},
+		"diff":          {usage: "diff [--against <ref>] [target...]  compare downloaded sources with the explorer", define: diffCommand},
+		"check":         {usage: "check [--lock] [--output json|text] [target...]  fail when the verified sources drifted from the local tree or lock, for CI", define: checkCommand},
+		"update":        {usage: "update [--yes] [flags] [target...]  review the changed sources before downloading them", define: updateCommand},
+		"crosscheck":    {usage: "crosscheck [--sourcify-url <url>] [target...]  diff the sources verified on the explorer and on Sourcify", define: crosscheckCommand},
+		"compare":       {usage: "compare <target> <target>     diff the verified sources of two deployments", define: noFlags(runCompare)},
+		"list":          {usage: "list [--names]                list configured contracts", define: listCommand},
+		"status":        {usage: "status [--offline]            show whether downloaded contracts are up to date", define: statusCommand},
+		"history":       {usage: "history [--fetches] [--output text|json] <target>  show when a contract's sources changed across the recorded fetches", define: historyCommand},
+		"verify":        {usage: "verify [flags] [target...]    verify downloaded sources against the chain", define: verifyCommand},
+		"prune":         {usage: "prune [-n]                    remove downloads of contracts no longer configured", define: pruneCommand},
+		"tui":           {usage: "tui                           browse, download and diff contracts interactively", define: noFlags(runTUI)},
+		"serve":         {usage: "serve [--addr <addr>] [--rate <n>]  serve verified sources over HTTP", define: serveCommand},
+		"migrate":       {usage: "migrate [-n]                  upgrade config.json to the current config version", define: migrateCommand},
+		"login":         {usage: "login <chain>                 store the API key of a chain's explorer in the OS keychain", define: noFlags(runLogin)},
+		"logout":        {usage: "logout <chain>                remove the API key of a chain's explorer from the OS keychain", define: noFlags(runLogout)},
+		"doctor":        {usage: "doctor                        check the config, API keys and tools", define: noFlags(runDoctor)},
+		"version":       {usage: "version [--check]             print the build information", define: versionCommand},
+		"import":        {usage: "import <source> <path>        add deployed contracts to config.json", define: noFlags(runImport)},
+		"export":        {usage: "export [--out <file>] <target>  zip a downloaded contract with its metadata", define: exportCommand},
+		"snapshot":      {usage: "snapshot [--out <dir>] [target...]  archive the downloaded contracts with a Merkle root of their files", define: snapshotCommand},
+		"daemon":        {usage: "daemon [flags]                sync the contracts on their schedules until interrupted", define: daemonCommand},
+		"mirror":        {usage: "mirror [flags] [target...]    download into the mirror repository, commit and push", define: mirrorCommand},
+		"search":        {usage: "search [-i] [-F] <pattern> [target...]  grep the downloaded sources", define: searchCommand},
+		"verify-submit": {usage: "verify-submit [flags] <target>  submit a standard-json input for verification on the explorer or Sourcify", define: verifySubmitCommand},
+		"embed":         {usage: "embed [--out <file>] [--pkg <name>] [--const]  write a Go file embedding the downloaded sources and ABIs", define: embedCommand},
+		"constants":     {usage: "constants [flags] [target...]  write a Go package of the ABI, bytecode and addresses of each contract", define: constantsCommand},
+		"sbom":          {usage: "sbom                          write a CycloneDX SBOM of contractDir", define: noFlags(runSBOM)},
+		"pragmas":       {usage: "pragmas                       report the pragmas of the downloaded sources against their verified compiler", define: noFlags(runPragmas)},
+		"stats":         {usage: "stats                         summarize the contracts downloaded into contractDir", define: noFlags(runStats)},
+		"completion":    {usage: "completion bash|zsh|fish      print a shell completion script", define: noFlags(runCompletion)},
 	}
 }
 
-func run() error {
-	c, err := loadConfig()
-	if err != nil {
-		return err
-	}
+func main() {
+	// the first SIGINT or SIGTERM cancels ctx, letting in-flight writes finish; a second one kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
 
-	targetAddress := c.Contracts[c.Target]
-	explorer := blockExploers[targetAddress.Chain]
+	err := run(ctx, os.Args[1:])
+	flushTraces()
 
-	rawCodes, err := getRawContractCode(explorer.endpoint, targetAddress.Address, explorer.apiKey)
 	if err != nil {
-		return err
+		// errors may carry request URLs with their API key
+		fmt.Fprintln(os.Stderr, redactAPIKeys(err.Error()))
+		os.Exit(exitCode(err))
 	}
+}
 
-	sourceCodes, err := parseContractCode(rawCodes)
-	if err != nil {
-		return err
+// run dispatches to the subcommand named by args[0].
+// Without a known subcommand, args are passed to download, so `etherscan-downloader <target>` keeps working.
+func run(ctx context.Context, args []string) error {
+	if len(args) > 0 && (args[0] == "help" || args[0] == "-h" || args[0] == "--help") {
+		usage()
+		return nil
 	}
 
-	for _, sourceCode := range sourceCodes {
-		for path, source := range sourceCode.Sources {
-			if err := os.MkdirAll(targetDir(c.ContractDir, c.Target, path), os.ModePerm); err != nil {
-				return err
-			}
-
-			f, err := os.Create(targetPath(c.ContractDir, c.Target, path))
-			if err != nil {
-				return err
-			}
-			defer f.Close()
-
-			f.Write([]byte(source.Content))
+	name := "download"
+	if len(args) > 0 {
+		if _, ok := commands[args[0]]; ok {
+			name, args = args[0], args[1:]
 		}
 	}
 
-	return nil
-}
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	addConfigFlags(fs)
+	run := commands[name].define(fs)
+	fs.Parse(args)
 
-func loadConfig() (*Config, error) {
-	bs, err := os.ReadFile("config.json")
-	if err != nil {
-		return nil, err
+	if runTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, runTimeout)
+		defer cancel()
 	}
 
-	c := &Config{}
-
-	if err := json.NewDecoder(bytes.NewBuffer(bs)).Decode(c); err != nil {
-		return nil, err
+	err := run(ctx, fs.Args())
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		// e.g. a killed solc fails with "signal: killed", which doesn't tell why
+		err = fmt.Errorf("timed out after %s: %w", runTimeout, err)
 	}
 
-	return c, err
+	return err
 }
 
-func getContractURL(endpoint string, address string, apikey string) string {
-	const url = "%s/api?module=contract&action=getsourcecode&address=%s&apikey=%s"
-	return fmt.Sprintf(url, endpoint, address, apikey)
-}
-
-func targetDir(rootDir string, dir string, path string) string {
-	return filepath.Dir(targetPath(rootDir, dir, path))
-}
-
-func targetPath(rootDir string, dir string, path string) string {
-	return filepath.Join(rootDir, dir, path)
-}
-
-func getRawContractCode(endpoint, address string, apiKey string) ([]*RawCode, error) {
-	url := getContractURL(endpoint, address, apiKey)
-	resp, err := http.DefaultClient.Get(url)
-	if err != nil {
-		return nil, err
-	}
-
-	contractCodeResponse := &Response{}
-
-	bs, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+// commandNames returns the names of the subcommands in order.
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	if err := json.NewDecoder(bytes.NewBuffer(bs)).Decode(contractCodeResponse); err != nil {
-		return []*RawCode{{SourceCode: string(bs), IsOneSource: true}}, nil
-	}
-
-	if contractCodeResponse.Status != "1" {
-		return nil, fmt.Errorf("bad status: %s, message: %s", contractCodeResponse.Status, contractCodeResponse.Status)
-	}
-
-	return contractCodeResponse.Codes, nil
+	return names
 }
 
-func parseContractCode(rawCodes []*RawCode) ([]*SourceCode, error) {
-	sourceCodes := make([]*SourceCode, 0, len(rawCodes))
-	if len(rawCodes) == 1 && rawCodes[0].IsOneSource {
-		return []*SourceCode{{
-			Sources: Sources{"main.sol": &Contract{Content: rawCodes[0].SourceCode}},
-		}}, nil
-	}
-
-	for _, rawCode := range rawCodes {
-		sourceCode := &SourceCode{}
-		if err := json.Unmarshal([]byte(rawCode.SourceCode[1:len(rawCode.SourceCode)-1]), sourceCode); err != nil {
-			return []*SourceCode{{
-				Sources: Sources{"main.sol": &Contract{Content: rawCodes[0].SourceCode}},
-			}}, nil
-		}
+func usage() {
+	names := commandNames()
 
-		sourceCodes = append(sourceCodes, sourceCode)
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, "  "+commands[name].usage)
 	}
 
-	return sourceCodes, nil
-}
-
-type Response struct {
-	Status  string     `json:"status"`
-	Message string     `json:"message"`
-	Codes   []*RawCode `json:"result"`
+	fmt.Fprintf(os.Stderr, "usage: etherscan-downloader <command> [args]\n\ncommands:\n%s\n", strings.Join(lines, "\n"))
 }
-
-type RawCode struct {
-	SourceCode           string `json:"SourceCode"`
-	Abi                  string `json:"ABI"`
-	ContractName         string `json:"ContractName"`
-	CompilerVersion      string `json:"CompilerVersion"`
-	OptimizationUsed     string `json:"OptimizationUsed"`
-	Runs                 string `json:"Runs"`
-	ConstructorArguments string `json:"ConstructorArguments"`
-	EVMVersion           string `json:"EVMVersion"`
-	Library              string `json:"Library"`
-	LicenseType          string `json:"LicenseType"`
-	Proxy                string `json:"Proxy"`
-	Implementation       string `json:"Implementation"`
-	SwarmSource          string `json:"SwarmSource"`
-	IsOneSource          bool
-}
-
-// SourceCodeFields
-type SourceCode struct {
-	Language string   `json:"language"`
-	Sources  Sources  `json:"sources"`
-	Settings Settings `json:"settings"`
-}
-
-type Sources map[string]*Contract
-
-type Contract struct {
-	Content string `json:"content"`
-}
-
-type Settings struct {
-	Optimizer       *Optimizer      `json:"optimizer"`
-	OutputSelection OutputSelection `json:"outputSelection"`
-	Libraries       Libraries       `json:"libraries"`
-}
-
-type Optimizer struct {
-	Enabled bool `json:"enabled"`
-	Runs    int  `json:"runs"`
-}
-
-type OutputSelection map[string]map[string][]string
-
-type Libraries interface{}
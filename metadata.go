@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	metadataFile      = "metadata.json"
+	standardInputFile = "standard-input.json"
+	remappingsFile    = "remappings.txt"
+)
+
+// Metadata describes a downloaded contract. It is written to metadata.json next to its sources.
+type Metadata struct {
+	ContractName         string         `json:"contractName"`
+	Chain                chain          `json:"chain"`
+	Address              string         `json:"address"`
+	CompilerVersion      string         `json:"compilerVersion"`
+	ZksolcVersion        string         `json:"zksolcVersion,omitempty"` // the zksolc compiling CompilerVersion's output for EraVM, on zkSync
+	OptimizationUsed     bool           `json:"optimizationUsed"`
+	Runs                 int            `json:"runs"`
+	EVMVersion           string         `json:"evmVersion"`
+	ConstructorArguments string         `json:"constructorArguments,omitempty"`
+	LicenseType          string         `json:"licenseType"`
+	Proxy                bool           `json:"proxy"`
+	Implementation       string         `json:"implementation,omitempty"`
+	SimilarMatch         string         `json:"similarMatch,omitempty"`
+	Libraries            Libraries      `json:"libraries,omitempty"`
+	Remappings           []string       `json:"remappings,omitempty"`
+	Token                *TokenMetadata `json:"token,omitempty"`
+	Layout               *SourceLayout  `json:"layout,omitempty"`
+}
+
+func newMetadata(d *deployment, rawCode *RawCode, sourceCode *SourceCode) *Metadata {
+	runs, _ := strconv.Atoi(rawCode.Runs)
+
+	return &Metadata{
+		ContractName:         rawCode.ContractName,
+		Chain:                d.Chain,
+		Address:              d.Address,
+		CompilerVersion:      rawCode.CompilerVersion,
+		ZksolcVersion:        rawCode.CompilerZksolcVersion,
+		OptimizationUsed:     rawCode.OptimizationUsed == "1",
+		Runs:                 runs,
+		EVMVersion:           rawCode.EVMVersion,
+		ConstructorArguments: rawCode.ConstructorArguments,
+		LicenseType:          rawCode.LicenseType,
+		Proxy:                rawCode.Proxy == "1",
+		Implementation:       rawCode.Implementation,
+		SimilarMatch:         rawCode.SimilarMatch,
+		Libraries:            sourceCode.Settings.Libraries,
+		Remappings:           sourceCode.Settings.Remappings,
+	}
+}
+
+// writeMetadata writes metadata.json, the solc standard-json input reconstructed from the verified sources
+// and, when the verification used remappings, remappings.txt.
+func writeMetadata(dir string, m *Metadata, sourceCode *SourceCode) error {
+	if err := os.MkdirAll(dir, dirMode); err != nil {
+		return err
+	}
+
+	if err := writeJSON(filepath.Join(dir, metadataFile), m); err != nil {
+		return err
+	}
+
+	if err := writeJSON(filepath.Join(dir, standardInputFile), sourceCode); err != nil {
+		return err
+	}
+
+	if len(sourceCode.Settings.Remappings) == 0 {
+		return nil
+	}
+
+	remappings := strings.Join(sourceCode.Settings.Remappings, "\n") + "\n"
+	return os.WriteFile(filepath.Join(dir, remappingsFile), []byte(remappings), fileMode)
+}
+
+func writeJSON(path string, v interface{}) error {
+	bs, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(bs, '\n'), fileMode)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ipfsChunkSize is the largest file solc hashes as a single IPFS block.
+const ipfsChunkSize = 256 * 1024
+
+// ipfsHash returns the CIDv0 multihash solc computes for content: the sha256 of a
+// dag-pb node wrapping a UnixFS file. Only single-block files are supported.
+func ipfsHash(content []byte) ([]byte, error) {
+	if len(content) > ipfsChunkSize {
+		return nil, errors.New("ipfs hash of multi-block files is not supported")
+	}
+
+	unixfs := []byte{0x08, 0x02} // Type = File
+	if len(content) > 0 {
+		unixfs = append(unixfs, 0x12)
+		unixfs = appendUvarint(unixfs, uint64(len(content)))
+		unixfs = append(unixfs, content...)
+	}
+	unixfs = append(unixfs, 0x18)
+	unixfs = appendUvarint(unixfs, uint64(len(content)))
+
+	node := []byte{0x0a}
+	node = appendUvarint(node, uint64(len(unixfs)))
+	node = append(node, unixfs...)
+
+	sum := sha256.Sum256(node)
+	return append([]byte{0x12, 0x20}, sum[:]...), nil
+}
+
+func appendUvarint(bs []byte, v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return append(bs, buf[:binary.PutUvarint(buf, v)]...)
+}
+
+// verifyMetadataHash compiles the sources and checks that the metadata hash embedded in the deployed bytecode
+// can be reproduced: for IPFS hashes by hashing the recompiled metadata, for Swarm hashes by comparing with
+// the hash in the recompiled bytecode.
+func verifyMetadataHash(ctx context.Context, d *deployment, compilerVersion string, contractName string, sourceCode *SourceCode) error {
+	explorer, ok := blockExploers[d.Chain]
+	if !ok {
+		return unsupportedChain(d.Chain)
+	}
+
+	output, err := compileWithOutput(ctx, compilerVersion, sourceCode, OutputSelection{
+		"*": {"*": {"metadata", "evm.deployedBytecode.object"}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, compiled := findContract(output, contractName)
+	if compiled == nil || compiled.EVM == nil || compiled.EVM.DeployedBytecode == nil {
+		return fmt.Errorf("contract %s not found in compiler output", contractName)
+	}
+
+	code, err := getCode(ctx, explorer, d.Address)
+	if err != nil {
+		return err
+	}
+
+	onChain, err := decodeHex(code)
+	if err != nil {
+		return fmt.Errorf("on-chain code: %w", err)
+	}
+
+	onChainMetadata, err := bytecodeMetadata(onChain)
+	if err != nil {
+		return fmt.Errorf("on-chain code: %w", err)
+	}
+
+	if hash, ok := onChainMetadata["ipfs"].([]byte); ok {
+		recomputed, err := ipfsHash([]byte(compiled.Metadata))
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(hash, recomputed) {
+			return fmt.Errorf("metadata hash mismatch: on-chain ipfs %s, recomputed %s", base58(hash), base58(recomputed))
+		}
+
+		return nil
+	}
+
+	local, err := decodeHex(compiled.EVM.DeployedBytecode.Object)
+	if err != nil {
+		return fmt.Errorf("compiled code: %w", err)
+	}
+
+	localMetadata, err := bytecodeMetadata(local)
+	if err != nil {
+		return fmt.Errorf("compiled code: %w", err)
+	}
+
+	for _, key := range []string{"bzzr1", "bzzr0"} {
+		if hash, ok := onChainMetadata[key].([]byte); ok {
+			recomputed, _ := localMetadata[key].([]byte)
+			if !bytes.Equal(hash, recomputed) {
+				return fmt.Errorf("metadata hash mismatch: on-chain %s %x, recomputed %x", key, hash, recomputed)
+			}
+
+			return nil
+		}
+	}
+
+	return errors.New("no metadata hash in deployed bytecode")
+}
+
+// bytecodeMetadata decodes the CBOR metadata trailer of code.
+func bytecodeMetadata(code []byte) (map[string]interface{}, error) {
+	trailer := code[len(stripMetadata(code)):]
+	if len(trailer) <= 2 {
+		return nil, errors.New("no metadata in bytecode")
+	}
+
+	return decodeMetadataCBOR(trailer[:len(trailer)-2])
+}
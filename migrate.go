@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+)
+
+// configVersion is the version of the config schema written by this tool.
+// A config without a version is of version 1, the schema before versioning.
+const configVersion = 2
+
+// configMigration upgrades config.json from the version before to, editing it in place.
+type configMigration struct {
+	to       int
+	describe string
+	apply    func(bs []byte) ([]byte, int, error) // returns the edited file and the number of changes
+}
+
+var configMigrations = []*configMigration{
+	{to: 2, describe: "name the chains of contracts, e.g. \"chain\": \"ethereum\" instead of 1", apply: migrateChainNames},
+}
+
+// checkConfigVersion fails on configs written for a newer tool, whose fields would be silently ignored.
+func checkConfigVersion(c *Config) error {
+	if c.Version > configVersion {
+		return fmt.Errorf("%s is of config version %d, newer than the %d of this tool: upgrade etherscan-downloader", configPath, c.Version, configVersion)
+	}
+
+	return nil
+}
+
+func migrateCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	dryRun := fs.Bool("n", false, "print the migrations without writing config.json")
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 0 {
+			return errors.New("usage: migrate [-n]")
+		}
+
+		if configPath == "-" {
+			return errStdinConfig
+		}
+
+		bs, err := readConfig()
+		if err != nil {
+			return err
+		}
+
+		c := &Config{}
+		if err := json.Unmarshal(stripJSONC(bs), c); err != nil {
+			return &configError{fmt.Errorf("%s: %w", configPath, err)}
+		}
+		if err := checkConfigVersion(c); err != nil {
+			return &configError{err}
+		}
+
+		from := c.Version
+		if from == 0 {
+			from = 1
+		}
+		if from == configVersion {
+			fmt.Printf("%s is up to date (version %d)\n", configPath, configVersion)
+			return nil
+		}
+
+		for _, m := range configMigrations {
+			if m.to <= from {
+				continue
+			}
+
+			var n int
+			if bs, n, err = m.apply(bs); err != nil {
+				return fmt.Errorf("migrate to version %d: %w", m.to, err)
+			}
+			fmt.Printf("version %d: %s (%d changes)\n", m.to, m.describe, n)
+		}
+
+		if bs, err = setConfigVersion(bs, configVersion); err != nil {
+			return err
+		}
+
+		if *dryRun {
+			return nil
+		}
+
+		if err := writeConfigFile(bs); err != nil {
+			return err
+		}
+
+		fmt.Printf("migrated %s from version %d to %d\n", configPath, from, configVersion)
+
+		return nil
+	}
+}
+
+// memberRange is the byte range of a member of a JSON object.
+type memberRange struct {
+	name string
+	// key is where the name's quote is, start and end are the range of the value.
+	key, start, end int
+}
+
+// objectMembers returns the members of the JSON object starting at offset in bs, at its opening brace or before it.
+// Comments in bs are skipped over.
+func objectMembers(bs []byte, offset int) ([]*memberRange, error) {
+	bs = stripJSONC(bs)
+	dec := json.NewDecoder(bytes.NewReader(bs[offset:]))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errors.New("not an object")
+	}
+
+	members := []*memberRange{}
+	for dec.More() {
+		before := offset + int(dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+
+		end := offset + int(dec.InputOffset())
+		members = append(members, &memberRange{name: tok.(string), key: before + bytes.IndexByte(bs[before:], '"'), start: end - len(raw), end: end})
+	}
+
+	return members, nil
+}
+
+// migrateChainNames replaces the numeric chains of the contracts by their names, for the chains which have one.
+func migrateChainNames(bs []byte) ([]byte, int, error) {
+	names := map[chain]string{}
+	for name, ch := range chainNames {
+		names[ch] = name
+	}
+
+	_, entries, err := contractEntries(bs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// edit from the end, so the offsets of the earlier entries stay valid
+	edited := append([]byte{}, bs...)
+	n := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		valueStart := e.key + bytes.IndexByte(bs[e.key:e.end], ':') + 1
+		members, err := objectMembers(bs, valueStart)
+		if err != nil {
+			// not an object, left to the validation of the config
+			continue
+		}
+
+		for _, m := range members {
+			if m.name != "chain" {
+				continue
+			}
+
+			id, err := strconv.ParseUint(string(bs[m.start:m.end]), 10, 64)
+			if err != nil {
+				continue
+			}
+			name, ok := names[chain(id)]
+			if !ok {
+				continue
+			}
+
+			edited = append(append(append([]byte{}, edited[:m.start]...), strconv.Quote(name)...), edited[m.end:]...)
+			n++
+		}
+	}
+
+	return edited, n, nil
+}
+
+// setConfigVersion sets the top-level "version" of config.json, adding it before the first member when missing.
+func setConfigVersion(bs []byte, version int) ([]byte, error) {
+	start := bytes.IndexByte(stripJSONC(bs), '{')
+	if start < 0 {
+		return nil, fmt.Errorf("%s is not a JSON object", configPath)
+	}
+
+	members, err := objectMembers(bs, start)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath, err)
+	}
+
+	value := strconv.Itoa(version)
+	for _, m := range members {
+		if m.name == "version" {
+			return append(append(append([]byte{}, bs[:m.start]...), value...), bs[m.end:]...), nil
+		}
+	}
+
+	entry := `"version": ` + value
+	if len(members) == 0 {
+		return append(append(append([]byte{}, bs[:start+1]...), entry...), bs[start+1:]...), nil
+	}
+
+	key := members[0].key
+	if bytes.LastIndexByte(bs[:key], '\n') < start {
+		// the first member is on the line of the brace
+		return append(append(append([]byte{}, bs[:key]...), entry+", "...), bs[key:]...), nil
+	}
+
+	return append(append(append([]byte{}, bs[:key]...), entry+",\n"+lineIndent(bs, key)...), bs[key:]...), nil
+}
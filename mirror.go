@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MirrorConfig is a git repository maintained as a mirror of the verified sources by the mirror command.
+type MirrorConfig struct {
+	Repo   string `json:"repo"`             // URL to clone and push to
+	Branch string `json:"branch,omitempty"` // default main
+	Dir    string `json:"dir,omitempty"`    // working clone, default .mirror
+	Path   string `json:"path,omitempty"`   // directory of the sources in the repository, default contracts
+
+	// AuthorName and AuthorEmail are the identity of the commits, instead of git's user.name and user.email.
+	AuthorName  string `json:"authorName,omitempty"`
+	AuthorEmail string `json:"authorEmail,omitempty"`
+}
+
+// mirrorCommand clones or pulls the configured mirror repository, downloads the contracts into it,
+// and commits and pushes what changed.
+func mirrorCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	flags := addDownloadFlags(fs)
+	noPush := fs.Bool("no-push", false, "commit but don't push")
+
+	return func(ctx context.Context, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		m := c.Mirror
+		if m == nil || m.Repo == "" {
+			return &configError{errors.New("mirror.repo is not configured")}
+		}
+		branch := firstNonEmpty(m.Branch, "main")
+		clone := firstNonEmpty(m.Dir, ".mirror")
+
+		if err := syncMirror(ctx, clone, m.Repo, branch); err != nil {
+			return err
+		}
+
+		c.ContractDir = filepath.Join(clone, firstNonEmpty(m.Path, "contracts"))
+
+		if len(args) == 0 {
+			args = c.names()
+		}
+		dl, err := flags.downloader(c)
+		if err != nil {
+			return err
+		}
+		ctx = dl.withClient(ctx)
+
+		deployments, err := c.deployments(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		// commit what was downloaded even when some contracts failed, and report the failures afterwards
+		downloadErr := dl.downloadAll(ctx, deployments)
+
+		subject, body, err := mirrorMessage(ctx, clone, c.ContractDir, deployments)
+		if err != nil {
+			return err
+		}
+		if subject == "" {
+			fmt.Fprintln(humanOut, "mirror: no changes")
+			return downloadErr
+		}
+
+		if _, err := git(ctx, clone, "add", "-A", "--", "."); err != nil {
+			return err
+		}
+		commit := []string{}
+		if m.AuthorName != "" {
+			commit = append(commit, "-c", "user.name="+m.AuthorName)
+		}
+		if m.AuthorEmail != "" {
+			commit = append(commit, "-c", "user.email="+m.AuthorEmail)
+		}
+		commit = append(commit, "commit", "-q", "-m", subject, "-m", body)
+		if _, err := git(ctx, clone, commit...); err != nil {
+			return err
+		}
+		fmt.Fprintf(humanOut, "mirror: %s\n", subject)
+
+		if !*noPush {
+			if _, err := git(ctx, clone, "push", "-q", "origin", "HEAD:"+branch); err != nil {
+				return err
+			}
+			fmt.Fprintf(humanOut, "mirror: pushed to %s %s\n", m.Repo, branch)
+		}
+
+		return downloadErr
+	}
+}
+
+// syncMirror clones repo into dir, or updates dir to the remote's branch when it is already cloned.
+func syncMirror(ctx context.Context, dir string, repo string, branch string) error {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		_, err := git(ctx, "", "clone", "-q", "--branch", branch, repo, dir)
+		return err
+	}
+
+	if _, err := git(ctx, dir, "fetch", "-q", "origin", branch); err != nil {
+		return err
+	}
+	// a clean tree at the remote's branch, so the commit has only what this run downloaded
+	if _, err := git(ctx, dir, "checkout", "-q", "-f", "-B", branch, "origin/"+branch); err != nil {
+		return err
+	}
+	_, err := git(ctx, dir, "clean", "-q", "-fd")
+	return err
+}
+
+// mirrorMessage returns the commit message of the changes in the clone: a subject counting the changed contracts
+// and a body listing each with its chain, address and the changed files. The subject is "" without changes.
+func mirrorMessage(ctx context.Context, clone string, contractDir string, deployments []*deployment) (string, string, error) {
+	status, err := git(ctx, clone, "status", "--porcelain", "--untracked-files=all", "--", ".")
+	if err != nil {
+		return "", "", err
+	}
+
+	rel, err := filepath.Rel(clone, contractDir)
+	if err != nil {
+		return "", "", err
+	}
+	prefix := filepath.ToSlash(rel) + "/"
+
+	folders := map[string]*deployment{}
+	for _, d := range deployments {
+		folders[filepath.ToSlash(d.folder())] = d
+	}
+
+	changed := map[string]int{}
+	for _, line := range strings.Split(strings.TrimRight(status, "\n"), "\n") {
+		if len(line) < 4 {
+			continue
+		}
+		file := strings.Trim(line[3:], `"`)
+		if i := strings.Index(file, " -> "); i >= 0 {
+			file = file[i+len(" -> "):]
+		}
+
+		// every download rewrites its fetch time, which alone is no change of the sources
+		if path.Base(file) == provenanceFile {
+			continue
+		}
+
+		// the longest configured folder containing the file, as libraries are nested in their contract's
+		folder := ""
+		if rest := strings.TrimPrefix(file, prefix); rest != file {
+			for f := range folders {
+				if strings.HasPrefix(rest, f+"/") && len(f) > len(folder) {
+					folder = f
+				}
+			}
+		}
+		changed[firstNonEmpty(folder, "(other)")]++
+	}
+	if len(changed) == 0 {
+		return "", "", nil
+	}
+
+	names := make([]string, 0, len(changed))
+	for name := range changed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	body := &bytes.Buffer{}
+	for _, name := range names {
+		if d, ok := folders[name]; ok {
+			fmt.Fprintf(body, "- %s: %s:%s, %d files\n", d.Name, d.Chain, d.Address, changed[name])
+		} else {
+			fmt.Fprintf(body, "- %s: %d files\n", name, changed[name])
+		}
+	}
+
+	n := len(names)
+	if _, ok := changed["(other)"]; ok {
+		n--
+	}
+
+	return fmt.Sprintf("Update verified sources of %d contracts", n), strings.TrimSpace(body.String()), nil
+}
+
+// git runs git in dir, returning its output.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
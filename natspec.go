@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const docsDir = "docs"
+
+// NatSpec is the devdoc or userdoc output of a compiled contract.
+type NatSpec struct {
+	Title   string                    `json:"title"`
+	Author  string                    `json:"author"`
+	Details string                    `json:"details"`
+	Notice  string                    `json:"notice"`
+	Methods map[string]*NatSpecMember `json:"methods"`
+	Events  map[string]*NatSpecMember `json:"events"`
+}
+
+type NatSpecMember struct {
+	Notice  string            `json:"notice"`
+	Details string            `json:"details"`
+	Params  map[string]string `json:"params"`
+	Returns map[string]string `json:"returns"`
+}
+
+// writeDocs compiles the sources with devdoc/userdoc output and renders docs/<source>/<Contract>.md per contract.
+func writeDocs(ctx context.Context, dir string, rawCode *RawCode, sourceCode *SourceCode) error {
+	output, err := compileWithOutput(ctx, rawCode.CompilerVersion, sourceCode, OutputSelection{"*": {"*": {"devdoc", "userdoc"}}})
+	if err != nil {
+		return err
+	}
+
+	for path, contracts := range output.Contracts {
+		for name, c := range contracts {
+			out := filepath.Join(dir, docsDir, filepath.FromSlash(path), name+".md")
+			if err := os.MkdirAll(filepath.Dir(out), dirMode); err != nil {
+				return err
+			}
+
+			if err := os.WriteFile(out, []byte(renderNatSpec(name, c.Devdoc, c.Userdoc)), fileMode); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func renderNatSpec(name string, devdoc *NatSpec, userdoc *NatSpec) string {
+	if devdoc == nil {
+		devdoc = &NatSpec{}
+	}
+	if userdoc == nil {
+		userdoc = &NatSpec{}
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "# %s\n\n", name)
+	writeParagraph(b, devdoc.Title)
+	if devdoc.Author != "" {
+		writeParagraph(b, "Author: "+devdoc.Author)
+	}
+	writeParagraph(b, userdoc.Notice)
+	writeParagraph(b, devdoc.Details)
+
+	writeNatSpecMembers(b, "Functions", devdoc.Methods, userdoc.Methods)
+	writeNatSpecMembers(b, "Events", devdoc.Events, userdoc.Events)
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+func writeNatSpecMembers(b *strings.Builder, title string, dev map[string]*NatSpecMember, user map[string]*NatSpecMember) {
+	signatures := []string{}
+	for sig := range dev {
+		signatures = append(signatures, sig)
+	}
+	for sig := range user {
+		if _, ok := dev[sig]; !ok {
+			signatures = append(signatures, sig)
+		}
+	}
+
+	if len(signatures) == 0 {
+		return
+	}
+	sort.Strings(signatures)
+
+	fmt.Fprintf(b, "## %s\n\n", title)
+	for _, sig := range signatures {
+		d, u := dev[sig], user[sig]
+		if d == nil {
+			d = &NatSpecMember{}
+		}
+		if u == nil {
+			u = &NatSpecMember{}
+		}
+
+		fmt.Fprintf(b, "### `%s`\n\n", sig)
+		writeParagraph(b, u.Notice)
+		writeParagraph(b, d.Details)
+		writeNatSpecTable(b, "Parameter", d.Params)
+		writeNatSpecTable(b, "Return", d.Returns)
+	}
+}
+
+func writeNatSpecTable(b *strings.Builder, header string, rows map[string]string) {
+	if len(rows) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(rows))
+	for name := range rows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(b, "| %s | Description |\n| --- | --- |\n", header)
+	for _, name := range names {
+		fmt.Fprintf(b, "| `%s` | %s |\n", name, strings.ReplaceAll(rows[name], "\n", " "))
+	}
+	fmt.Fprintln(b)
+}
+
+func writeParagraph(b *strings.Builder, s string) {
+	if s = strings.TrimSpace(s); s != "" {
+		fmt.Fprintf(b, "%s\n\n", s)
+	}
+}
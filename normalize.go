@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NormalizeConfig is how sources are normalized before they are written, so mixed line endings don't pollute diffs.
+// Sources are always UTF-8, as the explorers return them in JSON.
+type NormalizeConfig struct {
+	// LineEndings is lf (the default), crlf, or keep to write the line endings as verified.
+	LineEndings string `json:"lineEndings,omitempty"`
+	// KeepBOM keeps byte order marks, which are stripped by default.
+	KeepBOM bool `json:"keepBOM,omitempty"`
+}
+
+// validate checks the settings of n, which may be nil for the defaults.
+func (n *NormalizeConfig) validate() error {
+	if n == nil {
+		return nil
+	}
+
+	switch n.LineEndings {
+	case "", "lf", "crlf", "keep":
+		return nil
+	}
+
+	return fmt.Errorf("normalize: unknown lineEndings %q, want lf, crlf or keep", n.LineEndings)
+}
+
+// apply returns source normalized as configured by n, which may be nil for the defaults.
+func (n *NormalizeConfig) apply(source string) string {
+	lineEndings, keepBOM := "lf", false
+	if n != nil {
+		lineEndings, keepBOM = firstNonEmpty(n.LineEndings, lineEndings), n.KeepBOM
+	}
+
+	if !keepBOM {
+		source = strings.TrimPrefix(source, "\ufeff")
+	}
+
+	switch lineEndings {
+	case "lf":
+		source = strings.ReplaceAll(source, "\r\n", "\n")
+	case "crlf":
+		source = strings.ReplaceAll(strings.ReplaceAll(source, "\r\n", "\n"), "\n", "\r\n")
+	}
+
+	return source
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const packageJSONFile = "package.json"
+
+var (
+	// openZeppelinVersionPattern matches the header of OpenZeppelin sources,
+	// e.g. "// OpenZeppelin Contracts (last updated v4.9.0) (token/ERC20/ERC20.sol)" or "// OpenZeppelin Contracts v4.4.1 (access/Ownable.sol)".
+	openZeppelinVersionPattern = regexp.MustCompile(`OpenZeppelin Contracts(?: \(last updated)? v(\d+\.\d+\.\d+)`)
+	// versionedPackagePattern matches a package imported with its version in the path, e.g. "@openzeppelin/contracts@4.9.3/".
+	versionedPackagePattern = regexp.MustCompile(`^((?:@[^/@]+/)?[^/@]+)@(\d+\.\d+\.\d+[^/]*)/`)
+)
+
+// foundryLibPackages are the npm packages of the lib/ directories foundry projects vendor them into as git submodules.
+var foundryLibPackages = map[string]string{
+	"openzeppelin-contracts":             "@openzeppelin/contracts",
+	"openzeppelin-contracts-upgradeable": "@openzeppelin/contracts-upgradeable",
+	"solmate":                            "solmate",
+	"solady":                             "solady",
+}
+
+// PackageJSON is the package.json pinning the npm packages the verified sources vendor.
+type PackageJSON struct {
+	Name         string            `json:"name"`
+	Private      bool              `json:"private"`
+	Dependencies map[string]string `json:"dependencies"`
+}
+
+// npmPackage returns the npm package a source key belongs to along with the version in its path, if any,
+// e.g. "@openzeppelin/contracts" for "node_modules/@openzeppelin/contracts/token/ERC20/ERC20.sol".
+func npmPackage(key string) (string, string, bool) {
+	rest := key
+	if i := strings.LastIndex(key, "node_modules/"); i >= 0 {
+		rest = key[i+len("node_modules/"):]
+	} else if strings.HasPrefix(key, "lib/") {
+		dir, _, _ := strings.Cut(strings.TrimPrefix(key, "lib/"), "/")
+		pkg, ok := foundryLibPackages[dir]
+		return pkg, "", ok
+	} else if !strings.HasPrefix(key, "@") {
+		return "", "", false
+	}
+
+	if m := versionedPackagePattern.FindStringSubmatch(rest); m != nil {
+		return m[1], m[2], true
+	}
+
+	segments := 1
+	if strings.HasPrefix(rest, "@") {
+		segments = 2
+	}
+	parts := strings.SplitN(rest, "/", segments+1)
+	if len(parts) <= segments {
+		return "", "", false
+	}
+
+	return strings.Join(parts[:segments], "/"), "", true
+}
+
+// detectPackages returns the versions of the npm packages vendored in sources, and the packages whose version is unknown.
+// A version is the one in the import paths, or for OpenZeppelin the latest in the headers of its files,
+// the release the files have been last updated in.
+func detectPackages(sources Sources) (map[string]string, []string) {
+	versions := map[string]string{}
+	seen := map[string]bool{}
+	for key, source := range sources {
+		pkg, version, ok := npmPackage(key)
+		if !ok {
+			continue
+		}
+		seen[pkg] = true
+
+		if version == "" && strings.HasPrefix(pkg, "@openzeppelin/") {
+			if m := openZeppelinVersionPattern.FindStringSubmatch(source.Content); m != nil {
+				version = m[1]
+			}
+		}
+		if version != "" && (versions[pkg] == "" || compareVersions(version, versions[pkg]) > 0) {
+			versions[pkg] = version
+		}
+	}
+
+	unknown := []string{}
+	for pkg := range seen {
+		if versions[pkg] == "" {
+			unknown = append(unknown, pkg)
+		}
+	}
+	sort.Strings(unknown)
+
+	return versions, unknown
+}
+
+// writePackageJSON writes package.json pinning the versions detected for the packages vendored in sourceCode,
+// so JS tooling can install them instead of the vendored copies.
+func writePackageJSON(dir string, d *deployment, sourceCode *SourceCode) error {
+	versions, unknown := detectPackages(sourceCode.Sources)
+	for _, pkg := range unknown {
+		fmt.Fprintf(humanOut, "%s: package.json: can't tell the version of %s, left out\n", d.Name, pkg)
+	}
+	if len(versions) == 0 {
+		return nil
+	}
+
+	name := strings.ToLower(strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' || r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '-'
+	}, d.Name))
+
+	return writeJSON(filepath.Join(dir, packageJSONFile), &PackageJSON{Name: name, Private: true, Dependencies: versions})
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// windowsReserved are the device names NTFS doesn't allow as file names, with or without an extension.
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// sourcePath returns the relative path a source key is written to on this OS.
+// See safeSourcePath.
+func sourcePath(key string) string {
+	return safeSourcePath(key, runtime.GOOS == "windows")
+}
+
+// safeSourcePath converts the slash-separated source key to a relative path of the OS, dropping empty, "." and ".."
+// segments so it can't escape the contract directory. For windows, characters and names invalid on NTFS are escaped
+// with "_".
+func safeSourcePath(key string, windows bool) string {
+	segments := []string{}
+	for _, segment := range strings.FieldsFunc(key, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if segment == "." || segment == ".." {
+			continue
+		}
+
+		if windows {
+			segment = windowsSafeName(segment)
+		}
+		segments = append(segments, segment)
+	}
+
+	return filepath.Join(segments...)
+}
+
+// windowsSafeName escapes the characters NTFS doesn't allow in name, trailing dots and spaces, and reserved device names.
+func windowsSafeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+
+	if trimmed := strings.TrimRight(name, ". "); trimmed != name {
+		name = trimmed + strings.Repeat("_", len(name)-len(trimmed))
+	}
+
+	base := name
+	if i := strings.IndexByte(base, '.'); i >= 0 {
+		base = base[:i]
+	}
+	if windowsReserved[strings.ToUpper(base)] {
+		name = "_" + name
+	}
+
+	return name
+}
+
+// caseCollision is a file whose path differs from another's only by case, which overwrite each other
+// on case-insensitive filesystems, the default of macOS and Windows.
+type caseCollision struct {
+	file  *pendingFile
+	other *pendingFile
+}
+
+// caseCollisions returns the files colliding by case with another of files sorting before them.
+func caseCollisions(files []*pendingFile) []*caseCollision {
+	sorted := append([]*pendingFile{}, files...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].path < sorted[j].path })
+
+	collisions := []*caseCollision{}
+	byFolded := map[string]*pendingFile{}
+	for _, f := range sorted {
+		folded := strings.ToLower(filepath.Clean(f.path))
+		if other, ok := byFolded[folded]; ok && filepath.Clean(other.path) != filepath.Clean(f.path) {
+			collisions = append(collisions, &caseCollision{file: f, other: other})
+			continue
+		}
+		byFolded[folded] = f
+	}
+
+	return collisions
+}
+
+// caseDisambiguated returns path with a numbered suffix before its extension, e.g. "utils_2.sol",
+// which doesn't fold to any of taken, the case-folded paths already used.
+func caseDisambiguated(path string, taken map[string]bool) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s_%d%s", base, n, ext)
+		if !taken[strings.ToLower(candidate)] {
+			return candidate
+		}
+	}
+}
+
+// caseInsensitiveFS reports whether the filesystem holding dir, or its nearest existing parent, ignores case,
+// probing it with a temporary file.
+func caseInsensitiveFS(dir string) (bool, error) {
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false, nil
+		}
+		dir = parent
+	}
+
+	f, err := os.CreateTemp(dir, ".case-probe-")
+	if err != nil {
+		return false, err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	_, err = os.Stat(filepath.Join(dir, strings.ToUpper(filepath.Base(f.Name()))))
+
+	return err == nil, nil
+}
+
+// resolveCaseCollisions handles the files of d colliding by case according to the --case-collisions policy:
+// renaming the later ones, updating written, or failing, by default only where the filesystem of dir ignores case.
+func (dl *downloader) resolveCaseCollisions(dir string, d *deployment, files []*pendingFile, written map[string][]byte) error {
+	collisions := caseCollisions(files)
+	if len(collisions) == 0 {
+		return nil
+	}
+
+	if dl.caseCollisions == "rename" {
+		taken := map[string]bool{}
+		for _, f := range files {
+			taken[strings.ToLower(filepath.Clean(f.path))] = true
+		}
+
+		for _, c := range collisions {
+			renamed := caseDisambiguated(c.file.path, taken)
+			taken[strings.ToLower(renamed)] = true
+
+			rel, err := filepath.Rel(dir, c.file.path)
+			renamedRel, renamedErr := filepath.Rel(dir, renamed)
+			if content, ok := written[rel]; ok && err == nil && renamedErr == nil {
+				delete(written, rel)
+				written[renamedRel] = content
+			}
+			fmt.Fprintf(os.Stderr, "%s: warning: %s collides with %s by case, writing it as %s\n", d.Name, c.file.source, c.other.source, renamed)
+			c.file.path = renamed
+		}
+
+		return nil
+	}
+
+	insensitive := dl.caseCollisions == "error"
+	if !insensitive {
+		var err error
+		if insensitive, err = caseInsensitiveFS(dir); err != nil {
+			return err
+		}
+	}
+
+	c := collisions[0]
+	if insensitive {
+		return fmt.Errorf("%s and %s differ only by case and would overwrite each other: download onto a case-sensitive filesystem or with --case-collisions rename", c.other.source, c.file.source)
+	}
+
+	for _, c := range collisions {
+		fmt.Fprintf(os.Stderr, "%s: warning: %s and %s differ only by case, the tree can't be checked out on macOS or Windows\n", d.Name, c.other.source, c.file.source)
+	}
+
+	return nil
+}
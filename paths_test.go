@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSafeSourcePath(t *testing.T) {
+	for _, tt := range []struct {
+		key     string
+		windows bool
+		want    string
+	}{
+		{"src/Token.sol", false, filepath.Join("src", "Token.sol")},
+		{"contracts/./token//Token.sol", false, filepath.Join("contracts", "token", "Token.sol")},
+		// ".." segments are dropped rather than resolved, so the path stays below the contract directory
+		{"../../etc/passwd", false, filepath.Join("etc", "passwd")},
+		{"src/../../Token.sol", false, filepath.Join("src", "Token.sol")},
+		{"..", false, ""},
+		// absolute paths are made relative
+		{"/etc/passwd", false, filepath.Join("etc", "passwd")},
+		{`\Windows\System32\drivers`, false, filepath.Join("Windows", "System32", "drivers")},
+		{"/home/user/project/src/Token.sol", false, filepath.Join("home", "user", "project", "src", "Token.sol")},
+		// a drive letter is a segment like another, escaped on windows
+		{"C:/Users/dev/Token.sol", false, filepath.Join("C:", "Users", "dev", "Token.sol")},
+		{`C:\Users\dev\Token.sol`, true, filepath.Join("C_", "Users", "dev", "Token.sol")},
+		{"src/con/aux.sol", true, filepath.Join("src", "_con", "_aux.sol")},
+		{"src/con/aux.sol", false, filepath.Join("src", "con", "aux.sol")},
+		{"@openzeppelin/contracts/token/ERC20.sol", true, filepath.Join("@openzeppelin", "contracts", "token", "ERC20.sol")},
+	} {
+		if got := safeSourcePath(tt.key, tt.windows); got != tt.want {
+			t.Errorf("safeSourcePath(%q, %t) = %q, want %q", tt.key, tt.windows, got, tt.want)
+		}
+	}
+}
+
+func TestWindowsSafeName(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		want string
+	}{
+		{"Token.sol", "Token.sol"},
+		{"C:", "C_"},
+		{`a<b>c:d"e|f?g*h.sol`, "a_b_c_d_e_f_g_h.sol"},
+		{"tab\there.sol", "tab_here.sol"},
+		// trailing dots and spaces, which NTFS drops
+		{"Token.sol.", "Token.sol_"},
+		{"lib. .", "lib___"},
+		// reserved device names, with or without an extension and in any case
+		{"CON", "_CON"},
+		{"nul.sol", "_nul.sol"},
+		{"Com1.tar.gz", "_Com1.tar.gz"},
+		{"LPT9", "_LPT9"},
+		{"CONSOLE.sol", "CONSOLE.sol"},
+		{"COM10", "COM10"},
+		{"my.con", "my.con"},
+	} {
+		if got := windowsSafeName(tt.name); got != tt.want {
+			t.Errorf("windowsSafeName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// modes of the directories and files written
+var (
+	dirMode  os.FileMode = 0o755
+	fileMode os.FileMode = 0o644
+)
+
+// PermissionsConfig are the modes of the written directories and files, as octal strings like "0750".
+type PermissionsConfig struct {
+	DirMode  string `json:"dirMode,omitempty"`
+	FileMode string `json:"fileMode,omitempty"`
+}
+
+// configurePermissions sets the modes of written directories and files from p, with the fields set in override taking precedence.
+func configurePermissions(p *PermissionsConfig, override *PermissionsConfig) error {
+	merged := &PermissionsConfig{}
+	if p != nil {
+		*merged = *p
+	}
+	if override != nil {
+		merged.DirMode = firstNonEmpty(override.DirMode, merged.DirMode)
+		merged.FileMode = firstNonEmpty(override.FileMode, merged.FileMode)
+	}
+
+	if merged.DirMode != "" {
+		mode, err := parseMode(merged.DirMode)
+		if err != nil {
+			return fmt.Errorf("dirMode: %w", err)
+		}
+		dirMode = mode
+	}
+
+	if merged.FileMode != "" {
+		mode, err := parseMode(merged.FileMode)
+		if err != nil {
+			return fmt.Errorf("fileMode: %w", err)
+		}
+		fileMode = mode
+	}
+
+	return nil
+}
+
+func parseMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("bad mode %q, want octal permissions like 0755", s)
+	}
+
+	return os.FileMode(mode), nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	solcVersionFile = ".solc-version"
+	foundryFile     = "foundry.toml"
+)
+
+// normalizeCompilerVersion returns the plain semver of an explorer CompilerVersion like "v0.8.15+commit.e14f2714".
+func normalizeCompilerVersion(compilerVersion string) string {
+	version, _, _ := strings.Cut(strings.TrimPrefix(compilerVersion, "v"), "+")
+	return version
+}
+
+// writeCompilerPin writes .solc-version for solc-select / svm and, with foundry, a foundry.toml default profile
+// using the verified compiler settings.
+func writeCompilerPin(dir string, rawCode *RawCode, sourceCode *SourceCode, foundry bool) error {
+	if strings.HasPrefix(rawCode.CompilerVersion, "vyper") || rawCode.CompilerVersion == "" {
+		return nil
+	}
+
+	version := normalizeCompilerVersion(rawCode.CompilerVersion)
+	if err := os.WriteFile(filepath.Join(dir, solcVersionFile), []byte(version+"\n"), fileMode); err != nil {
+		return err
+	}
+
+	if !foundry {
+		return nil
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintln(b, "[profile.default]")
+	fmt.Fprintln(b, `src = "."`)
+	fmt.Fprintf(b, "solc_version = %q\n", version)
+	if o := sourceCode.Settings.Optimizer; o != nil {
+		fmt.Fprintf(b, "optimizer = %t\n", o.Enabled)
+		fmt.Fprintf(b, "optimizer_runs = %d\n", o.Runs)
+	}
+	if v := sourceCode.Settings.EVMVersion; v != "" {
+		fmt.Fprintf(b, "evm_version = %q\n", v)
+	}
+	if len(sourceCode.Settings.Remappings) > 0 {
+		fmt.Fprintln(b, "remappings = [")
+		for _, r := range sourceCode.Settings.Remappings {
+			fmt.Fprintf(b, "  %q,\n", r)
+		}
+		fmt.Fprintln(b, "]")
+	}
+
+	return os.WriteFile(filepath.Join(dir, foundryFile), []byte(b.String()), fileMode)
+}
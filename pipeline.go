@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// pipeline stages
+const (
+	stageFetch = "fetch"
+	stageParse = "parse"
+	stageWrite = "write"
+)
+
+// defaults of --fetch-workers, --parse-workers and --pipeline-buffer
+const (
+	defaultFetchWorkers   = 1
+	defaultParseWorkers   = 1
+	defaultPipelineBuffer = 8
+)
+
+// pipeline runs the sources of the contracts of a run through channel-connected stages: fetch workers get them
+// from the explorer into the run's cache, parse workers decode them, and the run writes them one contract at a time
+// in the order of the config, as the checkpoint, the budget and the claims of the written files expect.
+// The write stage has a single worker, the run itself. At most buffer contracts are fetched ahead of the one being
+// written, and the sources of each are dropped from the cache once written unless a later contract of the run is the same,
+// so a large batch neither holds every contract's sources at once nor sends requests long before they are needed,
+// while the next contracts are fetched and parsed during the writes. A contract failing to fetch ahead is fetched again
+// when written, reporting its error.
+type pipeline struct {
+	dl           *downloader
+	fetchWorkers int
+	parseWorkers int
+
+	items []*pipelineItem
+	last  map[string]int // the index of the last item of each contract, whose sources are kept until then
+	slots chan struct{}  // a token per contract fetched ahead of the writes
+	stats map[string]*stageStats
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type pipelineItem struct {
+	d     *deployment
+	skip  bool          // not downloaded, e.g. already downloaded according to the checkpoint
+	ahead bool          // fetched ahead, holding a slot until written
+	ready chan struct{} // closed once fetched and parsed, or not fetched ahead
+}
+
+// stageStats are the contracts a stage handled, the time its workers spent on them,
+// and the time they waited for the next stage to take them, the backpressure of the stages after it.
+type stageStats struct {
+	mu      sync.Mutex
+	items   int
+	busy    time.Duration
+	blocked time.Duration
+}
+
+func (s *stageStats) add(busy time.Duration, blocked time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.items++
+	s.busy += busy
+	s.blocked += blocked
+}
+
+// newPipeline returns the pipeline of deployments, skip telling those the run doesn't download.
+func newPipeline(dl *downloader, deployments []*deployment, skip func(d *deployment) bool) *pipeline {
+	buffer := dl.pipelineBuffer
+	if buffer < 1 {
+		buffer = 1
+	}
+
+	p := &pipeline{
+		dl:           dl,
+		fetchWorkers: dl.fetchWorkers,
+		parseWorkers: dl.parseWorkers,
+		slots:        make(chan struct{}, buffer),
+		stats:        map[string]*stageStats{stageFetch: {}, stageParse: {}, stageWrite: {}},
+	}
+	if p.fetchWorkers < 1 {
+		p.fetchWorkers = 1
+	}
+	if p.parseWorkers < 1 {
+		p.parseWorkers = 1
+	}
+
+	p.last = map[string]int{}
+	for i, d := range deployments {
+		p.items = append(p.items, &pipelineItem{d: d, skip: skip(d), ready: make(chan struct{})})
+		p.last[rawCodeKey(d)] = i
+	}
+
+	return p
+}
+
+// start starts the fetch and parse stages, until stop is called.
+func (p *pipeline) start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+
+	fetch := make(chan *pipelineItem)
+	parse := make(chan *pipelineItem, cap(p.slots))
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer close(fetch)
+
+		for _, item := range p.items {
+			if item.skip || !p.prefetches(item.d) {
+				close(item.ready)
+				continue
+			}
+
+			select {
+			case p.slots <- struct{}{}:
+				item.ahead = true
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case fetch <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	fetchers := sync.WaitGroup{}
+	for i := 0; i < p.fetchWorkers; i++ {
+		fetchers.Add(1)
+		go func() {
+			defer fetchers.Done()
+
+			for item := range fetch {
+				start := time.Now()
+				// the errors are reported by the download checking and fetching the contract again
+				if item.d.Source == sourceTenderly || p.dl.fetched.checkHasCode(ctx, item.d) == nil {
+					_, _ = p.dl.fetched.fetch(ctx, item.d)
+				}
+				busy := time.Since(start)
+
+				start = time.Now()
+				select {
+				case parse <- item:
+				case <-ctx.Done():
+					return
+				}
+				p.observe(stageFetch, busy, time.Since(start))
+			}
+		}()
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		fetchers.Wait()
+		close(parse)
+	}()
+
+	for i := 0; i < p.parseWorkers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+
+			for item := range parse {
+				start := time.Now()
+				p.dl.fetched.parse(item.d)
+				close(item.ready)
+				p.observe(stageParse, time.Since(start), 0)
+			}
+		}()
+	}
+}
+
+// prefetches reports whether the sources of d are fetched ahead of its download, not when its download sends
+// no request for them or the budget has no call left.
+func (p *pipeline) prefetches(d *deployment) bool {
+	if d.Chain == 0 {
+		return false
+	}
+
+	if ok, _ := p.dl.breaker.allow(d.Chain); !ok {
+		return false
+	}
+
+	if requestBudget.exhausted() {
+		return false
+	}
+
+	_, cached, err := p.dl.unverified.cachedAt(d)
+
+	return err == nil && !cached
+}
+
+// wait waits until the sources of the i-th contract are fetched and parsed, if they are fetched ahead,
+// returning when they were ready.
+func (p *pipeline) wait(ctx context.Context, i int) time.Time {
+	start := time.Now()
+	select {
+	case <-p.items[i].ready:
+	case <-ctx.Done():
+	}
+
+	return start
+}
+
+// written releases the slot of the i-th contract once written, from started on after waiting since waited,
+// and forgets its sources unless a later contract of the run is the same.
+func (p *pipeline) written(i int, waited time.Time, started time.Time) {
+	p.observe(stageWrite, time.Since(started), started.Sub(waited))
+	p.release(i)
+
+	if d := p.items[i].d; p.last[rawCodeKey(d)] == i {
+		p.dl.fetched.evict(d)
+	}
+}
+
+// release releases the slot of the i-th contract, once written or left out of the run after wait.
+func (p *pipeline) release(i int) {
+	select {
+	case <-p.items[i].ready:
+		if p.items[i].ahead {
+			<-p.slots
+		}
+	default:
+		// interrupted before it was fetched, the run ends
+	}
+}
+
+// stop stops the stages and waits for their workers.
+func (p *pipeline) stop() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+func (p *pipeline) observe(stage string, busy time.Duration, blocked time.Duration) {
+	p.stats[stage].add(busy, blocked)
+	stageDuration.observe(busy.Seconds(), stage)
+	stageBlocked.observe(blocked.Seconds(), stage)
+}
+
+// printStats prints the contracts each stage handled, the time its workers spent on them and waited,
+// for the writes the time spent waiting on the fetches.
+func (p *pipeline) printStats(w io.Writer) {
+	workers := map[string]int{stageFetch: p.fetchWorkers, stageParse: p.parseWorkers, stageWrite: 1}
+	for _, stage := range []string{stageFetch, stageParse, stageWrite} {
+		s := p.stats[stage]
+		s.mu.Lock()
+		noun := "workers"
+		if workers[stage] == 1 {
+			noun = "worker"
+		}
+		fmt.Fprintf(w, "%s: %d contracts, %d %s, %s busy, %s waiting\n", stage, s.items, workers[stage], noun, s.busy.Round(time.Millisecond), s.blocked.Round(time.Millisecond))
+		s.mu.Unlock()
+	}
+}
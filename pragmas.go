@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+var solidityPragmaPattern = regexp.MustCompile(`\bpragma\s+solidity\s+([^;]+);`)
+
+// pragmaMismatch is a source file whose pragma the verified compiler doesn't satisfy.
+type pragmaMismatch struct {
+	contract string
+	file     string
+	pragma   string
+}
+
+// runPragmas prints the pragma ranges of the Solidity sources of each contract in contractDir next to its verified
+// compiler, flagging the files whose pragmas the compiler doesn't satisfy, which fail to recompile with it.
+func runPragmas(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: pragmas")
+	}
+
+	c, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	contracts, err := findDownloaded(c.ContractDir)
+	if err != nil {
+		return err
+	}
+
+	mismatches := []*pragmaMismatch{}
+	unparsed := []*pragmaMismatch{}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONTRACT\tCOMPILER\tPRAGMAS\tMISMATCHES")
+	for _, dc := range contracts {
+		rel, err := filepath.Rel(c.ContractDir, dc.Dir)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		compiler := dc.Metadata.CompilerVersion
+		if strings.HasPrefix(compiler, "vyper") {
+			continue
+		}
+		version := normalizeCompilerVersion(compiler)
+
+		ranges := map[string]int{}
+		n := 0
+		for _, file := range dc.Files {
+			if filepath.Ext(file) != ".sol" {
+				continue
+			}
+
+			bs, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			fileRel, err := filepath.Rel(dc.Dir, file)
+			if err != nil {
+				return err
+			}
+
+			for _, m := range solidityPragmaPattern.FindAllStringSubmatch(commentPattern.ReplaceAllString(string(bs), ""), -1) {
+				pragma := strings.Join(strings.Fields(m[1]), " ")
+				ranges[pragma]++
+				if version == "" {
+					continue
+				}
+
+				ok, err := satisfiesPragma(version, pragma)
+				switch {
+				case err != nil:
+					unparsed = append(unparsed, &pragmaMismatch{contract: name, file: filepath.ToSlash(fileRel), pragma: pragma})
+				case !ok:
+					mismatches = append(mismatches, &pragmaMismatch{contract: name, file: filepath.ToSlash(fileRel), pragma: pragma})
+					n++
+				}
+			}
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", name, firstNonEmpty(version, "(unknown)"), strings.Join(sortedByCount(ranges), ", "), n)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, m := range unparsed {
+		fmt.Fprintf(os.Stderr, "warning: %s: %s: can't parse pragma solidity %s\n", m.contract, m.file, m.pragma)
+	}
+
+	if len(mismatches) == 0 {
+		return nil
+	}
+
+	fmt.Println("\nfiles whose pragma the verified compiler doesn't satisfy")
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, m := range mismatches {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", m.contract, m.file, m.pragma)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%d files have a pragma their verified compiler doesn't satisfy", len(mismatches))
+}
+
+// satisfiesPragma reports whether the compiler version satisfies the range of a pragma solidity,
+// e.g. "^0.8.0", ">=0.6.0 <0.8.0", "0.5.0 - 0.6.0" or "^0.7.0 || ^0.8.0".
+func satisfiesPragma(version string, pragma string) (bool, error) {
+	for _, alternative := range strings.Split(pragma, "||") {
+		fields := strings.Fields(alternative)
+		if len(fields) == 0 {
+			return false, fmt.Errorf("empty range in %q", pragma)
+		}
+
+		// a hyphen range, inclusive on both ends
+		if len(fields) == 3 && fields[1] == "-" {
+			fields = []string{">=" + fields[0], "<=" + fields[2]}
+		}
+
+		comparators := []string{}
+		for i := 0; i < len(fields); i++ {
+			// an operator separated from its version, e.g. ">= 0.5.0"
+			if strings.Trim(fields[i], "<>=^~") == "" && i+1 < len(fields) {
+				comparators = append(comparators, fields[i]+fields[i+1])
+				i++
+				continue
+			}
+			comparators = append(comparators, fields[i])
+		}
+
+		ok := true
+		for _, comparator := range comparators {
+			satisfied, err := satisfiesComparator(version, comparator)
+			if err != nil {
+				return false, err
+			}
+			ok = ok && satisfied
+		}
+		if ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// satisfiesComparator reports whether version satisfies a single comparator of a range, e.g. "^0.8.0" or "<0.9".
+func satisfiesComparator(version string, comparator string) (bool, error) {
+	operator := strings.TrimRight(comparator, "0123456789.xX*")
+	bound := comparator[len(operator):]
+
+	parts := []int{}
+	for _, part := range strings.Split(bound, ".") {
+		if part == "x" || part == "X" || part == "*" {
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return false, fmt.Errorf("bad version %q in %q", bound, comparator)
+		}
+		parts = append(parts, n)
+	}
+	if len(parts) == 0 && operator != "" {
+		return false, fmt.Errorf("bad version %q in %q", bound, comparator)
+	}
+
+	lower := joinVersion(parts)
+	cmp := compareVersions(version, lower)
+	switch operator {
+	case ">=":
+		return cmp >= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "^":
+		// up to the next breaking version: the first non-zero part is kept
+		upper := append([]int{}, parts...)
+		for len(upper) < 3 {
+			upper = append(upper, 0)
+		}
+		i := 0
+		for i < len(parts)-1 && upper[i] == 0 {
+			i++
+		}
+		return cmp >= 0 && compareVersions(version, joinVersion(bumped(upper, i))) < 0, nil
+	case "~":
+		i := 1
+		if len(parts) == 1 {
+			i = 0
+		}
+		return cmp >= 0 && compareVersions(version, joinVersion(bumped(parts, i))) < 0, nil
+	case "", "=":
+		if len(parts) < 3 {
+			// a partial version is a wildcard, e.g. 0.8 for any 0.8.x
+			if len(parts) == 0 {
+				return true, nil
+			}
+			return cmp >= 0 && compareVersions(version, joinVersion(bumped(parts, len(parts)-1))) < 0, nil
+		}
+		return cmp == 0, nil
+	}
+
+	return false, fmt.Errorf("unknown operator %q in %q", operator, comparator)
+}
+
+// bumped returns parts up to i, with the part at i incremented, e.g. 0.9 for 0.8.19 and 1.
+func bumped(parts []int, i int) []int {
+	out := append([]int{}, parts[:i+1]...)
+	out[i]++
+
+	return out
+}
+
+// joinVersion returns the dotted version of parts, compared by compareVersions with the missing parts as zeros.
+func joinVersion(parts []int) string {
+	s := make([]string, 0, len(parts))
+	for _, p := range parts {
+		s = append(s, strconv.Itoa(p))
+	}
+
+	return strings.Join(s, ".")
+}
@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestSatisfiesPragma(t *testing.T) {
+	for _, tt := range []struct {
+		version string
+		pragma  string
+		want    bool
+	}{
+		{"0.8.19", "^0.8.0", true},
+		{"0.9.0", "^0.8.0", false},
+		{"0.7.6", "^0.8.0", false},
+		{"0.6.12", "^0.6.2", true},
+		{"0.6.1", "^0.6.2", false},
+		{"0.0.4", "^0.0.3", false}, // a caret below 0.1 pins the patch version
+		{"0.8.19", "0.8.19", true},
+		{"0.8.20", "0.8.19", false},
+		{"0.8.20", "=0.8.19", false},
+		{"0.8.4", "0.8", true}, // a partial version is a wildcard
+		{"0.8.4", "0.8.x", true},
+		{"0.9.0", "0.8.*", false},
+		{"0.7.6", ">=0.6.0 <0.8.0", true},
+		{"0.8.0", ">=0.6.0 <0.8.0", false},
+		{"0.5.17", ">= 0.5.0 < 0.6.0", true},
+		{"0.6.0", "0.5.0 - 0.6.0", true},
+		{"0.6.1", "0.5.0 - 0.6.0", false},
+		{"0.7.6", "^0.6.0 || ^0.7.0", true},
+		{"0.8.0", "^0.6.0 || ^0.7.0", false},
+		{"0.5.16", "~0.5.12", true},
+		{"0.6.0", "~0.5.12", false},
+		{"0.8.19", ">0.8.18", true},
+		{"0.8.18", ">0.8.18", false},
+		{"0.8.18", "<=0.8.18", true},
+	} {
+		got, err := satisfiesPragma(tt.version, tt.pragma)
+		if err != nil {
+			t.Errorf("satisfiesPragma(%q, %q): %s", tt.version, tt.pragma, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("satisfiesPragma(%q, %q) = %t, want %t", tt.version, tt.pragma, got, tt.want)
+		}
+	}
+}
+
+func TestSatisfiesPragmaErrors(t *testing.T) {
+	for _, pragma := range []string{"", "||", ">=a.b", "!0.8.0", "^"} {
+		if ok, err := satisfiesPragma("0.8.19", pragma); err == nil {
+			t.Errorf("satisfiesPragma(%q) = %t, want an error", pragma, ok)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	provenanceFile  = "PROVENANCE.json"
+	rawResponseFile = "raw-response.json"
+)
+
+// Provenance records where and when the sources of a contract were downloaded from, in PROVENANCE.json.
+type Provenance struct {
+	URL          string    `json:"url"` // the explorer API URL queried, with the API key redacted
+	FetchedAt    time.Time `json:"fetchedAt"`
+	Tool         string    `json:"tool"`
+	ToolVersion  string    `json:"toolVersion"`
+	ChainID      chain     `json:"chainId"`
+	Address      string    `json:"address"`
+	ResultSHA256 string    `json:"resultSha256"`        // of the raw explorer response
+	Integrity    string    `json:"integrity,omitempty"` // of the verified sources, to pin as the contract's integrity in config.json
+}
+
+// writeProvenance writes PROVENANCE.json for d into dir from the response its sources were fetched from
+// and the integrity of the verified sources, empty for unverified contracts.
+func writeProvenance(dir string, d *deployment, r *sourceResponse, integrity string) error {
+	return writeJSON(filepath.Join(dir, provenanceFile), &Provenance{
+		URL:          r.url,
+		FetchedAt:    r.fetchedAt,
+		Tool:         "etherscan-downloader",
+		ToolVersion:  version,
+		ChainID:      d.Chain,
+		Address:      d.Address,
+		ResultSHA256: r.sha256,
+		Integrity:    integrity,
+	})
+}
+
+// writeRawResponse writes the explorer response the sources were parsed from, as served, into raw-response.json.
+// Nothing is written unless --raw-response kept it.
+func writeRawResponse(dir string, r *sourceResponse) error {
+	if r.raw == nil {
+		return nil
+	}
+
+	return os.WriteFile(filepath.Join(dir, rawResponseFile), r.raw, fileMode)
+}
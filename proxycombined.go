@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	combinedProxyFile     = "proxy.json"
+	proxyPartDir          = "proxy"
+	implementationPartDir = "implementation"
+)
+
+// CombinedProxy describes the directory of a proxy downloaded with --combine-proxy, in proxy.json.
+type CombinedProxy struct {
+	ChainID            chain  `json:"chainId"`
+	Proxy              string `json:"proxy"`
+	ProxyName          string `json:"proxyName"`
+	Implementation     string `json:"implementation"`
+	ImplementationName string `json:"implementationName,omitempty"` // empty when the implementation isn't verified
+}
+
+// downloadCombined downloads the proxy d and its current implementation into one directory, for --combine-proxy:
+// the proxy into proxy/, the implementation into implementation/, each with its sources, metadata.json and abi.json,
+// and next to them abi.merged.json, the proxy's ABI plus the implementation's, and proxy.json tying the two together.
+func (dl *downloader) downloadCombined(ctx context.Context, dir string, d *deployment, rawCodes []*RawCode) error {
+	impl := strings.ToLower(rawCodes[0].Implementation)
+
+	proxy, implementation := combinedParts(d, impl)
+	if err := dl.download(ctx, proxy); err != nil {
+		return err
+	}
+	if err := dl.download(ctx, implementation); err != nil {
+		return fmt.Errorf("implementation %s: %w", impl, err)
+	}
+
+	implCodes, err := dl.fetched.fetch(ctx, implementation)
+	if err != nil {
+		return err
+	}
+
+	combined := &CombinedProxy{ChainID: d.Chain, Proxy: d.Address, ProxyName: rawCodes[0].ContractName, Implementation: impl}
+	if !isUnverified(implCodes) {
+		combined.ImplementationName = implCodes[0].ContractName
+
+		if hasABI(rawCodes[0].Abi) && hasABI(implCodes[0].Abi) {
+			if err := writeMergedABI(dir, rawCodes[0].Abi, implCodes[0].Abi); err != nil {
+				return fmt.Errorf("merge ABI of %s: %w", impl, err)
+			}
+		}
+	}
+
+	if err := writeJSON(filepath.Join(dir, combinedProxyFile), combined); err != nil {
+		return err
+	}
+
+	return runHooks(ctx, d, dir, "postDownload", d.PostDownload)
+}
+
+// combinedParts returns the deployments of the proxy d and its implementation at impl downloaded into
+// the proxy/ and implementation/ directories of d.
+func combinedParts(d *deployment, impl string) (*deployment, *deployment) {
+	proxy := &deployment{
+		Name:      filepath.Join(d.folder(), proxyPartDir),
+		Chain:     d.Chain,
+		Address:   d.Address,
+		OutDir:    d.OutDir,
+		Integrity: d.Integrity,
+		Source:    d.Source,
+		combined:  true,
+	}
+
+	implementation := &deployment{
+		Name:     filepath.Join(d.folder(), implementationPartDir),
+		Chain:    d.Chain,
+		Address:  impl,
+		OutDir:   d.OutDir,
+		Source:   d.Source,
+		combined: true,
+	}
+
+	return proxy, implementation
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const implementationHistoryFile = "implementations.json"
+
+// implementationRecord is an implementation a proxy was seen pointing at, in implementations.json.
+type implementationRecord struct {
+	Address   string    `json:"address"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	// SourceHash is the sha256 of the implementation's verified sources, empty when it is unverified.
+	SourceHash string `json:"sourceHash,omitempty"`
+}
+
+// recordImplementation adds the implementation of the proxy d to the history in dir,
+// so the upgrade timeline of the proxy can be reconstructed from repeated downloads.
+func (dl *downloader) recordImplementation(ctx context.Context, dir string, d *deployment, rawCode *RawCode) error {
+	address := strings.ToLower(rawCode.Implementation)
+
+	hash := ""
+	rawCodes, err := dl.fetched.fetch(ctx, implementationDeployment(d, address))
+	if err != nil {
+		return err
+	}
+	if !isUnverified(rawCodes) {
+		sourceCodes, err := parseContractCode(rawCodes)
+		if err != nil {
+			return err
+		}
+		hash = sourcesHash(sourceCodes)
+	}
+
+	path := filepath.Join(dir, implementationHistoryFile)
+	history := []*implementationRecord{}
+	if bs, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(bs, &history); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if n := len(history); n > 0 && history[n-1].Address == address && history[n-1].SourceHash == hash {
+		history[n-1].LastSeen = now
+	} else {
+		history = append(history, &implementationRecord{Address: address, FirstSeen: now, LastSeen: now, SourceHash: hash})
+	}
+
+	return writeJSON(path, history)
+}
+
+// implementationDeployment is the implementation at address of the proxy d,
+// downloaded into the proxy's implementations/<address> so previous implementations are kept side by side.
+func implementationDeployment(d *deployment, address string) *deployment {
+	address = strings.ToLower(address)
+
+	return &deployment{
+		Name:    filepath.Join(d.folder(), "implementations", address),
+		Chain:   d.Chain,
+		Address: address,
+		OutDir:  d.OutDir,
+		Source:  d.Source,
+	}
+}
+
+// sourcesHash returns the sha256 of the sources of sourceCodes, independent of their order.
+func sourcesHash(sourceCodes []*SourceCode) string {
+	h := sha256.New()
+	for _, sourceCode := range sourceCodes {
+		paths := make([]string, 0, len(sourceCode.Sources))
+		for path := range sourceCode.Sources {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			h.Write([]byte(path))
+			h.Write([]byte{0})
+			h.Write([]byte(sourceCode.Sources[path].Content))
+			h.Write([]byte{0})
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// pruneCommand removes the entries of contractDir which don't belong to a configured contract.
+func pruneCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	dryRun := fs.Bool("n", false, "only print what would be removed")
+
+	return func(ctx context.Context, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		entries, err := os.ReadDir(c.ContractDir)
+		if err != nil {
+			return err
+		}
+
+		keep := map[string]bool{storeDir: true, sbomFile: true, embedFile: true, constantsDir: true}
+		for name, cc := range c.Contracts {
+			keep[firstNonEmpty(cc.As, name)] = true
+		}
+
+		for _, e := range entries {
+			if keep[e.Name()] {
+				continue
+			}
+
+			path := filepath.Join(c.ContractDir, e.Name())
+			if !e.IsDir() || !downloaded(path) {
+				// e.g. a README or a directory of hand-written contracts
+				fmt.Println("skip", path+": not downloaded")
+				continue
+			}
+			fmt.Println("remove", path)
+
+			if *dryRun {
+				continue
+			}
+
+			if err := os.RemoveAll(path); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// downloaded reports whether dir holds a contract written by a download, by one of the files the download writes in it.
+func downloaded(dir string) bool {
+	for _, name := range []string{metadataFile, provenanceFile, combinedProxyFile, unverifiedFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keylessInterval is the interval between requests to explorers without an API key, their free keyless rate.
+const keylessInterval = 5 * time.Second
+
+// rateLimiter spaces requests at least interval apart.
+type rateLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// wait blocks until the next request may be sent, or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+
+	wait := time.Now()
+	defer rateLimitWait.since(wait)
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// holdOff delays the next request by at least d, e.g. for the Retry-After of a rate limited one.
+func (l *rateLimiter) holdOff(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if at := time.Now().Add(d); at.After(l.next) {
+		l.next = at
+	}
+}
+
+const (
+	// maxRateLimitRetries is how many times a rate limited request is sent again.
+	maxRateLimitRetries = 3
+
+	// rateLimitBackoff is the first wait after a rate limit without a Retry-After, doubled on each retry.
+	rateLimitBackoff = time.Second
+
+	// maxRetryAfter is the longest wait asked for by an explorer which is waited for,
+	// a longer one fails the request instead.
+	maxRetryAfter = time.Minute
+)
+
+// retryRateLimited calls do again while it fails with a rateLimitedError, at most maxRateLimitRetries times,
+// holding off all requests to the explorer at endpoint for the wait it asked for.
+func retryRateLimited(ctx context.Context, endpoint string, keyless bool, do func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := do()
+
+		var rateLimited *rateLimitedError
+		// a replayed fixture answers the retry the same way
+		if !errors.As(err, &rateLimited) || attempt == maxRateLimitRetries || replaying(ctx) {
+			return err
+		}
+
+		wait := rateLimited.retryAfter
+		if wait <= 0 {
+			wait = rateLimitBackoff << attempt
+		}
+		if wait > maxRetryAfter {
+			return fmt.Errorf("%w (retry after %s)", err, wait)
+		}
+
+		u, parseErr := url.Parse(endpoint)
+		if parseErr != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "warning: %s: %v, retrying in %s\n", u.Host, err, wait)
+		explorerLimiter(u.Host, keyless).holdOff(wait)
+
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+// retryAfter returns the wait asked for by the Retry-After header h, in seconds or as an HTTP date, zero without one.
+func retryAfter(h http.Header, now time.Time) time.Duration {
+	v := strings.TrimSpace(h.Get("Retry-After"))
+	if v == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(v); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+
+	return 0
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*rateLimiter{}
+)
+
+// explorerLimiter returns the rate limiter of requests to host.
+// Requests with an API key are limited to the rate of the key's tier, and keyless ones to the keyless rate
+// unless the explorer's key is optional. The chains of an aggregated API like Routescan's share its host's limit.
+func explorerLimiter(host string, keyless bool) *rateLimiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	if explorer, ok := explorerFor(host); ok && explorer.keyOptional() {
+		keyless = false
+	}
+
+	key := host
+	if keyless {
+		key += " keyless"
+	}
+
+	l, ok := limiters[key]
+	if !ok {
+		if keyless {
+			fmt.Fprintf(os.Stderr, "warning: no API key for %s, throttling to 1 request per %s\n", host, keylessInterval)
+			l = &rateLimiter{interval: keylessInterval}
+		} else {
+			l = &rateLimiter{interval: time.Duration(float64(time.Second) / explorerRate(host))}
+		}
+		limiters[key] = l
+	}
+
+	return l
+}
+
+// explorerFor returns the explorer whose API is at host.
+func explorerFor(host string) (blockExplorer, bool) {
+	for _, explorer := range blockExploers {
+		if explorer.host() == host {
+			return explorer, true
+		}
+	}
+
+	return blockExplorer{}, false
+}
+
+// explorerRate returns the requests per second allowed by the explorer whose API is at host.
+func explorerRate(host string) float64 {
+	if explorer, ok := explorerFor(host); ok && explorer.rate > 0 {
+		return explorer.rate
+	}
+
+	return freeTierRate
+}
+
+var (
+	slotsMu sync.Mutex
+	slots   = map[string]chan struct{}{}
+)
+
+// acquireSlot blocks until a request to host may be in flight, or ctx is done.
+// The returned func releases the slot.
+func acquireSlot(ctx context.Context, host string) (func(), error) {
+	slotsMu.Lock()
+	sem, ok := slots[host]
+	if !ok {
+		if explorer, found := explorerFor(host); found && explorer.inFlight > 0 {
+			sem = make(chan struct{}, explorer.inFlight)
+		}
+		slots[host] = sem
+	}
+	slotsMu.Unlock()
+
+	if sem == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	var once sync.Once
+	return func() { once.Do(func() { <-sem }) }, nil
+}
+
+// releasingBody releases the request's slot when the response body is closed, as the request is in flight until then.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// mainSource returns the source key defining the contract name, preferring <name>.sol when several do.
+func mainSource(sources Sources, name string) (string, bool) {
+	definition := regexp.MustCompile(`(?m)^\s*(?:abstract\s+)?(?:contract|library|interface)\s+` + regexp.QuoteMeta(name) + `\b`)
+
+	candidates := []string{}
+	for file, source := range sources {
+		if strings.HasSuffix(file, ".sol") && definition.MatchString(commentPattern.ReplaceAllString(source.Content, "")) {
+			candidates = append(candidates, file)
+		}
+	}
+	if len(candidates) == 0 {
+		for file := range sources {
+			if path.Base(file) == name+".vy" {
+				return file, true
+			}
+		}
+		return "", false
+	}
+	sort.Strings(candidates)
+
+	for _, file := range candidates {
+		if path.Base(file) == name+".sol" {
+			return file, true
+		}
+	}
+
+	return candidates[0], true
+}
+
+// reachable returns the source files imported, directly or transitively, by main, main included.
+func (g importGraph) reachable(main string) map[string]bool {
+	seen := map[string]bool{main: true}
+	queue := []string{main}
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		for _, imported := range g[file] {
+			if _, ok := g[imported]; ok && !seen[imported] {
+				seen[imported] = true
+				queue = append(queue, imported)
+			}
+		}
+	}
+
+	return seen
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// apiKeyParamPattern matches the apikey parameter of URLs and forms, whichever explorer's key it is.
+var apiKeyParamPattern = regexp.MustCompile(`(?i)(\bapikey=)[^&\s"']+`)
+
+// redactAPIKeys replaces the API keys in s, e.g. of a request URL in an error or echoed in a response, with REDACTED:
+// the values of apikey parameters, and the keys of the configured explorers wherever they appear.
+func redactAPIKeys(s string) string {
+	s = apiKeyParamPattern.ReplaceAllString(s, "${1}REDACTED")
+
+	keys := []string{}
+	for _, explorer := range blockExploers {
+		keys = append(keys, explorer.apiKey)
+	}
+	keychainKeys.Lock()
+	for _, key := range keychainKeys.keys {
+		keys = append(keys, key)
+	}
+	keychainKeys.Unlock()
+
+	for _, key := range keys {
+		// short values would redact unrelated text, and aren't keys anyway
+		if len(key) >= 8 {
+			s = strings.ReplaceAll(s, key, "REDACTED")
+		}
+	}
+
+	return s
+}
+
+// redactURLError returns err with the API key left out of the URL of the *url.Error the http client failed with,
+// e.g. `Get "https://api.etherscan.io/api?...&apikey=REDACTED": dial tcp: i/o timeout`.
+func redactURLError(err error) error {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		ue.URL = redactAPIKeys(ue.URL)
+	}
+
+	return err
+}
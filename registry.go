@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// RegistryConfig is a registry or factory contract whose getter returns the address of the contract to download,
+// called at download time, e.g. {"address": "eth:0x1F98...", "call": "getPool(address,address,uint24)", "args": [...]}.
+type RegistryConfig struct {
+	Address string   `json:"address"`        // of the registry, chain-prefixed or on the contract's chain
+	Call    string   `json:"call"`           // the getter's signature, e.g. addresses(bytes32)
+	Args    []string `json:"args,omitempty"` // its arguments, bytes32 as hex or as text padded with zeros
+}
+
+// resolve returns the chain and bare address of the registry, on ch when its address isn't chain-prefixed.
+func (r *RegistryConfig) resolve(ch chain) (chain, string, error) {
+	return ConfigContract{Chain: ch, Address: r.Address}.resolve()
+}
+
+// calldata returns the hex calldata of the getter called with the arguments.
+func (r *RegistryConfig) calldata() (string, error) {
+	name, params, ok := strings.Cut(strings.ReplaceAll(r.Call, " ", ""), "(")
+	if !ok || name == "" || !strings.HasSuffix(params, ")") {
+		return "", fmt.Errorf("call %q: want a signature like getPool(address,address,uint24)", r.Call)
+	}
+
+	types := []string{}
+	if params = strings.TrimSuffix(params, ")"); params != "" {
+		types = strings.Split(params, ",")
+	}
+	if len(types) != len(r.Args) {
+		return "", fmt.Errorf("%s takes %d arguments, got %d", r.Call, len(types), len(r.Args))
+	}
+
+	canonical := make([]string, len(types))
+	data := []byte{}
+	for i, typ := range types {
+		word, t, err := encodeArgument(typ, r.Args[i])
+		if err != nil {
+			return "", fmt.Errorf("%s argument %d: %w", r.Call, i+1, err)
+		}
+		canonical[i] = t
+		data = append(data, word...)
+	}
+
+	selector := keccak256([]byte(name + "(" + strings.Join(canonical, ",") + ")"))[:4]
+
+	return "0x" + hex.EncodeToString(append(selector, data...)), nil
+}
+
+// encodeArgument returns the ABI encoding of the static argument arg of type typ, and the canonical name of typ.
+func encodeArgument(typ string, arg string) ([]byte, string, error) {
+	word := make([]byte, 32)
+
+	switch {
+	case typ == "address":
+		if !isAddress(arg) {
+			return nil, "", fmt.Errorf("%w: %s", errBadAddress, arg)
+		}
+		bs, _ := hex.DecodeString(arg[2:])
+		copy(word[12:], bs)
+		return word, typ, nil
+
+	case typ == "bool":
+		v, err := strconv.ParseBool(arg)
+		if err != nil {
+			return nil, "", err
+		}
+		if v {
+			word[31] = 1
+		}
+		return word, typ, nil
+
+	case strings.HasPrefix(typ, "uint") || strings.HasPrefix(typ, "int"):
+		signed := strings.HasPrefix(typ, "int")
+		bits, canonical, err := integerBits(typ, signed)
+		if err != nil {
+			return nil, "", err
+		}
+
+		n, ok := new(big.Int).SetString(arg, 0)
+		if !ok {
+			return nil, "", fmt.Errorf("bad %s: %s", typ, arg)
+		}
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+		if signed {
+			limit.Rsh(limit, 1)
+			if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+				return nil, "", fmt.Errorf("%s out of range: %s", typ, arg)
+			}
+			if n.Sign() < 0 {
+				// two's complement over the 256 bits of the word
+				n = new(big.Int).Add(n, new(big.Int).Lsh(big.NewInt(1), 256))
+			}
+		} else if n.Sign() < 0 || n.Cmp(limit) >= 0 {
+			return nil, "", fmt.Errorf("%s out of range: %s", typ, arg)
+		}
+		n.FillBytes(word)
+		return word, canonical, nil
+
+	case strings.HasPrefix(typ, "bytes") && typ != "bytes":
+		size, err := strconv.Atoi(strings.TrimPrefix(typ, "bytes"))
+		if err != nil || size < 1 || size > 32 {
+			return nil, "", fmt.Errorf("unsupported type %s", typ)
+		}
+
+		bs := []byte(arg)
+		if strings.HasPrefix(arg, "0x") {
+			if bs, err = hex.DecodeString(arg[2:]); err != nil {
+				return nil, "", fmt.Errorf("bad %s: %s", typ, arg)
+			}
+		}
+		if len(bs) > size {
+			return nil, "", fmt.Errorf("%s longer than %d bytes: %s", typ, size, arg)
+		}
+		copy(word, bs)
+		return word, typ, nil
+	}
+
+	return nil, "", fmt.Errorf("unsupported type %s, the getter can only take static types", typ)
+}
+
+// integerBits returns the size of the integer type typ, e.g. 24 for uint24, and its canonical name, uint256 for uint.
+func integerBits(typ string, signed bool) (int, string, error) {
+	prefix := "uint"
+	if signed {
+		prefix = "int"
+	}
+
+	size := strings.TrimPrefix(typ, prefix)
+	if size == "" {
+		return 256, prefix + "256", nil
+	}
+
+	bits, err := strconv.Atoi(size)
+	if err != nil || bits < 8 || bits > 256 || bits%8 != 0 {
+		return 0, "", fmt.Errorf("unsupported type %s", typ)
+	}
+
+	return bits, typ, nil
+}
+
+// resolveDeployments resolves what the config of the deployments ds leaves to the chain: the chain of those without one,
+// e.g. a bare address target, is detected, and the addresses of those with a registry are those their registries return,
+// called concurrently.
+func resolveDeployments(ctx context.Context, ds []*deployment) error {
+	if err := detectChains(ctx, ds, fetchedCache(ctx)); err != nil {
+		return err
+	}
+
+	errs := make([]error, len(ds))
+	wg := sync.WaitGroup{}
+	for i, d := range ds {
+		if d.Registry == nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, d *deployment) {
+			defer wg.Done()
+			if err := resolveRegistry(ctx, d); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", d.Name, err)
+			}
+		}(i, d)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveRegistry sets the address of d to the one its registry returns, through the chain's RPC when configured
+// and the explorer's eth_call proxy otherwise, failing when the registry returns none.
+func resolveRegistry(ctx context.Context, d *deployment) error {
+	r := d.Registry
+
+	ch, registry, err := r.resolve(d.Chain)
+	if err != nil {
+		return fmt.Errorf("registry: %w", err)
+	}
+
+	explorer, ok := blockExploers[ch]
+	if !ok {
+		return unsupportedChain(ch)
+	}
+
+	data, err := r.calldata()
+	if err != nil {
+		return fmt.Errorf("registry: %w", err)
+	}
+
+	var result string
+	if explorer.rpc != "" {
+		err = rpcCall(ctx, explorer.rpc, "eth_call", []interface{}{map[string]string{"to": registry, "data": data}, "latest"}, &result)
+	} else {
+		result, err = ethCall(ctx, explorer, registry, data)
+	}
+	if err != nil {
+		return fmt.Errorf("registry %s %s: %w", registry, r.Call, err)
+	}
+
+	bs, err := hex.DecodeString(strings.TrimPrefix(result, "0x"))
+	if err != nil || len(bs) < 32 {
+		return fmt.Errorf("registry %s %s: bad result %q, want an address", registry, r.Call, result)
+	}
+
+	address := "0x" + hex.EncodeToString(bs[12:32])
+	if strings.Trim(address[2:], "0") == "" {
+		return fmt.Errorf("registry %s %s returned the zero address", registry, r.Call)
+	}
+
+	d.Chain, d.Address = ch, address
+	fmt.Fprintf(humanOut, "%s: %s of %s is %s\n", d.Name, r.Call, registry, address)
+
+	return nil
+}
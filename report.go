@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// runReport collects what a run did to each contract, printed as a summary table at the end of the run
+// and written as an HTML page by --report, e.g. to share the outcome of a scheduled sync with the team.
+type runReport struct {
+	path    string // of the HTML page, none without --report
+	started time.Time
+
+	mu        sync.Mutex
+	contracts []*reportContract
+	byName    map[string]*reportContract
+	total     int
+}
+
+// reportContract is a row of the report.
+type reportContract struct {
+	Name     string
+	Chain    chain
+	Address  string
+	Status   string // download, skip, error or deferred, as the events of --output ndjson
+	Reason   string
+	Error    string
+	Duration time.Duration
+	Dir      string // relative to the report, for the links into the source tree
+	Files    int
+	Changes  []*fileChange // of the files written, against those they replaced
+}
+
+func newRunReport(path string) *runReport {
+	return &runReport{path: path, started: time.Now(), byName: map[string]*reportContract{}}
+}
+
+// contract returns the row of the contract named name, adding it. The caller holds mu.
+func (r *runReport) contract(name string) *reportContract {
+	c, ok := r.byName[name]
+	if !ok {
+		c = &reportContract{Name: name, Status: "download"}
+		r.byName[name] = c
+		r.contracts = append(r.contracts, c)
+	}
+
+	return c
+}
+
+// record adds the outcome of an event of the run.
+func (r *runReport) record(e *event) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e.Event == "summary" {
+		r.total = e.Total
+		return
+	}
+
+	c := r.contract(e.Name)
+	c.Chain, c.Address = e.Chain, e.Address
+	switch e.Event {
+	case "download":
+		// a contract skipped as unverified is downloaded without error too
+		if c.Status != "skip" {
+			c.Status = e.Event
+		}
+		c.Duration = time.Duration(e.DurationMS) * time.Millisecond
+	default:
+		c.Status = e.Event
+		c.Reason = e.Reason
+		c.Error = e.Error
+	}
+}
+
+// files records the files about to be written for d into dir, and for --report how they change the files there.
+func (r *runReport) files(d *deployment, dir string, pending []*pendingFile) error {
+	if r == nil {
+		return nil
+	}
+
+	changes := []*fileChange{}
+	for _, f := range pending {
+		if r.path == "" {
+			break
+		}
+
+		rel, err := filepath.Rel(dir, f.path)
+		if err != nil {
+			return err
+		}
+
+		bs, err := os.ReadFile(f.path)
+		switch {
+		case os.IsNotExist(err):
+			changes = append(changes, &fileChange{Status: "A", Path: filepath.ToSlash(rel)})
+		case err != nil:
+			return err
+		case !bytes.Equal(bs, f.content):
+			changes = append(changes, &fileChange{Status: "M", Path: filepath.ToSlash(rel)})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+
+	link, err := filepath.Rel(filepath.Dir(r.path), dir)
+	if err != nil {
+		if link, err = filepath.Abs(dir); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.contract(d.Name)
+	c.Chain, c.Address = d.Chain, d.Address
+	c.Dir = filepath.ToSlash(link)
+	c.Files += len(pending)
+	c.Changes = append(c.Changes, changes...)
+
+	return nil
+}
+
+// write writes the report into its file, if any.
+func (r *runReport) write() error {
+	if r == nil || r.path == "" {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	page := r.page()
+
+	b := &bytes.Buffer{}
+	if err := reportTemplate.Execute(b, page); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), dirMode); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(r.path, b.Bytes())
+}
+
+func (r *runReport) page() *reportPage {
+	page := &reportPage{Started: r.started.Format(time.RFC3339), Duration: time.Since(r.started).Round(time.Second), Total: r.total, Contracts: r.contracts}
+	for _, c := range r.contracts {
+		page.Statuses.add(c.Status)
+		page.Files += c.Files
+		page.Changes += len(c.Changes)
+	}
+
+	return page
+}
+
+// ANSI colors of the statuses in the summary, all of the same length so the table stays aligned
+var statusColors = map[string]string{"download": "\x1b[32m", "skip": "\x1b[33m", "error": "\x1b[31m", "deferred": "\x1b[33m"}
+
+const colorReset = "\x1b[0m"
+
+// printSummary prints a table of the contracts of the run with their chain, files written, status and duration,
+// and the totals, the statuses colored when color is set.
+func (r *runReport) printSummary(w io.Writer, color bool) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.contracts) == 0 {
+		return
+	}
+
+	header := "STATUS"
+	if color {
+		// the default color, so the header is as long as the colored statuses for the tabwriter
+		header = "\x1b[39m" + header + colorReset
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\nCONTRACT\tCHAIN\tFILES\t%s\tDURATION\n", header)
+	for _, c := range r.contracts {
+		status := reportLabels[c.Status]
+		if color {
+			status = statusColors[c.Status] + status + colorReset
+		}
+		duration := "-"
+		if c.Duration > 0 {
+			duration = c.Duration.String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", c.Name, c.Chain, c.Files, status, duration)
+	}
+	tw.Flush()
+
+	p := r.page()
+	noun := "contracts"
+	if len(r.contracts) == 1 {
+		noun = "contract"
+	}
+	fmt.Fprintf(w, "%d %s in %s: %d downloaded, %d skipped, %d failed, %d deferred, %d files written\n",
+		len(r.contracts), noun, p.Duration, p.Statuses.Downloaded, p.Statuses.Skipped, p.Statuses.Failed, p.Statuses.Deferred, p.Files)
+}
+
+// useColor reports whether w is a terminal which colors may be written to, unless NO_COLOR is set (see no-color.org).
+func useColor(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || !isTerminal(f) {
+		return false
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+
+	return os.Getenv("TERM") != "dumb"
+}
+
+var reportLabels = map[string]string{"download": "downloaded", "skip": "skipped", "error": "failed", "deferred": "deferred"}
+
+type reportPage struct {
+	Started   string
+	Duration  time.Duration
+	Total     int
+	Statuses  reportStatuses
+	Files     int
+	Changes   int
+	Contracts []*reportContract
+}
+
+type reportStatuses struct {
+	Downloaded, Skipped, Failed, Deferred int
+}
+
+func (s *reportStatuses) add(status string) {
+	switch status {
+	case "download":
+		s.Downloaded++
+	case "skip":
+		s.Skipped++
+	case "error":
+		s.Failed++
+	case "deferred":
+		s.Deferred++
+	}
+}
+
+func (c *reportContract) Message() string {
+	return firstNonEmpty(c.Error, c.Reason)
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"link": func(dir string, file string) string {
+		if file == "" {
+			return dir + "/"
+		}
+		return dir + "/" + file
+	},
+	"label": func(status string) string {
+		return reportLabels[status]
+	},
+}).Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>etherscan-downloader run of {{.Started}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { padding: .2em .8em; text-align: left; vertical-align: top; border-bottom: 1px solid #ddd; }
+ul { margin: 0; padding-left: 1em; }
+.error { color: #d73a49; } .skip, .deferred { color: #b08800; } .A { color: #22863a; } .M { color: #005cc5; }
+</style></head><body>
+<h1>run of {{.Started}}</h1>
+<p>{{.Total}} contracts in {{.Duration}}: {{.Statuses.Downloaded}} downloaded, {{.Statuses.Skipped}} skipped, {{.Statuses.Failed}} failed, {{.Statuses.Deferred}} deferred.
+{{.Files}} files written, {{.Changes}} of them added or changed.</p>
+<table>
+<tr><th>contract</th><th>chain</th><th>address</th><th>status</th><th>duration</th><th>files</th><th>changes</th><th>error</th></tr>
+{{range .Contracts}}<tr class="{{.Status}}">
+<td>{{if .Dir}}<a href="{{link .Dir ""}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}</td>
+<td>{{.Chain}}</td><td>{{.Address}}</td><td>{{label .Status}}</td><td>{{if .Duration}}{{.Duration}}{{end}}</td><td>{{if .Files}}{{.Files}}{{end}}</td>
+<td>{{$dir := .Dir}}{{if .Changes}}<ul>{{range .Changes}}<li><span class="{{.Status}}">{{.Status}}</span> <a href="{{link $dir .Path}}">{{.Path}}</a></li>{{end}}</ul>{{end}}</td>
+<td>{{.Message}}</td>
+</tr>
+{{end}}</table>
+</body></html>
+`))
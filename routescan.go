@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+)
+
+// routescanAPI is the api of chains on Routescan's aggregated explorer API, e.g. Avalanche's Snowtrace and many L2s
+// and subnets: Etherscan's API served for every chain at one host, routed by the chain id in the path.
+const routescanAPI = "routescan"
+
+// routescanRate is the requests per second Routescan allows across all its chains, with or without an API key.
+const routescanRate = 2
+
+// Routescan networks, its mainnets and testnets.
+const (
+	routescanMainnet = "mainnet"
+	routescanTestnet = "testnet"
+)
+
+// routescanEndpoint returns the Etherscan-compatible API of the chain ch on Routescan's network.
+// It has no trailing slash, as Routescan doesn't route the //api of an endpoint joined with one.
+func routescanEndpoint(network string, ch chain) string {
+	return fmt.Sprintf("https://api.routescan.io/v2/network/%s/evm/%d/etherscan", network, ch)
+}
+
+// keyOptional reports whether the explorer's API serves requests without an API key at its usual rate,
+// unlike Etherscan's, throttled to the keyless rate.
+func (e blockExplorer) keyOptional() bool {
+	return e.api == zksyncAPI || e.api == routescanAPI
+}
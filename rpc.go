@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// rpcCall calls the JSON-RPC method of the node at endpoint and decodes its result into result.
+func rpcCall(ctx context.Context, endpoint string, method string, params []interface{}, result interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", method, resp.Status)
+	}
+
+	r := &proxyResponse{}
+	if err := json.NewDecoder(limitBody(resp.Body)).Decode(r); err != nil {
+		return fmt.Errorf("decode %s response: %w", method, err)
+	}
+
+	if r.Error != nil {
+		return rpcError(method, r.Error.Message)
+	}
+
+	return json.Unmarshal(r.Result, result)
+}
+
+// checkHasCode fails with errNoCode when there is no code at the address of d on its chain, as reported by the chain's RPC.
+// It does nothing when no RPC is configured for the chain.
+func checkHasCode(ctx context.Context, d *deployment) error {
+	explorer, ok := blockExploers[d.Chain]
+	if !ok || explorer.rpc == "" {
+		return nil
+	}
+
+	code := ""
+	if err := rpcCall(ctx, explorer.rpc, "eth_getCode", []interface{}{d.Address, "latest"}, &code); err != nil {
+		return fmt.Errorf("rpc: %w", err)
+	}
+
+	if code == "" || code == "0x" {
+		return fmt.Errorf("%w at %s on %s, check the chain of the contract", errNoCode, d.Address, d.Chain)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+var errEscapesSandbox = errors.New("refusing to write outside the output directories")
+
+// sandbox confines writes to its root directories, following the symlinks already in the tree.
+type sandbox struct {
+	roots []string // resolved
+}
+
+// newSandbox returns a sandbox of dirs, skipping empty ones.
+func newSandbox(dirs ...string) (*sandbox, error) {
+	s := &sandbox{}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+
+		root, err := resolvePath(dir)
+		if err != nil {
+			return nil, err
+		}
+		s.roots = append(s.roots, root)
+	}
+
+	return s, nil
+}
+
+// check fails with errEscapesSandbox when writing path would write outside the roots,
+// i.e. when path, or the symlinks on its way, resolve outside them.
+func (s *sandbox) check(path string) error {
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return err
+	}
+
+	for _, root := range s.roots {
+		if rel, err := filepath.Rel(root, resolved); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s resolves to %s", errEscapesSandbox, path, resolved)
+}
+
+// checkTree checks every symlink under dir, as files written into dir by name would be written through them.
+func (s *sandbox) checkTree(dir string) error {
+	err := filepath.WalkDir(dir, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if e.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+
+		return s.check(path)
+	})
+
+	return err
+}
+
+// resolvePath returns the absolute path of path with the symlinks of its existing part resolved.
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	rest := []string{}
+	for p := abs; ; p = filepath.Dir(p) {
+		resolved, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			for i := len(rest) - 1; i >= 0; i-- {
+				resolved = filepath.Join(resolved, rest[i])
+			}
+			return resolved, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+
+		if filepath.Dir(p) == p {
+			return abs, nil
+		}
+		rest = append(rest, filepath.Base(p))
+	}
+}
+
+// sandbox returns the sandbox of the downloads of d: contractDir, d's outDir and the shared library directory.
+func (dl *downloader) sandbox(d *deployment) (*sandbox, error) {
+	return newSandbox(dl.contractDir, d.OutDir, dl.libDir)
+}
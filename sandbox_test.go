@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// sandboxTree returns a sandbox of a contracts directory, with a directory outside it and symlinks to both in it.
+func sandboxTree(t *testing.T) (*sandbox, string, string) {
+	t.Helper()
+
+	tmp := t.TempDir()
+	root, outside := filepath.Join(tmp, "contracts"), filepath.Join(tmp, "outside")
+	for _, dir := range []string{filepath.Join(root, "token", "src"), outside} {
+		if err := os.MkdirAll(dir, dirMode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for link, target := range map[string]string{
+		filepath.Join(root, "token", "lib"):      filepath.Join(root, "token", "src"),
+		filepath.Join(root, "token", "escape"):   outside,
+		filepath.Join(root, "token", "relative"): filepath.Join("..", "..", "outside"),
+	} {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symlinks: %s", err)
+		}
+	}
+
+	s, err := newSandbox(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, root, outside
+}
+
+func TestSandboxCheck(t *testing.T) {
+	s, root, outside := sandboxTree(t)
+
+	for _, tt := range []struct {
+		path    string
+		escapes bool
+	}{
+		{filepath.Join(root, "token", "src", "Token.sol"), false},
+		{filepath.Join(root, "token", "new", "dir", "Token.sol"), false},
+		{filepath.Join(root, "token", "lib", "Token.sol"), false},
+		{root, false},
+		// through a symlink to outside the root, absolute or relative
+		{filepath.Join(root, "token", "escape", "Token.sol"), true},
+		{filepath.Join(root, "token", "relative", "Token.sol"), true},
+		// through ".." segments
+		{root + string(filepath.Separator) + filepath.Join("..", "outside", "Token.sol"), true},
+		{root + string(filepath.Separator) + filepath.Join("token", "..", "..", "Token.sol"), true},
+		{filepath.Join(root, "..") + string(filepath.Separator) + "contracts-other", true},
+		// an absolute path elsewhere
+		{filepath.Join(outside, "Token.sol"), true},
+		{filepath.Join(string(filepath.Separator), "etc", "passwd"), true},
+	} {
+		err := s.check(tt.path)
+		if escapes := errors.Is(err, errEscapesSandbox); escapes != tt.escapes || (err != nil && !escapes) {
+			t.Errorf("check(%s) = %v, want escaping %t", tt.path, err, tt.escapes)
+		}
+	}
+}
+
+func TestSandboxCheckTree(t *testing.T) {
+	s, root, _ := sandboxTree(t)
+
+	if err := s.checkTree(filepath.Join(root, "token", "src")); err != nil {
+		t.Errorf("a tree without symlinks: %s", err)
+	}
+	if err := s.checkTree(filepath.Join(root, "missing")); err != nil {
+		t.Errorf("a missing tree: %s", err)
+	}
+	if err := s.checkTree(filepath.Join(root, "token")); !errors.Is(err, errEscapesSandbox) {
+		t.Errorf("a tree with symlinks to outside the root: %v", err)
+	}
+
+	for _, link := range []string{"escape", "relative"} {
+		if err := os.Remove(filepath.Join(root, "token", link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.checkTree(filepath.Join(root, "token")); err != nil {
+		t.Errorf("a tree with a symlink inside the root: %s", err)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const sbomFile = "sbom.json"
+
+// CycloneDX is a CycloneDX 1.5 BOM.
+type CycloneDX struct {
+	BOMFormat   string                `json:"bomFormat"`
+	SpecVersion string                `json:"specVersion"`
+	Version     int                   `json:"version"`
+	Metadata    *CycloneDXMetadata    `json:"metadata"`
+	Components  []*CycloneDXComponent `json:"components"`
+}
+
+type CycloneDXMetadata struct {
+	Timestamp string `json:"timestamp"`
+}
+
+type CycloneDXComponent struct {
+	Type       string                `json:"type"`
+	Name       string                `json:"name"`
+	Version    string                `json:"version,omitempty"`
+	Licenses   []*CycloneDXLicense   `json:"licenses,omitempty"`
+	Properties []*CycloneDXProperty  `json:"properties,omitempty"`
+	Components []*CycloneDXComponent `json:"components,omitempty"`
+}
+
+type CycloneDXLicense struct {
+	Expression string `json:"expression"`
+}
+
+type CycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// downloadedContract is a contract found in contractDir by its metadata.json.
+type downloadedContract struct {
+	Dir      string
+	Metadata *Metadata
+	Files    []string
+}
+
+// findDownloaded returns the contracts downloaded into contractDir along with their source files,
+// attributing each file to the innermost contract directory containing it.
+func findDownloaded(contractDir string) ([]*downloadedContract, error) {
+	contracts := map[string]*downloadedContract{}
+	files := []string{}
+
+	err := filepath.WalkDir(contractDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		if d.Name() == metadataFile {
+			m, err := loadMetadata(path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+
+			contracts[filepath.Dir(path)] = &downloadedContract{Dir: filepath.Dir(path), Metadata: m}
+			return nil
+		}
+
+		if isSourceFile(path) {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		for dir := filepath.Dir(file); ; dir = filepath.Dir(dir) {
+			if c, ok := contracts[dir]; ok {
+				c.Files = append(c.Files, file)
+				break
+			}
+
+			if dir == contractDir || dir == "." || dir == filepath.Dir(dir) {
+				break
+			}
+		}
+	}
+
+	result := make([]*downloadedContract, 0, len(contracts))
+	for _, c := range contracts {
+		sort.Strings(c.Files)
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Dir < result[j].Dir })
+
+	return result, nil
+}
+
+func isSourceFile(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".sol" || ext == ".vy"
+}
+
+func loadMetadata(path string) (*Metadata, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Metadata{}
+	if err := json.Unmarshal(bs, m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// runSBOM writes a CycloneDX SBOM of every contract in contractDir to contractDir/sbom.json
+// and prints a summary of the licenses found.
+func runSBOM(ctx context.Context, args []string) error {
+	c, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	contractDir := c.ContractDir
+
+	contracts, err := findDownloaded(contractDir)
+	if err != nil {
+		return err
+	}
+
+	bom := &CycloneDX{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Metadata:    &CycloneDXMetadata{Timestamp: time.Now().UTC().Format(time.RFC3339)},
+		Components:  []*CycloneDXComponent{},
+	}
+	summary := map[string]int{}
+
+	for _, c := range contracts {
+		rel, err := filepath.Rel(contractDir, c.Dir)
+		if err != nil {
+			return err
+		}
+
+		component := &CycloneDXComponent{
+			Type:     "library",
+			Name:     filepath.ToSlash(rel),
+			Version:  c.Metadata.Address,
+			Licenses: cycloneDXLicenses(spdxLicense(c.Metadata.LicenseType)),
+			Properties: []*CycloneDXProperty{
+				{Name: "ethereum:chainId", Value: fmt.Sprintf("%d", c.Metadata.Chain)},
+				{Name: "ethereum:contractName", Value: c.Metadata.ContractName},
+				{Name: "explorer:licenseType", Value: c.Metadata.LicenseType},
+			},
+		}
+
+		for _, file := range c.Files {
+			bs, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+
+			license := sourceLicense(string(bs))
+			if license == "" {
+				summary["(none)"]++
+			} else {
+				summary[license]++
+			}
+
+			fileRel, err := filepath.Rel(c.Dir, file)
+			if err != nil {
+				return err
+			}
+
+			component.Components = append(component.Components, &CycloneDXComponent{
+				Type:     "file",
+				Name:     filepath.ToSlash(fileRel),
+				Licenses: cycloneDXLicenses(license),
+			})
+		}
+
+		bom.Components = append(bom.Components, component)
+	}
+
+	if err := writeJSON(filepath.Join(contractDir, sbomFile), bom); err != nil {
+		return err
+	}
+
+	licenses := make([]string, 0, len(summary))
+	for license := range summary {
+		licenses = append(licenses, license)
+	}
+	sort.Strings(licenses)
+
+	fmt.Printf("%d contracts, licenses by file:\n", len(contracts))
+	for _, license := range licenses {
+		fmt.Printf("  %-*s %d\n", longest(licenses), license, summary[license])
+	}
+
+	return nil
+}
+
+func cycloneDXLicenses(expression string) []*CycloneDXLicense {
+	if expression == "" {
+		return nil
+	}
+
+	return []*CycloneDXLicense{{Expression: expression}}
+}
+
+func longest(ss []string) int {
+	n := 0
+	for _, s := range ss {
+		if len(s) > n {
+			n = len(s)
+		}
+	}
+
+	return n
+}
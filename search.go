@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// searchCommand greps the downloaded sources of the configured contracts, printing the contract, file and line of each match.
+func searchCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	ignoreCase := fs.Bool("i", false, "ignore case")
+	fixed := fs.Bool("F", false, "match the pattern as a fixed string instead of a regular expression")
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) == 0 {
+			return errors.New("usage: search [-i] [-F] <pattern> [target...]")
+		}
+
+		pattern := args[0]
+		if *fixed {
+			pattern = regexp.QuoteMeta(pattern)
+		}
+		if *ignoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("bad pattern: %w", err)
+		}
+
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		deployments, err := c.deployments(ctx, args[1:])
+		if err != nil {
+			return err
+		}
+
+		w := bufio.NewWriter(os.Stdout)
+		defer w.Flush()
+
+		matches := 0
+		for _, d := range deployments {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			n, err := searchDir(w, d.Name, d.dir(c.ContractDir), re)
+			if err != nil {
+				return fmt.Errorf("%s: %w", d.Name, err)
+			}
+			matches += n
+		}
+
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%d matches\n", matches)
+
+		return nil
+	}
+}
+
+// searchDir prints the lines of the source files in dir matching re as "<name> <file>:<line>: <text>",
+// returning the number of matches. A contract not downloaded has none.
+func searchDir(w *bufio.Writer, name string, dir string, re *regexp.Regexp) (int, error) {
+	matches := 0
+	err := filepath.WalkDir(dir, func(path string, e fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if e.IsDir() || !isSourceFile(path) {
+			return nil
+		}
+
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		for i, line := range bytes.Split(bs, []byte("\n")) {
+			if re.Match(line) {
+				matches++
+				fmt.Fprintf(w, "%s %s:%d: %s\n", name, filepath.ToSlash(rel), i+1, strings.TrimSpace(string(line)))
+			}
+		}
+
+		return nil
+	})
+
+	return matches, err
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	selectorsFile  = "selectors.json"
+	signaturesFile = "signatures.txt"
+)
+
+// writeSelectors writes the 4-byte selectors of the contract's functions as selectors.json (selector → signature)
+// and signatures.txt (one "selector signature" per line).
+func writeSelectors(dir string, rawCode *RawCode) error {
+	entries, err := parseABI(rawCode.Abi)
+	if err != nil {
+		return fmt.Errorf("parse ABI: %w", err)
+	}
+
+	selectors := map[string]string{}
+	for _, e := range entries {
+		if e.Type == "function" {
+			selectors[e.selector()] = e.signature()
+		}
+	}
+
+	if err := writeJSON(filepath.Join(dir, selectorsFile), selectors); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, signaturesFile), []byte(formatSignatures(selectors)), fileMode)
+}
+
+func formatSignatures(selectors map[string]string) string {
+	keys := make([]string, 0, len(selectors))
+	for selector := range selectors {
+		keys = append(keys, selector)
+	}
+	sort.Strings(keys)
+
+	b := &strings.Builder{}
+	for _, selector := range keys {
+		fmt.Fprintf(b, "%s %s\n", selector, selectors[selector])
+	}
+
+	return b.String()
+}
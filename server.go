@@ -0,0 +1,269 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// verifiedSource is the verified source tree of a contract as served by serve.
+type verifiedSource struct {
+	Metadata *Metadata         `json:"metadata"`
+	Sources  map[string]string `json:"sources"`
+}
+
+// sourceService fetches verified sources through a shared rate limit and caches them.
+type sourceService struct {
+	limit <-chan time.Time
+	cache cache
+}
+
+// newSourceService returns a service making at most rate explorer calls per second, caching the sources in c.
+func newSourceService(rate float64, c cache) *sourceService {
+	return &sourceService{
+		limit: time.Tick(time.Duration(float64(time.Second) / rate)),
+		cache: c,
+	}
+}
+
+// source returns the verified sources of address on ch.
+func (s *sourceService) source(ctx context.Context, ch chain, address string) (*verifiedSource, error) {
+	key := contractKey(ch, address)
+
+	cached, ok, err := s.cache.get(key)
+	if err != nil {
+		// the explorer is still there when the cache is down
+		log.Printf("cache: %v", err)
+	}
+	if ok {
+		src := &verifiedSource{}
+		if err := json.Unmarshal(cached, src); err == nil {
+			cacheRequests.inc("hit")
+			return src, nil
+		}
+	}
+	cacheRequests.inc("miss")
+
+	wait := time.Now()
+	<-s.limit
+	rateLimitWait.since(wait)
+
+	start := time.Now()
+	d := &deployment{Name: address, Chain: ch, Address: address}
+	rawCodes, err := fetchRawCode(ctx, d)
+	if err != nil {
+		downloadFailures.inc("explorer")
+		return nil, err
+	}
+
+	if isUnverified(rawCodes) {
+		downloadFailures.inc("not_verified")
+		return nil, errNotVerified
+	}
+
+	sourceCodes, err := parseContractCode(rawCodes)
+	if err != nil {
+		downloadFailures.inc("parse")
+		return nil, err
+	}
+	downloadDuration.since(start)
+
+	src := &verifiedSource{Metadata: newMetadata(d, rawCodes[0], sourceCodes[0]), Sources: map[string]string{}}
+	for _, sourceCode := range sourceCodes {
+		for p, source := range sourceCode.Sources {
+			src.Sources[p] = source.Content
+		}
+	}
+
+	bs, err := json.Marshal(src)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cache.set(key, bs); err != nil {
+		log.Printf("cache: %v", err)
+	}
+
+	return src, nil
+}
+
+// serverShutdownTimeout bounds the wait for in-flight requests once serve is interrupted.
+const serverShutdownTimeout = 10 * time.Second
+
+// serveCommand serves verified sources over HTTP at GET /source/{chainId}/{address},
+// as JSON or, with ?format=zip or Accept: application/zip, as a zip of the source tree,
+// and with TLS, as the gRPC service of proto/downloader.proto.
+func serveCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	rate := fs.Float64("rate", 5, "maximum explorer calls per second")
+	ui := fs.Bool("ui", false, "also serve a read-only web UI of the contracts downloaded into contractDir at /ui/")
+	tlsCert := fs.String("tls-cert", "", "serve HTTPS and HTTP/2, and with it gRPC, with this certificate file")
+	tlsKey := fs.String("tls-key", "", "the key file of --tls-cert")
+
+	return func(ctx context.Context, args []string) error {
+		if *rate <= 0 {
+			return fmt.Errorf("--rate must be positive")
+		}
+		if (*tlsCert == "") != (*tlsKey == "") {
+			return &configError{errors.New("--tls-cert and --tls-key go together")}
+		}
+
+		c, err := loadConfig()
+		if errors.Is(err, os.ErrNotExist) && !*ui {
+			// sources can be served without a config
+			c, err = &Config{}, nil
+		}
+		if err != nil {
+			return err
+		}
+
+		sourceCache, err := c.Cache.open()
+		if err != nil {
+			return err
+		}
+		service := newSourceService(*rate, sourceCache)
+
+		mux := http.NewServeMux()
+		mux.Handle("/source/", &sourceHandler{service: service})
+		mux.HandleFunc("/metrics", metricsHandler)
+		mux.Handle(grpcService, &grpcHandler{service: service})
+
+		if *ui {
+			mux.Handle("/ui/", &uiHandler{contractDir: c.ContractDir, normalize: c.Normalize, service: service})
+		}
+
+		srv := &http.Server{Addr: *addr, Handler: mux}
+		shutdown := make(chan error, 1)
+		go func() {
+			<-ctx.Done()
+			// in-flight requests finish unless they outlast serverShutdownTimeout
+			sctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+			defer cancel()
+			shutdown <- srv.Shutdown(sctx)
+		}()
+
+		log.Printf("listening on %s", *addr)
+
+		if *tlsCert != "" {
+			// net/http negotiates HTTP/2, which gRPC needs, over TLS
+			err = srv.ListenAndServeTLS(*tlsCert, *tlsKey)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+
+		return <-shutdown
+	}
+}
+
+type sourceHandler struct {
+	service *sourceService
+}
+
+func (h *sourceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/source/"), "/")
+	if len(parts) != 2 {
+		http.Error(w, "want /source/{chainId}/{address}", http.StatusNotFound)
+		return
+	}
+
+	id, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid chain id: "+parts[0], http.StatusBadRequest)
+		return
+	}
+	ch := chain(id)
+
+	if _, ok := blockExploers[ch]; !ok {
+		http.Error(w, unsupportedChain(ch).Error(), http.StatusNotFound)
+		return
+	}
+
+	address := parts[1]
+	if !isAddress(address) {
+		http.Error(w, "invalid address: "+address, http.StatusBadRequest)
+		return
+	}
+
+	ctx, span := startSpan(extractTraceparent(r.Context(), r.Header), "GET /source", spanServer, "chain.id", parts[0], "contract.address", address)
+	src, err := h.service.source(ctx, ch, address)
+	span.end(err)
+	if errors.Is(err, errNotVerified) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		// errors may contain the request URL and with it the API key
+		log.Printf("%s: %s", address, redactAPIKeys(err.Error()))
+		http.Error(w, "explorer request failed", http.StatusBadGateway)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "zip" || r.Header.Get("Accept") == "application/zip" {
+		writeSourceZip(w, src)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeJSON(w, src)
+}
+
+// writeSourceZip writes the sources and metadata.json of src as a zip.
+func writeSourceZip(w http.ResponseWriter, src *verifiedSource) {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", src.Metadata.Address+".zip"))
+
+	zw := zip.NewWriter(w)
+
+	paths := make([]string, 0, len(src.Sources))
+	for p := range src.Sources {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		f, err := zw.Create(path.Clean("/" + p)[1:])
+		if err != nil {
+			log.Print(err)
+			return
+		}
+		if _, err := f.Write([]byte(src.Sources[p])); err != nil {
+			log.Print(err)
+			return
+		}
+	}
+
+	if f, err := zw.Create(metadataFile); err == nil {
+		encodeJSON(f, src.Metadata)
+	}
+
+	if err := zw.Close(); err != nil {
+		log.Print(err)
+	}
+}
+
+// encodeJSON writes v to w indented like writeJSON.
+func encodeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
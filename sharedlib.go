@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// libraryPackage returns the package a dependency source key belongs to, as the remapping prefix of the package
+// and its directory under the shared library directory, e.g. "@openzeppelin/contracts/" and "@openzeppelin/contracts"
+// for "@openzeppelin/contracts/token/ERC20/ERC20.sol". Sources which are not dependencies have no package.
+func libraryPackage(key string) (string, string, bool) {
+	rest := key
+	if i := strings.LastIndex(key, "node_modules/"); i >= 0 {
+		rest = key[i+len("node_modules/"):]
+	} else if strings.HasPrefix(key, "lib/") {
+		rest = strings.TrimPrefix(key, "lib/")
+	} else if !strings.HasPrefix(key, "@") {
+		return "", "", false
+	}
+
+	segments := 1
+	if strings.HasPrefix(rest, "@") {
+		segments = 2
+	}
+
+	parts := strings.SplitN(rest, "/", segments+1)
+	if len(parts) <= segments {
+		return "", "", false
+	}
+
+	pkg := strings.Join(parts[:segments], "/")
+	prefix := key[:len(key)-len(rest)] + pkg + "/"
+
+	return prefix, pkg, true
+}
+
+// shareLibraries decides which dependency sources are written once to the shared library directory instead of dir.
+// It returns their destinations by source key and the remappings dir needs to find them.
+// A package stays in dir when any of its files already exists in the shared directory with different content.
+func (dl *downloader) shareLibraries(dir string, sources Sources) (map[string]string, []string, error) {
+	if dl.libDir == "" {
+		return nil, nil, nil
+	}
+
+	type pkg struct {
+		dir   string
+		files map[string]string
+		local bool
+	}
+
+	pkgs := map[string]*pkg{}
+	for key, source := range sources {
+		prefix, pkgDir, ok := libraryPackage(key)
+		if !ok {
+			continue
+		}
+
+		p, ok := pkgs[prefix]
+		if !ok {
+			p = &pkg{dir: filepath.Join(dl.libDir, sourcePath(pkgDir)), files: map[string]string{}}
+			pkgs[prefix] = p
+		}
+
+		dst := filepath.Join(p.dir, sourcePath(strings.TrimPrefix(key, prefix)))
+		p.files[key] = dst
+
+		existing, err := os.ReadFile(dst)
+		if err == nil && !bytes.Equal(existing, []byte(source.Content)) {
+			p.local = true
+		} else if err != nil && !os.IsNotExist(err) {
+			return nil, nil, err
+		}
+	}
+
+	shared := map[string]string{}
+	remappings := []string{}
+	for prefix, p := range pkgs {
+		if p.local {
+			continue
+		}
+
+		for key, dst := range p.files {
+			shared[key] = dst
+		}
+
+		target, err := filepath.Rel(dir, p.dir)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		remappings = append(remappings, prefix+"="+filepath.ToSlash(target)+"/")
+	}
+	sort.Strings(remappings)
+
+	return shared, remappings, nil
+}
+
+// appendRemappings adds remappings to dir/remappings.txt.
+func appendRemappings(dir string, remappings []string) error {
+	if len(remappings) == 0 {
+		return nil
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, remappingsFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, fileMode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.WriteString(strings.Join(remappings, "\n") + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	openchainLookupURL = "https://api.openchain.xyz/signature-database/v1/lookup"
+	fourByteURL        = "https://www.4byte.directory/api/v1/signatures/"
+)
+
+// lookupSignatures returns the best-guess signatures of selectors from the openchain signature database,
+// falling back to 4byte.directory for the selectors it doesn't know. Unknown selectors are left out.
+func lookupSignatures(ctx context.Context, selectors []string) (map[string]string, error) {
+	signatures := map[string]string{}
+	if len(selectors) == 0 {
+		return signatures, nil
+	}
+
+	openchain := &struct {
+		OK     bool `json:"ok"`
+		Result struct {
+			Function map[string][]struct {
+				Name string `json:"name"`
+			} `json:"function"`
+		} `json:"result"`
+	}{}
+	u := openchainLookupURL + "?" + url.Values{"function": {strings.Join(selectors, ",")}, "filter": {"true"}}.Encode()
+	if err := getJSON(ctx, u, openchain); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: openchain signature lookup: %s\n", err)
+	}
+	for selector, candidates := range openchain.Result.Function {
+		if len(candidates) > 0 {
+			signatures[selector] = candidates[0].Name
+		}
+	}
+
+	for _, selector := range selectors {
+		if _, ok := signatures[selector]; ok {
+			continue
+		}
+
+		fourByte := &struct {
+			Results []struct {
+				TextSignature string `json:"text_signature"`
+			} `json:"results"`
+		}{}
+		// the oldest submission of a selector is the most likely one, later ones are often collisions
+		u := fourByteURL + "?" + url.Values{"hex_signature": {selector}, "ordering": {"created_at"}}.Encode()
+		if err := getJSON(ctx, u, fourByte); err != nil {
+			return nil, fmt.Errorf("4byte lookup: %w", err)
+		}
+		if len(fourByte.Results) > 0 {
+			signatures[selector] = fourByte.Results[0].TextSignature
+		}
+	}
+
+	return signatures, nil
+}
+
+// writeGuessedSignatures writes the signatures of the unverified bytecode's dispatcher selectors
+// looked up in signature databases as selectors.json and signatures.txt.
+func writeGuessedSignatures(ctx context.Context, dir string, bytecode []byte) (int, error) {
+	signatures, err := lookupSignatures(ctx, dispatcherSelectors(bytecode))
+	if err != nil {
+		return 0, err
+	}
+
+	if err := writeJSON(filepath.Join(dir, selectorsFile), signatures); err != nil {
+		return 0, err
+	}
+
+	return len(signatures), os.WriteFile(filepath.Join(dir, signaturesFile), []byte(formatSignatures(signatures)), fileMode)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// SigningConfig signs SHA256SUMS of each downloaded contract, so downstream can verify the sources came from this pipeline.
+type SigningConfig struct {
+	Tool        string `json:"tool"`                  // minisign or cosign
+	Key         string `json:"key"`                   // path of the secret key
+	PasswordEnv string `json:"passwordEnv,omitempty"` // environment variable holding the key's password
+}
+
+func (s *SigningConfig) validate() error {
+	if s == nil {
+		return nil
+	}
+
+	switch s.Tool {
+	case "minisign", "cosign":
+	default:
+		return fmt.Errorf("signing.tool: unknown signing tool: %q, want minisign or cosign", s.Tool)
+	}
+
+	if s.Key == "" {
+		return fmt.Errorf("signing.key: the %s key is not configured", s.Tool)
+	}
+
+	return nil
+}
+
+// signatureFile is the name of the signature of SHA256SUMS written by the tool.
+func (s *SigningConfig) signatureFile() string {
+	if s.Tool == "cosign" {
+		return checksumsFile + ".sig"
+	}
+	return checksumsFile + ".minisig"
+}
+
+// sign signs dir/SHA256SUMS, writing the signature next to it.
+func (s *SigningConfig) sign(ctx context.Context, dir string) error {
+	sums := filepath.Join(dir, checksumsFile)
+	sig := filepath.Join(dir, s.signatureFile())
+	password := ""
+	if s.PasswordEnv != "" {
+		password = os.Getenv(s.PasswordEnv)
+	}
+
+	var cmd *exec.Cmd
+	switch s.Tool {
+	case "minisign":
+		cmd = exec.CommandContext(ctx, "minisign", "-S", "-s", s.Key, "-m", sums, "-x", sig)
+		if s.PasswordEnv != "" {
+			// minisign reads the password from stdin when it isn't a terminal
+			cmd.Stdin = strings.NewReader(password + "\n")
+		} else {
+			cmd.Stdin = os.Stdin
+		}
+	case "cosign":
+		cmd = exec.CommandContext(ctx, "cosign", "sign-blob", "--yes", "--key", s.Key, "--output-signature", sig, sums)
+		cmd.Env = os.Environ()
+		if s.PasswordEnv != "" {
+			cmd.Env = append(cmd.Env, "COSIGN_PASSWORD="+password)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sign %s with %s: %w: %s", sums, s.Tool, err, strings.TrimSpace(out.String()))
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const snapshotManifestFile = "SNAPSHOT.json"
+
+// Snapshot is the manifest of a snapshot archive, in SNAPSHOT.json at its root.
+type Snapshot struct {
+	CreatedAt string              `json:"createdAt"`
+	Root      string              `json:"root"` // Merkle root of every file of the contracts, see merkleRoot
+	Contracts []*SnapshotContract `json:"contracts"`
+}
+
+// SnapshotContract is a contract of a snapshot, its files under Dir in the archive.
+type SnapshotContract struct {
+	Name    string          `json:"name"`
+	ChainID chain           `json:"chainId"`
+	Address string          `json:"address"`
+	Dir     string          `json:"dir"`
+	Root    string          `json:"root"` // Merkle root of its files alone
+	Files   []*SnapshotFile `json:"files"`
+}
+
+// SnapshotFile is a file of a snapshot, Path relative to the archive's root.
+type SnapshotFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// snapshotCommand bundles the downloaded contracts, their sources, metadata and the other files of their directories,
+// into a timestamped tar.gz with a manifest of the hash of every file and a Merkle root of them all,
+// to prove later exactly what was verified on a given date.
+func snapshotCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	out := fs.String("out", "snapshots", "directory to write the archive into")
+
+	return func(ctx context.Context, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		names := args
+		if len(names) == 0 {
+			names = c.names()
+		}
+
+		deployments, err := c.deployments(ctx, names)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		snapshot := &Snapshot{CreatedAt: now.Format(time.RFC3339), Contracts: []*SnapshotContract{}}
+		contents := map[string][]byte{}
+		for _, d := range deployments {
+			dir := d.dir(c.ContractDir)
+			if _, err := os.Stat(filepath.Join(dir, metadataFile)); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: %s is not downloaded, left out of the snapshot\n", d.Name)
+				continue
+			}
+
+			sc, err := snapshotContract(d, dir, contents)
+			if err != nil {
+				return fmt.Errorf("%s: %w", d.Name, err)
+			}
+			snapshot.Contracts = append(snapshot.Contracts, sc)
+		}
+		if len(snapshot.Contracts) == 0 {
+			return errors.New("no downloaded contract to snapshot")
+		}
+
+		leaves := []*SnapshotFile{}
+		for _, sc := range snapshot.Contracts {
+			leaves = append(leaves, sc.Files...)
+		}
+		snapshot.Root = merkleRoot(leaves)
+
+		archive, err := snapshotArchive(snapshot, contents)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(*out, dirMode); err != nil {
+			return err
+		}
+
+		dst := filepath.Join(*out, fmt.Sprintf("snapshot-%s-%s.tar.gz", now.Format("20060102T150405Z"), snapshot.Root[:12]))
+		if err := writeFileAtomic(dst, archive); err != nil {
+			return err
+		}
+
+		noun := "contracts"
+		if len(snapshot.Contracts) == 1 {
+			noun = "contract"
+		}
+		fmt.Printf("wrote %d %s, %d files to %s\nroot %s\n", len(snapshot.Contracts), noun, len(leaves), dst, snapshot.Root)
+
+		return nil
+	}
+}
+
+// snapshotContract reads the files under dir of the download of d into contents, keyed by their path in the archive.
+// Symlinks, e.g. of --dedup symlink, are stored as the files they point to.
+func snapshotContract(d *deployment, dir string, contents map[string][]byte) (*SnapshotContract, error) {
+	sc := &SnapshotContract{Name: d.Name, ChainID: d.Chain, Address: d.Address, Dir: path.Join("contracts", filepath.ToSlash(d.folder())), Files: []*SnapshotFile{}}
+
+	err := filepath.WalkDir(dir, func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if e.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		bs, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		name := path.Join(sc.Dir, filepath.ToSlash(rel))
+		if _, ok := contents[name]; ok {
+			return fmt.Errorf("%s is in the snapshot twice", name)
+		}
+		contents[name] = bs
+
+		sum := sha256.Sum256(bs)
+		sc.Files = append(sc.Files, &SnapshotFile{Path: name, SHA256: hex.EncodeToString(sum[:])})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sc.Root = merkleRoot(sc.Files)
+
+	return sc, nil
+}
+
+// merkleRoot returns the hex Merkle root of files in the order of their paths: each leaf is
+// sha256(0x00 || path || 0x00 || sha256(content)), each node sha256(0x01 || left || right),
+// and the last node of an odd level is carried up as it is. The root of no files is sha256 of nothing.
+func merkleRoot(files []*SnapshotFile) string {
+	sorted := append([]*SnapshotFile{}, files...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+
+	level := [][]byte{}
+	for _, f := range sorted {
+		sum, _ := hex.DecodeString(f.SHA256)
+		leaf := sha256.Sum256(append(append(append([]byte{0}, f.Path...), 0), sum...))
+		level = append(level, leaf[:])
+	}
+
+	if len(level) == 0 {
+		sum := sha256.Sum256(nil)
+		return hex.EncodeToString(sum[:])
+	}
+
+	for len(level) > 1 {
+		next := [][]byte{}
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			node := sha256.Sum256(append(append([]byte{1}, level[i]...), level[i+1]...))
+			next = append(next, node[:])
+		}
+		level = next
+	}
+
+	return hex.EncodeToString(level[0])
+}
+
+// snapshotArchive returns the tar.gz of the manifest and contents, the same bytes for the same snapshot:
+// the files sorted by path, with fixed modes and times.
+func snapshotArchive(snapshot *Snapshot, contents map[string][]byte) ([]byte, error) {
+	manifest, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(contents))
+	for name := range contents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b := &bytes.Buffer{}
+	gw := gzip.NewWriter(b)
+	tw := tar.NewWriter(gw)
+
+	write := func(name string, bs []byte) error {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(bs)), ModTime: time.Unix(0, 0), Typeflag: tar.TypeReg, Format: tar.FormatPAX}); err != nil {
+			return err
+		}
+		_, err := tw.Write(bs)
+		return err
+	}
+
+	if err := write(snapshotManifestFile, append(manifest, '\n')); err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if err := write(name, contents[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
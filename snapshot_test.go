@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestMerkleRoot(t *testing.T) {
+	// sha256 of "a", "b" and "c"
+	a := &SnapshotFile{Path: "a.sol", SHA256: "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"}
+	b := &SnapshotFile{Path: "b.sol", SHA256: "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d"}
+	c := &SnapshotFile{Path: "c.sol", SHA256: "2e7d2c03a9507ae265ecf5b5356885a53393a2029d241394997265a1a25aefc6"}
+
+	for _, tt := range []struct {
+		name  string
+		files []*SnapshotFile
+		want  string
+	}{
+		{"none", nil, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"one", []*SnapshotFile{a}, "758d5ab93af5558657944da6a3fe9ad988000e7fc1a5b456e194bd85e814aaf0"},
+		{"two", []*SnapshotFile{a, b}, "65bb515863bae28e9af71989db2bfeca4cfc0b94cde7c671410059ebc8fc1743"},
+		{"unsorted", []*SnapshotFile{b, a}, "65bb515863bae28e9af71989db2bfeca4cfc0b94cde7c671410059ebc8fc1743"},
+		{"odd", []*SnapshotFile{c, a, b}, "299922ba783e2615c7f1f335a4d472b97964101e37d5c599376ed5c48988c388"},
+	} {
+		if got := merkleRoot(tt.files); got != tt.want {
+			t.Errorf("%s: merkleRoot = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
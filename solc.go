@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const solcBinURL = "https://binaries.soliditylang.org"
+
+// SolcList is the build list (list.json) of a solc-bin platform directory.
+type SolcList struct {
+	Builds []*SolcBuild `json:"builds"`
+}
+
+type SolcBuild struct {
+	Path        string `json:"path"`
+	LongVersion string `json:"longVersion"`
+}
+
+// SolcOutput is the standard-json output of solc.
+type SolcOutput struct {
+	Errors    []*SolcError                        `json:"errors"`
+	Contracts map[string]map[string]*SolcContract `json:"contracts"`
+	Sources   map[string]*SolcSource              `json:"sources"`
+}
+
+type SolcContract struct {
+	ABI           json.RawMessage `json:"abi"`
+	EVM           *SolcEVM        `json:"evm"`
+	Devdoc        *NatSpec        `json:"devdoc"`
+	Userdoc       *NatSpec        `json:"userdoc"`
+	StorageLayout json.RawMessage `json:"storageLayout"`
+	Metadata      string          `json:"metadata"`
+}
+
+type SolcEVM struct {
+	Bytecode         *SolcBytecode `json:"bytecode"`
+	DeployedBytecode *SolcBytecode `json:"deployedBytecode"`
+}
+
+type SolcBytecode struct {
+	Object              string                     `json:"object"`
+	LinkReferences      json.RawMessage            `json:"linkReferences"`
+	ImmutableReferences map[string][]*SolcCodeSpan `json:"immutableReferences"`
+}
+
+type SolcCodeSpan struct {
+	Start  int `json:"start"`
+	Length int `json:"length"`
+}
+
+type SolcError struct {
+	Severity         string `json:"severity"`
+	FormattedMessage string `json:"formattedMessage"`
+}
+
+// solcPlatform returns the solc-bin platform directory of the running OS and architecture.
+// macosx-amd64 serves arm64 Macs too, its builds being universal since 0.8.24 and run by Rosetta 2 before,
+// while linux-arm64 only has the builds of recent versions.
+func solcPlatform() (string, error) {
+	switch runtime.GOOS + "/" + runtime.GOARCH {
+	case "linux/amd64":
+		return "linux-amd64", nil
+	case "linux/arm64":
+		return "linux-arm64", nil
+	case "darwin/amd64", "darwin/arm64":
+		return "macosx-amd64", nil
+	case "windows/amd64":
+		return "windows-amd64", nil
+	}
+
+	return "", fmt.Errorf("no solc builds for %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+// cachedSolcPath returns where the solc binary of version (e.g. "0.8.15+commit.e14f2714") is cached in the user cache directory.
+func cachedSolcPath(version string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(cacheDir, "etherscan-downloader", "solc", "solc-"+version)
+	if runtime.GOOS == "windows" {
+		path += ".exe"
+	}
+
+	return path, nil
+}
+
+// solcPath returns the path of the solc binary of compilerVersion (e.g. "v0.8.15+commit.e14f2714"),
+// downloading it from solc-bin into the user cache directory if needed.
+func solcPath(ctx context.Context, compilerVersion string) (string, error) {
+	if strings.HasPrefix(compilerVersion, "vyper") {
+		return "", fmt.Errorf("not a solc version: %s", compilerVersion)
+	}
+
+	version := strings.TrimPrefix(compilerVersion, "v")
+
+	path, err := cachedSolcPath(version)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	platform, err := solcPlatform()
+	if err != nil {
+		return "", err
+	}
+
+	list := &SolcList{}
+	if err := getJSON(ctx, solcBinURL+"/"+platform+"/list.json", list); err != nil {
+		return "", err
+	}
+
+	var build *SolcBuild
+	for _, b := range list.Builds {
+		if b.LongVersion == version {
+			build = b
+			break
+		}
+	}
+
+	if build == nil {
+		return "", fmt.Errorf("solc %s is not available for %s", version, platform)
+	}
+
+	resp, err := httpGet(ctx, solcBinURL+"/"+platform+"/"+build.Path)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download solc %s: %s", version, resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), dirMode); err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".solc-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return "", err
+	}
+
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	if err := os.Chmod(f.Name(), 0o755); err != nil {
+		return "", err
+	}
+
+	return path, os.Rename(f.Name(), path)
+}
+
+// compileStandardJSON compiles input with solc compilerVersion.
+func compileStandardJSON(ctx context.Context, compilerVersion string, input *SourceCode) (*SolcOutput, error) {
+	solc, err := solcPath(ctx, compilerVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, solc, "--standard-json")
+	cmd.Stdin = bytes.NewReader(bs)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("solc: %w: %s", err, stderr.String())
+	}
+
+	output := &SolcOutput{}
+	if err := json.Unmarshal(stdout.Bytes(), output); err != nil {
+		return nil, fmt.Errorf("solc: %w", err)
+	}
+
+	return output, nil
+}
+
+// compileWithOutput compiles input with solc compilerVersion requesting selection, failing on compilation errors.
+func compileWithOutput(ctx context.Context, compilerVersion string, input *SourceCode, selection OutputSelection) (*SolcOutput, error) {
+	input, err := withOutputSelection(input, selection)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := compileStandardJSON(ctx, compilerVersion, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := compileErrors(output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// verifyCompiles compiles the reconstructed standard-json input and fails on compilation errors.
+func verifyCompiles(ctx context.Context, compilerVersion string, input *SourceCode) error {
+	if !strings.EqualFold(input.Language, "Solidity") && input.Language != "" {
+		return fmt.Errorf("cannot compile %s sources", input.Language)
+	}
+
+	output, err := compileStandardJSON(ctx, compilerVersion, input)
+	if err != nil {
+		return err
+	}
+
+	return compileErrors(output)
+}
+
+func compileErrors(output *SolcOutput) error {
+	messages := []string{}
+	for _, e := range output.Errors {
+		if e.Severity == "error" {
+			messages = append(messages, e.FormattedMessage)
+		}
+	}
+
+	if len(messages) > 0 {
+		return errors.New("compilation failed:\n" + strings.Join(messages, "\n"))
+	}
+
+	return nil
+}
+
+// withOutputSelection returns a copy of input whose settings request selection instead of the verified outputSelection.
+func withOutputSelection(input *SourceCode, selection OutputSelection) (*SourceCode, error) {
+	bs, err := json.Marshal(input.Settings)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[string]json.RawMessage{}
+	if err := json.Unmarshal(bs, &settings); err != nil {
+		return nil, err
+	}
+
+	if settings["outputSelection"], err = json.Marshal(selection); err != nil {
+		return nil, err
+	}
+
+	if bs, err = json.Marshal(settings); err != nil {
+		return nil, err
+	}
+
+	out := *input
+	out.Settings = Settings{}
+	if err := json.Unmarshal(bs, &out.Settings); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+func getJSON(ctx context.Context, url string, v interface{}) error {
+	resp, err := httpGet(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+func parseContractCode(rawCodes []*RawCode) ([]*SourceCode, error) {
+	sourceCodes := make([]*SourceCode, 0, len(rawCodes))
+	for _, rawCode := range rawCodes {
+		sourceCode := &SourceCode{}
+		if err := json.Unmarshal([]byte(rawCode.SourceCode[1:len(rawCode.SourceCode)-1]), sourceCode); err != nil {
+			return []*SourceCode{flatSourceCode(rawCodes[0])}, nil
+		}
+
+		sourceCodes = append(sourceCodes, sourceCode)
+	}
+
+	return sourceCodes, nil
+}
+
+// flatSourceCode builds the standard-json input of a single-file verification as main.sol,
+// taking the settings from the explorer's fields.
+func flatSourceCode(rawCode *RawCode) *SourceCode {
+	runs, _ := strconv.Atoi(rawCode.Runs)
+
+	return &SourceCode{
+		Language: "Solidity",
+		Sources:  Sources{"main.sol": &Contract{Content: rawCode.SourceCode}},
+		Settings: Settings{
+			Optimizer:  &Optimizer{Enabled: rawCode.OptimizationUsed == "1", Runs: runs},
+			EVMVersion: evmVersion(rawCode.EVMVersion),
+			OutputSelection: OutputSelection{
+				"*": {"*": {"abi", "evm.bytecode", "evm.deployedBytecode"}},
+			},
+			Libraries: flatLibraries("main.sol", rawCode.Library),
+		},
+	}
+}
+
+// evmVersion returns the solc evmVersion setting for the explorer's EVMVersion field,
+// which is "Default" when the compiler's default was used.
+func evmVersion(v string) string {
+	if strings.EqualFold(v, "default") {
+		return ""
+	}
+
+	return v
+}
+
+// SourceCodeFields
+type SourceCode struct {
+	Language string   `json:"language"`
+	Sources  Sources  `json:"sources"`
+	Settings Settings `json:"settings"`
+}
+
+type Sources map[string]*Contract
+
+type Contract struct {
+	Content string `json:"content"`
+}
+
+// Settings are the solc settings of a verification.
+// Only the fields used by this tool are typed; the verified JSON is kept as is and
+// marshaled unchanged, so fields like viaIR or metadata survive the round trip.
+type Settings struct {
+	Remappings      []string        `json:"remappings,omitempty"`
+	Optimizer       *Optimizer      `json:"optimizer"`
+	EVMVersion      string          `json:"evmVersion,omitempty"`
+	OutputSelection OutputSelection `json:"outputSelection"`
+	Libraries       Libraries       `json:"libraries"`
+
+	raw json.RawMessage
+}
+
+func (s *Settings) UnmarshalJSON(bs []byte) error {
+	type settings Settings
+	if err := json.Unmarshal(bs, (*settings)(s)); err != nil {
+		return err
+	}
+
+	s.raw = append(json.RawMessage(nil), bs...)
+	return nil
+}
+
+func (s Settings) MarshalJSON() ([]byte, error) {
+	if s.raw != nil {
+		return s.raw, nil
+	}
+
+	type settings Settings
+	return json.Marshal(settings(s))
+}
+
+type Optimizer struct {
+	Enabled bool `json:"enabled"`
+	Runs    int  `json:"runs"`
+}
+
+type OutputSelection map[string]map[string][]string
+
+// Libraries maps a source file to the addresses of the libraries linked into it, by library name.
+type Libraries map[string]map[string]string
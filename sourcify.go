@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const defaultSourcifyURL = "https://sourcify.dev/server"
+
+// sourcifyContract is a contract verified on Sourcify, as returned by its v2 API.
+type sourcifyContract struct {
+	Match   string  `json:"match"` // exact_match or match
+	Sources Sources `json:"sources"`
+}
+
+// addSourcifyFlag adds the flag selecting the Sourcify server to fs.
+func addSourcifyFlag(fs *flag.FlagSet) *string {
+	return fs.String("sourcify-url", defaultSourcifyURL, "URL of the Sourcify server")
+}
+
+// fetchSourcify returns the sources of address on ch verified on the Sourcify server at baseURL,
+// errNotVerified when Sourcify has no match.
+func fetchSourcify(ctx context.Context, baseURL string, ch chain, address string) (*sourcifyContract, error) {
+	u := fmt.Sprintf("%s/v2/contract/%d/%s?fields=sources", strings.TrimSuffix(baseURL, "/"), ch, address)
+	resp, err := httpGet(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w on sourcify", errNotVerified)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sourcify: %s", resp.Status)
+	}
+
+	c := &sourcifyContract{}
+	if err := json.NewDecoder(limitBody(resp.Body)).Decode(c); err != nil {
+		return nil, fmt.Errorf("decode sourcify response: %w", err)
+	}
+
+	return c, nil
+}
+
+// submitSourcify submits s for the verification of address on ch to the Sourcify server at baseURL,
+// checking the status of the job every interval until it completes, and returns the match, e.g. exact_match.
+func submitSourcify(ctx context.Context, baseURL string, ch chain, address string, s *verifySubmission, interval time.Duration) (string, error) {
+	base := strings.TrimSuffix(baseURL, "/")
+
+	body, err := json.Marshal(map[string]interface{}{
+		"stdJsonInput":       json.RawMessage(s.input),
+		"compilerVersion":    strings.TrimPrefix(s.compilerVersion, "v"),
+		"contractIdentifier": s.contractName,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	job := &struct {
+		VerificationID string `json:"verificationId"`
+	}{}
+	err = sourcifyDo(ctx, http.MethodPost, fmt.Sprintf("%s/v2/verify/%d/%s", base, ch, address), body, job)
+	var apiErr *sourcifyError
+	if errors.As(err, &apiErr) && apiErr.CustomCode == "already_verified" {
+		return "already verified", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		status := &struct {
+			IsJobCompleted bool `json:"isJobCompleted"`
+			Contract       struct {
+				Match string `json:"match"`
+			} `json:"contract"`
+			Error *sourcifyError `json:"error"`
+		}{}
+		if err := sourcifyDo(ctx, http.MethodGet, base+"/v2/verify/"+job.VerificationID, nil, status); err != nil {
+			return "", err
+		}
+
+		if status.IsJobCompleted {
+			if status.Error != nil {
+				return "", fmt.Errorf("verification failed: %w", status.Error)
+			}
+			return status.Contract.Match, nil
+		}
+
+		t := time.NewTimer(interval)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return "", ctx.Err()
+		}
+	}
+}
+
+// sourcifyError is an error of the Sourcify API.
+type sourcifyError struct {
+	CustomCode string `json:"customCode"`
+	Message    string `json:"message"`
+}
+
+func (e *sourcifyError) Error() string { return e.CustomCode + ": " + e.Message }
+
+// sourcifyDo sends a request with the JSON body to the Sourcify API and decodes its response into v,
+// returning the message of an error response as the error.
+func sourcifyDo(ctx context.Context, method string, u string, body []byte, v interface{}) error {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		e := &sourcifyError{}
+		if err := json.NewDecoder(limitBody(resp.Body)).Decode(e); err != nil || e.Message == "" {
+			return fmt.Errorf("%s %s: %s", method, u, resp.Status)
+		}
+		return e
+	}
+
+	return json.NewDecoder(limitBody(resp.Body)).Decode(v)
+}
+
+// crosscheckCommand fetches the targets from both the explorer and Sourcify and prints the files
+// where the two disagree, failing with errDrift when they do for any target.
+func crosscheckCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	sourcifyURL := addSourcifyFlag(fs)
+
+	return func(ctx context.Context, args []string) error {
+		c, err := loadConfig()
+		if errors.Is(err, os.ErrNotExist) {
+			// addresses can be cross-checked without a config
+			c, err = &Config{}, nil
+		}
+		if err != nil {
+			return err
+		}
+
+		deployments, err := c.deployments(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		disagree := 0
+		for _, d := range deployments {
+			explorer, err := fetchCompared(ctx, d)
+			if err != nil && !errors.Is(err, errNotVerified) {
+				return fmt.Errorf("%s: %w", d.Name, err)
+			}
+
+			verified, sourcifyErr := fetchSourcify(ctx, *sourcifyURL, d.Chain, d.Address)
+			if sourcifyErr != nil && !errors.Is(sourcifyErr, errNotVerified) {
+				return fmt.Errorf("%s: %w", d.Name, sourcifyErr)
+			}
+
+			switch {
+			case err != nil && sourcifyErr != nil:
+				fmt.Printf("%s: verified on neither\n", d.Name)
+				continue
+			case sourcifyErr != nil:
+				fmt.Printf("%s: not verified on sourcify\n", d.Name)
+				continue
+			case err != nil:
+				disagree++
+				fmt.Printf("%s: verified on sourcify (%s) only\n", d.Name, verified.Match)
+				continue
+			}
+
+			// "D" only on the explorer, "A" only on Sourcify
+			changes := compareSources(explorer.sources, verified.Sources)
+			if len(changes) == 0 {
+				fmt.Printf("%s: agree (%s)\n", d.Name, verified.Match)
+				continue
+			}
+
+			disagree++
+			fmt.Printf("%s: disagree on %d files (%s)\n", d.Name, len(changes), verified.Match)
+			for _, change := range changes {
+				fmt.Printf("%s %s\n", change.Status, path.Join(d.Name, change.Path))
+			}
+		}
+
+		if disagree > 0 {
+			return fmt.Errorf("%w: %d of %d contracts", errDrift, disagree, len(deployments))
+		}
+
+		return nil
+	}
+}
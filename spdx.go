@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// spdxID returns the SPDX identifier of the explorer's license type, or "" when it has none (e.g. "None")
+// or isn't one of the explorer's, as there is then no identifier to write.
+func spdxID(licenseType string) string {
+	if _, ok := explorerLicenses[strings.ToLower(strings.TrimSpace(licenseType))]; !ok {
+		return ""
+	}
+
+	return spdxLicense(licenseType)
+}
+
+// normalizeSPDX inserts the SPDX line for id into a Solidity source lacking one and,
+// with replace, rewrites a conflicting one. It returns the source and what was done, "" when nothing.
+func normalizeSPDX(source string, id string, replace bool) (string, string) {
+	m := spdxPattern.FindStringSubmatchIndex(source)
+	if m == nil {
+		// after a byte order mark, if any
+		bom := ""
+		if strings.HasPrefix(source, "\ufeff") {
+			bom, source = "\ufeff", strings.TrimPrefix(source, "\ufeff")
+		}
+
+		return bom + "// SPDX-License-Identifier: " + id + "\n" + source, "inserted " + id
+	}
+
+	current := source[m[2]:m[3]]
+	if current == id || !replace {
+		return source, ""
+	}
+
+	return source[:m[2]] + id + source[m[3]:], fmt.Sprintf("replaced %s with %s", current, id)
+}
+
+// spdxMode validates the --spdx flag, returning whether conflicting lines are replaced.
+func spdxMode(mode string) (bool, error) {
+	switch strings.ToLower(mode) {
+	case "", "insert":
+		return false, nil
+	case "normalize":
+		return true, nil
+	}
+
+	return false, fmt.Errorf("unknown --spdx mode: %s, want insert or normalize", mode)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+)
+
+// runStats prints the files, lines of Solidity, compiler, license and proxy of each contract in contractDir,
+// followed by the totals and the distributions of compiler versions and licenses.
+func runStats(ctx context.Context, args []string) error {
+	c, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	contracts, err := findDownloaded(c.ContractDir)
+	if err != nil {
+		return err
+	}
+
+	compilers := map[string]int{}
+	licenses := map[string]int{}
+	files, lines, proxies := 0, 0, 0
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONTRACT\tFILES\tSOLIDITY LINES\tCOMPILER\tLICENSE\tPROXY")
+	for _, dc := range contracts {
+		rel, err := filepath.Rel(c.ContractDir, dc.Dir)
+		if err != nil {
+			return err
+		}
+
+		n := 0
+		for _, file := range dc.Files {
+			if filepath.Ext(file) != ".sol" {
+				continue
+			}
+
+			bs, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			n += countLines(bs)
+		}
+
+		m := dc.Metadata
+		proxy := "no"
+		if m.Proxy {
+			proxy = "yes"
+			proxies++
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\n", filepath.ToSlash(rel), len(dc.Files), n, m.CompilerVersion, m.LicenseType, proxy)
+
+		files += len(dc.Files)
+		lines += n
+		compilers[firstNonEmpty(m.CompilerVersion, "(unknown)")]++
+		licenses[firstNonEmpty(m.LicenseType, "(unknown)")]++
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%d contracts (%d proxies), %d files, %d lines of Solidity\n", len(contracts), proxies, files, lines)
+
+	for _, dist := range []struct {
+		title  string
+		counts map[string]int
+	}{
+		{"compiler versions", compilers},
+		{"licenses", licenses},
+	} {
+		fmt.Printf("\n%s\n", dist.title)
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, k := range sortedByCount(dist.counts) {
+			fmt.Fprintf(tw, "  %s\t%d\n", k, dist.counts[k])
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// countLines returns the number of lines of bs, counting a last line without a newline.
+func countLines(bs []byte) int {
+	n := bytes.Count(bs, []byte("\n"))
+	if len(bs) > 0 && !bytes.HasSuffix(bs, []byte("\n")) {
+		n++
+	}
+	return n
+}
+
+// sortedByCount returns the keys of counts, the most frequent first.
+func sortedByCount(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	return keys
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const storageLayoutFile = "storage-layout.json"
+
+// writeStorageLayout compiles the sources with storageLayout output and saves the layout of the verified contract.
+func writeStorageLayout(ctx context.Context, dir string, rawCode *RawCode, sourceCode *SourceCode) error {
+	output, err := compileWithOutput(ctx, rawCode.CompilerVersion, sourceCode, OutputSelection{"*": {"*": {"storageLayout"}}})
+	if err != nil {
+		return err
+	}
+
+	_, compiled := findContract(output, rawCode.ContractName)
+	if compiled == nil || len(compiled.StorageLayout) == 0 {
+		return fmt.Errorf("no storage layout for %s in compiler output", rawCode.ContractName)
+	}
+
+	return os.WriteFile(filepath.Join(dir, storageLayoutFile), append(compiled.StorageLayout, '\n'), fileMode)
+}
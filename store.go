@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const storeDir = ".store"
+
+const (
+	hardlink = "hardlink"
+	symlink  = "symlink"
+)
+
+// contentStore stores file contents once by their SHA-256 under dir and links them into contract trees.
+type contentStore struct {
+	dir  string
+	mode string // hardlink or symlink
+}
+
+func newContentStore(contractDir string, mode string) (*contentStore, error) {
+	if mode != hardlink && mode != symlink {
+		return nil, fmt.Errorf("unknown dedup mode: %s (want %s or %s)", mode, hardlink, symlink)
+	}
+
+	return &contentStore{dir: filepath.Join(contractDir, storeDir), mode: mode}, nil
+}
+
+// put stores content if it is not stored yet and returns its path in the store.
+func (s *contentStore) put(content []byte) (string, error) {
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	path := filepath.Join(s.dir, hash[:2], hash)
+
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), dirMode); err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return "", err
+	}
+
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	return path, os.Rename(f.Name(), path)
+}
+
+// link stores content and links it to dst, replacing any existing file.
+func (s *contentStore) link(dst string, content []byte) error {
+	stored, err := s.put(content)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if s.mode == hardlink {
+		return os.Link(stored, dst)
+	}
+
+	target, err := filepath.Rel(filepath.Dir(dst), stored)
+	if err != nil {
+		return err
+	}
+
+	return os.Symlink(target, dst)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sourceTenderly is the source of contracts fetched from a Tenderly project instead of the explorer.
+const sourceTenderly = "tenderly"
+
+const (
+	defaultTenderlyEndpoint  = "https://api.tenderly.co/api/v1"
+	defaultTenderlyAccessEnv = "TENDERLY_ACCESS_KEY"
+)
+
+// TenderlyConfig is the Tenderly project the contracts with "source": "tenderly" are fetched from,
+// those pushed to it or added from a fork, private or public.
+type TenderlyConfig struct {
+	Account string `json:"account"` // the slug of the account or organization owning the project
+	Project string `json:"project"` // the slug of the project
+	// AccessKeyEnv is the environment variable the access token is read from, TENDERLY_ACCESS_KEY by default.
+	AccessKeyEnv string `json:"accessKeyEnv,omitempty"`
+	// Endpoint is the base URL of Tenderly's API, for tests or a proxy.
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// tenderlyProject is the TenderlyConfig of config.json, nil without one.
+var tenderlyProject *TenderlyConfig
+
+// validate checks the settings of t, which may be nil for no Tenderly project.
+func (t *TenderlyConfig) validate() error {
+	if t == nil {
+		return nil
+	}
+
+	if t.Account == "" || t.Project == "" {
+		return errors.New("tenderly: set the account and project slugs")
+	}
+	if t.Endpoint != "" {
+		if u, err := url.Parse(t.Endpoint); err != nil || u.Host == "" {
+			return fmt.Errorf("tenderly: bad endpoint %q", t.Endpoint)
+		}
+	}
+
+	return nil
+}
+
+// accessKey returns the access token of the project, failing when the environment variable isn't set.
+func (t *TenderlyConfig) accessKey() (string, error) {
+	env := firstNonEmpty(t.AccessKeyEnv, defaultTenderlyAccessEnv)
+	key := os.Getenv(env)
+	if key == "" {
+		return "", &configError{fmt.Errorf("tenderly: %s is not set, create an access token in the account settings of Tenderly", env)}
+	}
+
+	return key, nil
+}
+
+// contractURL returns the URL of the contract at address on ch in the project.
+func (t *TenderlyConfig) contractURL(ch chain, address string) string {
+	return fmt.Sprintf("%s/account/%s/project/%s/contract/%d/%s", strings.TrimSuffix(firstNonEmpty(t.Endpoint, defaultTenderlyEndpoint), "/"),
+		url.PathEscape(t.Account), url.PathEscape(t.Project), ch, strings.ToLower(address))
+}
+
+// tenderlyContract is a contract of a Tenderly project, with its sources when they were pushed or verified.
+type tenderlyContract struct {
+	ContractName      string `json:"contract_name"`
+	CompilerVersion   string `json:"compiler_version"`
+	EVMVersion        string `json:"evm_version"`
+	Language          string `json:"language"`
+	OptimizationsUsed bool   `json:"optimizations_used"`
+	OptimizationRuns  int    `json:"optimization_runs"`
+	Data              *struct {
+		ContractInfo []struct {
+			Path   string `json:"path"`
+			Name   string `json:"name"`
+			Source string `json:"source"`
+		} `json:"contract_info"`
+		ABI json.RawMessage `json:"abi"`
+	} `json:"data"`
+}
+
+// getTenderlySourceResponse fetches the contract at address on ch from the Tenderly project t,
+// translated to the RawCode of a getsourcecode response so it is downloaded like any other.
+// A contract the project doesn't have, or has without sources, is unverified.
+func getTenderlySourceResponse(ctx context.Context, t *TenderlyConfig, ch chain, address string) (*sourceResponse, error) {
+	var r *sourceResponse
+	err := retryRateLimited(ctx, firstNonEmpty(t.Endpoint, defaultTenderlyEndpoint), false, func() (err error) {
+		r, err = getTenderlySourceResponseOnce(ctx, t, ch, address)
+		return err
+	})
+
+	return r, err
+}
+
+func getTenderlySourceResponseOnce(ctx context.Context, t *TenderlyConfig, ch chain, address string) (*sourceResponse, error) {
+	key, err := t.accessKey()
+	if err != nil {
+		return nil, err
+	}
+
+	u := t.contractURL(ch, address)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Access-Key", key)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := explorerDoer(ctx).Do(req)
+	if err != nil {
+		return nil, redactURLError(err)
+	}
+	defer resp.Body.Close()
+
+	body, h, raw := readResponse(limitBody(resp.Body))
+
+	r := &sourceResponse{url: u, fetchedAt: time.Now().UTC()}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		io.Copy(io.Discard, body)
+		r.codes = []*RawCode{{Abi: "Contract source code not verified"}}
+		r.sha256 = hex.EncodeToString(h.Sum(nil))
+		if raw != nil {
+			r.raw = raw.Bytes()
+		}
+		return r, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, fmt.Errorf("tenderly: %s, check the access token and that it can read %s/%s", resp.Status, t.Account, t.Project)
+	case http.StatusTooManyRequests:
+		return nil, &rateLimitedError{err: fmt.Errorf("%w: tenderly: %s", errRateLimited, resp.Status), retryAfter: retryAfter(resp.Header, time.Now())}
+	default:
+		return nil, fmt.Errorf("tenderly: %s", resp.Status)
+	}
+
+	bs, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// the project's contracts wrap the contract in "contract", the public ones are the contract itself
+	wrapped := &struct {
+		Contract *tenderlyContract `json:"contract"`
+	}{}
+	if err := json.Unmarshal(bs, wrapped); err != nil {
+		return nil, fmt.Errorf("decode tenderly response: %w", err)
+	}
+	c := wrapped.Contract
+	if c == nil {
+		c = &tenderlyContract{}
+		if err := json.Unmarshal(bs, c); err != nil {
+			return nil, fmt.Errorf("decode tenderly response: %w", err)
+		}
+	}
+
+	code, err := c.rawCode()
+	if err != nil {
+		return nil, err
+	}
+	r.codes = []*RawCode{code}
+	r.sha256 = hex.EncodeToString(h.Sum(nil))
+	if raw != nil {
+		r.raw = raw.Bytes()
+	}
+
+	return r, nil
+}
+
+// rawCode returns the contract as the RawCode of Etherscan's getsourcecode, its sources as a standard json input.
+func (c *tenderlyContract) rawCode() (*RawCode, error) {
+	if c.Data == nil || len(c.Data.ContractInfo) == 0 {
+		return &RawCode{Abi: "Contract source code not verified", ContractName: c.ContractName}, nil
+	}
+
+	language := "Solidity"
+	if strings.EqualFold(c.Language, "vyper") {
+		language = "Vyper"
+	}
+
+	input := &SourceCode{
+		Language: language,
+		Sources:  Sources{},
+		Settings: Settings{
+			Optimizer:  &Optimizer{Enabled: c.OptimizationsUsed, Runs: c.OptimizationRuns},
+			EVMVersion: evmVersion(c.EVMVersion),
+			OutputSelection: OutputSelection{
+				"*": {"*": {"abi", "evm.bytecode", "evm.deployedBytecode"}},
+			},
+		},
+	}
+	for _, info := range c.Data.ContractInfo {
+		path := firstNonEmpty(info.Path, info.Name)
+		if path == "" {
+			return nil, errors.New("tenderly: a source without a path")
+		}
+		input.Sources[path] = &Contract{Content: info.Source}
+	}
+
+	bs, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	version := c.CompilerVersion
+	if version != "" && !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	if language == "Vyper" {
+		version = "vyper:" + strings.TrimPrefix(c.CompilerVersion, "v")
+	}
+
+	optimization := "0"
+	if c.OptimizationsUsed {
+		optimization = "1"
+	}
+
+	return &RawCode{
+		// Etherscan wraps standard json inputs in a second pair of braces
+		SourceCode:       "{" + string(bs) + "}",
+		Abi:              string(c.Data.ABI),
+		ContractName:     c.ContractName,
+		CompilerVersion:  version,
+		OptimizationUsed: optimization,
+		Runs:             strconv.Itoa(c.OptimizationRuns),
+		EVMVersion:       firstNonEmpty(c.EVMVersion, "Default"),
+	}, nil
+}
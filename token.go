@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+)
+
+// TokenMetadata is the token a contract implements, in metadata.json.
+type TokenMetadata struct {
+	Standard string `json:"standard"` // erc20 or erc721
+	Name     string `json:"name,omitempty"`
+	Symbol   string `json:"symbol,omitempty"`
+	Decimals *int   `json:"decimals,omitempty"`
+}
+
+// selectors of the token getters
+const (
+	nameSelector     = "0x06fdde03"
+	symbolSelector   = "0x95d89b41"
+	decimalsSelector = "0x313ce567"
+)
+
+// tokenStandard returns the token standard the ABI implements, or "" when it is not a token.
+func tokenStandard(abi []*ABIEntry) string {
+	functions := map[string]bool{}
+	for _, e := range abi {
+		if e.Type == "function" {
+			functions[e.Name] = true
+		}
+	}
+
+	switch {
+	case functions["ownerOf"] && functions["balanceOf"]:
+		return "erc721"
+	case functions["decimals"] && functions["balanceOf"] && functions["transfer"]:
+		return "erc20"
+	}
+
+	return ""
+}
+
+// fetchTokenMetadata calls the name, symbol and, for ERC-20, decimals getters of the token d.
+// It returns nil when the ABI is not a token's. The getters are optional in both standards,
+// so one that reverts leaves its field out.
+func fetchTokenMetadata(ctx context.Context, d *deployment, abi []*ABIEntry) (*TokenMetadata, error) {
+	standard := tokenStandard(abi)
+	if standard == "" {
+		return nil, nil
+	}
+
+	explorer, ok := blockExploers[d.Chain]
+	if !ok {
+		return nil, unsupportedChain(d.Chain)
+	}
+
+	token := &TokenMetadata{Standard: standard}
+
+	for _, getter := range []struct {
+		selector string
+		dst      *string
+	}{
+		{nameSelector, &token.Name},
+		{symbolSelector, &token.Symbol},
+	} {
+		result, err := ethCall(ctx, explorer, d.Address, getter.selector)
+		if errors.Is(err, errReverted) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		*getter.dst = decodeStringResult(result)
+	}
+
+	if standard == "erc20" {
+		result, err := ethCall(ctx, explorer, d.Address, decimalsSelector)
+		if err != nil && !errors.Is(err, errReverted) {
+			return nil, err
+		}
+
+		if err == nil {
+			n, ok := new(big.Int).SetString(strings.TrimPrefix(result, "0x"), 16)
+			if !ok || !n.IsInt64() || n.Int64() > 255 {
+				return nil, fmt.Errorf("decimals: bad result %s", result)
+			}
+			decimals := int(n.Int64())
+			token.Decimals = &decimals
+		}
+	}
+
+	return token, nil
+}
+
+// decodeStringResult decodes the result of a getter returning a string,
+// or a bytes32 as some early tokens do, returning "" when it is neither.
+func decodeStringResult(result string) string {
+	bs, err := hex.DecodeString(strings.TrimPrefix(result, "0x"))
+	if err != nil {
+		return ""
+	}
+
+	if len(bs) == 32 {
+		s := bytes.TrimRight(bs, "\x00")
+		if utf8.Valid(s) {
+			return string(s)
+		}
+		return ""
+	}
+
+	if len(bs) < 64 {
+		return ""
+	}
+
+	offset := new(big.Int).SetBytes(bs[:32])
+	if !offset.IsInt64() || offset.Int64()+32 > int64(len(bs)) {
+		return ""
+	}
+	start := offset.Int64() + 32
+
+	length := new(big.Int).SetBytes(bs[start-32 : start])
+	if !length.IsInt64() || start+length.Int64() > int64(len(bs)) {
+		return ""
+	}
+
+	s := bs[start : start+length.Int64()]
+	if !utf8.Valid(s) {
+		return ""
+	}
+
+	return string(s)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Spans are exported as OTLP/HTTP JSON to $OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, or $OTEL_EXPORTER_OTLP_ENDPOINT/v1/traces.
+// Without either, spans are not recorded.
+
+// span kinds of OTLP
+const (
+	spanInternal = 1
+	spanServer   = 2
+	spanClient   = 3
+)
+
+type span struct {
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     int
+	start    time.Time
+	attrs    map[string]string
+	remote   bool // a parent propagated from another service, not recorded here
+}
+
+type spanKey struct{}
+
+// startSpan starts a span named name as a child of ctx's span, with attrs given as key, value pairs.
+func startSpan(ctx context.Context, name string, kind int, attrs ...string) (context.Context, *span) {
+	s := &span{name: name, kind: kind, start: time.Now(), attrs: map[string]string{}}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		s.attrs[attrs[i]] = attrs[i+1]
+	}
+
+	if parent, ok := ctx.Value(spanKey{}).(*span); ok {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	rand.Read(s.spanID[:])
+
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func (s *span) set(key, value string) {
+	s.attrs[key] = value
+}
+
+// end finishes the span, marking it as failed with err if not nil.
+func (s *span) end(err error) {
+	if t := activeTracer(); t != nil {
+		t.record(s, time.Now(), err)
+	}
+}
+
+// injectTraceparent sets the W3C traceparent header of ctx's span on h.
+func injectTraceparent(ctx context.Context, h http.Header) {
+	if s, ok := ctx.Value(spanKey{}).(*span); ok {
+		h.Set("traceparent", "00-"+hex.EncodeToString(s.traceID[:])+"-"+hex.EncodeToString(s.spanID[:])+"-01")
+	}
+}
+
+// extractTraceparent returns ctx with the remote parent span of a W3C traceparent header in h, if any.
+func extractTraceparent(ctx context.Context, h http.Header) context.Context {
+	parts := strings.Split(h.Get("traceparent"), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ctx
+	}
+
+	s := &span{remote: true}
+	if _, err := hex.Decode(s.traceID[:], []byte(parts[1])); err != nil {
+		return ctx
+	}
+	if _, err := hex.Decode(s.spanID[:], []byte(parts[2])); err != nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// otlpExporter batches finished spans and posts them to an OTLP/HTTP endpoint.
+type otlpExporter struct {
+	endpoint string
+	client   *http.Client
+
+	mu    sync.Mutex
+	spans []*otlpSpan
+}
+
+var (
+	tracerOnce sync.Once
+	tracer     *otlpExporter
+)
+
+// activeTracer returns the exporter of the endpoint of the environment, started by the first span to end, or nil without an endpoint.
+func activeTracer() *otlpExporter {
+	tracerOnce.Do(func() { tracer = newOTLPExporter() })
+
+	return tracer
+}
+
+func newOTLPExporter() *otlpExporter {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		if base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); base != "" {
+			endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+		}
+	}
+	if endpoint == "" {
+		return nil
+	}
+
+	e := &otlpExporter{endpoint: endpoint, client: &http.Client{Timeout: 10 * time.Second}}
+	go func() {
+		for range time.Tick(5 * time.Second) {
+			e.flush()
+		}
+	}()
+
+	return e
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []*otlpKeyValue `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"` // 1 ok, 2 error
+	Message string `json:"message,omitempty"`
+}
+
+func (e *otlpExporter) record(s *span, end time.Time, err error) {
+	o := &otlpSpan{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(end.UnixNano(), 10),
+		Status:            &otlpStatus{Code: 1},
+	}
+	if s.parentID != [8]byte{} {
+		o.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	for k, v := range s.attrs {
+		kv := &otlpKeyValue{Key: k}
+		kv.Value.StringValue = v
+		o.Attributes = append(o.Attributes, kv)
+	}
+	if err != nil {
+		o.Status = &otlpStatus{Code: 2, Message: redactAPIKeys(err.Error())}
+	}
+
+	e.mu.Lock()
+	e.spans = append(e.spans, o)
+	e.mu.Unlock()
+}
+
+// flush posts the recorded spans.
+func (e *otlpExporter) flush() {
+	e.mu.Lock()
+	spans := e.spans
+	e.spans = nil
+	e.mu.Unlock()
+
+	if len(spans) == 0 {
+		return
+	}
+
+	body := map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": []interface{}{map[string]interface{}{
+					"key":   "service.name",
+					"value": map[string]string{"stringValue": "etherscan-downloader"},
+				}},
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]string{"name": "etherscan-downloader"},
+				"spans": spans,
+			}},
+		}},
+	}
+
+	bs, err := json.Marshal(body)
+	if err != nil {
+		return
+	}
+
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(bs))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export traces: %s\n", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		fmt.Fprintf(os.Stderr, "export traces: %s: %s\n", resp.Status, strings.TrimSpace(string(msg)))
+	}
+}
+
+// flushTraces exports the spans recorded so far, before the process exits.
+func flushTraces() {
+	// without a span ended, there is nothing to flush and no exporter to start
+	tracerOnce.Do(func() {})
+	if tracer != nil {
+		tracer.flush()
+	}
+}
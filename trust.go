@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// similar match policies
+const (
+	policyAllow = "allow"
+	policyWarn  = "warn"
+	policyFail  = "fail"
+)
+
+// checkSimilarMatch applies policy to a contract whose source the explorer only shows through a similar match,
+// i.e. the source verified for another contract with the same bytecode.
+func checkSimilarMatch(d *deployment, rawCode *RawCode, policy string) error {
+	if rawCode.SimilarMatch == "" {
+		return nil
+	}
+
+	msg := fmt.Sprintf("source is a similar match of %s, not verified for %s itself", rawCode.SimilarMatch, d.Address)
+
+	switch policy {
+	case policyAllow:
+		return nil
+	case "", policyWarn:
+		fmt.Fprintf(os.Stderr, "%s: warning: %s\n", d.Name, msg)
+		return nil
+	case policyFail:
+		return fmt.Errorf("%s", msg)
+	}
+
+	return fmt.Errorf("unknown similar match policy: %s", policy)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const clearScreen = "\033[H\033[2J"
+
+// tui is an interactive session over the configured contracts.
+type tui struct {
+	ctx context.Context
+	c   *Config
+	dl  *downloader
+	in  *bufio.Reader
+	out io.Writer
+	log []string
+}
+
+// runTUI lists the configured contracts with their status and lets the operator download, diff and browse them.
+func runTUI(ctx context.Context, args []string) error {
+	if !isTerminal(os.Stdin) {
+		return errors.New("tui needs a terminal")
+	}
+
+	c, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	dl, err := addDownloadFlags(flag.NewFlagSet("download", flag.ContinueOnError)).downloader(c)
+	if err != nil {
+		return err
+	}
+	ctx = dl.withClient(ctx)
+
+	t := &tui{ctx: ctx, c: c, dl: dl, in: bufio.NewReader(os.Stdin), out: os.Stdout}
+	for {
+		t.render()
+
+		line, err := t.in.ReadString('\n')
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "q" {
+			return nil
+		}
+
+		t.handle(fields[0], fields[1:])
+	}
+}
+
+func (t *tui) render() {
+	fmt.Fprint(t.out, clearScreen)
+	fmt.Fprintf(t.out, "%-4s %-24s %-6s %-44s %s\n", "#", "NAME", "CHAIN", "ADDRESS", "LOCAL")
+	for i, name := range t.c.names() {
+		ch, address, err := t.c.Contracts[name].resolve()
+		if err != nil {
+			address = err.Error()
+		}
+		fmt.Fprintf(t.out, "%-4d %-24s %-6s %-44s %s\n", i+1, name, ch, address, localStatus(t.c.dir(name)))
+	}
+
+	if len(t.log) > 0 {
+		fmt.Fprintf(t.out, "\n%s\n", t.log[len(t.log)-1])
+	}
+
+	fmt.Fprint(t.out, "\n[d]ownload <#...|all>  [f] diff <#>  [t]ree <#>  [l]og  [q]uit > ")
+}
+
+// handle runs the action cmd on the contracts selected by args.
+func (t *tui) handle(cmd string, args []string) {
+	switch cmd {
+	case "l":
+		t.page(strings.Join(t.log, "\n"))
+		return
+	case "d", "f", "t":
+	default:
+		t.logf("unknown command: %s", cmd)
+		return
+	}
+
+	names, err := t.selected(args)
+	if err != nil {
+		t.logf("%s", err)
+		return
+	}
+
+	for _, name := range names {
+		switch cmd {
+		case "d":
+			t.download(name)
+		case "f":
+			t.diff(name)
+		case "t":
+			t.tree(name)
+		}
+	}
+}
+
+// selected returns the names of the contracts numbered in args, or all of them for "all".
+func (t *tui) selected(args []string) ([]string, error) {
+	names := t.c.names()
+	if len(args) == 1 && args[0] == "all" {
+		return names, nil
+	}
+	if len(args) == 0 {
+		return nil, errors.New("select contracts by number")
+	}
+
+	selected := make([]string, 0, len(args))
+	for _, arg := range args {
+		i, err := strconv.Atoi(arg)
+		if err != nil || i < 1 || i > len(names) {
+			return nil, fmt.Errorf("no contract #%s", arg)
+		}
+		selected = append(selected, names[i-1])
+	}
+
+	return selected, nil
+}
+
+func (t *tui) download(name string) {
+	d, err := t.c.configured(name)
+	if err != nil {
+		t.logf("%s: %s", name, err)
+		return
+	}
+
+	start := time.Now()
+	if err := t.dl.download(t.ctx, d); err != nil {
+		t.logf("%s: download failed: %s", name, err)
+		return
+	}
+
+	t.logf("%s: downloaded in %s", name, time.Since(start).Round(time.Millisecond))
+}
+
+func (t *tui) diff(name string) {
+	d, err := t.c.configured(name)
+	if err != nil {
+		t.logf("%s: %s", name, err)
+		return
+	}
+	if d.unresolved() {
+		if err := d.downloaded(d.dir(t.c.ContractDir)); err != nil {
+			t.logf("%s: %s", name, err)
+			return
+		}
+	}
+
+	changes, err := diffDeployment(t.ctx, t.c, d, d.dir(t.c.ContractDir))
+	if err != nil {
+		t.logf("%s: diff failed: %s", name, err)
+		return
+	}
+
+	lines := []string{}
+	for _, change := range changes {
+		lines = append(lines, change.Status+" "+change.Path)
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "no changes")
+	}
+
+	t.logf("%s: %d files differ", name, len(changes))
+	t.page(name + "\n\n" + strings.Join(lines, "\n"))
+}
+
+// tree shows the files downloaded for name.
+func (t *tui) tree(name string) {
+	root := t.c.dir(name)
+
+	lines := []string{root}
+	err := filepath.WalkDir(root, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		entry := e.Name()
+		if e.IsDir() {
+			entry += "/"
+		}
+		lines = append(lines, strings.Repeat("  ", strings.Count(rel, string(filepath.Separator))+1)+entry)
+
+		return nil
+	})
+	if err != nil {
+		t.logf("%s: %s", name, err)
+		return
+	}
+
+	t.page(strings.Join(lines, "\n"))
+}
+
+// page shows text until enter is pressed.
+func (t *tui) page(text string) {
+	fmt.Fprint(t.out, clearScreen)
+	fmt.Fprintln(t.out, text)
+	fmt.Fprint(t.out, "\n(enter to go back) ")
+	t.in.ReadString('\n')
+}
+
+func (t *tui) logf(format string, a ...interface{}) {
+	t.log = append(t.log, time.Now().Format("15:04:05")+" "+fmt.Sprintf(format, a...))
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// flattenedFilePattern matches the markers flatteners leave before each file, e.g. truffle-flattener's
+	// "// File: @openzeppelin/contracts/math/SafeMath.sol" or hardhat's "// File @openzeppelin/contracts/utils/Context.sol@v4.9.3".
+	flattenedFilePattern = regexp.MustCompile(`(?m)^[ \t]*//[ \t]*File:?[ \t]+(\S+?\.sol)(?:@\S*)?[ \t]*\r?$`)
+	pragmaPattern        = regexp.MustCompile(`(?m)^[ \t]*pragma[ \t]+solidity[^;]*;[ \t]*\r?\n?`)
+	topLevelPattern      = regexp.MustCompile(`(?m)^(?:abstract[ \t]+)?(?:contract|interface|library|struct|enum|error|function|type)[ \t]+([A-Za-z_$][A-Za-z0-9_$]*)`)
+	identifierPattern    = regexp.MustCompile(`[A-Za-z_$][A-Za-z0-9_$]*`)
+)
+
+// unflattenedFile is a file of a flattened source, between its marker and the next.
+type unflattenedFile struct {
+	path    string
+	content string
+	defines []string
+}
+
+// unflatten splits a single-file verification carrying the markers of a flattener back into the files it was made of,
+// adding to each the imports of the earlier files declaring what it uses, as flatteners write dependencies first.
+// It reports false when sources is not a single flattened file.
+func unflatten(sources Sources) (Sources, bool) {
+	if len(sources) != 1 {
+		return nil, false
+	}
+	var content string
+	for _, source := range sources {
+		content = source.Content
+	}
+
+	markers := flattenedFilePattern.FindAllStringSubmatchIndex(content, -1)
+	if len(markers) < 2 {
+		return nil, false
+	}
+
+	// the preamble's pragma, e.g. of hardhat's header, is given to the files without their own
+	pragma := pragmaPattern.FindString(content[:markers[0][0]])
+
+	files := []*unflattenedFile{}
+	seen := map[string]bool{}
+	for i, m := range markers {
+		p := path.Clean(strings.TrimPrefix(content[m[2]:m[3]], "/"))
+		if seen[p] || strings.HasPrefix(p, "../") {
+			return nil, false
+		}
+		seen[p] = true
+
+		end := len(content)
+		if i+1 < len(markers) {
+			end = markers[i+1][0]
+		}
+		body := strings.Trim(content[m[1]:end], "\r\n") + "\n"
+
+		f := &unflattenedFile{path: p, content: body}
+		for _, def := range topLevelPattern.FindAllStringSubmatch(commentPattern.ReplaceAllString(body, ""), -1) {
+			f.defines = append(f.defines, def[1])
+		}
+		files = append(files, f)
+	}
+
+	declaredBy := map[string]*unflattenedFile{}
+	split := Sources{}
+	for _, f := range files {
+		imports := map[string]bool{}
+		for _, id := range identifierPattern.FindAllString(commentPattern.ReplaceAllString(f.content, ""), -1) {
+			if dep, ok := declaredBy[id]; ok && dep != f {
+				imports[relativeImport(f.path, dep.path)] = true
+			}
+		}
+
+		split[f.path] = &Contract{Content: withImports(f.content, pragma, imports)}
+
+		for _, id := range f.defines {
+			if _, ok := declaredBy[id]; !ok {
+				declaredBy[id] = f
+			}
+		}
+	}
+
+	return split, true
+}
+
+// relativeImport returns the import path of dep from the file at from, e.g. "../utils/Context.sol".
+func relativeImport(from string, dep string) string {
+	fromDir := strings.Split(path.Dir(from), "/")
+	if path.Dir(from) == "." {
+		fromDir = nil
+	}
+	depParts := strings.Split(dep, "/")
+
+	common := 0
+	for common < len(fromDir) && common < len(depParts)-1 && fromDir[common] == depParts[common] {
+		common++
+	}
+
+	rel := strings.Repeat("../", len(fromDir)-common) + strings.Join(depParts[common:], "/")
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+
+	return rel
+}
+
+// withImports adds imports after the pragma of content, and pragma when content has none.
+func withImports(content string, pragma string, imports map[string]bool) string {
+	if len(imports) == 0 && (pragma == "" || pragmaPattern.MatchString(content)) {
+		return content
+	}
+
+	paths := make([]string, 0, len(imports))
+	for p := range imports {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	b := &strings.Builder{}
+	for _, p := range paths {
+		b.WriteString("import \"" + p + "\";\n")
+	}
+	if len(paths) > 0 {
+		b.WriteString("\n")
+	}
+
+	loc := pragmaPattern.FindStringIndex(content)
+	if loc == nil {
+		head := strings.TrimRight(pragma, "\r\n")
+		if head != "" {
+			head += "\n\n"
+		}
+		return head + b.String() + content
+	}
+
+	at := loc[1]
+	sep := "\n"
+	if !strings.HasSuffix(content[:at], "\n") {
+		sep = "\n\n"
+	}
+
+	return content[:at] + sep + b.String() + strings.TrimLeft(content[at:], "\r\n")
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	unverifiedFile   = "UNVERIFIED"
+	bytecodeFile     = "bytecode.hex"
+	heuristicABIFile = "abi.heuristic.json"
+)
+
+const (
+	opEQ     = 0x14
+	opPUSH1  = 0x60
+	opPUSH4  = 0x63
+	opPUSH32 = 0x7f
+)
+
+// HeuristicABIEntry is a function recovered from bytecode. Only its selector is known.
+type HeuristicABIEntry struct {
+	Type     string `json:"type"`
+	Selector string `json:"selector"`
+}
+
+// isUnverified reports whether the explorer has no verified source for the contract.
+func isUnverified(rawCodes []*RawCode) bool {
+	return len(rawCodes) == 0 || rawCodes[0].SourceCode == ""
+}
+
+// saveUnverified saves the runtime bytecode of an unverified contract with a best-effort ABI recovered from its
+// function dispatcher, and marks the directory as UNVERIFIED. It returns the bytecode.
+func saveUnverified(ctx context.Context, dir string, d *deployment) ([]byte, error) {
+	explorer, ok := blockExploers[d.Chain]
+	if !ok {
+		return nil, unsupportedChain(d.Chain)
+	}
+
+	code, err := getCode(ctx, explorer, d.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	bytecode, err := decodeHex(code)
+	if err != nil {
+		return nil, fmt.Errorf("on-chain code: %w", err)
+	}
+
+	if len(bytecode) == 0 {
+		return nil, fmt.Errorf("no source code and no code at %s on chain %d", d.Address, d.Chain)
+	}
+
+	if err := os.MkdirAll(dir, dirMode); err != nil {
+		return nil, err
+	}
+
+	marker := fmt.Sprintf("source code of %s on chain %d is not verified\n", d.Address, d.Chain)
+	if err := os.WriteFile(filepath.Join(dir, unverifiedFile), []byte(marker), fileMode); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, bytecodeFile), []byte(code+"\n"), fileMode); err != nil {
+		return nil, err
+	}
+
+	abi := []*HeuristicABIEntry{}
+	for _, selector := range dispatcherSelectors(bytecode) {
+		abi = append(abi, &HeuristicABIEntry{Type: "function", Selector: selector})
+	}
+
+	return bytecode, writeJSON(filepath.Join(dir, heuristicABIFile), abi)
+}
+
+// dispatcherSelectors returns the function selectors compared against in the bytecode's dispatcher,
+// i.e. 4-byte pushes shortly followed by EQ.
+func dispatcherSelectors(code []byte) []string {
+	type op struct {
+		code byte
+		arg  []byte
+	}
+
+	ops := []op{}
+	for pc := 0; pc < len(code); pc++ {
+		o := op{code: code[pc]}
+		if o.code >= opPUSH1 && o.code <= opPUSH32 {
+			n := int(o.code-opPUSH1) + 1
+			end := pc + 1 + n
+			if end > len(code) {
+				end = len(code)
+			}
+			o.arg = code[pc+1 : end]
+			pc += n
+		}
+		ops = append(ops, o)
+	}
+
+	seen := map[string]bool{}
+	for i, o := range ops {
+		if o.code != opPUSH4 || len(o.arg) != 4 {
+			continue
+		}
+
+		for j := i + 1; j < len(ops) && j <= i+2; j++ {
+			if ops[j].code == opEQ {
+				selector := "0x" + hex.EncodeToString(o.arg)
+				if selector != "0x00000000" && selector != "0xffffffff" {
+					seen[selector] = true
+				}
+				break
+			}
+		}
+	}
+
+	selectors := make([]string, 0, len(seen))
+	for selector := range seen {
+		selectors = append(selectors, selector)
+	}
+	sort.Strings(selectors)
+
+	return selectors
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	unverifiedCacheFile  = "unverified.json"
+	defaultUnverifiedTTL = time.Hour
+)
+
+// unverifiedCache remembers when contracts were found unverified, in unverified.json in the user cache directory,
+// so the runs of a big config don't query them again until ttl passed. A nil cache remembers nothing.
+type unverifiedCache struct {
+	path    string
+	ttl     time.Duration
+	refresh bool // --refresh: query every contract, still recording the outcome
+
+	mu      sync.Mutex
+	checked map[string]time.Time // when each unverified chain:address was found so, nil until loaded
+}
+
+// newUnverifiedCache returns the cache of unverified contracts kept for ttl, e.g. "6h" (default 1h), nil when ttl is "0".
+func newUnverifiedCache(ttl string, refresh bool) (*unverifiedCache, error) {
+	d := defaultUnverifiedTTL
+	if ttl != "" {
+		var err error
+		if d, err = time.ParseDuration(ttl); err != nil {
+			return nil, fmt.Errorf("unverifiedTTL: %w", err)
+		}
+		if d < 0 {
+			return nil, errors.New("unverifiedTTL: can't be negative")
+		}
+	}
+	if d == 0 {
+		return nil, nil
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		// without a cache directory there is nothing to remember across runs
+		return nil, nil
+	}
+
+	return &unverifiedCache{path: filepath.Join(cacheDir, "etherscan-downloader", unverifiedCacheFile), ttl: d, refresh: refresh}, nil
+}
+
+// unverifiedKey returns the key of d in the cache, the contract of an explorer and of a Tenderly project told apart.
+func unverifiedKey(d *deployment) string {
+	if d.Source != "" {
+		return fmt.Sprintf("%s:%d:%s", d.Source, d.Chain, strings.ToLower(d.Address))
+	}
+
+	return fmt.Sprintf("%d:%s", d.Chain, strings.ToLower(d.Address))
+}
+
+// cachedAt returns when d was found unverified, if it was less than ttl ago and --refresh isn't set.
+func (c *unverifiedCache) cachedAt(d *deployment) (time.Time, bool, error) {
+	if c == nil || c.refresh {
+		return time.Time{}, false, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return time.Time{}, false, err
+	}
+
+	at, ok := c.checked[unverifiedKey(d)]
+	if !ok || time.Since(at) >= c.ttl {
+		return time.Time{}, false, nil
+	}
+
+	return at, true, nil
+}
+
+// record saves whether d was found unverified, forgetting it once verified.
+func (c *unverifiedCache) record(d *deployment, unverified bool) error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return err
+	}
+
+	key := unverifiedKey(d)
+	if _, ok := c.checked[key]; !ok && !unverified {
+		return nil
+	}
+
+	if unverified {
+		c.checked[key] = time.Now().UTC()
+	} else {
+		delete(c.checked, key)
+	}
+
+	for k, at := range c.checked {
+		if time.Since(at) >= c.ttl {
+			delete(c.checked, k)
+		}
+	}
+
+	bs, err := json.Marshal(c.checked)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), dirMode); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(c.path, bs); err != nil {
+		return fmt.Errorf("unverified cache: %w", err)
+	}
+
+	return nil
+}
+
+// load reads the cache the first time it is used.
+func (c *unverifiedCache) load() error {
+	if c.checked != nil {
+		return nil
+	}
+
+	c.checked = map[string]time.Time{}
+
+	bs, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unverified cache: %w", err)
+	}
+
+	if err := json.Unmarshal(bs, &c.checked); err != nil {
+		// a corrupt cache only costs the queries it saved
+		fmt.Fprintf(os.Stderr, "warning: unverified cache %s: %s, starting over\n", c.path, err)
+		c.checked = map[string]time.Time{}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// updateCommand shows the unified diffs between the downloaded and the verified sources of each target
+// and downloads the targets whose changes are confirmed, or all of them with --yes.
+func updateCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	flags := addDownloadFlags(fs)
+	yes := fs.Bool("yes", false, "apply the changes without asking")
+
+	return func(ctx context.Context, args []string) error {
+		if !*yes && !isTerminal(os.Stdin) {
+			return errors.New("stdin is not a terminal, pass --yes to apply the changes without confirming")
+		}
+
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		dl, err := flags.downloader(c)
+		if err != nil {
+			return err
+		}
+		ctx = dl.withClient(ctx)
+
+		deployments, err := c.deployments(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		p := &prompter{r: bufio.NewReader(os.Stdin), w: os.Stderr}
+		accepted := []*deployment{}
+		stale := map[*deployment][]string{}
+		for _, d := range deployments {
+			dir := d.dir(c.ContractDir)
+
+			// compared with the files the download writes, so that it leaves none to remove or add next time
+			changes, remote, err := dl.sourceDiff().diff(ctx, d, dir)
+			if err != nil {
+				return fmt.Errorf("%s: %w", d.Name, err)
+			}
+			if len(changes) == 0 {
+				fmt.Fprintf(humanOut, "%s: up to date\n", d.Name)
+				continue
+			}
+
+			for _, change := range changes {
+				local := ""
+				if change.Status != "A" {
+					bs, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(change.Path)))
+					if err != nil {
+						return err
+					}
+					local = string(bs)
+				}
+
+				verified := ""
+				if change.Status != "D" {
+					verified = remote[change.Path]
+				} else {
+					stale[d] = append(stale[d], change.Path)
+				}
+
+				name := path.Join(d.Name, change.Path)
+				fmt.Print(unifiedDiff("a/"+name, "b/"+name, local, verified))
+			}
+
+			if !*yes {
+				answer, err := p.ask(fmt.Sprintf("apply %d changed files of %s? [y/N]", len(changes), d.Name), "")
+				if err != nil {
+					return err
+				}
+				if a := strings.ToLower(answer); a != "y" && a != "yes" {
+					fmt.Fprintf(humanOut, "%s: skipped\n", d.Name)
+					continue
+				}
+			}
+			accepted = append(accepted, d)
+		}
+
+		if len(accepted) == 0 {
+			return nil
+		}
+
+		if err := dl.downloadAll(ctx, accepted); err != nil {
+			return err
+		}
+
+		// the files no longer verified are removed, as downloads only add and overwrite files
+		for _, d := range accepted {
+			for _, file := range stale[d] {
+				if err := os.Remove(filepath.Join(d.dir(c.ContractDir), filepath.FromSlash(file))); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+				fmt.Fprintf(humanOut, "%s: removed %s\n", d.Name, file)
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// humanOut receives the messages for people, stdout unless it carries machine-readable output.
+var humanOut io.Writer = os.Stdout
+
+// verifyChecks are the checks run against downloaded sources.
+type verifyChecks struct {
+	compiles     bool
+	bytecode     bool
+	metadataHash bool
+}
+
+func (v *verifyChecks) run(ctx context.Context, d *deployment, compilerVersion string, contractName string, sourceCode *SourceCode) error {
+	if (v.compiles || v.bytecode || v.metadataHash) && blockExploers[d.Chain].api == zksyncAPI {
+		// solc's output isn't what zksolc deploys to EraVM
+		return errors.New("the checks compile with solc, the contracts of a zkSync explorer are compiled by zksolc")
+	}
+
+	if v.compiles {
+		if err := verifyCompiles(ctx, compilerVersion, sourceCode); err != nil {
+			return err
+		}
+	}
+
+	if v.bytecode {
+		match, err := verifyBytecode(ctx, d, compilerVersion, contractName, sourceCode)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(humanOut, "%s: bytecode match: %s\n", d.Name, match)
+		if match == bytecodeMismatch {
+			return errors.New("deployed bytecode does not match the verified sources")
+		}
+	}
+
+	if v.metadataHash {
+		if err := verifyMetadataHash(ctx, d, compilerVersion, contractName, sourceCode); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(humanOut, "%s: metadata hash reproduced\n", d.Name)
+	}
+
+	return nil
+}
+
+// verifyCommand runs the checks against already downloaded sources, using their metadata.json and standard-input.json.
+// Without check flags, all checks run.
+func verifyCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	checks := &verifyChecks{}
+	fs.BoolVar(&checks.compiles, "compiles", false, "compile the sources with the verified compiler and settings")
+	fs.BoolVar(&checks.bytecode, "bytecode", false, "compare the compiled runtime bytecode with the deployed code")
+	fs.BoolVar(&checks.metadataHash, "metadata-hash", false, "reproduce the metadata hash in the deployed bytecode")
+
+	return func(ctx context.Context, args []string) error {
+		if !checks.compiles && !checks.bytecode && !checks.metadataHash {
+			checks = &verifyChecks{compiles: true, bytecode: true, metadataHash: true}
+		}
+
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		deployments, err := c.deployments(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range deployments {
+			dir := d.dir(c.ContractDir)
+
+			m, err := loadMetadata(filepath.Join(dir, metadataFile))
+			if err != nil {
+				return fmt.Errorf("%s: %w", d.Name, err)
+			}
+
+			input, err := loadStandardInput(filepath.Join(dir, standardInputFile))
+			if err != nil {
+				return fmt.Errorf("%s: %w", d.Name, err)
+			}
+
+			if err := checks.run(ctx, d, m.CompilerVersion, m.ContractName, input); err != nil {
+				return fmt.Errorf("%s: %w", d.Name, err)
+			}
+		}
+
+		return nil
+	}
+}
+
+func loadStandardInput(path string) (*SourceCode, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &SourceCode{}
+	if err := json.Unmarshal(bs, input); err != nil {
+		return nil, err
+	}
+
+	return input, nil
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// verifySubmission is a verification to submit to the explorer's verifysourcecode API.
+type verifySubmission struct {
+	input           []byte // the standard-json input, sent as is
+	contractName    string // <source path>:<contract>
+	compilerVersion string
+	constructorArgs string // hex, without 0x
+	licenseType     string
+}
+
+// verifySubmitCommand submits a standard-json input for verification of the target's address
+// and waits for the explorer, or Sourcify, to process it. The settings default to the target's download.
+func verifySubmitCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	input := fs.String("input", "", "standard-json input to submit (default the target's standard-input.json)")
+	contract := fs.String("contract", "", "contract to verify as <source path>:<name> (default the target's metadata.json contract)")
+	compiler := fs.String("compiler", "", "compiler version, e.g. v0.8.19+commit.7dd6d404 (default the target's metadata.json)")
+	constructorArgs := fs.String("constructor-args", "", "ABI-encoded constructor arguments in hex (default the target's metadata.json)")
+	license := fs.String("license", "", "license, e.g. MIT or 3 (default the target's metadata.json)")
+	poll := fs.Duration("poll", 5*time.Second, "interval of checking the verification status")
+	sourcify := fs.Bool("sourcify", false, "submit the verification to Sourcify instead of the explorer")
+	sourcifyURL := addSourcifyFlag(fs)
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return errors.New("usage: verify-submit [flags] <target>")
+		}
+
+		c, err := loadConfig()
+		if errors.Is(err, os.ErrNotExist) {
+			// addresses can be verified without a config, given the flags
+			c, err = &Config{}, nil
+		}
+		if err != nil {
+			return err
+		}
+
+		d, err := c.deployment(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		dir := d.dir(c.ContractDir)
+
+		inputPath := firstNonEmpty(*input, filepath.Join(dir, standardInputFile))
+		bs, err := os.ReadFile(inputPath)
+		if err != nil {
+			return err
+		}
+		sourceCode := &SourceCode{}
+		if err := json.Unmarshal(bs, sourceCode); err != nil {
+			return fmt.Errorf("%s: %w", inputPath, err)
+		}
+
+		m := &Metadata{}
+		if *contract == "" || *compiler == "" {
+			if m, err = loadMetadata(filepath.Join(dir, metadataFile)); err != nil {
+				return fmt.Errorf("%w (or pass --contract and --compiler)", err)
+			}
+		}
+
+		contractName := *contract
+		if contractName == "" {
+			file, ok := mainSource(sourceCode.Sources, m.ContractName)
+			if !ok {
+				return fmt.Errorf("no source of %s defines %s, pass --contract", inputPath, m.ContractName)
+			}
+			contractName = file + ":" + m.ContractName
+		}
+
+		s := &verifySubmission{
+			input:           bs,
+			contractName:    contractName,
+			compilerVersion: firstNonEmpty(*compiler, m.CompilerVersion),
+			constructorArgs: strings.TrimPrefix(firstNonEmpty(*constructorArgs, m.ConstructorArguments), "0x"),
+			licenseType:     firstNonEmpty(*license, m.LicenseType),
+		}
+
+		if *sourcify {
+			match, err := submitSourcify(ctx, *sourcifyURL, d.Chain, d.Address, s, *poll)
+			if err != nil {
+				return fmt.Errorf("%s: sourcify: %w", d.Name, err)
+			}
+			fmt.Printf("%s: %s on sourcify\n", d.Name, match)
+
+			return nil
+		}
+
+		explorer, ok := blockExploers[d.Chain]
+		if !ok {
+			return unsupportedChain(d.Chain)
+		}
+
+		guid, err := submitVerification(ctx, explorer, d.Address, s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", d.Name, err)
+		}
+		if guid == "" {
+			fmt.Printf("%s: already verified\n", d.Name)
+			return nil
+		}
+		fmt.Fprintf(os.Stderr, "%s: submitted %s, guid %s\n", d.Name, contractName, guid)
+
+		result, err := waitVerification(ctx, explorer, guid, *poll)
+		if err != nil {
+			return fmt.Errorf("%s: %w", d.Name, err)
+		}
+		fmt.Printf("%s: %s\n", d.Name, result)
+
+		return nil
+	}
+}
+
+// submitVerification submits s for the verification of address and returns the GUID to check its status with,
+// "" when the contract is already verified.
+func submitVerification(ctx context.Context, explorer blockExplorer, address string, s *verifySubmission) (string, error) {
+	form := url.Values{
+		"contractaddress":       {address},
+		"sourceCode":            {string(s.input)},
+		"codeformat":            {"solidity-standard-json-input"},
+		"contractname":          {s.contractName},
+		"compilerversion":       {s.compilerVersion},
+		"constructorArguements": {s.constructorArgs}, // sic
+	}
+	if s.licenseType != "" {
+		code, ok := explorerLicenseCode(s.licenseType)
+		if !ok {
+			return "", fmt.Errorf("unknown license: %s", s.licenseType)
+		}
+		form.Set("licenseType", strconv.Itoa(code))
+	}
+
+	params := url.Values{"module": {"contract"}, "action": {"verifysourcecode"}}
+	if key := explorer.key(); key != "" {
+		params.Set("apikey", key)
+	}
+	u := strings.TrimSuffix(explorer.endpoint, "/") + "/api?" + params.Encode()
+
+	var r *explorerResponse
+	err := retryRateLimited(ctx, explorer.endpoint, !explorer.hasKey(), func() (err error) {
+		r, err = explorerStatus(ctx, explorer, u, form)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	result := resultString(r)
+	switch {
+	case r.Status == "1":
+		return result, nil
+	case strings.Contains(strings.ToLower(result), "already verified"):
+		return "", nil
+	}
+
+	return "", explorerError(r)
+}
+
+// waitVerification checks the status of the verification guid every interval until the explorer processed it,
+// returning its result, e.g. "Pass - Verified".
+func waitVerification(ctx context.Context, explorer blockExplorer, guid string, interval time.Duration) (string, error) {
+	params := url.Values{"module": {"contract"}, "action": {"checkverifystatus"}, "guid": {guid}}
+	if key := explorer.key(); key != "" {
+		params.Set("apikey", key)
+	}
+	u := strings.TrimSuffix(explorer.endpoint, "/") + "/api?" + params.Encode()
+
+	for {
+		var r *explorerResponse
+		err := retryRateLimited(ctx, explorer.endpoint, !explorer.hasKey(), func() (err error) {
+			r, err = explorerStatus(ctx, explorer, u, nil)
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+
+		result := resultString(r)
+		lower := strings.ToLower(result)
+		switch {
+		case r.Status == "1", strings.Contains(lower, "already verified"):
+			return result, nil
+		case !strings.Contains(lower, "pending"):
+			return "", fmt.Errorf("verification failed: %s", result)
+		}
+
+		t := time.NewTimer(interval)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return "", ctx.Err()
+		}
+	}
+}
+
+// explorerStatus sends a request to the explorer API, a POST of form unless it is nil, and returns its response
+// whatever its status, as the verification API reports progress like "Pending in queue" with status 0.
+// Rate limits are returned as errors, to be retried.
+func explorerStatus(ctx context.Context, explorer blockExplorer, u string, form url.Values) (*explorerResponse, error) {
+	send := explorerGet
+	if form != nil {
+		send = func(ctx context.Context, explorer blockExplorer, u string) (*http.Response, error) {
+			return explorerPost(ctx, explorer, u, form)
+		}
+	}
+
+	resp, err := send(ctx, explorer, u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	r := &explorerResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(r); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	if r.Status != "1" && strings.Contains(strings.ToLower(resultString(r)), "rate limit") {
+		return nil, explorerError(r)
+	}
+
+	return r, nil
+}
+
+// resultString returns the result of r when it is a string, like the results of the verification API.
+func resultString(r *explorerResponse) string {
+	var s string
+	if err := json.Unmarshal(r.Result, &s); err != nil {
+		return string(r.Result)
+	}
+
+	return s
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// set at build time with
+// go build -ldflags "-X main.version=v1.2.3 -X main.commit=$(git rev-parse HEAD) -X main.date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
+
+const latestReleaseURL = "https://api.github.com/repos/nasjp/etherscan-downloader/releases/latest"
+
+// versionCommand prints the build information, and with --check whether a newer release exists.
+func versionCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	check := fs.Bool("check", false, "check GitHub for a newer release")
+
+	return func(ctx context.Context, args []string) error {
+		c, d := buildInfo()
+		fmt.Printf("etherscan-downloader %s (commit %s, built %s, %s %s/%s)\n", version, c, d, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+		if !*check {
+			return nil
+		}
+
+		latest, err := latestRelease(ctx)
+		if err != nil {
+			return fmt.Errorf("check for updates: %w", err)
+		}
+
+		if version != "dev" && compareVersions(latest, version) <= 0 {
+			fmt.Println("up to date")
+			return nil
+		}
+
+		fmt.Printf("%s is available: https://github.com/nasjp/etherscan-downloader/releases/tag/%s\n", latest, latest)
+
+		return nil
+	}
+}
+
+// buildInfo returns the commit and date of the build,
+// falling back to the VCS information go embeds when they weren't set with -ldflags.
+func buildInfo() (string, string) {
+	c, d := commit, date
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && c == "":
+				c = s.Value
+			case s.Key == "vcs.time" && d == "":
+				d = s.Value
+			}
+		}
+	}
+
+	if c == "" {
+		c = "unknown"
+	}
+	if d == "" {
+		d = "unknown"
+	}
+
+	return c, d
+}
+
+// latestRelease returns the tag of the latest GitHub release.
+func latestRelease(ctx context.Context) (string, error) {
+	resp, err := httpGet(ctx, latestReleaseURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: %s", latestReleaseURL, resp.Status)
+	}
+
+	release := &struct {
+		TagName string `json:"tag_name"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(release); err != nil {
+		return "", err
+	}
+
+	return release.TagName, nil
+}
+
+// compareVersions compares the dot-separated numeric versions a and b (with an optional "v" prefix).
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(strings.SplitN(as[i], "-", 2)[0])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(strings.SplitN(bs[i], "-", 2)[0])
+		}
+
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// uiHandler serves a read-only web UI of the contracts downloaded into contractDir at /ui/:
+// the list of contracts, each contract's metadata and files, its highlighted sources
+// and the diff of the download against the sources currently verified on the explorer.
+type uiHandler struct {
+	contractDir string
+	normalize   *NormalizeConfig
+	service     *sourceService
+}
+
+// uiContract is a downloaded contract as shown by the UI.
+type uiContract struct {
+	Name     string // its directory relative to contractDir
+	Metadata *Metadata
+	Files    []string // relative to its directory
+}
+
+func (h *uiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	contracts, err := h.contracts()
+	if err != nil {
+		log.Print(err)
+		http.Error(w, "listing contractDir failed", http.StatusInternalServerError)
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/ui/")
+	if rest == "" {
+		h.render(w, "index", contracts)
+		return
+	}
+
+	// the longest contract name prefixing the path, as contracts may be nested, e.g. libraries/
+	var c *uiContract
+	for _, candidate := range contracts {
+		if (rest == candidate.Name || strings.HasPrefix(rest, candidate.Name+"/")) && (c == nil || len(candidate.Name) > len(c.Name)) {
+			c = candidate
+		}
+	}
+	if c == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	file := strings.TrimPrefix(strings.TrimPrefix(rest, c.Name), "/")
+	switch {
+	case file == "":
+		h.render(w, "contract", c)
+	case r.URL.Query().Get("view") == "diff" && file == "-":
+		h.renderDiff(w, r, c)
+	default:
+		h.renderSource(w, r, c, file)
+	}
+}
+
+// contracts returns the contracts downloaded into contractDir.
+func (h *uiHandler) contracts() ([]*uiContract, error) {
+	downloaded, err := findDownloaded(h.contractDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	contracts := make([]*uiContract, 0, len(downloaded))
+	for _, dc := range downloaded {
+		rel, err := filepath.Rel(h.contractDir, dc.Dir)
+		if err != nil {
+			return nil, err
+		}
+
+		c := &uiContract{Name: filepath.ToSlash(rel), Metadata: dc.Metadata}
+		for _, file := range dc.Files {
+			fileRel, err := filepath.Rel(dc.Dir, file)
+			if err != nil {
+				return nil, err
+			}
+			c.Files = append(c.Files, filepath.ToSlash(fileRel))
+		}
+		contracts = append(contracts, c)
+	}
+
+	return contracts, nil
+}
+
+func (h *uiHandler) renderSource(w http.ResponseWriter, r *http.Request, c *uiContract, file string) {
+	// only the files found by findDownloaded are served, so the path can't leave contractDir
+	i := sort.SearchStrings(c.Files, file)
+	if i == len(c.Files) || c.Files[i] != file {
+		http.NotFound(w, r)
+		return
+	}
+
+	bs, err := os.ReadFile(filepath.Join(h.contractDir, filepath.FromSlash(c.Name), filepath.FromSlash(file)))
+	if err != nil {
+		log.Print(err)
+		http.Error(w, "reading the source failed", http.StatusInternalServerError)
+		return
+	}
+
+	h.render(w, "source", map[string]interface{}{"Contract": c, "File": file, "Code": highlightSolidity(string(bs))})
+}
+
+// uiDiff is the diff of one file between the download and the explorer.
+type uiDiff struct {
+	Path  string
+	Lines []diffLine
+}
+
+func (h *uiHandler) renderDiff(w http.ResponseWriter, r *http.Request, c *uiContract) {
+	src, err := h.service.source(r.Context(), c.Metadata.Chain, c.Metadata.Address)
+	if err != nil {
+		// errors may contain the request URL and with it the API key
+		log.Printf("%s: %s", c.Metadata.Address, redactAPIKeys(err.Error()))
+		http.Error(w, "explorer request failed", http.StatusBadGateway)
+		return
+	}
+
+	dir := filepath.Join(h.contractDir, filepath.FromSlash(c.Name))
+	diffs := []*uiDiff{}
+	verified := map[string]bool{}
+	for key, content := range src.Sources {
+		file := filepath.ToSlash(sourcePath(key))
+		verified[file] = true
+
+		local, err := os.ReadFile(filepath.Join(dir, sourcePath(key)))
+		if err != nil && !os.IsNotExist(err) {
+			log.Print(err)
+			http.Error(w, "reading the source failed", http.StatusInternalServerError)
+			return
+		}
+
+		if lines := diffLines(splitLines(string(local)), splitLines(h.normalize.apply(content))); changed(lines) {
+			diffs = append(diffs, &uiDiff{Path: file, Lines: withContext(lines)})
+		}
+	}
+
+	for _, file := range c.Files {
+		if !verified[file] && !strings.HasPrefix(file, "libraries/") && !strings.HasPrefix(file, "implementations/") {
+			bs, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+			if err != nil {
+				log.Print(err)
+				http.Error(w, "reading the source failed", http.StatusInternalServerError)
+				return
+			}
+			diffs = append(diffs, &uiDiff{Path: file, Lines: diffLines(splitLines(string(bs)), nil)})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+
+	h.render(w, "diff", map[string]interface{}{"Contract": c, "Diffs": diffs})
+}
+
+// withContext returns the changed lines with diffContext lines around them, marking the gaps with "...".
+func withContext(lines []diffLine) []diffLine {
+	keep := make([]bool, len(lines))
+	for i, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		for j := i - diffContext; j <= i+diffContext; j++ {
+			if j >= 0 && j < len(lines) {
+				keep[j] = true
+			}
+		}
+	}
+
+	result := []diffLine{}
+	for i, l := range lines {
+		switch {
+		case keep[i]:
+			result = append(result, l)
+		case i == 0 || keep[i-1]:
+			result = append(result, diffLine{' ', "..."})
+		}
+	}
+
+	return result
+}
+
+func changed(lines []diffLine) bool {
+	for _, l := range lines {
+		if l.op != ' ' {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *uiHandler) render(w http.ResponseWriter, name string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := uiTemplates.ExecuteTemplate(w, name, data); err != nil {
+		log.Print(err)
+	}
+}
+
+var (
+	solidityToken = regexp.MustCompile(`(?s)//[^\n]*|/\*.*?\*/|"(?:\\.|[^"\\\n])*"|'(?:\\.|[^'\\\n])*'|\b0x[0-9a-fA-F]+\b|\b\d+\b|\b[A-Za-z_$][A-Za-z0-9_$]*\b`)
+
+	solidityKeywords = map[string]bool{}
+)
+
+func init() {
+	for _, k := range strings.Fields(`pragma solidity import from as contract abstract interface library is using for struct enum event error
+		modifier function constructor fallback receive returns return if else while do break continue try catch revert emit new delete
+		public private internal external pure view payable constant immutable override virtual memory storage calldata indexed anonymous
+		mapping address bool string bytes int uint true false this super assembly unchecked type`) {
+		solidityKeywords[k] = true
+	}
+}
+
+// highlightSolidity returns code as HTML with comments, strings, numbers and keywords in spans of those classes.
+func highlightSolidity(code string) template.HTML {
+	b := &strings.Builder{}
+	last := 0
+	for _, m := range solidityToken.FindAllStringIndex(code, -1) {
+		token := code[m[0]:m[1]]
+
+		class := ""
+		switch {
+		case strings.HasPrefix(token, "//") || strings.HasPrefix(token, "/*"):
+			class = "comment"
+		case token[0] == '"' || token[0] == '\'':
+			class = "string"
+		case token[0] >= '0' && token[0] <= '9':
+			class = "number"
+		case solidityKeywords[token] || isSizedType(token):
+			class = "keyword"
+		default:
+			continue
+		}
+
+		b.WriteString(template.HTMLEscapeString(code[last:m[0]]))
+		b.WriteString(`<span class="` + class + `">` + template.HTMLEscapeString(token) + `</span>`)
+		last = m[1]
+	}
+	b.WriteString(template.HTMLEscapeString(code[last:]))
+
+	return template.HTML(b.String())
+}
+
+var sizedType = regexp.MustCompile(`^(?:u?int|bytes)\d+$`)
+
+// isSizedType reports whether token is a sized elementary type, e.g. uint256 or bytes32.
+func isSizedType(token string) bool {
+	return sizedType.MatchString(token)
+}
+
+var uiTemplates = template.Must(template.New("ui").Funcs(template.FuncMap{
+	"join": path.Join,
+	"op":   func(l diffLine) string { return string(l.op) },
+	"text": func(l diffLine) string { return l.text },
+}).Parse(`
+{{define "head"}}<!doctype html>
+<html><head><meta charset="utf-8"><title>{{.}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { padding: .2em .8em; text-align: left; border-bottom: 1px solid #ddd; }
+pre { background: #f7f7f7; padding: 1em; overflow-x: auto; }
+.comment { color: #6a737d; } .string { color: #032f62; } .number { color: #005cc5; } .keyword { color: #d73a49; font-weight: bold; }
+.add { background: #e6ffed; } .del { background: #ffeef0; }
+</style></head><body>
+{{end}}
+
+{{define "foot"}}</body></html>{{end}}
+
+{{define "index"}}{{template "head" "contracts"}}
+<h1>contracts</h1>
+<table>
+<tr><th>directory</th><th>contract</th><th>chain</th><th>address</th><th>compiler</th><th>license</th><th>proxy</th></tr>
+{{range .}}<tr><td><a href="/ui/{{.Name}}">{{.Name}}</a></td><td>{{.Metadata.ContractName}}</td><td>{{.Metadata.Chain}}</td><td>{{.Metadata.Address}}</td><td>{{.Metadata.CompilerVersion}}</td><td>{{.Metadata.LicenseType}}</td><td>{{if .Metadata.Proxy}}yes{{end}}</td></tr>
+{{end}}</table>
+{{template "foot"}}{{end}}
+
+{{define "contract"}}{{template "head" .Name}}
+<p><a href="/ui/">contracts</a></p>
+<h1>{{.Name}}</h1>
+<table>
+<tr><th>contract</th><td>{{.Metadata.ContractName}}</td></tr>
+<tr><th>chain</th><td>{{.Metadata.Chain}}</td></tr>
+<tr><th>address</th><td>{{.Metadata.Address}}</td></tr>
+<tr><th>compiler</th><td>{{.Metadata.CompilerVersion}}</td></tr>
+<tr><th>optimization</th><td>{{if .Metadata.OptimizationUsed}}{{.Metadata.Runs}} runs{{else}}off{{end}}</td></tr>
+<tr><th>evm version</th><td>{{.Metadata.EVMVersion}}</td></tr>
+<tr><th>license</th><td>{{.Metadata.LicenseType}}</td></tr>
+{{if .Metadata.Proxy}}<tr><th>implementation</th><td>{{.Metadata.Implementation}}</td></tr>{{end}}
+</table>
+<p><a href="/ui/{{.Name}}/-?view=diff">diff against the explorer</a></p>
+<h2>files</h2>
+<ul>
+{{$name := .Name}}{{range .Files}}<li><a href="/ui/{{join $name .}}">{{.}}</a></li>
+{{end}}</ul>
+{{template "foot"}}{{end}}
+
+{{define "source"}}{{template "head" .File}}
+<p><a href="/ui/">contracts</a> / <a href="/ui/{{.Contract.Name}}">{{.Contract.Name}}</a></p>
+<h1>{{.File}}</h1>
+<pre>{{.Code}}</pre>
+{{template "foot"}}{{end}}
+
+{{define "diff"}}{{template "head" .Contract.Name}}
+<p><a href="/ui/">contracts</a> / <a href="/ui/{{.Contract.Name}}">{{.Contract.Name}}</a></p>
+<h1>{{.Contract.Name}}: downloaded vs. verified</h1>
+{{range .Diffs}}<h2>{{.Path}}</h2>
+<pre>{{range .Lines}}<span class="{{if eq (op .) "+"}}add{{else if eq (op .) "-"}}del{{end}}">{{op .}}{{text .}}</span>
+{{end}}</pre>
+{{else}}<p>identical</p>
+{{end}}
+{{template "foot"}}{{end}}
+`))
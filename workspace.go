@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// workspaceConfigFile is the config of each sub-project of a monorepo, found by --workspace.
+const workspaceConfigFile = "etherscan.config.json"
+
+// findWorkspaceConfigs returns the workspaceConfigFile files under root, leaving out hidden directories and node_modules.
+func findWorkspaceConfigs(root string) ([]string, error) {
+	configs := []string{}
+	err := filepath.WalkDir(root, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if e.IsDir() {
+			if path != root && (strings.HasPrefix(e.Name(), ".") || e.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if e.Name() == workspaceConfigFile {
+			configs = append(configs, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(configs)
+
+	return configs, nil
+}
+
+// runWorkspace runs download for the config of every sub-project under the current directory, in the directory of
+// the config so its relative paths are its own, and with the explorers of the built-in table and its own config only.
+// The sub-projects share the explorer responses fetched, besides the rate limits shared by the whole process.
+// A failing sub-project doesn't stop the others unless failFast is set. The commands of the configs, those of whoever
+// wrote the sub-project, are run only with commands: a config with any fails otherwise.
+func runWorkspace(ctx context.Context, failFast bool, commands bool, download func(ctx context.Context, c *Config, fetched *rawCodeCache) error) error {
+	configs, err := findWorkspaceConfigs(".")
+	if err != nil {
+		return err
+	}
+	if len(configs) == 0 {
+		return &configError{fmt.Errorf("no %s under the current directory", workspaceConfigFile)}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	defer os.Chdir(wd)
+
+	defer func(path string) { configPath = path }(configPath)
+	configPath = workspaceConfigFile
+
+	defer func(allowed bool) { configCommandsAllowed = allowed }(configCommandsAllowed)
+	configCommandsAllowed = commands
+
+	// each config configures the explorers anew, not on top of the previous ones
+	explorers, tenderly := blockExploers, tenderlyProject
+	defer func() { blockExploers, tenderlyProject = explorers, tenderly }()
+
+	fetched := newRawCodeCache()
+	var firstErr error
+	failed := 0
+	for _, config := range configs {
+		dir := filepath.Dir(config)
+		fmt.Fprintf(humanOut, "== %s ==\n", config)
+
+		if err := os.Chdir(filepath.Join(wd, dir)); err != nil {
+			return err
+		}
+
+		blockExploers = map[chain]blockExplorer{}
+		for ch, explorer := range explorers {
+			blockExploers[ch] = explorer
+		}
+
+		err := func() error {
+			c, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if fields := c.commandFields(); len(fields) > 0 && !commands {
+				return &configError{fmt.Errorf("%s: commands of a workspace config: not run without --workspace-commands", strings.Join(fields, ", "))}
+			}
+
+			return download(ctx, c, fetched)
+		}()
+		if err == nil {
+			continue
+		}
+
+		if failFast || ctx.Err() != nil || errors.Is(err, errBudgetExhausted) {
+			return fmt.Errorf("%s: %w", config, err)
+		}
+
+		fmt.Fprintf(os.Stderr, "%s: %s\n", config, redactAPIKeys(err.Error()))
+		failed++
+		if firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", config, err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d workspace configs failed, the first: %w", failed, len(configs), firstErr)
+	}
+
+	return nil
+}
+
+// commandFields returns the fields of c running commands besides the explorers' apiKeyCmd, which is run as the
+// config is loaded: the hooks of its contracts, the analyzer, the decompiler and the signing tool.
+func (c *Config) commandFields() []string {
+	fields := []string{}
+	for _, name := range c.names() {
+		if hooks := c.Contracts[name].Hooks; hooks != nil && len(hooks.PostDownload) > 0 {
+			fields = append(fields, "contracts."+name+".hooks")
+		}
+	}
+	if c.Analyzer != nil {
+		fields = append(fields, "analyzer")
+	}
+	if c.Decompiler != nil {
+		fields = append(fields, "decompiler")
+	}
+	if c.Signing != nil {
+		fields = append(fields, "signing")
+	}
+
+	return fields
+}
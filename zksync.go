@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// zksyncAPI is the api of explorers serving the zkSync Era block explorer's own API,
+// which has the verification of a contract at contract_verification/info/<address> instead of getsourcecode.
+const zksyncAPI = "zksync"
+
+// zksyncVerification is the verification info of a contract on the zkSync Era explorer.
+type zksyncVerification struct {
+	Artifacts struct {
+		ABI json.RawMessage `json:"abi"`
+	} `json:"artifacts"`
+	Request struct {
+		CodeFormat            string          `json:"codeFormat"`   // solidity-standard-json-input, solidity-single-file or vyper-multi-file
+		ContractName          string          `json:"contractName"` // e.g. contracts/Vault.sol:Vault
+		SourceCode            json.RawMessage `json:"sourceCode"`   // the standard json input as an object, or the source as a string
+		CompilerSolcVersion   string          `json:"compilerSolcVersion"`
+		CompilerZksolcVersion string          `json:"compilerZksolcVersion"`
+		CompilerVyperVersion  string          `json:"compilerVyperVersion"`
+		OptimizationUsed      bool            `json:"optimizationUsed"`
+		ConstructorArguments  string          `json:"constructorArguments"`
+	} `json:"request"`
+}
+
+// getZksyncSourceResponse fetches the verification of address from the zkSync Era explorer,
+// translated to the RawCode of a getsourcecode response so it is downloaded like any other.
+func getZksyncSourceResponse(ctx context.Context, explorer blockExplorer, address string) (*sourceResponse, error) {
+	var r *sourceResponse
+	err := retryRateLimited(ctx, explorer.endpoint, false, func() (err error) {
+		r, err = getZksyncSourceResponseOnce(ctx, explorer, address)
+		return err
+	})
+
+	return r, err
+}
+
+func getZksyncSourceResponseOnce(ctx context.Context, explorer blockExplorer, address string) (*sourceResponse, error) {
+	u := strings.TrimSuffix(explorer.endpoint, "/") + "/contract_verification/info/" + url.PathEscape(address)
+	resp, err := explorerGet(ctx, explorer, u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, h, raw := readResponse(resp.Body)
+
+	r := &sourceResponse{url: u, fetchedAt: time.Now().UTC()}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		// an unverified contract, which has no source code
+		io.Copy(io.Discard, body)
+		r.codes = []*RawCode{{Abi: "Contract source code not verified"}}
+		r.sha256 = hex.EncodeToString(h.Sum(nil))
+		if raw != nil {
+			r.raw = raw.Bytes()
+		}
+		return r, nil
+	default:
+		return nil, fmt.Errorf("contract_verification/info: %s", resp.Status)
+	}
+
+	v := &zksyncVerification{}
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		return nil, fmt.Errorf("decode contract_verification response: %w", err)
+	}
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		return nil, err
+	}
+
+	code, err := v.rawCode()
+	if err != nil {
+		return nil, err
+	}
+	r.codes = []*RawCode{code}
+	r.sha256 = hex.EncodeToString(h.Sum(nil))
+	if raw != nil {
+		r.raw = raw.Bytes()
+	}
+
+	return r, nil
+}
+
+// rawCode returns the verification as the RawCode of Etherscan's getsourcecode.
+func (v *zksyncVerification) rawCode() (*RawCode, error) {
+	req := &v.Request
+
+	var source string
+	switch {
+	case len(req.SourceCode) > 0 && req.SourceCode[0] == '"':
+		if err := json.Unmarshal(req.SourceCode, &source); err != nil {
+			return nil, fmt.Errorf("decode sourceCode: %w", err)
+		}
+	case len(req.SourceCode) > 0:
+		// Etherscan wraps standard json inputs in a second pair of braces
+		source = "{" + string(req.SourceCode) + "}"
+	}
+
+	name := req.ContractName
+	if i := strings.LastIndexByte(name, ':'); i >= 0 {
+		name = name[i+1:]
+	}
+
+	optimization := "0"
+	if req.OptimizationUsed {
+		optimization = "1"
+	}
+
+	version := req.CompilerSolcVersion
+	if version != "" && !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	if strings.HasPrefix(req.CodeFormat, "vyper") {
+		version = "vyper:" + strings.TrimPrefix(req.CompilerVyperVersion, "v")
+	}
+
+	return &RawCode{
+		SourceCode:            source,
+		Abi:                   string(v.Artifacts.ABI),
+		ContractName:          name,
+		CompilerVersion:       version,
+		OptimizationUsed:      optimization,
+		ConstructorArguments:  strings.TrimPrefix(req.ConstructorArguments, "0x"),
+		CompilerZksolcVersion: req.CompilerZksolcVersion,
+	}, nil
+}
+
+// zksyncRPC calls the JSON-RPC method of a proxy request on the node of a zkSync Era explorer, which has no proxy module.
+func zksyncRPC(ctx context.Context, explorer blockExplorer, params url.Values) (string, error) {
+	action := params.Get("action")
+	if explorer.rpc == "" {
+		return "", fmt.Errorf("%s: the zkSync explorer at %s has no proxy module, set the rpc of its chain in config.json's explorers", action, explorer.host())
+	}
+
+	var args []interface{}
+	switch action {
+	case "eth_getCode":
+		args = []interface{}{params.Get("address"), params.Get("tag")}
+	case "eth_call":
+		args = []interface{}{map[string]string{"to": params.Get("to"), "data": params.Get("data")}, params.Get("tag")}
+	default:
+		return "", fmt.Errorf("%s: not supported by zkSync explorers", action)
+	}
+
+	result := ""
+	if err := rpcCall(ctx, explorer.rpc, action, args, &result); err != nil {
+		return "", fmt.Errorf("rpc: %w", err)
+	}
+
+	return result, nil
+}